@@ -5,6 +5,7 @@ import (
 	"github.com/iamvkosarev/book-shelf/internal/app"
 	"github.com/joho/godotenv"
 	"log"
+	"os"
 )
 
 func main() {
@@ -13,6 +14,25 @@ func main() {
 	if err != nil {
 		log.Fatalf("loading config error: %s\n", err)
 	}
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case app.CommandImportCalibre:
+			if err = app.ImportCalibre(cfg, os.Args[2:], os.Stdout); err != nil {
+				log.Fatalf("calibre import error: %s\n", err)
+			}
+			return
+		case app.CommandBackup:
+			if err = app.Backup(cfg, os.Args[2:], os.Stdout); err != nil {
+				log.Fatalf("backup error: %s\n", err)
+			}
+			return
+		case app.CommandRestore:
+			if err = app.Restore(cfg, os.Args[2:], os.Stdout); err != nil {
+				log.Fatalf("restore error: %s\n", err)
+			}
+			return
+		}
+	}
 	if err = app.Run(cfg); err != nil {
 		log.Fatalf("app error: %s\n", err)
 	}
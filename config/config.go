@@ -30,12 +30,24 @@ type Authorization struct {
 	TokenTTL   time.Duration `env:"TOKEN_TTL"`
 }
 
+type OAI struct {
+	RepositoryName       string `env:"OAI_REPOSITORY_NAME" env-default:"Book Shelf"`
+	RepositoryIdentifier string `env:"OAI_REPOSITORY_IDENTIFIER" env-default:"book-shelf.local"`
+	AdminEmail           string `env:"OAI_ADMIN_EMAIL" env-default:"admin@book-shelf.local"`
+}
+
+type ONIX struct {
+	CurrencyCode string `env:"ONIX_CURRENCY_CODE" env-default:"USD"` // ISO 4217 code of the book prices
+}
+
 type Config struct {
 	Authorization Authorization
 	Http          Http
 	Router        Router
 	Database      Database
 	App           App
+	OAI           OAI
+	ONIX          ONIX
 }
 
 func LoadConfig() (*Config, error) {
@@ -8,9 +8,22 @@ import (
 	"github.com/iamvkosarev/book-shelf/config"
 	"github.com/iamvkosarev/book-shelf/internal/handler"
 	"github.com/iamvkosarev/book-shelf/internal/router"
+	"github.com/iamvkosarev/book-shelf/internal/router/middleware"
+	"github.com/iamvkosarev/book-shelf/internal/storage/calibre"
 	"github.com/iamvkosarev/book-shelf/internal/storage/postgres"
 	"github.com/iamvkosarev/book-shelf/internal/usecase"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/backup"
 	"github.com/iamvkosarev/book-shelf/internal/usecase/books"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/exports"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/harvest"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/highlights"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/imports"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/progress"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/ratings"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/reviews"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/shelves"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/social"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/stats"
 	"github.com/iamvkosarev/book-shelf/pkg/logs"
 	"log/slog"
 	"net/http"
@@ -62,7 +75,67 @@ func Run(cfg *config.Config) error {
 
 	booksStorage := postgres.NewBooksStorage(pool)
 	booksUsecase := books.NewBooksUsecase(booksStorage, authorsUsecase, publishersUsecase, tagsUsecase)
-	booksHandler := handler.NewBookHandler(booksUsecase)
+	if pool != nil {
+		if filled, err := booksUsecase.FillNormalizedTitles(ctx); err != nil {
+			joinedErrors = errors.Join(joinedErrors, fmt.Errorf("failed to fill normalized titles: %w", err))
+			cancel()
+		} else if filled > 0 {
+			log.Info("filled normalized titles", slog.Int("books", filled))
+		}
+	}
+	ratingsUsecase := ratings.NewRatingsUsecase(postgres.NewRatingsStorage(pool))
+	booksHandler := handler.NewBookHandler(booksUsecase, ratingsUsecase, middleware.UserIDFromContext)
+	ratingHandler := handler.NewRatingHandler(ratingsUsecase, middleware.UserIDFromContext)
+
+	importUsecase := imports.NewImportUsecase(
+		imports.ImportUsecaseDeps{
+			BooksUsecase:          booksUsecase,
+			AuthorsUsecase:        authorsUsecase,
+			TagsUsecase:           tagsUsecase,
+			PublishersUsecase:     publishersUsecase,
+			CalibreOpener:         calibre.NewOpener(),
+			CalibreImportsStorage: postgres.NewCalibreImportsStorage(pool),
+			CurrencyCode:          cfg.ONIX.CurrencyCode,
+		},
+	)
+	importHandler := handler.NewImportHandler(importUsecase)
+
+	exportUsecase := exports.NewExportUsecase(booksUsecase, cfg.ONIX.CurrencyCode)
+	exportHandler := handler.NewExportHandler(exportUsecase)
+
+	opdsHandler := handler.NewOPDSHandler(booksUsecase, authorsUsecase, tagsUsecase, publishersUsecase)
+
+	harvestUsecase := harvest.NewHarvestUsecase(
+		harvest.HarvestUsecaseDeps{
+			Storage:           postgres.NewHarvestStorage(pool),
+			BooksUsecase:      booksUsecase,
+			TagsUsecase:       tagsUsecase,
+			PublishersUsecase: publishersUsecase,
+		},
+	)
+	oaiHandler := handler.NewOAIHandler(harvestUsecase, cfg.OAI)
+
+	backupUsecase := backup.NewBackupUsecase(postgres.NewBackupStorage(pool))
+	backupHandler := handler.NewBackupHandler(backupUsecase, middleware.UserIDFromContext)
+
+	highlightsUsecase := highlights.NewHighlightsUsecase(postgres.NewHighlightsStorage(pool), booksUsecase)
+	highlightHandler := handler.NewHighlightHandler(highlightsUsecase, middleware.UserIDFromContext)
+
+	shelvesUsecase := shelves.NewShelvesUsecase(postgres.NewShelvesStorage(pool), booksUsecase)
+	shelfHandler := handler.NewShelfHandler(shelvesUsecase, ratingsUsecase, middleware.UserIDFromContext)
+
+	reviewsUsecase := reviews.NewReviewsUsecase(postgres.NewReviewsStorage(pool))
+	reviewHandler := handler.NewReviewHandler(reviewsUsecase, middleware.UserIDFromContext)
+
+	progressUsecase := progress.NewProgressUsecase(postgres.NewProgressStorage(pool))
+	progressHandler := handler.NewProgressHandler(progressUsecase, middleware.UserIDFromContext)
+	koreaderHandler := handler.NewKOReaderHandler(progressUsecase)
+
+	statsUsecase := stats.NewStatsUsecase(postgres.NewStatsStorage(pool))
+	statsHandler := handler.NewStatsHandler(statsUsecase, middleware.UserIDFromContext)
+
+	socialUsecase := social.NewSocialUsecase(postgres.NewSocialStorage(pool), booksUsecase)
+	socialHandler := handler.NewSocialHandler(socialUsecase, ratingsUsecase, middleware.UserIDFromContext)
 
 	tokenUsecase, err := usecase.NewTokenUsecase(cfg.Authorization)
 	if err != nil {
@@ -86,8 +159,22 @@ func Run(cfg *config.Config) error {
 			TagsHandler:       tagsHandler,
 			BooksHandler:      booksHandler,
 			UserHandler:       usersHandler,
+			ImportHandler:     importHandler,
+			ExportHandler:     exportHandler,
+			OPDSHandler:       opdsHandler,
+			OAIHandler:        oaiHandler,
+			BackupHandler:     backupHandler,
+			HighlightHandler:  highlightHandler,
+			ShelfHandler:      shelfHandler,
+			RatingHandler:     ratingHandler,
+			ReviewHandler:     reviewHandler,
+			ProgressHandler:   progressHandler,
+			KOReaderHandler:   koreaderHandler,
+			StatsHandler:      statsHandler,
+			SocialHandler:     socialHandler,
 			UserRoleChecker:   usersUsecase,
 			UserIDExtractor:   tokenUsecase,
+			UserCredentials:   usersUsecase,
 		},
 	)
 
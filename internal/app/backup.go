@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/config"
+	"github.com/iamvkosarev/book-shelf/internal/storage/postgres"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/backup"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+const (
+	CommandBackup  = "backup"
+	CommandRestore = "restore"
+)
+
+func Backup(cfg *config.Config, args []string, output io.Writer) error {
+	flags := flag.NewFlagSet(CommandBackup, flag.ContinueOnError)
+	flags.SetOutput(output)
+	includeUsers := flags.Bool("users", false, "include users, credentials, roles and ratings")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: " + CommandBackup + " [-users] <archive.zip>")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	backupUsecase, closePool, err := newBackupUsecase(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closePool()
+
+	file, err := os.Create(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	manifest, err := backupUsecase.Backup(ctx, file, *includeUsers)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(flags.Arg(0))
+		return err
+	}
+	fmt.Fprintf(output, "schema version: %d\n", manifest.SchemaVersion)
+	printBackupCounts(output, manifest.Counts, manifest.IncludesUsers)
+	return nil
+}
+
+func Restore(cfg *config.Config, args []string, output io.Writer) error {
+	flags := flag.NewFlagSet(CommandRestore, flag.ContinueOnError)
+	flags.SetOutput(output)
+	conflict := flags.String("conflict", string(backup.ConflictFail), "conflict strategy: fail, skip or overwrite")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: " + CommandRestore + " [-conflict=fail|skip|overwrite] <archive.zip>")
+	}
+	strategy, err := backup.ParseConflictStrategy(*conflict)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	backupUsecase, closePool, err := newBackupUsecase(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closePool()
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	report, err := backupUsecase.Restore(ctx, file, info.Size(), strategy, uuid.Nil)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(
+		output, "archive created at: %s, schema version: %d\n",
+		report.Manifest.CreatedAt.Format("2006-01-02 15:04:05"), report.Manifest.SchemaVersion,
+	)
+	printBackupCounts(output, report.Restored, report.Manifest.IncludesUsers)
+	return nil
+}
+
+func newBackupUsecase(ctx context.Context, cfg *config.Config) (*backup.BackupUsecase, func(), error) {
+	pool, err := postgres.NewPool(ctx, cfg.Database)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize postgres pool: %w", err)
+	}
+	return backup.NewBackupUsecase(postgres.NewBackupStorage(pool)), pool.Close, nil
+}
+
+func printBackupCounts(output io.Writer, counts backup.Counts, includesUsers bool) {
+	fmt.Fprintf(
+		output, "publishers: %d, authors: %d, tags: %d, books: %d\n",
+		counts.Publishers, counts.Authors, counts.Tags, counts.Books,
+	)
+	if includesUsers {
+		fmt.Fprintf(output, "users: %d, ratings: %d\n", counts.Users, counts.Ratings)
+	}
+	if counts.SkippedLinks > 0 {
+		fmt.Fprintf(output, "skipped links to missing authors or tags: %d\n", counts.SkippedLinks)
+	}
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/iamvkosarev/book-shelf/config"
+	"github.com/iamvkosarev/book-shelf/internal/storage/calibre"
+	"github.com/iamvkosarev/book-shelf/internal/storage/postgres"
+	"github.com/iamvkosarev/book-shelf/internal/usecase"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/books"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/imports"
+	"io"
+	"os/signal"
+	"syscall"
+)
+
+const CommandImportCalibre = "import-calibre"
+
+func ImportCalibre(cfg *config.Config, args []string, output io.Writer) error {
+	flags := flag.NewFlagSet(CommandImportCalibre, flag.ContinueOnError)
+	flags.SetOutput(output)
+	dryRun := flags.Bool("dry-run", false, "report planned changes without writing them")
+	createMissing := flags.Bool("create-missing", true, "create missing authors, tags and publishers")
+	force := flags.Bool("force", false, "create books refused as likely duplicates")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: " + CommandImportCalibre + " [-dry-run] [-create-missing=false] [-force] <metadata.db>")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	pool, err := postgres.NewPool(ctx, cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to initialize postgres pool: %w", err)
+	}
+	defer pool.Close()
+
+	authorsUsecase := usecase.NewAuthorsUsecase(postgres.NewAuthorsStorage(pool))
+	publishersUsecase := usecase.NewPublishersUsecase(postgres.NewPublishersStorage(pool))
+	tagsUsecase := usecase.NewTagsUsecase(postgres.NewTagsStorage(pool))
+	booksUsecase := books.NewBooksUsecase(
+		postgres.NewBooksStorage(pool), authorsUsecase, publishersUsecase, tagsUsecase,
+	)
+	if _, err = booksUsecase.FillNormalizedTitles(ctx); err != nil {
+		return err
+	}
+	importUsecase := imports.NewImportUsecase(
+		imports.ImportUsecaseDeps{
+			BooksUsecase:          booksUsecase,
+			AuthorsUsecase:        authorsUsecase,
+			TagsUsecase:           tagsUsecase,
+			PublishersUsecase:     publishersUsecase,
+			CalibreOpener:         calibre.NewOpener(),
+			CalibreImportsStorage: postgres.NewCalibreImportsStorage(pool),
+			CurrencyCode:          cfg.ONIX.CurrencyCode,
+		},
+	)
+
+	report, err := importUsecase.ImportCalibre(
+		ctx, flags.Arg(0), imports.Options{DryRun: *dryRun, CreateMissing: *createMissing, Force: *force},
+	)
+	if err != nil {
+		return err
+	}
+	printCalibreReport(output, report)
+	return nil
+}
+
+func printCalibreReport(output io.Writer, report imports.Report) {
+	counts := make(map[imports.RowStatus]int)
+	for _, row := range report.Rows {
+		counts[row.Status]++
+		if row.Status == imports.RowFailed {
+			fmt.Fprintf(output, "calibre book %d: %v\n", row.Row, row.Errors)
+			for _, id := range row.Candidates {
+				fmt.Fprintf(output, "  likely duplicate of book %s\n", id)
+			}
+			for _, candidate := range row.CandidateRows {
+				fmt.Fprintf(output, "  likely duplicate of calibre book %d\n", candidate)
+			}
+		}
+	}
+	fmt.Fprintf(
+		output, "dry run: %t, planned: %d, created: %d, existing: %d, failed: %d\n",
+		report.DryRun,
+		counts[imports.RowPlanned],
+		counts[imports.RowCreated],
+		counts[imports.RowExisting],
+		counts[imports.RowFailed],
+	)
+	fmt.Fprintf(
+		output, "new authors: %d, new tags: %d, new publishers: %d\n",
+		len(report.Planned.Authors), len(report.Planned.Tags), len(report.Planned.Publishers),
+	)
+	if !report.Orphans.Empty() {
+		fmt.Fprintf(output, "left unused: %s\n", report.Orphans)
+	}
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -21,7 +22,7 @@ type AuthorUsecase interface {
 	AddAuthor(ctx context.Context, input usecase.AddAuthorInput) (uuid.UUID, error)
 	GetAuthor(ctx context.Context, id uuid.UUID) (model.Author, error)
 	UpdateAuthor(ctx context.Context, id uuid.UUID, input usecase.UpdateAuthorInput) error
-	RemoveAuthor(ctx context.Context, id uuid.UUID) error
+	RemoveAuthor(ctx context.Context, id uuid.UUID, policy model.RemovePolicy) error
 	ListAuthors(ctx context.Context) ([]model.Author, error)
 }
 
@@ -107,7 +108,7 @@ func (p AuthorHandler) GetAuthor(writer http.ResponseWriter, request *http.Reque
 		Pseudonym:  author.Pseudonym,
 	}
 
-	sendOkJSON(writer, response)
+	sendRepresentation(writer, request, negotiateRepresentation(request), response, response)
 }
 
 func (p AuthorHandler) RemoveAuthor(writer http.ResponseWriter, request *http.Request) {
@@ -123,7 +124,13 @@ func (p AuthorHandler) RemoveAuthor(writer http.ResponseWriter, request *http.Re
 		return
 	}
 
-	if err = p.authorUsecase.RemoveAuthor(request.Context(), id); err != nil {
+	policy, err := parseRemovePolicy(request)
+	if err != nil {
+		SendError(writer, err)
+		return
+	}
+
+	if err = p.authorUsecase.RemoveAuthor(request.Context(), id, policy); err != nil {
 		SendError(writer, err)
 		logs.Error("failed to remove author", err, slog.String("author_id", idStr))
 		return
@@ -199,3 +206,79 @@ func (p AuthorHandler) UpdateAuthor(writer http.ResponseWriter, request *http.Re
 	}
 	sendOk(writer)
 }
+
+func (p AuthorHandler) PatchAuthor(writer http.ResponseWriter, request *http.Request) {
+	idStr := mux.Vars(request)[VarID]
+	if idStr == "" {
+		sendBadRequest(writer, MissingAuthorID)
+		return
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		sendBadRequest(writer, InvalidAuthorID)
+		return
+	}
+	members, ok := decodeMergePatch(writer, request)
+	if !ok {
+		return
+	}
+
+	author, err := p.authorUsecase.GetAuthor(request.Context(), id)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to get author", err, slog.String("author_id", idStr))
+		return
+	}
+	merged := AddAuthorRequest{
+		FirstName:  author.FirstName,
+		LastName:   author.LastName,
+		MiddleName: author.MiddleName,
+		Pseudonym:  author.Pseudonym,
+	}
+
+	input, err := mergeAuthorPatch(members, &merged)
+	if err != nil {
+		SendError(writer, err)
+		return
+	}
+	if validationErr(writer, p.validate, merged) {
+		return
+	}
+
+	if err = p.authorUsecase.UpdateAuthor(request.Context(), id, input); err != nil {
+		SendError(writer, err)
+		logs.Error("failed to patch author", err, slog.String("author_id", idStr))
+		return
+	}
+	sendOk(writer)
+}
+
+func mergeAuthorPatch(members map[string]json.RawMessage, merged *AddAuthorRequest) (usecase.UpdateAuthorInput, error) {
+	var (
+		input usecase.UpdateAuthorInput
+		err   error
+	)
+	for key, raw := range members {
+		switch key {
+		case "first_name":
+			input.Clear.FirstName, err = mergeNullable(key, raw, &merged.FirstName)
+			input.FirstName = merged.FirstName
+		case "last_name":
+			input.Clear.LastName, err = mergeNullable(key, raw, &merged.LastName)
+			input.LastName = merged.LastName
+		case "middle_name":
+			input.Clear.MiddleName, err = mergeNullable(key, raw, &merged.MiddleName)
+			input.MiddleName = merged.MiddleName
+		case "pseudonym":
+			input.Clear.Pseudonym, err = mergeNullable(key, raw, &merged.Pseudonym)
+			input.Pseudonym = merged.Pseudonym
+		default:
+			err = unknownPatchMember(key)
+		}
+		if err != nil {
+			return usecase.UpdateAuthorInput{}, err
+		}
+	}
+	return input, nil
+}
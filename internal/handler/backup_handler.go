@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/backup"
+	"github.com/iamvkosarev/book-shelf/pkg/logs"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	ContentTypeZip = "application/zip"
+
+	VarUsers    = "users"
+	VarConflict = "conflict"
+
+	MaxBackupBodySize = 1 << 30
+
+	backupTempPattern = "restore-*.zip"
+)
+
+type BackupUsecase interface {
+	Backup(ctx context.Context, writer io.Writer, includeUsers bool) (backup.Manifest, error)
+	Restore(
+		ctx context.Context,
+		reader io.ReaderAt,
+		size int64,
+		strategy backup.ConflictStrategy,
+		callerID uuid.UUID,
+	) (backup.RestoreReport, error)
+}
+
+type BackupHandler struct {
+	backupUsecase     BackupUsecase
+	userIDFromContext UserIDFromContext
+}
+
+func NewBackupHandler(usecase BackupUsecase, userIDFromContext UserIDFromContext) *BackupHandler {
+	return &BackupHandler{
+		backupUsecase:     usecase,
+		userIDFromContext: userIDFromContext,
+	}
+}
+
+type RestoreResponse struct {
+	ArchiveCreatedAt time.Time     `json:"archive_created_at"`
+	SchemaVersion    uint          `json:"schema_version"`
+	IncludesUsers    bool          `json:"includes_users"`
+	Restored         backup.Counts `json:"restored"`
+}
+
+func (h BackupHandler) Backup(writer http.ResponseWriter, request *http.Request) {
+	var body bytes.Buffer
+	manifest, err := h.backupUsecase.Backup(request.Context(), &body, request.URL.Query().Get(VarUsers) == "true")
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to back up catalog", err)
+		return
+	}
+	writer.Header().Set(
+		"Content-Disposition",
+		fmt.Sprintf(`attachment; filename="book-shelf-%s.zip"`, manifest.CreatedAt.Format("20060102-150405")),
+	)
+	sendOkBody(writer, ContentTypeZip, body.Bytes())
+}
+
+// Restore leaves the account of the calling admin as it is, an archive user
+// with the same id or email is not restored.
+func (h BackupHandler) Restore(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+	strategy, err := backup.ParseConflictStrategy(request.URL.Query().Get(VarConflict))
+	if err != nil {
+		SendError(writer, err)
+		return
+	}
+
+	file, ok := receiveTempFile(writer, request, MaxBackupBodySize, backupTempPattern, "backup archive")
+	if !ok {
+		return
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to stat backup archive", err)
+		return
+	}
+	report, err := h.backupUsecase.Restore(request.Context(), file, info.Size(), strategy, userID)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to restore backup archive", err)
+		return
+	}
+	sendOkJSON(
+		writer, RestoreResponse{
+			ArchiveCreatedAt: report.Manifest.CreatedAt,
+			SchemaVersion:    report.Manifest.SchemaVersion,
+			IncludesUsers:    report.Manifest.IncludesUsers,
+			Restored:         report.Restored,
+		},
+	)
+}
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/books"
+	"github.com/iamvkosarev/book-shelf/pkg/logs"
+	"log/slog"
+	"net/http"
+)
+
+const (
+	VarAtomic = "atomic"
+
+	MaxBatchOperations = 10000
+)
+
+type BatchOperationRequest struct {
+	Op   books.BatchOperationKind `json:"op"`
+	ID   uuid.UUID                `json:"id"`
+	Data json.RawMessage          `json:"data"`
+}
+
+type BatchItemResponse struct {
+	Index   int                      `json:"index"`
+	Op      books.BatchOperationKind `json:"op"`
+	ID      *uuid.UUID               `json:"id,omitempty"`
+	Status  int                      `json:"status"`
+	Error   string                   `json:"error,omitempty"`
+	Details any                      `json:"details,omitempty"`
+}
+
+type BatchBooksResponse struct {
+	Atomic    bool                `json:"atomic"`
+	Committed bool                `json:"committed"`
+	Items     []BatchItemResponse `json:"items"`
+}
+
+func (p BookHandler) BatchBooks(writer http.ResponseWriter, request *http.Request) {
+	var requestData []BatchOperationRequest
+	if ok := decode(writer, request, &requestData); !ok {
+		return
+	}
+	if len(requestData) > MaxBatchOperations {
+		SendError(writer, model.ErrBatchTooLarge)
+		return
+	}
+	atomic := request.URL.Query().Get(VarAtomic) != "false"
+	force := request.URL.Query().Get(VarForce) == "true"
+
+	operations := make([]books.BatchOperation, len(requestData))
+	decodeErrors := make([]error, len(requestData))
+	for i, item := range requestData {
+		operations[i], decodeErrors[i] = p.batchOperation(item, force)
+	}
+
+	var (
+		results   []books.BatchItemResult
+		committed bool
+		err       error
+	)
+	if hasAnyError(decodeErrors) && atomic {
+		results = make([]books.BatchItemResult, len(operations))
+		for i := range results {
+			results[i] = books.BatchItemResult{ID: operations[i].ID, Err: model.ErrBatchRolledBack}
+		}
+	} else {
+		results, committed, err = p.bookUsecase.ApplyBatch(request.Context(), validOperations(operations, decodeErrors), atomic)
+		if err != nil {
+			SendError(writer, err)
+			logs.Error("failed to apply books batch", err, slog.Int("operations", len(operations)))
+			return
+		}
+		results = mergeBatchResults(results, decodeErrors)
+	}
+	for i, decodeErr := range decodeErrors {
+		if decodeErr != nil {
+			results[i].Err = decodeErr
+		}
+	}
+
+	response := BatchBooksResponse{
+		Atomic:    atomic,
+		Committed: committed,
+		Items:     make([]BatchItemResponse, len(results)),
+	}
+	for i, result := range results {
+		item := BatchItemResponse{
+			Index:  i,
+			Op:     requestData[i].Op,
+			Status: batchSuccessStatus(requestData[i].Op),
+		}
+		if result.ID != uuid.Nil {
+			id := result.ID
+			item.ID = &id
+		}
+		if result.Err != nil {
+			item.Status, item.Error, item.Details = describeError(result.Err)
+		}
+		response.Items[i] = item
+	}
+	sendOkJSON(writer, response)
+}
+
+// batchOperation takes force from the query like AddBook does, it lets every
+// create of the batch through the likely-duplicate check.
+func (p BookHandler) batchOperation(item BatchOperationRequest, force bool) (books.BatchOperation, error) {
+	operation := books.BatchOperation{Kind: item.Op, ID: item.ID}
+	switch item.Op {
+	case books.BatchCreate:
+		var data AddBookRequest
+		if err := json.Unmarshal(item.Data, &data); err != nil {
+			return operation, invalidPatchMember("data")
+		}
+		if err := p.validate.Struct(data); err != nil {
+			return operation, model.NewInternalError(http.StatusBadRequest, validationMessage(err))
+		}
+		operation.Create = books.CreateBookInput{
+			AuthorsIDs:  data.AuthorsIDs,
+			PublisherID: data.PublisherID,
+			TagsIDs:     data.TagsIDs,
+			PublishedAt: data.PublishedAt,
+			Title:       data.Title,
+			Description: data.Description,
+			Price:       data.Price,
+			Pages:       data.Pages,
+			ISBN:        data.ISBN,
+			Force:       force,
+		}
+	case books.BatchUpdate:
+		var data UpdateBookRequest
+		if err := json.Unmarshal(item.Data, &data); err != nil {
+			return operation, invalidPatchMember("data")
+		}
+		if err := p.validate.Struct(data); err != nil {
+			return operation, model.NewInternalError(http.StatusBadRequest, validationMessage(err))
+		}
+		operation.Update = books.UpdateBookPatch{
+			PublisherID: data.PublisherID,
+			AuthorsIDs:  data.AuthorsIDs,
+			TagsIDs:     data.TagsIDs,
+			PublishedAt: data.PublishedAt,
+			Title:       data.Title,
+			Description: data.Description,
+			Price:       data.Price,
+			Pages:       data.Pages,
+			ISBN:        data.ISBN,
+		}
+	}
+	return operation, nil
+}
+
+func validOperations(operations []books.BatchOperation, decodeErrors []error) []books.BatchOperation {
+	valid := make([]books.BatchOperation, 0, len(operations))
+	for i, operation := range operations {
+		if decodeErrors[i] == nil {
+			valid = append(valid, operation)
+		}
+	}
+	return valid
+}
+
+func mergeBatchResults(results []books.BatchItemResult, decodeErrors []error) []books.BatchItemResult {
+	merged := make([]books.BatchItemResult, len(decodeErrors))
+	next := 0
+	for i, decodeErr := range decodeErrors {
+		if decodeErr != nil {
+			continue
+		}
+		merged[i] = results[next]
+		next++
+	}
+	return merged
+}
+
+func hasAnyError(errs []error) bool {
+	for _, err := range errs {
+		if err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func batchSuccessStatus(op books.BatchOperationKind) int {
+	if op == books.BatchCreate {
+		return http.StatusCreated
+	}
+	return http.StatusOK
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -35,17 +36,27 @@ type BookUsecase interface {
 		parameters books.ListBookParameters,
 		expandAuthors, expandTags, expandPublisher bool,
 	) ([]model.Book, error)
+	ListDuplicates(ctx context.Context) ([][]model.Book, error)
+	ApplyBatch(ctx context.Context, operations []books.BatchOperation, atomic bool) ([]books.BatchItemResult, bool, error)
 }
 
 type BookHandler struct {
-	bookUsecase BookUsecase
-	validate    *validator.Validate
+	bookUsecase       BookUsecase
+	ratingsUsecase    RatingsUsecase
+	userIDFromContext UserIDFromContext
+	validate          *validator.Validate
 }
 
-func NewBookHandler(usecase BookUsecase) *BookHandler {
+func NewBookHandler(
+	usecase BookUsecase,
+	ratingsUsecase RatingsUsecase,
+	userIDFromContext UserIDFromContext,
+) *BookHandler {
 	return &BookHandler{
-		bookUsecase: usecase,
-		validate:    validator.New(),
+		bookUsecase:       usecase,
+		ratingsUsecase:    ratingsUsecase,
+		userIDFromContext: userIDFromContext,
+		validate:          validator.New(),
 	}
 }
 
@@ -57,7 +68,8 @@ type AddBookRequest struct {
 	Title       string      `json:"title" validate:"required,min=1,max=100"`
 	Description *string     `json:"description" validate:"omitempty,max=1000"`
 	Price       *float64    `json:"price"`
-	Mark        *int16      `json:"mark"`
+	Pages       *int32      `json:"pages" validate:"omitempty,min=1"`
+	ISBN        *string     `json:"isbn" validate:"omitempty,max=17"`
 }
 
 type AddBookResponse struct {
@@ -80,7 +92,9 @@ func (p BookHandler) AddBook(writer http.ResponseWriter, request *http.Request)
 		Title:       requestData.Title,
 		Description: requestData.Description,
 		Price:       requestData.Price,
-		Mark:        requestData.Mark,
+		Pages:       requestData.Pages,
+		ISBN:        requestData.ISBN,
+		Force:       request.URL.Query().Get(VarForce) == "true",
 	}
 
 	id, err := p.bookUsecase.AddBook(request.Context(), input)
@@ -101,7 +115,10 @@ type BookResponse struct {
 	Title       string             `json:"title"`
 	Description *string            `json:"description"`
 	Price       *float64           `json:"price"`
+	Pages       *int32             `json:"pages"`
 	Mark        *int16             `json:"mark"`
+	Rating      RatingResponse     `json:"rating"`
+	ISBN        *string            `json:"isbn"`
 	Publisher   *PublisherResponse `json:"publisher"`
 	Authors     []AuthorResponse   `json:"authors"`
 	Tags        []TagResponse      `json:"tags"`
@@ -120,10 +137,11 @@ func (p BookHandler) GetBook(writer http.ResponseWriter, request *http.Request)
 		return
 	}
 
+	kind := negotiateRepresentation(request)
 	expend := parseQueryToStringMap(request, VarExpend)
-	expendAuthorsData := hasKeyInMap(expend, VarExpendValueAuthors)
-	expendTagsData := hasKeyInMap(expend, VarExpendValueTags)
-	expendPublisherData := hasKeyInMap(expend, VarExpendValuePublisher)
+	expendAuthorsData := hasKeyInMap(expend, VarExpendValueAuthors) || kind != representationJSON
+	expendTagsData := hasKeyInMap(expend, VarExpendValueTags) || kind != representationJSON
+	expendPublisherData := hasKeyInMap(expend, VarExpendValuePublisher) || kind != representationJSON
 
 	book, err := p.bookUsecase.GetBook(request.Context(), id, expendAuthorsData, expendTagsData, expendPublisherData)
 	if err != nil {
@@ -133,8 +151,15 @@ func (p BookHandler) GetBook(writer http.ResponseWriter, request *http.Request)
 	}
 
 	response := getBookResponse(book, expendAuthorsData, expendTagsData, expendPublisherData)
+	mine, err := userRatings(request, p.userIDFromContext, p.ratingsUsecase, []model.Book{book})
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to get user rating", err, slog.String("book_id", idStr))
+		return
+	}
+	setMineRating(&response, mine)
 
-	sendOkJSON(writer, response)
+	sendRepresentation(writer, request, kind, response, response)
 }
 
 func (p BookHandler) RemoveBook(writer http.ResponseWriter, request *http.Request) {
@@ -192,11 +217,47 @@ func (p BookHandler) ListBooks(writer http.ResponseWriter, request *http.Request
 		logs.Error("failed to list books", err)
 		return
 	}
+	mine, err := userRatings(request, p.userIDFromContext, p.ratingsUsecase, books)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to list user ratings", err)
+		return
+	}
 	response := ListBooksResponse{
 		make([]BookResponse, len(books)),
 	}
 	for i, book := range books {
 		response.Books[i] = getBookResponse(book, expendAuthorsData, expendTagsData, expendPublisherData)
+		setMineRating(&response.Books[i], mine)
+	}
+
+	sendOkJSON(writer, response)
+}
+
+type DuplicatesClusterResponse struct {
+	Books []BookResponse `json:"books"`
+}
+
+type ListDuplicatesResponse struct {
+	Clusters []DuplicatesClusterResponse `json:"clusters"`
+}
+
+func (p BookHandler) ListDuplicates(writer http.ResponseWriter, request *http.Request) {
+	clusters, err := p.bookUsecase.ListDuplicates(request.Context())
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to list duplicated books", err)
+		return
+	}
+	response := ListDuplicatesResponse{
+		make([]DuplicatesClusterResponse, len(clusters)),
+	}
+	for i, cluster := range clusters {
+		booksResponse := make([]BookResponse, len(cluster))
+		for j, book := range cluster {
+			booksResponse[j] = getBookResponse(book, false, false, false)
+		}
+		response.Clusters[i] = DuplicatesClusterResponse{Books: booksResponse}
 	}
 
 	sendOkJSON(writer, response)
@@ -210,7 +271,8 @@ type UpdateBookRequest struct {
 	Title       *string     `json:"title" validate:"omitempty,min=1,max=100"`
 	Description *string     `json:"description" validate:"omitempty,max=1000"`
 	Price       *float64    `json:"price"`
-	Mark        *int16      `json:"mark"`
+	Pages       *int32      `json:"pages" validate:"omitempty,min=1"`
+	ISBN        *string     `json:"isbn" validate:"omitempty,max=17"`
 }
 
 func (p BookHandler) UpdateBook(writer http.ResponseWriter, request *http.Request) {
@@ -240,7 +302,8 @@ func (p BookHandler) UpdateBook(writer http.ResponseWriter, request *http.Reques
 		Title:       requestData.Title,
 		Description: requestData.Description,
 		Price:       requestData.Price,
-		Mark:        requestData.Mark,
+		Pages:       requestData.Pages,
+		ISBN:        requestData.ISBN,
 	}
 	if err = p.bookUsecase.UpdateBook(request.Context(), id, patch); err != nil {
 		SendError(writer, err)
@@ -250,6 +313,109 @@ func (p BookHandler) UpdateBook(writer http.ResponseWriter, request *http.Reques
 	sendOk(writer)
 }
 
+func (p BookHandler) PatchBook(writer http.ResponseWriter, request *http.Request) {
+	idStr := mux.Vars(request)[VarID]
+	if idStr == "" {
+		sendBadRequest(writer, MissingBookID)
+		return
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		sendBadRequest(writer, InvalidBookID)
+		return
+	}
+	members, ok := decodeMergePatch(writer, request)
+	if !ok {
+		return
+	}
+
+	book, err := p.bookUsecase.GetBook(request.Context(), id, false, false, false)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to get book", err, slog.String("book_id", idStr))
+		return
+	}
+	merged := AddBookRequest{
+		PublisherID: book.PublisherID,
+		AuthorsIDs:  book.AuthorsIDs,
+		TagsIDs:     book.TagsIDs,
+		PublishedAt: book.PublishedAt,
+		Title:       book.Title,
+		Description: book.Description,
+		Price:       book.Price,
+		Pages:       book.Pages,
+		ISBN:        book.ISBN,
+	}
+
+	patch, err := mergeBookPatch(members, &merged)
+	if err != nil {
+		SendError(writer, err)
+		return
+	}
+	if validationErr(writer, p.validate, merged) {
+		return
+	}
+
+	if err = p.bookUsecase.UpdateBook(request.Context(), id, patch); err != nil {
+		SendError(writer, err)
+		logs.Error("failed to patch book", err, slog.String("book_id", idStr))
+		return
+	}
+	sendOk(writer)
+}
+
+func mergeBookPatch(members map[string]json.RawMessage, merged *AddBookRequest) (books.UpdateBookPatch, error) {
+	var (
+		patch books.UpdateBookPatch
+		err   error
+	)
+	for key, raw := range members {
+		switch key {
+		case "publisher_id":
+			patch.Clear.PublisherID, err = mergeNullable(key, raw, &merged.PublisherID)
+			patch.PublisherID = merged.PublisherID
+		case "authors_ids":
+			err = mergeSlice(key, raw, &merged.AuthorsIDs)
+			patch.AuthorsIDs = merged.AuthorsIDs
+		case "tags_ids":
+			err = mergeSlice(key, raw, &merged.TagsIDs)
+			patch.TagsIDs = merged.TagsIDs
+		case "published_at":
+			patch.Clear.PublishedAt, err = mergeNullable(key, raw, &merged.PublishedAt)
+			patch.PublishedAt = merged.PublishedAt
+		case "title":
+			var title *string
+			if _, err = mergeNullable(key, raw, &title); title != nil {
+				merged.Title = *title
+			} else {
+				merged.Title = ""
+			}
+			patch.Title = &merged.Title
+		case "description":
+			patch.Clear.Description, err = mergeNullable(key, raw, &merged.Description)
+			patch.Description = merged.Description
+		case "price":
+			patch.Clear.Price, err = mergeNullable(key, raw, &merged.Price)
+			patch.Price = merged.Price
+		case "pages":
+			patch.Clear.Pages, err = mergeNullable(key, raw, &merged.Pages)
+			patch.Pages = merged.Pages
+		case "mark":
+			err = readOnlyPatchMember(key)
+		case "isbn":
+			patch.Clear.ISBN, err = mergeNullable(key, raw, &merged.ISBN)
+			patch.ISBN = merged.ISBN
+		default:
+			err = unknownPatchMember(key)
+		}
+		if err != nil {
+			return books.UpdateBookPatch{}, err
+		}
+	}
+	return patch, nil
+}
+
 func getBookResponse(
 	book model.Book,
 	expendAuthorsData bool,
@@ -265,7 +431,10 @@ func getBookResponse(
 		Title:       book.Title,
 		Description: book.Description,
 		Price:       book.Price,
+		Pages:       book.Pages,
 		Mark:        book.Mark,
+		Rating:      getRatingResponse(book.Rating, nil),
+		ISBN:        book.ISBN,
 	}
 
 	if expendPublisherData && book.PublisherID != nil && *book.PublisherID != uuid.Nil {
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/books"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/exports"
+	"github.com/iamvkosarev/book-shelf/pkg/logs"
+	"github.com/iamvkosarev/book-shelf/pkg/marc"
+	"github.com/iamvkosarev/book-shelf/pkg/onix"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+const (
+	ContentTypeMARC    = "application/marc"
+	ContentTypeMARCXML = "application/marcxml+xml"
+	ContentTypeBibTeX  = "application/x-bibtex; charset=utf-8"
+	ContentTypeRIS     = "application/x-research-info-systems; charset=utf-8"
+	ContentTypeCSLJSON = "application/vnd.citationstyles.csl+json"
+	ContentTypeONIX    = "application/xml; charset=utf-8"
+
+	VarFormat = "format"
+)
+
+var citationContentTypes = map[exports.CitationFormat]string{
+	exports.CitationBibTeX:  ContentTypeBibTeX,
+	exports.CitationRIS:     ContentTypeRIS,
+	exports.CitationCSLJSON: ContentTypeCSLJSON,
+}
+
+type ExportUsecase interface {
+	BookMARC(ctx context.Context, id uuid.UUID) (marc.Record, error)
+	CiteBook(ctx context.Context, id uuid.UUID, format exports.CitationFormat) ([]byte, error)
+	CiteBooks(ctx context.Context, parameters books.ListBookParameters, format exports.CitationFormat) ([]byte, error)
+	ONIXFeed(ctx context.Context, parameters books.ListBookParameters) (onix.Message, error)
+}
+
+type ExportHandler struct {
+	exportUsecase ExportUsecase
+}
+
+func NewExportHandler(usecase ExportUsecase) *ExportHandler {
+	return &ExportHandler{
+		exportUsecase: usecase,
+	}
+}
+
+func (h ExportHandler) BookMARC(writer http.ResponseWriter, request *http.Request) {
+	h.sendBookMARC(writer, request, ContentTypeMARC, marc.WriteBinary)
+}
+
+func (h ExportHandler) BookMARCXML(writer http.ResponseWriter, request *http.Request) {
+	h.sendBookMARC(writer, request, ContentTypeMARCXML, marc.WriteXML)
+}
+
+func (h ExportHandler) sendBookMARC(
+	writer http.ResponseWriter,
+	request *http.Request,
+	contentType string,
+	write func(writer io.Writer, records ...marc.Record) error,
+) {
+	id, ok := bookIDFromRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	record, err := h.exportUsecase.BookMARC(request.Context(), id)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to export book marc", err, slog.String("book_id", id.String()))
+		return
+	}
+
+	var body bytes.Buffer
+	if err = write(&body, record); err != nil {
+		SendError(writer, err)
+		logs.Error("failed to encode book marc", err, slog.String("book_id", id.String()))
+		return
+	}
+	sendOkBody(writer, contentType, body.Bytes())
+}
+
+func (h ExportHandler) CiteBook(writer http.ResponseWriter, request *http.Request) {
+	id, ok := bookIDFromRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	format := citationFormat(request)
+	body, err := h.exportUsecase.CiteBook(request.Context(), id, format)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to cite book", err, slog.String("book_id", id.String()))
+		return
+	}
+	sendOkBody(writer, citationContentTypes[format], body)
+}
+
+func (h ExportHandler) CiteBooks(writer http.ResponseWriter, request *http.Request) {
+	authors, err := parseQueryToUUIDs(request, VarAuthorID)
+	if err != nil {
+		SendError(writer, err)
+		return
+	}
+
+	format := citationFormat(request)
+	body, err := h.exportUsecase.CiteBooks(request.Context(), books.ListBookParameters{AuthorsIDs: authors}, format)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to cite books", err)
+		return
+	}
+	sendOkBody(writer, citationContentTypes[format], body)
+}
+
+func (h ExportHandler) ONIXFeed(writer http.ResponseWriter, request *http.Request) {
+	authors, err := parseQueryToUUIDs(request, VarAuthorID)
+	if err != nil {
+		SendError(writer, err)
+		return
+	}
+
+	message, err := h.exportUsecase.ONIXFeed(request.Context(), books.ListBookParameters{AuthorsIDs: authors})
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to export onix feed", err)
+		return
+	}
+
+	var body bytes.Buffer
+	if err = onix.Write(&body, message); err != nil {
+		SendError(writer, err)
+		logs.Error("failed to encode onix feed", err)
+		return
+	}
+	sendOkBody(writer, ContentTypeONIX, body.Bytes())
+}
+
+func citationFormat(request *http.Request) exports.CitationFormat {
+	format := exports.CitationFormat(request.URL.Query().Get(VarFormat))
+	if format == "" {
+		return exports.CitationBibTeX
+	}
+	return format
+}
+
+func bookIDFromRequest(writer http.ResponseWriter, request *http.Request) (uuid.UUID, bool) {
+	idStr := mux.Vars(request)[VarID]
+	if idStr == "" {
+		sendBadRequest(writer, MissingBookID)
+		return uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		sendBadRequest(writer, InvalidBookID)
+		return uuid.Nil, false
+	}
+	return id, true
+}
@@ -0,0 +1,449 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/highlights"
+	"github.com/iamvkosarev/book-shelf/pkg/logs"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	MaxKindleClippingsBodySize = 16 << 20
+	HighlightsPageSize         = 50
+
+	VarKind   = "kind"
+	VarColour = "colour"
+	VarTag    = "tag"
+
+	ContentTypeMarkdown = "text/markdown; charset=utf-8"
+
+	InvalidHighlightID = "invalid highlight id"
+)
+
+type HighlightsUsecase interface {
+	AddHighlight(
+		ctx context.Context,
+		userID uuid.UUID,
+		bookID uuid.UUID,
+		input highlights.HighlightInput,
+	) (model.Highlight, error)
+	GetHighlight(ctx context.Context, userID uuid.UUID, id uuid.UUID) (model.Highlight, error)
+	UpdateHighlight(
+		ctx context.Context,
+		userID uuid.UUID,
+		id uuid.UUID,
+		input highlights.HighlightInput,
+	) (model.Highlight, error)
+	RemoveHighlight(ctx context.Context, userID uuid.UUID, id uuid.UUID) error
+	ListHighlights(
+		ctx context.Context,
+		userID uuid.UUID,
+		parameters highlights.ListParameters,
+	) ([]model.Highlight, error)
+	ExportMarkdown(ctx context.Context, userID uuid.UUID, bookID uuid.UUID) ([]byte, error)
+	ImportKindleClippings(ctx context.Context, userID uuid.UUID, reader io.Reader) (highlights.KindleReport, error)
+	ListUnmatchedSources(ctx context.Context, userID uuid.UUID) ([]highlights.UnmatchedSource, error)
+	ResolveSource(ctx context.Context, userID uuid.UUID, source highlights.Source, bookID uuid.UUID) (int, error)
+}
+
+type HighlightHandler struct {
+	highlightsUsecase HighlightsUsecase
+	userIDFromContext UserIDFromContext
+	validate          *validator.Validate
+}
+
+func NewHighlightHandler(usecase HighlightsUsecase, userIDFromContext UserIDFromContext) *HighlightHandler {
+	return &HighlightHandler{
+		highlightsUsecase: usecase,
+		userIDFromContext: userIDFromContext,
+		validate:          validator.New(),
+	}
+}
+
+type KindleSourceResponse struct {
+	Title         string      `json:"title"`
+	Author        string      `json:"author,omitempty"`
+	Clippings     int         `json:"clippings"`
+	BookID        *uuid.UUID  `json:"book_id,omitempty"`
+	CandidatesIDs []uuid.UUID `json:"candidates_ids,omitempty"`
+}
+
+type KindleImportResponse struct {
+	Clippings  int                    `json:"clippings"`
+	Imported   int                    `json:"imported"`
+	Duplicates int                    `json:"duplicates"`
+	Matched    []KindleSourceResponse `json:"matched"`
+	Unmatched  []KindleSourceResponse `json:"unmatched"`
+}
+
+type UnmatchedSourceResponse struct {
+	Title      string `json:"title"`
+	Author     string `json:"author,omitempty"`
+	Highlights int    `json:"highlights"`
+}
+
+type ResolveSourceRequest struct {
+	Title  string    `json:"title" validate:"required"`
+	Author string    `json:"author"`
+	BookID uuid.UUID `json:"book_id" validate:"required"`
+}
+
+type ResolveSourceResponse struct {
+	Resolved int `json:"resolved"`
+}
+
+type HighlightRequest struct {
+	Kind          model.HighlightKind    `json:"kind" validate:"required"`
+	Text          string                 `json:"text" validate:"max=10000"`
+	Page          *int32                 `json:"page" validate:"omitempty,min=1"`
+	LocationStart *int32                 `json:"location_start" validate:"omitempty,min=0"`
+	LocationEnd   *int32                 `json:"location_end" validate:"omitempty,min=0"`
+	Colour        *model.HighlightColour `json:"colour"`
+	Tags          []string               `json:"tags"`
+}
+
+type HighlightResponse struct {
+	ID            uuid.UUID              `json:"id"`
+	BookID        *uuid.UUID             `json:"book_id"`
+	Kind          model.HighlightKind    `json:"kind"`
+	Text          string                 `json:"text"`
+	Page          *int32                 `json:"page,omitempty"`
+	LocationStart *int32                 `json:"location_start,omitempty"`
+	LocationEnd   *int32                 `json:"location_end,omitempty"`
+	Colour        *model.HighlightColour `json:"colour,omitempty"`
+	Tags          []string               `json:"tags"`
+	ClippedAt     *time.Time             `json:"clipped_at,omitempty"`
+	SourceTitle   *string                `json:"source_title,omitempty"`
+	SourceAuthor  *string                `json:"source_author,omitempty"`
+	CreatedAt     time.Time              `json:"created_at"`
+	UpdatedAt     time.Time              `json:"updated_at"`
+}
+
+type ListHighlightsResponse struct {
+	Highlights []HighlightResponse `json:"highlights"`
+	Page       int                 `json:"page"`
+	HasNext    bool                `json:"has_next"`
+}
+
+// ListHighlights searches all the highlights of the user, q is a full-text
+// query and kind, colour and tag narrow it down.
+func (h HighlightHandler) ListHighlights(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+	h.listHighlights(writer, request, userID, nil)
+}
+
+func (h HighlightHandler) ListBookHighlights(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+	bookID, ok := bookIDFromRequest(writer, request)
+	if !ok {
+		return
+	}
+	h.listHighlights(writer, request, userID, &bookID)
+}
+
+func (h HighlightHandler) listHighlights(
+	writer http.ResponseWriter,
+	request *http.Request,
+	userID uuid.UUID,
+	bookID *uuid.UUID,
+) {
+	query := request.URL.Query()
+	parameters := highlights.ListParameters{
+		BookID: bookID,
+		Tag:    query.Get(VarTag),
+		Query:  query.Get(VarQuery),
+	}
+	var err error
+	if raw := query.Get(VarKind); raw != "" {
+		if parameters.Kind, err = highlights.ParseHighlightKind(raw); err != nil {
+			SendError(writer, err)
+			return
+		}
+	}
+	if raw := query.Get(VarColour); raw != "" {
+		if parameters.Colour, err = highlights.ParseHighlightColour(raw); err != nil {
+			SendError(writer, err)
+			return
+		}
+	}
+	page, ok := queryPage(writer, request)
+	if !ok {
+		return
+	}
+	parameters.Limit = HighlightsPageSize + 1
+	parameters.Offset = uint64((page - 1) * HighlightsPageSize)
+
+	list, err := h.highlightsUsecase.ListHighlights(request.Context(), userID, parameters)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to list highlights", err, slog.String("user_id", userID.String()))
+		return
+	}
+	response := ListHighlightsResponse{
+		Highlights: make([]HighlightResponse, 0, min(len(list), HighlightsPageSize)),
+		Page:       page,
+		HasNext:    len(list) > HighlightsPageSize,
+	}
+	for _, highlight := range list[:min(len(list), HighlightsPageSize)] {
+		response.Highlights = append(response.Highlights, getHighlightResponse(highlight))
+	}
+	sendOkJSON(writer, response)
+}
+
+func (h HighlightHandler) AddHighlight(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+	bookID, ok := bookIDFromRequest(writer, request)
+	if !ok {
+		return
+	}
+	input, ok := h.decodeHighlight(writer, request)
+	if !ok {
+		return
+	}
+
+	highlight, err := h.highlightsUsecase.AddHighlight(request.Context(), userID, bookID, input)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to add highlight", err, slog.String("book_id", bookID.String()))
+		return
+	}
+	sendCreatedJSON(writer, getHighlightResponse(highlight))
+}
+
+func (h HighlightHandler) GetHighlight(writer http.ResponseWriter, request *http.Request) {
+	userID, id, ok := h.highlightFromRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	highlight, err := h.highlightsUsecase.GetHighlight(request.Context(), userID, id)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to get highlight", err, slog.String("highlight_id", id.String()))
+		return
+	}
+	sendOkJSON(writer, getHighlightResponse(highlight))
+}
+
+func (h HighlightHandler) UpdateHighlight(writer http.ResponseWriter, request *http.Request) {
+	userID, id, ok := h.highlightFromRequest(writer, request)
+	if !ok {
+		return
+	}
+	input, ok := h.decodeHighlight(writer, request)
+	if !ok {
+		return
+	}
+
+	highlight, err := h.highlightsUsecase.UpdateHighlight(request.Context(), userID, id, input)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to update highlight", err, slog.String("highlight_id", id.String()))
+		return
+	}
+	sendOkJSON(writer, getHighlightResponse(highlight))
+}
+
+func (h HighlightHandler) RemoveHighlight(writer http.ResponseWriter, request *http.Request) {
+	userID, id, ok := h.highlightFromRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	if err := h.highlightsUsecase.RemoveHighlight(request.Context(), userID, id); err != nil {
+		SendError(writer, err)
+		logs.Error("failed to remove highlight", err, slog.String("highlight_id", id.String()))
+		return
+	}
+	sendOk(writer)
+}
+
+func (h HighlightHandler) ExportMarkdown(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+	bookID, ok := bookIDFromRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	body, err := h.highlightsUsecase.ExportMarkdown(request.Context(), userID, bookID)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to export highlights", err, slog.String("book_id", bookID.String()))
+		return
+	}
+	writer.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="highlights-%s.md"`, bookID))
+	sendOkBody(writer, ContentTypeMarkdown, body)
+}
+
+func (h HighlightHandler) ImportKindleClippings(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+
+	body := http.MaxBytesReader(writer, request.Body, MaxKindleClippingsBodySize)
+	report, err := h.highlightsUsecase.ImportKindleClippings(request.Context(), userID, body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			sendBadRequest(writer, "clippings file is too large")
+			return
+		}
+		SendError(writer, err)
+		logs.Error("failed to import kindle clippings", err, slog.String("user_id", userID.String()))
+		return
+	}
+	sendOkJSON(
+		writer, KindleImportResponse{
+			Clippings:  report.Clippings,
+			Imported:   report.Imported,
+			Duplicates: report.Duplicates,
+			Matched:    getKindleSourcesResponse(report.Matched),
+			Unmatched:  getKindleSourcesResponse(report.Unmatched),
+		},
+	)
+}
+
+func (h HighlightHandler) ListUnmatchedSources(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+
+	sources, err := h.highlightsUsecase.ListUnmatchedSources(request.Context(), userID)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to list unmatched highlight sources", err, slog.String("user_id", userID.String()))
+		return
+	}
+	response := make([]UnmatchedSourceResponse, len(sources))
+	for i, source := range sources {
+		response[i] = UnmatchedSourceResponse{
+			Title:      source.Title,
+			Author:     source.Author,
+			Highlights: source.Highlights,
+		}
+	}
+	sendOkJSON(writer, response)
+}
+
+func (h HighlightHandler) ResolveSource(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+
+	var req ResolveSourceRequest
+	if !decode(writer, request, &req) {
+		return
+	}
+	if validationErr(writer, h.validate, req) {
+		return
+	}
+
+	resolved, err := h.highlightsUsecase.ResolveSource(
+		request.Context(), userID, highlights.Source{Title: req.Title, Author: req.Author}, req.BookID,
+	)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to resolve highlight source", err, slog.String("user_id", userID.String()))
+		return
+	}
+	sendOkJSON(writer, ResolveSourceResponse{Resolved: resolved})
+}
+
+func (h HighlightHandler) decodeHighlight(
+	writer http.ResponseWriter,
+	request *http.Request,
+) (highlights.HighlightInput, bool) {
+	var req HighlightRequest
+	if !decode(writer, request, &req) {
+		return highlights.HighlightInput{}, false
+	}
+	if validationErr(writer, h.validate, req) {
+		return highlights.HighlightInput{}, false
+	}
+	return highlights.HighlightInput{
+		Kind:          req.Kind,
+		Text:          req.Text,
+		Page:          req.Page,
+		LocationStart: req.LocationStart,
+		LocationEnd:   req.LocationEnd,
+		Colour:        req.Colour,
+		Tags:          req.Tags,
+	}, true
+}
+
+func (h HighlightHandler) highlightFromRequest(
+	writer http.ResponseWriter,
+	request *http.Request,
+) (uuid.UUID, uuid.UUID, bool) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+	id, err := uuid.Parse(mux.Vars(request)[VarID])
+	if err != nil {
+		sendBadRequest(writer, InvalidHighlightID)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, id, true
+}
+
+func getHighlightResponse(highlight model.Highlight) HighlightResponse {
+	tags := highlight.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return HighlightResponse{
+		ID:            highlight.ID,
+		BookID:        highlight.BookID,
+		Kind:          highlight.Kind,
+		Text:          highlight.Text,
+		Page:          highlight.Page,
+		LocationStart: highlight.LocationStart,
+		LocationEnd:   highlight.LocationEnd,
+		Colour:        highlight.Colour,
+		Tags:          tags,
+		ClippedAt:     highlight.ClippedAt,
+		SourceTitle:   highlight.SourceTitle,
+		SourceAuthor:  highlight.SourceAuthor,
+		CreatedAt:     highlight.CreatedAt,
+		UpdatedAt:     highlight.UpdatedAt,
+	}
+}
+
+func getKindleSourcesResponse(sources []highlights.KindleSource) []KindleSourceResponse {
+	response := make([]KindleSourceResponse, len(sources))
+	for i, source := range sources {
+		response[i] = KindleSourceResponse{
+			Title:         source.Title,
+			Author:        source.Author,
+			Clippings:     source.Clippings,
+			BookID:        source.BookID,
+			CandidatesIDs: source.CandidatesIDs,
+		}
+	}
+	return response
+}
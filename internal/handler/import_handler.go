@@ -0,0 +1,291 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/imports"
+	"github.com/iamvkosarev/book-shelf/pkg/logs"
+	"io"
+	"net/http"
+	"os"
+	"unicode/utf8"
+)
+
+const (
+	VarDryRun        = "dry_run"
+	VarCreateMissing = "create_missing"
+	VarDelimiter     = "delimiter"
+	VarListSeparator = "list_separator"
+	VarColumnPrefix  = "column_"
+
+	MaxImportBodySize  = 32 << 20
+	MaxCalibreBodySize = 512 << 20
+	MaxEPUBBodySize    = 128 << 20
+
+	VarCommit = "commit"
+
+	calibreTempPattern = "calibre-*.db"
+	epubTempPattern    = "upload-*.epub"
+)
+
+type ImportUsecase interface {
+	ImportBooksCSV(ctx context.Context, reader io.Reader, options imports.CSVOptions) (imports.Report, error)
+	ImportGoodreadsCSV(ctx context.Context, reader io.Reader, options imports.Options) (imports.Report, error)
+	ImportCalibre(ctx context.Context, path string, options imports.Options) (imports.Report, error)
+	ImportMARC(ctx context.Context, reader io.Reader, options imports.Options) (imports.Report, error)
+	ImportONIX(ctx context.Context, reader io.Reader, options imports.Options) (imports.Report, error)
+	ImportEPUB(
+		ctx context.Context,
+		reader io.ReaderAt,
+		size int64,
+		options imports.EPUBOptions,
+	) (imports.EPUBResult, error)
+}
+
+type ImportHandler struct {
+	importUsecase ImportUsecase
+}
+
+func NewImportHandler(usecase ImportUsecase) *ImportHandler {
+	return &ImportHandler{
+		importUsecase: usecase,
+	}
+}
+
+type ImportRowResponse struct {
+	Row           int               `json:"row"`
+	Status        imports.RowStatus `json:"status"`
+	BookID        *uuid.UUID        `json:"book_id,omitempty"`
+	Errors        []string          `json:"errors,omitempty"`
+	CandidatesIDs []uuid.UUID       `json:"candidates_ids,omitempty"`
+	CandidateRows []int             `json:"candidate_rows,omitempty"`
+}
+
+type ImportCreationsResponse struct {
+	Authors    []string `json:"authors"`
+	Tags       []string `json:"tags"`
+	Publishers []string `json:"publishers"`
+}
+
+type ImportReportResponse struct {
+	DryRun  bool                     `json:"dry_run"`
+	Rows    []ImportRowResponse      `json:"rows"`
+	Planned ImportCreationsResponse  `json:"planned"`
+	Orphans *ImportCreationsResponse `json:"orphans,omitempty"`
+}
+
+func (h ImportHandler) ImportBooksCSV(writer http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query()
+	options := imports.CSVOptions{
+		Options:       importOptions(request),
+		Columns:       make(map[imports.CSVField]string),
+		ListSeparator: query.Get(VarListSeparator),
+	}
+	if delimiter := query.Get(VarDelimiter); delimiter != "" {
+		r, size := utf8.DecodeRuneInString(delimiter)
+		if size != len(delimiter) {
+			sendBadRequest(writer, "delimiter must be a single character")
+			return
+		}
+		options.Delimiter = r
+	}
+	for _, field := range imports.CSVFields {
+		if column := query.Get(VarColumnPrefix + string(field)); column != "" {
+			options.Columns[field] = column
+		}
+	}
+
+	body := http.MaxBytesReader(writer, request.Body, MaxImportBodySize)
+	report, err := h.importUsecase.ImportBooksCSV(request.Context(), body, options)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to import books csv", err)
+		return
+	}
+	sendOkJSON(writer, getImportReportResponse(report))
+}
+
+func (h ImportHandler) ImportGoodreadsCSV(writer http.ResponseWriter, request *http.Request) {
+	body := http.MaxBytesReader(writer, request.Body, MaxImportBodySize)
+	report, err := h.importUsecase.ImportGoodreadsCSV(request.Context(), body, importOptions(request))
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to import goodreads csv", err)
+		return
+	}
+	sendOkJSON(writer, getImportReportResponse(report))
+}
+
+func (h ImportHandler) ImportMARC(writer http.ResponseWriter, request *http.Request) {
+	body := http.MaxBytesReader(writer, request.Body, MaxImportBodySize)
+	report, err := h.importUsecase.ImportMARC(request.Context(), body, importOptions(request))
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to import marc records", err)
+		return
+	}
+	sendOkJSON(writer, getImportReportResponse(report))
+}
+
+func (h ImportHandler) ImportONIX(writer http.ResponseWriter, request *http.Request) {
+	body := http.MaxBytesReader(writer, request.Body, MaxImportBodySize)
+	report, err := h.importUsecase.ImportONIX(request.Context(), body, importOptions(request))
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to import onix message", err)
+		return
+	}
+	sendOkJSON(writer, getImportReportResponse(report))
+}
+
+func (h ImportHandler) ImportCalibre(writer http.ResponseWriter, request *http.Request) {
+	file, ok := receiveTempFile(writer, request, MaxCalibreBodySize, calibreTempPattern, "calibre library")
+	if !ok {
+		return
+	}
+	defer os.Remove(file.Name())
+	file.Close()
+
+	report, err := h.importUsecase.ImportCalibre(request.Context(), file.Name(), importOptions(request))
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to import calibre library", err)
+		return
+	}
+	sendOkJSON(writer, getImportReportResponse(report))
+}
+
+type BookFromEPUBResponse struct {
+	ID       *uuid.UUID              `json:"id,omitempty"`
+	Draft    AddBookRequest          `json:"draft"`
+	Language string                  `json:"language,omitempty"`
+	Created  ImportCreationsResponse `json:"created"`
+}
+
+func (h ImportHandler) BookFromEPUB(writer http.ResponseWriter, request *http.Request) {
+	file, ok := receiveTempFile(writer, request, MaxEPUBBodySize, epubTempPattern, "epub")
+	if !ok {
+		return
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to stat epub upload", err)
+		return
+	}
+	options := imports.EPUBOptions{
+		Commit: request.URL.Query().Get(VarCommit) == "true",
+		Force:  request.URL.Query().Get(VarForce) == "true",
+	}
+	result, err := h.importUsecase.ImportEPUB(request.Context(), file, info.Size(), options)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to import epub", err)
+		return
+	}
+
+	response := BookFromEPUBResponse{
+		Draft: AddBookRequest{
+			PublisherID: result.Draft.PublisherID,
+			AuthorsIDs:  result.Draft.AuthorsIDs,
+			TagsIDs:     result.Draft.TagsIDs,
+			PublishedAt: result.Draft.PublishedAt,
+			Title:       result.Draft.Title,
+			Description: result.Draft.Description,
+			ISBN:        result.Draft.ISBN,
+		},
+		Language: result.Language,
+		Created:  getImportCreationsResponse(result.Created),
+	}
+	if result.BookID != uuid.Nil {
+		response.ID = &result.BookID
+		sendCreatedJSON(writer, response)
+		return
+	}
+	sendOkJSON(writer, response)
+}
+
+// receiveTempFile stores the request body in a temporary file, for importers
+// that need random access; the file is left open at its end.
+func receiveTempFile(
+	writer http.ResponseWriter,
+	request *http.Request,
+	limit int64,
+	pattern string,
+	kind string,
+) (*os.File, bool) {
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to create "+kind+" temp file", err)
+		return nil, false
+	}
+
+	body := http.MaxBytesReader(writer, request.Body, limit)
+	_, err = io.Copy(file, body)
+	if err == nil {
+		return file, true
+	}
+	file.Close()
+	os.Remove(file.Name())
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		sendBadRequest(writer, kind+" is too large")
+		return nil, false
+	}
+	SendError(writer, err)
+	logs.Error("failed to store "+kind, err)
+	return nil, false
+}
+
+func importOptions(request *http.Request) imports.Options {
+	query := request.URL.Query()
+	return imports.Options{
+		DryRun:        query.Get(VarDryRun) == "true",
+		CreateMissing: query.Get(VarCreateMissing) == "true",
+		Force:         query.Get(VarForce) == "true",
+	}
+}
+
+func getImportReportResponse(report imports.Report) ImportReportResponse {
+	response := ImportReportResponse{
+		DryRun:  report.DryRun,
+		Rows:    make([]ImportRowResponse, len(report.Rows)),
+		Planned: getImportCreationsResponse(report.Planned),
+	}
+	if !report.Orphans.Empty() {
+		orphans := getImportCreationsResponse(report.Orphans)
+		response.Orphans = &orphans
+	}
+	for i, row := range report.Rows {
+		response.Rows[i] = ImportRowResponse{
+			Row:           row.Row,
+			Status:        row.Status,
+			Errors:        row.Errors,
+			CandidatesIDs: row.Candidates,
+			CandidateRows: row.CandidateRows,
+		}
+		if row.BookID != uuid.Nil {
+			id := row.BookID
+			response.Rows[i].BookID = &id
+		}
+	}
+	return response
+}
+
+func getImportCreationsResponse(creations imports.Creations) ImportCreationsResponse {
+	response := ImportCreationsResponse{
+		Authors:    make([]string, len(creations.Authors)),
+		Tags:       creations.Tags,
+		Publishers: creations.Publishers,
+	}
+	for i, author := range creations.Authors {
+		response.Authors[i] = author.String()
+	}
+	return response
+}
@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/pkg/logs"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// KOReaderRoot is the custom sync server address to enter in KOReader, the
+// protocol paths are resolved against it.
+const (
+	KOReaderRoot = "/koreader"
+
+	VarDocument = "document"
+
+	HeaderKOReaderUser = "x-auth-user"
+	HeaderKOReaderKey  = "x-auth-key"
+)
+
+// Error codes of the KOReader sync protocol, the device shows the message.
+const (
+	koreaderCodeUnknown        = 2000
+	koreaderCodeUnauthorized   = 2001
+	koreaderCodeUserExists     = 2002
+	koreaderCodeInvalidRequest = 2003
+	koreaderCodeNoDocument     = 2004
+)
+
+type KOReaderUsecase interface {
+	RegisterKOReader(ctx context.Context, username, key string) error
+	AuthenticateKOReader(ctx context.Context, username, key string) (uuid.UUID, error)
+	SyncDocumentProgress(
+		ctx context.Context,
+		userID uuid.UUID,
+		document string,
+		fraction float64,
+		position string,
+		device string,
+		deviceID string,
+	) (time.Time, error)
+	GetDocumentProgress(ctx context.Context, userID uuid.UUID, document string) (model.ReadingProgress, error)
+}
+
+type KOReaderHandler struct {
+	koreaderUsecase KOReaderUsecase
+}
+
+func NewKOReaderHandler(usecase KOReaderUsecase) *KOReaderHandler {
+	return &KOReaderHandler{
+		koreaderUsecase: usecase,
+	}
+}
+
+type KOReaderUserRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type KOReaderProgressRequest struct {
+	Document   string  `json:"document"`
+	Progress   string  `json:"progress"`
+	Percentage float64 `json:"percentage"`
+	Device     string  `json:"device"`
+	DeviceID   string  `json:"device_id"`
+}
+
+type KOReaderProgressResponse struct {
+	Document   string   `json:"document"`
+	Progress   string   `json:"progress,omitempty"`
+	Percentage *float64 `json:"percentage,omitempty"`
+	Device     string   `json:"device,omitempty"`
+	DeviceID   string   `json:"device_id,omitempty"`
+	Timestamp  int64    `json:"timestamp"`
+}
+
+func (h KOReaderHandler) CreateUser(writer http.ResponseWriter, request *http.Request) {
+	var req KOReaderUserRequest
+	if err := json.NewDecoder(request.Body).Decode(&req); err != nil || req.Username == "" || req.Password == "" {
+		sendKOReaderError(writer, http.StatusForbidden, koreaderCodeInvalidRequest, "Invalid request")
+		return
+	}
+
+	if err := h.koreaderUsecase.RegisterKOReader(request.Context(), req.Username, req.Password); err != nil {
+		switch {
+		case errors.Is(err, model.ErrKOReaderUsernameTaken):
+			sendKOReaderError(writer, http.StatusPaymentRequired, koreaderCodeUserExists, "Username is already registered.")
+		case errors.Is(err, model.ErrKOReaderNotLinked):
+			sendKOReaderError(writer, http.StatusForbidden, koreaderCodeInvalidRequest, err.Error())
+		default:
+			sendKOReaderUnknownError(writer, err, "failed to register koreader user")
+		}
+		return
+	}
+	sendCreatedJSON(writer, map[string]string{"username": req.Username})
+}
+
+func (h KOReaderHandler) Authorize(writer http.ResponseWriter, request *http.Request) {
+	if _, ok := h.authenticate(writer, request); !ok {
+		return
+	}
+	sendOkJSON(writer, map[string]string{"authorized": "OK"})
+}
+
+func (h KOReaderHandler) UpdateProgress(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.authenticate(writer, request)
+	if !ok {
+		return
+	}
+
+	var req KOReaderProgressRequest
+	if err := json.NewDecoder(request.Body).Decode(&req); err != nil {
+		sendKOReaderError(writer, http.StatusForbidden, koreaderCodeInvalidRequest, "Invalid request")
+		return
+	}
+	if req.Document == "" {
+		sendKOReaderError(writer, http.StatusForbidden, koreaderCodeNoDocument, "Field 'document' not provided.")
+		return
+	}
+
+	updatedAt, err := h.koreaderUsecase.SyncDocumentProgress(
+		request.Context(), userID, req.Document, req.Percentage, req.Progress, req.Device, req.DeviceID,
+	)
+	if err != nil {
+		// The device sends a fraction, the shared error speaks of percents.
+		if errors.Is(err, model.ErrProgressInvalidPercentage) {
+			sendKOReaderError(
+				writer, http.StatusForbidden, koreaderCodeInvalidRequest, "Field 'percentage' must be between 0 and 1.",
+			)
+			return
+		}
+		sendKOReaderUnknownError(writer, err, "failed to sync koreader progress")
+		return
+	}
+	sendOkJSON(writer, KOReaderProgressResponse{Document: req.Document, Timestamp: updatedAt.Unix()})
+}
+
+// GetProgress answers with an empty object for documents that were never
+// synced, that is what KOReader expects.
+func (h KOReaderHandler) GetProgress(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.authenticate(writer, request)
+	if !ok {
+		return
+	}
+	document := mux.Vars(request)[VarDocument]
+
+	progress, err := h.koreaderUsecase.GetDocumentProgress(request.Context(), userID, document)
+	if errors.Is(err, model.ErrProgressNotFound) {
+		sendOkJSON(writer, struct{}{})
+		return
+	}
+	if err != nil {
+		sendKOReaderUnknownError(writer, err, "failed to get koreader progress")
+		return
+	}
+
+	response := KOReaderProgressResponse{
+		Document:   document,
+		Percentage: &progress.Fraction,
+		Timestamp:  progress.UpdatedAt.Unix(),
+	}
+	if progress.Position != nil {
+		response.Progress = *progress.Position
+	}
+	if progress.Device != nil {
+		response.Device = *progress.Device
+	}
+	if progress.DeviceID != nil {
+		response.DeviceID = *progress.DeviceID
+	}
+	sendOkJSON(writer, response)
+}
+
+func (h KOReaderHandler) authenticate(writer http.ResponseWriter, request *http.Request) (uuid.UUID, bool) {
+	userID, err := h.koreaderUsecase.AuthenticateKOReader(
+		request.Context(), request.Header.Get(HeaderKOReaderUser), request.Header.Get(HeaderKOReaderKey),
+	)
+	if err != nil {
+		if errors.Is(err, model.ErrKOReaderUnauthorized) {
+			sendKOReaderError(writer, http.StatusUnauthorized, koreaderCodeUnauthorized, "Unauthorized")
+			return uuid.Nil, false
+		}
+		sendKOReaderUnknownError(writer, err, "failed to authenticate koreader user")
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func sendKOReaderError(writer http.ResponseWriter, status int, code int, message string) {
+	sendJSON(
+		writer, struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		}{code, message}, status,
+	)
+}
+
+func sendKOReaderUnknownError(writer http.ResponseWriter, err error, message string) {
+	logs.Error(message, err, slog.String("protocol", "koreader"))
+	sendKOReaderError(writer, http.StatusInternalServerError, koreaderCodeUnknown, "Unknown server error.")
+}
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/progress"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var errNotStubbed = errors.New("not stubbed")
+
+// koreaderStorageStub keeps KOReader accounts and document progress in
+// memory, the book progress methods are not used by the sync protocol.
+type koreaderStorageStub struct {
+	now       time.Time
+	accounts  map[string]model.KOReaderAccount
+	keyHashes map[string][]byte
+	documents map[string]model.ReadingProgress
+}
+
+func newKOReaderStorageStub(now time.Time) *koreaderStorageStub {
+	return &koreaderStorageStub{
+		now:       now,
+		accounts:  make(map[string]model.KOReaderAccount),
+		keyHashes: make(map[string][]byte),
+		documents: make(map[string]model.ReadingProgress),
+	}
+}
+
+func (s *koreaderStorageStub) SetBookProgress(
+	context.Context, uuid.UUID, uuid.UUID, *string, model.ReadingProgress,
+) error {
+	return errNotStubbed
+}
+
+func (s *koreaderStorageStub) GetBookProgress(context.Context, uuid.UUID, uuid.UUID) (model.ReadingProgress, error) {
+	return model.ReadingProgress{}, errNotStubbed
+}
+
+func (s *koreaderStorageStub) RemoveBookProgress(context.Context, uuid.UUID, uuid.UUID) error {
+	return errNotStubbed
+}
+
+func (s *koreaderStorageStub) ListProgress(context.Context, uuid.UUID) ([]model.ReadingProgress, error) {
+	return nil, errNotStubbed
+}
+
+func (s *koreaderStorageStub) SetDocumentProgress(
+	_ context.Context,
+	userID uuid.UUID,
+	document string,
+	readingProgress model.ReadingProgress,
+) (time.Time, error) {
+	readingProgress.UserID = userID
+	readingProgress.Document = &document
+	readingProgress.UpdatedAt = s.now
+	s.documents[userID.String()+document] = readingProgress
+	return s.now, nil
+}
+
+func (s *koreaderStorageStub) GetDocumentProgress(
+	_ context.Context,
+	userID uuid.UUID,
+	document string,
+) (model.ReadingProgress, error) {
+	readingProgress, ok := s.documents[userID.String()+document]
+	if !ok {
+		return model.ReadingProgress{}, model.ErrProgressNotFound
+	}
+	return readingProgress, nil
+}
+
+func (s *koreaderStorageStub) SetKOReaderAccount(
+	_ context.Context,
+	userID uuid.UUID,
+	username string,
+	keyHash []byte,
+) error {
+	s.accounts[username] = model.KOReaderAccount{UserID: userID, Username: username, CreatedAt: s.now}
+	s.keyHashes[username] = keyHash
+	return nil
+}
+
+func (s *koreaderStorageStub) GetKOReaderAccount(context.Context, uuid.UUID) (model.KOReaderAccount, error) {
+	return model.KOReaderAccount{}, errNotStubbed
+}
+
+func (s *koreaderStorageStub) RemoveKOReaderAccount(context.Context, uuid.UUID) error {
+	return errNotStubbed
+}
+
+func (s *koreaderStorageStub) GetKOReaderKeyHash(_ context.Context, username string) (uuid.UUID, []byte, error) {
+	account, ok := s.accounts[username]
+	if !ok {
+		return uuid.Nil, nil, model.ErrKOReaderNotLinked
+	}
+	return account.UserID, s.keyHashes[username], nil
+}
+
+type koreaderExchange struct {
+	Request struct {
+		Method  string            `json:"method"`
+		Path    string            `json:"path"`
+		Headers map[string]string `json:"headers"`
+		Body    json.RawMessage   `json:"body"`
+		RawBody string            `json:"raw_body"`
+	} `json:"request"`
+	Status   int             `json:"status"`
+	Response json.RawMessage `json:"response"`
+}
+
+// TestKOReaderSync replays the requests of every fixture in order against the
+// protocol routes. The user "reader" linked KOReader with the password
+// "secret", whose MD5 digest the device sends as the key.
+func TestKOReaderSync(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "koreader", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no KOReader fixtures")
+	}
+	for _, path := range paths {
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".json"), func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var exchanges []koreaderExchange
+			if err = json.Unmarshal(data, &exchanges); err != nil {
+				t.Fatalf("failed to decode %s: %v", path, err)
+			}
+
+			storage := newKOReaderStorageStub(time.Date(2024, 3, 5, 10, 11, 12, 0, time.UTC))
+			usecase := progress.NewProgressUsecase(storage)
+			if err = usecase.LinkKOReader(context.Background(), uuid.New(), "reader", "secret"); err != nil {
+				t.Fatal(err)
+			}
+			router := koreaderRouter(NewKOReaderHandler(usecase))
+
+			for i, exchange := range exchanges {
+				body := []byte(exchange.Request.RawBody)
+				if exchange.Request.Body != nil {
+					body = exchange.Request.Body
+				}
+				request := httptest.NewRequest(
+					exchange.Request.Method, KOReaderRoot+exchange.Request.Path, bytes.NewReader(body),
+				)
+				for name, value := range exchange.Request.Headers {
+					request.Header.Set(name, value)
+				}
+				recorder := httptest.NewRecorder()
+				router.ServeHTTP(recorder, request)
+
+				name := exchange.Request.Method + " " + exchange.Request.Path
+				if recorder.Code != exchange.Status {
+					t.Errorf("%d. %s: status = %d, want %d", i+1, name, recorder.Code, exchange.Status)
+				}
+				var got, want any
+				if err = json.Unmarshal(recorder.Body.Bytes(), &got); err != nil {
+					t.Fatalf("%d. %s: failed to decode %q: %v", i+1, name, recorder.Body.String(), err)
+				}
+				if err = json.Unmarshal(exchange.Response, &want); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%d. %s: response = %s, want %s", i+1, name, recorder.Body.String(), exchange.Response)
+				}
+			}
+		})
+	}
+}
+
+// koreaderRouter mounts the handler the way the router package does.
+func koreaderRouter(h *KOReaderHandler) http.Handler {
+	router := mux.NewRouter()
+	koreader := router.PathPrefix(KOReaderRoot).Subrouter()
+	koreader.HandleFunc("/users/create", h.CreateUser).Methods(http.MethodPost)
+	koreader.HandleFunc("/users/auth", h.Authorize).Methods(http.MethodGet)
+	koreader.HandleFunc("/syncs/progress", h.UpdateProgress).Methods(http.MethodPut)
+	koreader.HandleFunc("/syncs/progress/{"+VarDocument+"}", h.GetProgress).Methods(http.MethodGet)
+	return router
+}
@@ -0,0 +1,400 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ContentTypeJSON    = "application/json"
+	ContentTypeJSONLD  = "application/ld+json"
+	ContentTypeRDFXML  = "application/rdf+xml"
+	ContentTypeTurtle  = "text/turtle"
+	contentTypeAnyType = "*/*"
+
+	schemaContext = "https://schema.org"
+
+	namespaceRDF     = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	namespaceDCTerms = "http://purl.org/dc/terms/"
+	namespaceFOAF    = "http://xmlns.com/foaf/0.1/"
+	namespaceXSD     = "http://www.w3.org/2001/XMLSchema#"
+)
+
+type representation int
+
+const (
+	representationJSON representation = iota
+	representationJSONLD
+	representationRDFXML
+	representationTurtle
+)
+
+var representationTypes = map[string]representation{
+	ContentTypeJSON:    representationJSON,
+	ContentTypeJSONLD:  representationJSONLD,
+	ContentTypeRDFXML:  representationRDFXML,
+	ContentTypeTurtle:  representationTurtle,
+	contentTypeAnyType: representationJSON,
+	"application/*":    representationJSON,
+}
+
+// negotiateRepresentation picks the supported media type with the highest
+// quality from the Accept header, falling back to plain JSON.
+func negotiateRepresentation(request *http.Request) representation {
+	best, bestQuality := representationJSON, 0.0
+	for _, part := range strings.Split(request.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		candidate, ok := representationTypes[mediaType]
+		if !ok {
+			continue
+		}
+		quality := 1.0
+		if raw, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(raw, 64); err != nil {
+				continue
+			}
+		}
+		if quality > bestQuality {
+			best, bestQuality = candidate, quality
+		}
+	}
+	return best
+}
+
+type linkedResource interface {
+	jsonLD(base string) map[string]any
+	dublinCore(base string) *rdfGraph
+}
+
+func sendRepresentation(
+	writer http.ResponseWriter,
+	request *http.Request,
+	kind representation,
+	response any,
+	resource linkedResource,
+) {
+	writer.Header().Add("Vary", "Accept")
+	base := requestBaseURL(request)
+	switch kind {
+	case representationJSONLD:
+		document := resource.jsonLD(base)
+		document["@context"] = schemaContext
+		body, _ := json.Marshal(document)
+		sendOkBody(writer, ContentTypeJSONLD, body)
+	case representationRDFXML:
+		sendOkBody(writer, ContentTypeRDFXML+"; charset=utf-8", resource.dublinCore(base).rdfXML())
+	case representationTurtle:
+		sendOkBody(writer, ContentTypeTurtle+"; charset=utf-8", resource.dublinCore(base).turtle())
+	default:
+		sendOkJSON(writer, response)
+	}
+}
+
+func requestBaseURL(request *http.Request) string {
+	scheme := "http"
+	if request.TLS != nil {
+		scheme = "https"
+	}
+	if forwarded := request.Header.Get("X-Forwarded-Proto"); forwarded != "" {
+		scheme = forwarded
+	}
+	return scheme + "://" + request.Host
+}
+
+func resourceIRI(base, collection string, id uuid.UUID) string {
+	return base + "/" + collection + "/" + id.String()
+}
+
+func (b BookResponse) jsonLD(base string) map[string]any {
+	document := map[string]any{
+		"@type": "Book",
+		"@id":   resourceIRI(base, "books", b.ID),
+		"name":  b.Title,
+	}
+	if b.ISBN != nil {
+		document["isbn"] = *b.ISBN
+	}
+	if b.Description != nil {
+		document["description"] = *b.Description
+	}
+	if b.PublishedAt != nil {
+		document["datePublished"] = b.PublishedAt.Format(time.DateOnly)
+	}
+	if len(b.Authors) > 0 {
+		authors := make([]map[string]any, len(b.Authors))
+		for i, author := range b.Authors {
+			authors[i] = author.jsonLD(base)
+		}
+		document["author"] = authors
+	}
+	if b.Publisher != nil {
+		document["publisher"] = b.Publisher.jsonLD(base)
+	}
+	if len(b.Tags) > 0 {
+		keywords := make([]string, len(b.Tags))
+		for i, tag := range b.Tags {
+			keywords[i] = tag.Name
+		}
+		document["keywords"] = keywords
+	}
+	if b.Rating.Count > 0 {
+		document["aggregateRating"] = map[string]any{
+			"@type":       "AggregateRating",
+			"ratingValue": b.Rating.Average,
+			"ratingCount": b.Rating.Count,
+			"worstRating": model.RatingMin,
+			"bestRating":  model.RatingMax,
+		}
+	}
+	return document
+}
+
+func (b BookResponse) dublinCore(base string) *rdfGraph {
+	graph := newRDFGraph()
+	subject := graph.subject(resourceIRI(base, "books", b.ID), namespaceDCTerms+"BibliographicResource")
+	subject.literal(namespaceDCTerms+"identifier", "urn:uuid:"+b.ID.String(), "")
+	if b.ISBN != nil {
+		subject.literal(namespaceDCTerms+"identifier", "urn:isbn:"+*b.ISBN, "")
+	}
+	subject.literal(namespaceDCTerms+"title", b.Title, "")
+	if b.Description != nil {
+		subject.literal(namespaceDCTerms+"description", *b.Description, "")
+	}
+	if b.PublishedAt != nil {
+		subject.literal(namespaceDCTerms+"issued", b.PublishedAt.Format(time.DateOnly), namespaceXSD+"date")
+	}
+	for _, author := range b.Authors {
+		subject.resource(namespaceDCTerms+"creator", resourceIRI(base, "authors", author.ID))
+		author.describe(graph, base)
+	}
+	if b.Publisher != nil {
+		subject.resource(namespaceDCTerms+"publisher", resourceIRI(base, "publishers", b.Publisher.ID))
+		b.Publisher.describe(graph, base)
+	}
+	for _, tag := range b.Tags {
+		subject.literal(namespaceDCTerms+"subject", tag.Name, "")
+	}
+	return graph
+}
+
+func (a AuthorResponse) jsonLD(base string) map[string]any {
+	document := map[string]any{
+		"@type": "Person",
+		"@id":   resourceIRI(base, "authors", a.ID),
+		"name":  a.name(),
+	}
+	if a.FirstName != nil {
+		document["givenName"] = *a.FirstName
+	}
+	if a.LastName != nil {
+		document["familyName"] = *a.LastName
+	}
+	if a.MiddleName != nil {
+		document["additionalName"] = *a.MiddleName
+	}
+	if a.Pseudonym != nil {
+		document["alternateName"] = *a.Pseudonym
+	}
+	return document
+}
+
+func (a AuthorResponse) dublinCore(base string) *rdfGraph {
+	graph := newRDFGraph()
+	a.describe(graph, base)
+	return graph
+}
+
+func (a AuthorResponse) describe(graph *rdfGraph, base string) {
+	subject := graph.subject(resourceIRI(base, "authors", a.ID), namespaceDCTerms+"Agent")
+	subject.literal(namespaceFOAF+"name", a.name(), "")
+	if a.FirstName != nil {
+		subject.literal(namespaceFOAF+"givenName", *a.FirstName, "")
+	}
+	if a.LastName != nil {
+		subject.literal(namespaceFOAF+"familyName", *a.LastName, "")
+	}
+	if a.Pseudonym != nil {
+		subject.literal(namespaceFOAF+"nick", *a.Pseudonym, "")
+	}
+}
+
+func (a AuthorResponse) name() string {
+	return displayName(a.FirstName, a.MiddleName, a.LastName, a.Pseudonym)
+}
+
+func (p PublisherResponse) jsonLD(base string) map[string]any {
+	return map[string]any{
+		"@type": "Organization",
+		"@id":   resourceIRI(base, "publishers", p.ID),
+		"name":  p.Name,
+	}
+}
+
+func (p PublisherResponse) dublinCore(base string) *rdfGraph {
+	graph := newRDFGraph()
+	p.describe(graph, base)
+	return graph
+}
+
+func (p PublisherResponse) describe(graph *rdfGraph, base string) {
+	subject := graph.subject(resourceIRI(base, "publishers", p.ID), namespaceDCTerms+"Agent")
+	subject.literal(namespaceFOAF+"name", p.Name, "")
+}
+
+type rdfObject struct {
+	IRI      string
+	Literal  string
+	Datatype string
+}
+
+type rdfStatement struct {
+	Predicate string
+	Object    rdfObject
+}
+
+type rdfSubject struct {
+	IRI        string
+	Type       string
+	Statements []rdfStatement
+}
+
+type rdfGraph struct {
+	subjects []*rdfSubject
+	byIRI    map[string]*rdfSubject
+}
+
+var rdfPrefixes = []struct {
+	prefix    string
+	namespace string
+}{
+	{"rdf", namespaceRDF},
+	{"dcterms", namespaceDCTerms},
+	{"foaf", namespaceFOAF},
+	{"xsd", namespaceXSD},
+}
+
+func newRDFGraph() *rdfGraph {
+	return &rdfGraph{byIRI: make(map[string]*rdfSubject)}
+}
+
+func (g *rdfGraph) subject(iri, rdfType string) *rdfSubject {
+	if subject, ok := g.byIRI[iri]; ok {
+		return subject
+	}
+	subject := &rdfSubject{IRI: iri, Type: rdfType}
+	g.subjects = append(g.subjects, subject)
+	g.byIRI[iri] = subject
+	return subject
+}
+
+func (s *rdfSubject) literal(predicate, value, datatype string) {
+	s.Statements = append(s.Statements, rdfStatement{predicate, rdfObject{Literal: value, Datatype: datatype}})
+}
+
+func (s *rdfSubject) resource(predicate, iri string) {
+	s.Statements = append(s.Statements, rdfStatement{predicate, rdfObject{IRI: iri}})
+}
+
+func compactIRI(iri string) (prefix, local string, ok bool) {
+	for _, known := range rdfPrefixes {
+		if strings.HasPrefix(iri, known.namespace) {
+			return known.prefix, strings.TrimPrefix(iri, known.namespace), true
+		}
+	}
+	return "", "", false
+}
+
+func (g *rdfGraph) turtle() []byte {
+	var buffer bytes.Buffer
+	for _, known := range rdfPrefixes {
+		fmt.Fprintf(&buffer, "@prefix %s: <%s> .\n", known.prefix, known.namespace)
+	}
+	for _, subject := range g.subjects {
+		fmt.Fprintf(&buffer, "\n<%s> a %s", subject.IRI, turtleTerm(subject.Type))
+		for _, statement := range subject.Statements {
+			fmt.Fprintf(&buffer, " ;\n    %s ", turtleTerm(statement.Predicate))
+			if statement.Object.IRI != "" {
+				fmt.Fprintf(&buffer, "<%s>", statement.Object.IRI)
+				continue
+			}
+			buffer.WriteString(turtleString(statement.Object.Literal))
+			if statement.Object.Datatype != "" {
+				buffer.WriteString("^^" + turtleTerm(statement.Object.Datatype))
+			}
+		}
+		buffer.WriteString(" .\n")
+	}
+	return buffer.Bytes()
+}
+
+func turtleTerm(iri string) string {
+	if prefix, local, ok := compactIRI(iri); ok {
+		return prefix + ":" + local
+	}
+	return "<" + iri + ">"
+}
+
+var turtleEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\t", `\t`,
+)
+
+func turtleString(value string) string {
+	return `"` + turtleEscaper.Replace(value) + `"`
+}
+
+func (g *rdfGraph) rdfXML() []byte {
+	var buffer bytes.Buffer
+	buffer.WriteString(xml.Header)
+	buffer.WriteString("<rdf:RDF")
+	for _, known := range rdfPrefixes {
+		if known.prefix == "xsd" {
+			continue
+		}
+		fmt.Fprintf(&buffer, " xmlns:%s=\"%s\"", known.prefix, known.namespace)
+	}
+	buffer.WriteString(">\n")
+	for _, subject := range g.subjects {
+		fmt.Fprintf(&buffer, "  <rdf:Description rdf:about=\"%s\">\n", xmlEscape(subject.IRI))
+		fmt.Fprintf(&buffer, "    <rdf:type rdf:resource=\"%s\"/>\n", xmlEscape(subject.Type))
+		for _, statement := range subject.Statements {
+			prefix, local, _ := compactIRI(statement.Predicate)
+			element := prefix + ":" + local
+			switch {
+			case statement.Object.IRI != "":
+				fmt.Fprintf(&buffer, "    <%s rdf:resource=\"%s\"/>\n", element, xmlEscape(statement.Object.IRI))
+			case statement.Object.Datatype != "":
+				fmt.Fprintf(
+					&buffer, "    <%s rdf:datatype=\"%s\">%s</%s>\n",
+					element, xmlEscape(statement.Object.Datatype), xmlEscape(statement.Object.Literal), element,
+				)
+			default:
+				fmt.Fprintf(&buffer, "    <%s>%s</%s>\n", element, xmlEscape(statement.Object.Literal), element)
+			}
+		}
+		buffer.WriteString("  </rdf:Description>\n")
+	}
+	buffer.WriteString("</rdf:RDF>\n")
+	return buffer.Bytes()
+}
+
+func xmlEscape(value string) string {
+	var buffer bytes.Buffer
+	xml.EscapeText(&buffer, []byte(value))
+	return buffer.String()
+}
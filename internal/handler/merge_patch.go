@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/pkg/logs"
+	"mime"
+	"net/http"
+)
+
+const ContentTypeMergePatch = "application/merge-patch+json"
+
+var (
+	errUnsupportedPatchType = model.NewInternalError(
+		http.StatusUnsupportedMediaType,
+		fmt.Sprintf("content type must be %s", ContentTypeMergePatch),
+	)
+	errPatchNotObject = model.NewInternalError(http.StatusBadRequest, "merge patch must be a JSON object")
+)
+
+func decodeMergePatch(writer http.ResponseWriter, request *http.Request) (map[string]json.RawMessage, bool) {
+	mediaType, _, err := mime.ParseMediaType(request.Header.Get("Content-Type"))
+	if err != nil || mediaType != ContentTypeMergePatch {
+		SendError(writer, errUnsupportedPatchType)
+		return nil, false
+	}
+
+	var members map[string]json.RawMessage
+	if err = json.NewDecoder(request.Body).Decode(&members); err != nil || members == nil {
+		SendError(writer, errPatchNotObject)
+		if err != nil {
+			logs.Error("failed to decode the merge patch", err)
+		}
+		return nil, false
+	}
+	return members, true
+}
+
+func isJSONNull(raw json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
+
+func mergeNullable[t any](key string, raw json.RawMessage, target **t) (bool, error) {
+	if isJSONNull(raw) {
+		*target = nil
+		return true, nil
+	}
+	var value t
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return false, invalidPatchMember(key)
+	}
+	*target = &value
+	return false, nil
+}
+
+func mergeSlice[t any](key string, raw json.RawMessage, target *[]t) error {
+	value := make([]t, 0)
+	if !isJSONNull(raw) {
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return invalidPatchMember(key)
+		}
+	}
+	*target = value
+	return nil
+}
+
+func invalidPatchMember(key string) error {
+	return model.NewInternalError(http.StatusBadRequest, fmt.Sprintf("invalid value of '%s'", key)).WithFields(key)
+}
+
+func unknownPatchMember(key string) error {
+	return model.NewInternalError(http.StatusBadRequest, fmt.Sprintf("unknown field '%s'", key)).WithFields(key)
+}
+
+func readOnlyPatchMember(key string) error {
+	return model.NewInternalError(http.StatusBadRequest, fmt.Sprintf("field '%s' is read-only", key)).WithFields(key)
+}
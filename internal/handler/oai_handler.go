@@ -0,0 +1,430 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/config"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/harvest"
+	"github.com/iamvkosarev/book-shelf/pkg/logs"
+	"github.com/iamvkosarev/book-shelf/pkg/oaipmh"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	ContentTypeOAIPMH = "text/xml; charset=utf-8"
+
+	OAIPath = "/oai"
+
+	oaiArgVerb            = "verb"
+	oaiArgIdentifier      = "identifier"
+	oaiArgMetadataPrefix  = "metadataPrefix"
+	oaiArgFrom            = "from"
+	oaiArgUntil           = "until"
+	oaiArgSet             = "set"
+	oaiArgResumptionToken = "resumptionToken"
+)
+
+var oaiVerbArguments = map[string][]string{
+	oaipmh.VerbIdentify:            {},
+	oaipmh.VerbListMetadataFormats: {oaiArgIdentifier},
+	oaipmh.VerbListSets:            {oaiArgResumptionToken},
+	oaipmh.VerbGetRecord:           {oaiArgIdentifier, oaiArgMetadataPrefix},
+	oaipmh.VerbListIdentifiers: {
+		oaiArgMetadataPrefix, oaiArgFrom, oaiArgUntil, oaiArgSet, oaiArgResumptionToken,
+	},
+	oaipmh.VerbListRecords: {
+		oaiArgMetadataPrefix, oaiArgFrom, oaiArgUntil, oaiArgSet, oaiArgResumptionToken,
+	},
+}
+
+var oaiErrorCodes = []struct {
+	err  error
+	code string
+}{
+	{model.ErrHarvestBadResumptionToken, oaipmh.ErrorBadResumptionToken},
+	{model.ErrHarvestUnknownSet, oaipmh.ErrorBadArgument},
+	{model.ErrHarvestNoRecordsMatch, oaipmh.ErrorNoRecordsMatch},
+	{model.ErrBookNotFound, oaipmh.ErrorIDDoesNotExist},
+}
+
+type HarvestUsecase interface {
+	EarliestDatestamp(ctx context.Context) (time.Time, error)
+	ListSets(ctx context.Context) ([]harvest.Set, error)
+	GetRecord(ctx context.Context, id uuid.UUID) (harvest.Record, error)
+	ListRecords(
+		ctx context.Context,
+		parameters harvest.ListParameters,
+		token string,
+		expand bool,
+	) (harvest.Page, error)
+}
+
+type OAIHandler struct {
+	harvestUsecase HarvestUsecase
+	repository     config.OAI
+}
+
+func NewOAIHandler(usecase HarvestUsecase, repository config.OAI) *OAIHandler {
+	return &OAIHandler{
+		harvestUsecase: usecase,
+		repository:     repository,
+	}
+}
+
+type oaiArguments map[string]string
+
+func (h OAIHandler) Handle(writer http.ResponseWriter, request *http.Request) {
+	baseURL := requestBaseURL(request) + OAIPath
+	response := oaipmh.NewResponse(oaipmh.Request{URL: baseURL}, time.Now())
+
+	if err := request.ParseForm(); err != nil {
+		h.send(writer, response, oaiError(oaipmh.ErrorBadArgument, "request arguments cannot be parsed"))
+		return
+	}
+	arguments, oaiErr := parseOAIArguments(request)
+	if oaiErr != nil {
+		h.send(writer, response, oaiErr)
+		return
+	}
+	response.Request = oaipmh.Request{
+		URL:             baseURL,
+		Verb:            arguments[oaiArgVerb],
+		Identifier:      arguments[oaiArgIdentifier],
+		MetadataPrefix:  arguments[oaiArgMetadataPrefix],
+		From:            arguments[oaiArgFrom],
+		Until:           arguments[oaiArgUntil],
+		Set:             arguments[oaiArgSet],
+		ResumptionToken: arguments[oaiArgResumptionToken],
+	}
+
+	var err error
+	switch arguments[oaiArgVerb] {
+	case oaipmh.VerbIdentify:
+		err = h.identify(request.Context(), &response, baseURL)
+	case oaipmh.VerbListMetadataFormats:
+		err = h.listMetadataFormats(request.Context(), &response, arguments)
+	case oaipmh.VerbListSets:
+		err = h.listSets(request.Context(), &response, arguments)
+	case oaipmh.VerbGetRecord:
+		err = h.getRecord(request.Context(), &response, arguments)
+	case oaipmh.VerbListIdentifiers, oaipmh.VerbListRecords:
+		err = h.listRecords(request.Context(), &response, arguments)
+	}
+
+	var protocolError oaipmh.Error
+	if errors.As(err, &protocolError) {
+		h.send(writer, response, &protocolError)
+		return
+	}
+	for _, known := range oaiErrorCodes {
+		if errors.Is(err, known.err) {
+			h.send(writer, response, oaiError(known.code, known.err.Error()))
+			return
+		}
+	}
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to handle oai-pmh request", err, slog.String("verb", arguments[oaiArgVerb]))
+		return
+	}
+	h.send(writer, response, nil)
+}
+
+func parseOAIArguments(request *http.Request) (oaiArguments, *oaipmh.Error) {
+	verbs := request.Form[oaiArgVerb]
+	if len(verbs) != 1 {
+		return nil, oaiError(oaipmh.ErrorBadVerb, "exactly one verb argument is required")
+	}
+	allowed, ok := oaiVerbArguments[verbs[0]]
+	if !ok {
+		return nil, oaiError(oaipmh.ErrorBadVerb, "verb is not a legal OAI-PMH verb")
+	}
+
+	arguments := oaiArguments{oaiArgVerb: verbs[0]}
+	for name, values := range request.Form {
+		if name == oaiArgVerb {
+			continue
+		}
+		if !slices.Contains(allowed, name) {
+			return nil, oaiError(oaipmh.ErrorBadArgument, "illegal argument "+name)
+		}
+		if len(values) != 1 {
+			return nil, oaiError(oaipmh.ErrorBadArgument, "argument "+name+" is repeated")
+		}
+		arguments[name] = values[0]
+	}
+
+	if token, ok := arguments[oaiArgResumptionToken]; ok {
+		if len(arguments) != 2 || token == "" {
+			return nil, oaiError(oaipmh.ErrorBadArgument, "resumptionToken is an exclusive argument")
+		}
+		return arguments, nil
+	}
+	switch verbs[0] {
+	case oaipmh.VerbGetRecord:
+		if arguments[oaiArgIdentifier] == "" {
+			return nil, oaiError(oaipmh.ErrorBadArgument, "identifier is required")
+		}
+		fallthrough
+	case oaipmh.VerbListIdentifiers, oaipmh.VerbListRecords:
+		if arguments[oaiArgMetadataPrefix] == "" {
+			return nil, oaiError(oaipmh.ErrorBadArgument, "metadataPrefix is required")
+		}
+	}
+	return arguments, nil
+}
+
+func (h OAIHandler) identify(ctx context.Context, response *oaipmh.Response, baseURL string) error {
+	earliest, err := h.harvestUsecase.EarliestDatestamp(ctx)
+	if err != nil {
+		return err
+	}
+	response.Identify = &oaipmh.Identify{
+		RepositoryName:    h.repository.RepositoryName,
+		BaseURL:           baseURL,
+		ProtocolVersion:   oaipmh.ProtocolVersion,
+		AdminEmails:       []string{h.repository.AdminEmail},
+		EarliestDatestamp: oaipmh.FormatDatestamp(earliest),
+		DeletedRecord:     oaipmh.DeletedPersistent,
+		Granularity:       oaipmh.GranularitySeconds,
+	}
+	return nil
+}
+
+func (h OAIHandler) listMetadataFormats(
+	ctx context.Context,
+	response *oaipmh.Response,
+	arguments oaiArguments,
+) error {
+	if identifier, ok := arguments[oaiArgIdentifier]; ok {
+		id, err := h.parseIdentifier(identifier)
+		if err != nil {
+			return err
+		}
+		if _, err = h.harvestUsecase.GetRecord(ctx, id); err != nil {
+			return err
+		}
+	}
+	response.ListMetadataFormats = &oaipmh.ListMetadataFormats{
+		Formats: []oaipmh.MetadataFormat{
+			{
+				MetadataPrefix:    oaipmh.MetadataPrefixDC,
+				Schema:            oaipmh.SchemaOAIDC,
+				MetadataNamespace: oaipmh.NamespaceOAIDC,
+			},
+		},
+	}
+	return nil
+}
+
+func (h OAIHandler) listSets(ctx context.Context, response *oaipmh.Response, arguments oaiArguments) error {
+	if _, ok := arguments[oaiArgResumptionToken]; ok {
+		return model.ErrHarvestBadResumptionToken
+	}
+	sets, err := h.harvestUsecase.ListSets(ctx)
+	if err != nil {
+		return err
+	}
+	response.ListSets = &oaipmh.ListSets{Sets: make([]oaipmh.Set, len(sets))}
+	for i, set := range sets {
+		response.ListSets.Sets[i] = oaipmh.Set{Spec: set.Spec, Name: set.Name}
+	}
+	return nil
+}
+
+func (h OAIHandler) getRecord(ctx context.Context, response *oaipmh.Response, arguments oaiArguments) error {
+	if err := checkMetadataPrefix(arguments); err != nil {
+		return err
+	}
+	id, err := h.parseIdentifier(arguments[oaiArgIdentifier])
+	if err != nil {
+		return err
+	}
+	record, err := h.harvestUsecase.GetRecord(ctx, id)
+	if err != nil {
+		return err
+	}
+	response.GetRecord = &oaipmh.GetRecord{Record: h.record(record)}
+	return nil
+}
+
+func (h OAIHandler) listRecords(ctx context.Context, response *oaipmh.Response, arguments oaiArguments) error {
+	token := arguments[oaiArgResumptionToken]
+	var parameters harvest.ListParameters
+	if token == "" {
+		if err := checkMetadataPrefix(arguments); err != nil {
+			return err
+		}
+		var err error
+		if parameters, err = parseHarvestRange(arguments); err != nil {
+			return err
+		}
+		parameters.Set = arguments[oaiArgSet]
+	}
+
+	withMetadata := arguments[oaiArgVerb] == oaipmh.VerbListRecords
+	page, err := h.harvestUsecase.ListRecords(ctx, parameters, token, withMetadata)
+	if err != nil {
+		return err
+	}
+
+	var resumption *oaipmh.ResumptionToken
+	if page.Next != "" || token != "" {
+		resumption = &oaipmh.ResumptionToken{Value: page.Next, Cursor: page.Cursor}
+	}
+	if !withMetadata {
+		response.ListIdentifiers = &oaipmh.ListIdentifiers{ResumptionToken: resumption}
+		for _, record := range page.Records {
+			response.ListIdentifiers.Headers = append(response.ListIdentifiers.Headers, h.header(record.Header))
+		}
+		return nil
+	}
+	response.ListRecords = &oaipmh.ListRecords{ResumptionToken: resumption}
+	for _, record := range page.Records {
+		response.ListRecords.Records = append(response.ListRecords.Records, h.record(record))
+	}
+	return nil
+}
+
+func checkMetadataPrefix(arguments oaiArguments) error {
+	if arguments[oaiArgMetadataPrefix] != oaipmh.MetadataPrefixDC {
+		return oaipmh.Error{
+			Code:    oaipmh.ErrorCannotDisseminateFormat,
+			Message: "only " + oaipmh.MetadataPrefixDC + " is supported",
+		}
+	}
+	return nil
+}
+
+// parseHarvestRange turns the inclusive from/until arguments into the
+// half-open range used by the usecase, widening until to the end of its unit.
+func parseHarvestRange(arguments oaiArguments) (harvest.ListParameters, error) {
+	var (
+		parameters harvest.ListParameters
+		fromDay    *bool
+	)
+	if raw, ok := arguments[oaiArgFrom]; ok {
+		from, day, err := oaipmh.ParseDatestamp(raw)
+		if err != nil {
+			return parameters, oaipmh.Error{Code: oaipmh.ErrorBadArgument, Message: "from has an illegal syntax"}
+		}
+		parameters.From = &from
+		fromDay = &day
+	}
+	if raw, ok := arguments[oaiArgUntil]; ok {
+		until, day, err := oaipmh.ParseDatestamp(raw)
+		if err != nil {
+			return parameters, oaipmh.Error{Code: oaipmh.ErrorBadArgument, Message: "until has an illegal syntax"}
+		}
+		if fromDay != nil && *fromDay != day {
+			return parameters, oaipmh.Error{
+				Code:    oaipmh.ErrorBadArgument,
+				Message: "from and until must have the same granularity",
+			}
+		}
+		if parameters.From != nil && parameters.From.After(until) {
+			return parameters, oaipmh.Error{Code: oaipmh.ErrorBadArgument, Message: "from must not be after until"}
+		}
+		if day {
+			until = until.AddDate(0, 0, 1)
+		} else {
+			until = until.Add(time.Second)
+		}
+		parameters.Until = &until
+	}
+	return parameters, nil
+}
+
+func (h OAIHandler) identifierPrefix() string {
+	return "oai:" + h.repository.RepositoryIdentifier + ":"
+}
+
+func (h OAIHandler) parseIdentifier(identifier string) (uuid.UUID, error) {
+	raw, ok := strings.CutPrefix(identifier, h.identifierPrefix())
+	if !ok {
+		return uuid.Nil, model.ErrBookNotFound
+	}
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return uuid.Nil, model.ErrBookNotFound
+	}
+	return id, nil
+}
+
+func (h OAIHandler) header(header harvest.Header) oaipmh.Header {
+	result := oaipmh.Header{
+		Identifier: h.identifierPrefix() + header.ID.String(),
+		Datestamp:  oaipmh.FormatDatestamp(header.Datestamp),
+		SetSpecs:   header.Sets,
+	}
+	if header.Deleted {
+		result.Status = oaipmh.StatusDeleted
+	}
+	return result
+}
+
+func (h OAIHandler) record(record harvest.Record) oaipmh.Record {
+	result := oaipmh.Record{Header: h.header(record.Header)}
+	if record.Book == nil {
+		return result
+	}
+
+	book := record.Book
+	dc := oaipmh.NewDublinCore()
+	dc.Titles = []string{book.Title}
+	dc.Types = []string{"Text"}
+	dc.Identifiers = []string{"urn:uuid:" + book.ID.String()}
+	if book.ISBN != nil {
+		dc.Identifiers = append(dc.Identifiers, "urn:isbn:"+*book.ISBN)
+	}
+	for _, author := range book.Authors {
+		dc.Creators = append(dc.Creators, authorDisplayName(author))
+	}
+	for _, tag := range book.Tags {
+		dc.Subjects = append(dc.Subjects, tag.Name)
+	}
+	if book.Description != nil {
+		dc.Descriptions = []string{*book.Description}
+	}
+	if book.Publisher != nil {
+		dc.Publishers = []string{book.Publisher.Name}
+	}
+	if book.PublishedAt != nil {
+		dc.Dates = []string{book.PublishedAt.Format(time.DateOnly)}
+	}
+	result.Metadata = &oaipmh.Metadata{DC: dc}
+	return result
+}
+
+func (h OAIHandler) send(writer http.ResponseWriter, response oaipmh.Response, protocolError *oaipmh.Error) {
+	if protocolError != nil {
+		response.Errors = []oaipmh.Error{*protocolError}
+		response.Identify = nil
+		response.ListMetadataFormats = nil
+		response.ListSets = nil
+		response.GetRecord = nil
+		response.ListIdentifiers = nil
+		response.ListRecords = nil
+		if protocolError.Code == oaipmh.ErrorBadVerb || protocolError.Code == oaipmh.ErrorBadArgument {
+			response.Request = oaipmh.Request{URL: response.Request.URL}
+		}
+	}
+	var body bytes.Buffer
+	if err := oaipmh.Write(&body, response); err != nil {
+		SendError(writer, err)
+		logs.Error("failed to write oai-pmh response", err)
+		return
+	}
+	sendOkBody(writer, ContentTypeOAIPMH, body.Bytes())
+}
+
+func oaiError(code, message string) *oaipmh.Error {
+	return &oaipmh.Error{Code: code, Message: message}
+}
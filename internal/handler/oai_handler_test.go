@@ -0,0 +1,246 @@
+package handler
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/config"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/harvest"
+	"github.com/iamvkosarev/book-shelf/pkg/oaipmh"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseHarvestRange(t *testing.T) {
+	day := func(year int, month time.Month, day int) *time.Time {
+		date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		return &date
+	}
+	second := func(value string) *time.Time {
+		moment, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &moment
+	}
+	tests := []struct {
+		name      string
+		arguments oaiArguments
+		from      *time.Time
+		until     *time.Time
+		code      string
+	}{
+		{name: "open", arguments: oaiArguments{}},
+		{name: "from day", arguments: oaiArguments{oaiArgFrom: "2024-03-05"}, from: day(2024, 3, 5)},
+		{
+			name:      "until day is widened to the next day",
+			arguments: oaiArguments{oaiArgUntil: "2024-03-05"},
+			until:     day(2024, 3, 6),
+		},
+		{
+			name:      "until the last day of the year",
+			arguments: oaiArguments{oaiArgUntil: "2023-12-31"},
+			until:     day(2024, 1, 1),
+		},
+		{
+			name:      "until second is widened by a second",
+			arguments: oaiArguments{oaiArgUntil: "2024-03-05T23:59:59Z"},
+			until:     second("2024-03-06T00:00:00Z"),
+		},
+		{
+			name:      "same day",
+			arguments: oaiArguments{oaiArgFrom: "2024-03-05", oaiArgUntil: "2024-03-05"},
+			from:      day(2024, 3, 5),
+			until:     day(2024, 3, 6),
+		},
+		{
+			name:      "same second",
+			arguments: oaiArguments{oaiArgFrom: "2024-03-05T10:11:12Z", oaiArgUntil: "2024-03-05T10:11:12Z"},
+			from:      second("2024-03-05T10:11:12Z"),
+			until:     second("2024-03-05T10:11:13Z"),
+		},
+		{
+			name:      "mixed granularity",
+			arguments: oaiArguments{oaiArgFrom: "2024-03-05", oaiArgUntil: "2024-03-06T00:00:00Z"},
+			code:      oaipmh.ErrorBadArgument,
+		},
+		{
+			name:      "from after until",
+			arguments: oaiArguments{oaiArgFrom: "2024-03-06", oaiArgUntil: "2024-03-05"},
+			code:      oaipmh.ErrorBadArgument,
+		},
+		{name: "illegal from", arguments: oaiArguments{oaiArgFrom: "05.03.2024"}, code: oaipmh.ErrorBadArgument},
+		{
+			name:      "fractional until",
+			arguments: oaiArguments{oaiArgUntil: "2024-03-05T10:11:12.5Z"},
+			code:      oaipmh.ErrorBadArgument,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parameters, err := parseHarvestRange(test.arguments)
+			if test.code != "" {
+				var protocolError oaipmh.Error
+				if !errors.As(err, &protocolError) || protocolError.Code != test.code {
+					t.Fatalf("error = %v, want %s", err, test.code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !equalTime(parameters.From, test.from) {
+				t.Errorf("from = %v, want %v", parameters.From, test.from)
+			}
+			if !equalTime(parameters.Until, test.until) {
+				t.Errorf("until = %v, want %v", parameters.Until, test.until)
+			}
+		})
+	}
+}
+
+type harvestUsecaseStub struct {
+	page       harvest.Page
+	parameters harvest.ListParameters
+	token      string
+	calls      int
+}
+
+func (s *harvestUsecaseStub) EarliestDatestamp(context.Context) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+func (s *harvestUsecaseStub) ListSets(context.Context) ([]harvest.Set, error) {
+	return nil, nil
+}
+
+func (s *harvestUsecaseStub) GetRecord(context.Context, uuid.UUID) (harvest.Record, error) {
+	return harvest.Record{}, nil
+}
+
+func (s *harvestUsecaseStub) ListRecords(
+	_ context.Context,
+	parameters harvest.ListParameters,
+	token string,
+	_ bool,
+) (harvest.Page, error) {
+	s.calls++
+	s.parameters, s.token = parameters, token
+	return s.page, nil
+}
+
+// TestOAIResumptionToken follows the protocol rules for the token element:
+// it is left out of a complete single page, carries the next token on every
+// other page, and is sent empty on the last page of a resumed list.
+func TestOAIResumptionToken(t *testing.T) {
+	header := harvest.Header{ID: uuid.New(), Datestamp: time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)}
+	tests := []struct {
+		name  string
+		query url.Values
+		page  harvest.Page
+		want  *oaipmh.ResumptionToken
+	}{
+		{
+			name:  "single page",
+			query: url.Values{oaiArgMetadataPrefix: {oaipmh.MetadataPrefixDC}},
+			page:  harvest.Page{Records: []harvest.Record{{Header: header}}},
+		},
+		{
+			name:  "first page",
+			query: url.Values{oaiArgMetadataPrefix: {oaipmh.MetadataPrefixDC}},
+			page:  harvest.Page{Records: []harvest.Record{{Header: header}}, Next: "next"},
+			want:  &oaipmh.ResumptionToken{Value: "next"},
+		},
+		{
+			name:  "middle page",
+			query: url.Values{oaiArgResumptionToken: {"current"}},
+			page:  harvest.Page{Records: []harvest.Record{{Header: header}}, Cursor: 100, Next: "next"},
+			want:  &oaipmh.ResumptionToken{Value: "next", Cursor: 100},
+		},
+		{
+			name:  "last page",
+			query: url.Values{oaiArgResumptionToken: {"current"}},
+			page:  harvest.Page{Records: []harvest.Record{{Header: header}}, Cursor: 200},
+			want:  &oaipmh.ResumptionToken{Cursor: 200},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			usecase := &harvestUsecaseStub{page: test.page}
+			response := serveOAI(t, usecase, oaipmh.VerbListIdentifiers, test.query)
+			if len(response.Errors) > 0 {
+				t.Fatalf("errors = %+v", response.Errors)
+			}
+			if response.ListIdentifiers == nil || len(response.ListIdentifiers.Headers) != 1 {
+				t.Fatalf("list identifiers = %+v, want one header", response.ListIdentifiers)
+			}
+			got := response.ListIdentifiers.ResumptionToken
+			if (got == nil) != (test.want == nil) || got != nil && *got != *test.want {
+				t.Errorf("resumption token = %+v, want %+v", got, test.want)
+			}
+			if usecase.token != test.query.Get(oaiArgResumptionToken) {
+				t.Errorf("usecase token = %q, want %q", usecase.token, test.query.Get(oaiArgResumptionToken))
+			}
+		})
+	}
+}
+
+func TestOAIResumptionTokenIsExclusive(t *testing.T) {
+	queries := []url.Values{
+		{oaiArgResumptionToken: {"current"}, oaiArgMetadataPrefix: {oaipmh.MetadataPrefixDC}},
+		{oaiArgResumptionToken: {"current"}, oaiArgUntil: {"2024-03-05"}},
+		{oaiArgResumptionToken: {""}},
+	}
+	for _, query := range queries {
+		usecase := &harvestUsecaseStub{}
+		response := serveOAI(t, usecase, oaipmh.VerbListRecords, query)
+		if len(response.Errors) != 1 || response.Errors[0].Code != oaipmh.ErrorBadArgument {
+			t.Errorf("%v: errors = %+v, want %s", query, response.Errors, oaipmh.ErrorBadArgument)
+		}
+		if usecase.calls != 0 {
+			t.Errorf("%v: usecase was called", query)
+		}
+	}
+}
+
+// TestOAIUntilIsWidened checks the range that reaches the usecase, a harvest
+// until a day has to include every record stamped during that day.
+func TestOAIUntilIsWidened(t *testing.T) {
+	usecase := &harvestUsecaseStub{page: harvest.Page{Records: []harvest.Record{{}}}}
+	serveOAI(t, usecase, oaipmh.VerbListRecords, url.Values{
+		oaiArgMetadataPrefix: {oaipmh.MetadataPrefixDC},
+		oaiArgUntil:          {"2024-03-05"},
+		oaiArgSet:            {harvest.SetTag},
+	})
+	want := time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC)
+	if usecase.parameters.Until == nil || !usecase.parameters.Until.Equal(want) {
+		t.Errorf("until = %v, want %v", usecase.parameters.Until, want)
+	}
+	if usecase.parameters.Set != harvest.SetTag {
+		t.Errorf("set = %q, want %q", usecase.parameters.Set, harvest.SetTag)
+	}
+}
+
+func serveOAI(t *testing.T, usecase HarvestUsecase, verb string, query url.Values) oaipmh.Response {
+	t.Helper()
+	query.Set(oaiArgVerb, verb)
+	handler := NewOAIHandler(usecase, config.OAI{RepositoryIdentifier: "books.example.org"})
+	recorder := httptest.NewRecorder()
+	handler.Handle(recorder, httptest.NewRequest("GET", OAIPath+"?"+query.Encode(), nil))
+
+	var response oaipmh.Response
+	if err := xml.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode %s: %v", recorder.Body.String(), err)
+	}
+	return response
+}
+
+func equalTime(got, want *time.Time) bool {
+	if got == nil || want == nil {
+		return got == want
+	}
+	return got.Equal(*want)
+}
@@ -0,0 +1,360 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/books"
+	"github.com/iamvkosarev/book-shelf/pkg/logs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	OPDSRoot   = "/opds"
+	OPDSRootV2 = "/opds/v2"
+	OPDSRealm  = "Book Shelf"
+	OPDSTitle  = "Book Shelf"
+
+	OPDSPageSize = 50
+
+	VarQuery = "q"
+)
+
+type OPDSHandler struct {
+	bookUsecase      BookUsecase
+	authorUsecase    AuthorUsecase
+	tagUsecase       TagUsecase
+	publisherUsecase PublisherUsecase
+}
+
+func NewOPDSHandler(
+	bookUsecase BookUsecase,
+	authorUsecase AuthorUsecase,
+	tagUsecase TagUsecase,
+	publisherUsecase PublisherUsecase,
+) *OPDSHandler {
+	return &OPDSHandler{
+		bookUsecase:      bookUsecase,
+		authorUsecase:    authorUsecase,
+		tagUsecase:       tagUsecase,
+		publisherUsecase: publisherUsecase,
+	}
+}
+
+type opdsNavigationItem struct {
+	ID      string
+	Title   string
+	Href    string
+	Content string
+	Books   bool
+}
+
+type opdsFeed struct {
+	ID         string
+	Title      string
+	Path       string
+	Query      url.Values
+	Page       int
+	HasNext    bool
+	Navigation []opdsNavigationItem
+	Books      []model.Book
+	Search     bool
+}
+
+func (h OPDSHandler) Root(writer http.ResponseWriter, request *http.Request) {
+	feed := opdsFeed{
+		ID:    "root",
+		Title: OPDSTitle,
+		Path:  "",
+		Navigation: []opdsNavigationItem{
+			{ID: "new", Title: "Newest additions", Href: "/new", Content: "Recently added books", Books: true},
+			{ID: "books", Title: "All books", Href: "/books", Content: "Every book by title", Books: true},
+			{ID: "authors", Title: "By author", Href: "/authors", Content: "Books grouped by author"},
+			{ID: "tags", Title: "By tag", Href: "/tags", Content: "Books grouped by tag"},
+			{ID: "publishers", Title: "By publisher", Href: "/publishers", Content: "Books grouped by publisher"},
+		},
+		Search: true,
+	}
+	sendOPDSFeed(writer, request, feed)
+}
+
+func (h OPDSHandler) Authors(writer http.ResponseWriter, request *http.Request) {
+	authors, err := h.authorUsecase.ListAuthors(request.Context())
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to list opds authors", err)
+		return
+	}
+	items := make([]opdsNavigationItem, len(authors))
+	for i, author := range authors {
+		items[i] = opdsNavigationItem{
+			ID:    "author:" + author.ID.String(),
+			Title: authorDisplayName(author),
+			Href:  "/authors/" + author.ID.String(),
+			Books: true,
+		}
+	}
+	h.sendNavigation(writer, request, "authors", "By author", "/authors", items)
+}
+
+func (h OPDSHandler) Tags(writer http.ResponseWriter, request *http.Request) {
+	tags, err := h.tagUsecase.ListTags(request.Context())
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to list opds tags", err)
+		return
+	}
+	items := make([]opdsNavigationItem, len(tags))
+	for i, tag := range tags {
+		items[i] = opdsNavigationItem{
+			ID:    "tag:" + tag.ID.String(),
+			Title: tag.Name,
+			Href:  "/tags/" + tag.ID.String(),
+			Books: true,
+		}
+	}
+	h.sendNavigation(writer, request, "tags", "By tag", "/tags", items)
+}
+
+func (h OPDSHandler) Publishers(writer http.ResponseWriter, request *http.Request) {
+	publishers, err := h.publisherUsecase.ListPublishers(request.Context())
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to list opds publishers", err)
+		return
+	}
+	items := make([]opdsNavigationItem, len(publishers))
+	for i, publisher := range publishers {
+		items[i] = opdsNavigationItem{
+			ID:    "publisher:" + publisher.ID.String(),
+			Title: publisher.Name,
+			Href:  "/publishers/" + publisher.ID.String(),
+			Books: true,
+		}
+	}
+	h.sendNavigation(writer, request, "publishers", "By publisher", "/publishers", items)
+}
+
+func (h OPDSHandler) AuthorBooks(writer http.ResponseWriter, request *http.Request) {
+	id, ok := opdsID(writer, request, InvalidAuthorID)
+	if !ok {
+		return
+	}
+	author, err := h.authorUsecase.GetAuthor(request.Context(), id)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to get opds author", err, slog.String("author_id", id.String()))
+		return
+	}
+	h.sendBooks(
+		writer, request, "author:"+id.String(), authorDisplayName(author), "/authors/"+id.String(),
+		books.ListBookParameters{AuthorsIDs: []uuid.UUID{id}, Order: books.BooksOrderTitle},
+	)
+}
+
+func (h OPDSHandler) TagBooks(writer http.ResponseWriter, request *http.Request) {
+	id, ok := opdsID(writer, request, InvalidTagID)
+	if !ok {
+		return
+	}
+	tag, err := h.tagUsecase.GetTag(request.Context(), id)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to get opds tag", err, slog.String("tag_id", id.String()))
+		return
+	}
+	h.sendBooks(
+		writer, request, "tag:"+id.String(), tag.Name, "/tags/"+id.String(),
+		books.ListBookParameters{TagsIDs: []uuid.UUID{id}, Order: books.BooksOrderTitle},
+	)
+}
+
+func (h OPDSHandler) PublisherBooks(writer http.ResponseWriter, request *http.Request) {
+	id, ok := opdsID(writer, request, InvalidPublisherID)
+	if !ok {
+		return
+	}
+	publisher, err := h.publisherUsecase.GetPublisher(request.Context(), id)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to get opds publisher", err, slog.String("publisher_id", id.String()))
+		return
+	}
+	h.sendBooks(
+		writer, request, "publisher:"+id.String(), publisher.Name, "/publishers/"+id.String(),
+		books.ListBookParameters{PublisherID: &id, Order: books.BooksOrderTitle},
+	)
+}
+
+func (h OPDSHandler) NewBooks(writer http.ResponseWriter, request *http.Request) {
+	h.sendBooks(
+		writer, request, "new", "Newest additions", "/new",
+		books.ListBookParameters{Order: books.BooksOrderNewest},
+	)
+}
+
+func (h OPDSHandler) Books(writer http.ResponseWriter, request *http.Request) {
+	query := strings.TrimSpace(request.URL.Query().Get(VarQuery))
+	title := "All books"
+	if query != "" {
+		title = fmt.Sprintf("Search: %s", query)
+	}
+	h.sendBooks(
+		writer, request, "books", title, "/books",
+		books.ListBookParameters{Query: query, Order: books.BooksOrderTitle},
+	)
+}
+
+func (h OPDSHandler) SearchDescription(writer http.ResponseWriter, request *http.Request) {
+	base := opdsBase(request)
+	body, err := renderOpenSearchDescription(base + "/books?" + VarQuery + "={searchTerms}")
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to render opensearch description", err)
+		return
+	}
+	sendOkBody(writer, ContentTypeOpenSearch, body)
+}
+
+func (h OPDSHandler) sendNavigation(
+	writer http.ResponseWriter,
+	request *http.Request,
+	id, title, path string,
+	items []opdsNavigationItem,
+) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return strings.ToLower(items[i].Title) < strings.ToLower(items[j].Title)
+	})
+	page, ok := queryPage(writer, request)
+	if !ok {
+		return
+	}
+	start := min((page-1)*OPDSPageSize, len(items))
+	end := min(start+OPDSPageSize, len(items))
+	sendOPDSFeed(
+		writer, request, opdsFeed{
+			ID:         id,
+			Title:      title,
+			Path:       path,
+			Page:       page,
+			HasNext:    end < len(items),
+			Navigation: items[start:end],
+		},
+	)
+}
+
+func (h OPDSHandler) sendBooks(
+	writer http.ResponseWriter,
+	request *http.Request,
+	id, title, path string,
+	parameters books.ListBookParameters,
+) {
+	page, ok := queryPage(writer, request)
+	if !ok {
+		return
+	}
+	parameters.Offset = uint64((page - 1) * OPDSPageSize)
+	parameters.Limit = OPDSPageSize + 1
+
+	list, err := h.bookUsecase.ListBooks(request.Context(), parameters, true, true, true)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to list opds books", err, slog.String("feed", id))
+		return
+	}
+
+	feed := opdsFeed{
+		ID:      id,
+		Title:   title,
+		Path:    path,
+		Page:    page,
+		HasNext: len(list) > OPDSPageSize,
+		Books:   list[:min(len(list), OPDSPageSize)],
+		Search:  true,
+	}
+	if parameters.Query != "" {
+		feed.Query = url.Values{VarQuery: {parameters.Query}}
+	}
+	sendOPDSFeed(writer, request, feed)
+}
+
+func sendOPDSFeed(writer http.ResponseWriter, request *http.Request, feed opdsFeed) {
+	var (
+		body        []byte
+		contentType string
+		err         error
+	)
+	if isOPDSv2(request) {
+		body, err = renderOPDSJSON(opdsBase(request), feed)
+		contentType = ContentTypeOPDSJSON
+	} else {
+		body, err = renderOPDSAtom(opdsBase(request), feed)
+		contentType = ContentTypeAtomNavigation
+		if feed.Navigation == nil {
+			contentType = ContentTypeAtomAcquisition
+		}
+	}
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to render opds feed", err, slog.String("feed", feed.ID))
+		return
+	}
+	sendOkBody(writer, contentType, body)
+}
+
+func (f opdsFeed) link(base string, page int) string {
+	query := url.Values{}
+	for key, values := range f.Query {
+		query[key] = values
+	}
+	if page > 1 {
+		query.Set(VarPage, strconv.Itoa(page))
+	}
+	if len(query) == 0 {
+		return base + f.Path
+	}
+	return base + f.Path + "?" + query.Encode()
+}
+
+func isOPDSv2(request *http.Request) bool {
+	return request.URL.Path == OPDSRootV2 || strings.HasPrefix(request.URL.Path, OPDSRootV2+"/")
+}
+
+func opdsBase(request *http.Request) string {
+	if isOPDSv2(request) {
+		return OPDSRootV2
+	}
+	return OPDSRoot
+}
+
+func opdsID(writer http.ResponseWriter, request *http.Request, invalidMessage string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(request)[VarID])
+	if err != nil {
+		sendBadRequest(writer, invalidMessage)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func authorDisplayName(author model.Author) string {
+	return displayName(author.FirstName, author.MiddleName, author.LastName, author.Pseudonym)
+}
+
+func displayName(first, middle, last, pseudonym *string) string {
+	var parts []string
+	for _, part := range []*string{first, middle, last} {
+		if part != nil && strings.TrimSpace(*part) != "" {
+			parts = append(parts, strings.TrimSpace(*part))
+		}
+	}
+	if len(parts) == 0 && pseudonym != nil {
+		return *pseudonym
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,326 @@
+package handler
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"time"
+)
+
+const (
+	ContentTypeAtomNavigation  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	ContentTypeAtomAcquisition = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	ContentTypeOPDSJSON        = "application/opds+json"
+	ContentTypeOpenSearch      = "application/opensearchdescription+xml"
+
+	opdsAtomNamespace       = "http://www.w3.org/2005/Atom"
+	opdsDublinCoreNamespace = "http://purl.org/dc/terms/"
+	opdsNamespace           = "http://opds-spec.org/2010/catalog"
+	opdsOpenSearchNamespace = "http://a9.com/-/spec/opensearch/1.1/"
+	opdsIDPrefix            = "urn:book-shelf:opds:"
+	opdsBookLinkType        = "application/json"
+)
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Authors    []atomAuthor   `xml:"author"`
+	Identifier string         `xml:"dc:identifier,omitempty"`
+	Issued     string         `xml:"dc:issued,omitempty"`
+	Publisher  string         `xml:"dc:publisher,omitempty"`
+	Categories []atomCategory `xml:"category"`
+	Summary    *atomContent   `xml:"summary"`
+	Content    *atomContent   `xml:"content"`
+	Links      []atomLink     `xml:"link"`
+}
+
+type atomFeed struct {
+	XMLName      xml.Name    `xml:"feed"`
+	Namespace    string      `xml:"xmlns,attr"`
+	DublinCore   string      `xml:"xmlns:dc,attr"`
+	OPDS         string      `xml:"xmlns:opds,attr"`
+	OpenSearch   string      `xml:"xmlns:opensearch,attr"`
+	ID           string      `xml:"id"`
+	Title        string      `xml:"title"`
+	Updated      string      `xml:"updated"`
+	Author       atomAuthor  `xml:"author"`
+	Links        []atomLink  `xml:"link"`
+	ItemsPerPage int         `xml:"opensearch:itemsPerPage,omitempty"`
+	Entries      []atomEntry `xml:"entry"`
+}
+
+type openSearchURL struct {
+	Type     string `xml:"type,attr"`
+	Template string `xml:"template,attr"`
+}
+
+type openSearchDescription struct {
+	XMLName     xml.Name      `xml:"OpenSearchDescription"`
+	Namespace   string        `xml:"xmlns,attr"`
+	ShortName   string        `xml:"ShortName"`
+	Description string        `xml:"Description"`
+	InputCoding string        `xml:"InputEncoding"`
+	URL         openSearchURL `xml:"Url"`
+}
+
+func renderOPDSAtom(base string, feed opdsFeed) ([]byte, error) {
+	updated := time.Now().UTC().Format(time.RFC3339)
+	kind := ContentTypeAtomAcquisition
+	if feed.Navigation != nil {
+		kind = ContentTypeAtomNavigation
+	}
+
+	document := atomFeed{
+		Namespace:  opdsAtomNamespace,
+		DublinCore: opdsDublinCoreNamespace,
+		OPDS:       opdsNamespace,
+		OpenSearch: opdsOpenSearchNamespace,
+		ID:         opdsIDPrefix + feed.ID,
+		Title:      feed.Title,
+		Updated:    updated,
+		Author:     atomAuthor{Name: OPDSTitle},
+		Links: []atomLink{
+			{Rel: "self", Href: feed.link(base, feed.Page), Type: kind},
+			{Rel: "start", Href: base, Type: ContentTypeAtomNavigation},
+		},
+	}
+	if feed.Search {
+		document.Links = append(
+			document.Links,
+			atomLink{Rel: "search", Href: base + "/search.xml", Type: ContentTypeOpenSearch},
+		)
+	}
+	document.Links = append(document.Links, atomPagination(base, feed, kind)...)
+	if feed.Page > 1 || feed.HasNext {
+		document.ItemsPerPage = OPDSPageSize
+	}
+
+	for _, item := range feed.Navigation {
+		linkType := ContentTypeAtomNavigation
+		if item.Books {
+			linkType = ContentTypeAtomAcquisition
+		}
+		entry := atomEntry{
+			ID:      opdsIDPrefix + item.ID,
+			Title:   item.Title,
+			Updated: updated,
+			Links:   []atomLink{{Rel: "subsection", Href: base + item.Href, Type: linkType}},
+		}
+		if item.Content != "" {
+			entry.Content = &atomContent{Type: "text", Value: item.Content}
+		}
+		document.Entries = append(document.Entries, entry)
+	}
+	for _, book := range feed.Books {
+		document.Entries = append(document.Entries, atomBookEntry(book))
+	}
+
+	body, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+func atomPagination(base string, feed opdsFeed, kind string) []atomLink {
+	var links []atomLink
+	if feed.Page > 1 {
+		links = append(
+			links,
+			atomLink{Rel: "first", Href: feed.link(base, 1), Type: kind},
+			atomLink{Rel: "previous", Href: feed.link(base, feed.Page-1), Type: kind},
+		)
+	}
+	if feed.HasNext {
+		links = append(links, atomLink{Rel: "next", Href: feed.link(base, feed.Page+1), Type: kind})
+	}
+	return links
+}
+
+func atomBookEntry(book model.Book) atomEntry {
+	entry := atomEntry{
+		ID:      "urn:uuid:" + book.ID.String(),
+		Title:   book.Title,
+		Updated: book.CreatedAt.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Rel: "alternate", Href: "/books/" + book.ID.String(), Type: opdsBookLinkType},
+		},
+	}
+	for _, author := range book.Authors {
+		entry.Authors = append(entry.Authors, atomAuthor{Name: authorDisplayName(author)})
+	}
+	if book.ISBN != nil {
+		entry.Identifier = "urn:isbn:" + *book.ISBN
+	}
+	if book.PublishedAt != nil {
+		entry.Issued = book.PublishedAt.Format(time.DateOnly)
+	}
+	if book.Publisher != nil {
+		entry.Publisher = book.Publisher.Name
+	}
+	for _, tag := range book.Tags {
+		entry.Categories = append(entry.Categories, atomCategory{Term: tag.Name, Label: tag.Name})
+	}
+	if book.Description != nil && *book.Description != "" {
+		entry.Summary = &atomContent{Type: "text", Value: *book.Description}
+	}
+	return entry
+}
+
+func renderOpenSearchDescription(template string) ([]byte, error) {
+	document := openSearchDescription{
+		Namespace:   opdsOpenSearchNamespace,
+		ShortName:   OPDSTitle,
+		Description: "Search books by title, author or ISBN",
+		InputCoding: "UTF-8",
+		URL:         openSearchURL{Type: ContentTypeAtomAcquisition, Template: template},
+	}
+	body, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+type opdsLink struct {
+	Rel       string `json:"rel,omitempty"`
+	Href      string `json:"href"`
+	Type      string `json:"type,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Templated bool   `json:"templated,omitempty"`
+}
+
+type opdsMetadata struct {
+	Type         string   `json:"@type,omitempty"`
+	Identifier   string   `json:"identifier,omitempty"`
+	Title        string   `json:"title"`
+	Author       []string `json:"author,omitempty"`
+	Publisher    string   `json:"publisher,omitempty"`
+	Published    string   `json:"published,omitempty"`
+	Modified     string   `json:"modified,omitempty"`
+	Description  string   `json:"description,omitempty"`
+	Subject      []string `json:"subject,omitempty"`
+	ItemsPerPage int      `json:"itemsPerPage,omitempty"`
+	CurrentPage  int      `json:"currentPage,omitempty"`
+}
+
+type opdsPublication struct {
+	Metadata opdsMetadata `json:"metadata"`
+	Links    []opdsLink   `json:"links"`
+}
+
+type opdsDocument struct {
+	Metadata     opdsMetadata      `json:"metadata"`
+	Links        []opdsLink        `json:"links"`
+	Navigation   []opdsLink        `json:"navigation,omitempty"`
+	Publications []opdsPublication `json:"publications,omitempty"`
+}
+
+func renderOPDSJSON(base string, feed opdsFeed) ([]byte, error) {
+	document := opdsDocument{
+		Metadata: opdsMetadata{Title: feed.Title},
+		Links: []opdsLink{
+			{Rel: "self", Href: feed.link(base, feed.Page), Type: ContentTypeOPDSJSON},
+			{Rel: "start", Href: base, Type: ContentTypeOPDSJSON},
+		},
+	}
+	if feed.Search {
+		document.Links = append(
+			document.Links, opdsLink{
+				Rel:       "search",
+				Href:      base + "/books{?" + VarQuery + "}",
+				Type:      ContentTypeOPDSJSON,
+				Templated: true,
+			},
+		)
+	}
+	if feed.Page > 1 || feed.HasNext {
+		document.Metadata.ItemsPerPage = OPDSPageSize
+		document.Metadata.CurrentPage = feed.Page
+	}
+	if feed.Page > 1 {
+		document.Links = append(
+			document.Links,
+			opdsLink{Rel: "first", Href: feed.link(base, 1), Type: ContentTypeOPDSJSON},
+			opdsLink{Rel: "previous", Href: feed.link(base, feed.Page-1), Type: ContentTypeOPDSJSON},
+		)
+	}
+	if feed.HasNext {
+		document.Links = append(
+			document.Links,
+			opdsLink{Rel: "next", Href: feed.link(base, feed.Page+1), Type: ContentTypeOPDSJSON},
+		)
+	}
+
+	if feed.Navigation != nil {
+		document.Navigation = make([]opdsLink, len(feed.Navigation))
+		for i, item := range feed.Navigation {
+			document.Navigation[i] = opdsLink{
+				Rel:   "subsection",
+				Href:  base + item.Href,
+				Type:  ContentTypeOPDSJSON,
+				Title: item.Title,
+			}
+		}
+	} else {
+		document.Publications = make([]opdsPublication, len(feed.Books))
+		for i, book := range feed.Books {
+			document.Publications[i] = opdsBookPublication(book)
+		}
+	}
+	return json.Marshal(document)
+}
+
+func opdsBookPublication(book model.Book) opdsPublication {
+	metadata := opdsMetadata{
+		Type:       "http://schema.org/Book",
+		Identifier: "urn:uuid:" + book.ID.String(),
+		Title:      book.Title,
+		Modified:   book.CreatedAt.UTC().Format(time.RFC3339),
+	}
+	if book.ISBN != nil {
+		metadata.Identifier = "urn:isbn:" + *book.ISBN
+	}
+	for _, author := range book.Authors {
+		metadata.Author = append(metadata.Author, authorDisplayName(author))
+	}
+	if book.Publisher != nil {
+		metadata.Publisher = book.Publisher.Name
+	}
+	if book.PublishedAt != nil {
+		metadata.Published = book.PublishedAt.Format(time.DateOnly)
+	}
+	if book.Description != nil {
+		metadata.Description = *book.Description
+	}
+	for _, tag := range book.Tags {
+		metadata.Subject = append(metadata.Subject, tag.Name)
+	}
+	return opdsPublication{
+		Metadata: metadata,
+		Links: []opdsLink{
+			{Rel: "self", Href: "/books/" + book.ID.String(), Type: opdsBookLinkType},
+		},
+	}
+}
@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"context"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/progress"
+	"github.com/iamvkosarev/book-shelf/pkg/logs"
+	"log/slog"
+	"math"
+	"net/http"
+	"time"
+)
+
+type ProgressUsecase interface {
+	SetBookProgress(
+		ctx context.Context,
+		userID uuid.UUID,
+		bookID uuid.UUID,
+		document *string,
+		input progress.ProgressInput,
+	) (model.ReadingProgress, error)
+	GetBookProgress(ctx context.Context, userID uuid.UUID, bookID uuid.UUID) (model.ReadingProgress, error)
+	RemoveBookProgress(ctx context.Context, userID uuid.UUID, bookID uuid.UUID) error
+	ListProgress(ctx context.Context, userID uuid.UUID) ([]model.ReadingProgress, error)
+	LinkKOReader(ctx context.Context, userID uuid.UUID, username, password string) error
+	GetKOReaderAccount(ctx context.Context, userID uuid.UUID) (model.KOReaderAccount, error)
+	UnlinkKOReader(ctx context.Context, userID uuid.UUID) error
+}
+
+type ProgressHandler struct {
+	progressUsecase   ProgressUsecase
+	userIDFromContext UserIDFromContext
+	validate          *validator.Validate
+}
+
+func NewProgressHandler(usecase ProgressUsecase, userIDFromContext UserIDFromContext) *ProgressHandler {
+	return &ProgressHandler{
+		progressUsecase:   usecase,
+		userIDFromContext: userIDFromContext,
+		validate:          validator.New(),
+	}
+}
+
+type ProgressRequest struct {
+	Percentage *float64 `json:"percentage" validate:"required,min=0,max=100"`
+	Page       *int32   `json:"page" validate:"omitempty,min=1"`
+	Position   *string  `json:"position" validate:"omitempty,max=1000"`
+	Device     *string  `json:"device" validate:"omitempty,max=100"`
+	// KOReaderDocument links the KOReader document hash to the book.
+	KOReaderDocument *string `json:"koreader_document" validate:"omitempty,max=64"`
+}
+
+type ProgressResponse struct {
+	BookID           *uuid.UUID `json:"book_id"`
+	KOReaderDocument *string    `json:"koreader_document,omitempty"`
+	Percentage       float64    `json:"percentage"`
+	Page             *int32     `json:"page,omitempty"`
+	Position         *string    `json:"position,omitempty"`
+	Device           *string    `json:"device,omitempty"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+type ListProgressResponse struct {
+	Progress []ProgressResponse `json:"progress"`
+}
+
+type KOReaderAccountRequest struct {
+	Username string `json:"username" validate:"required,min=1,max=100"`
+	Password string `json:"password" validate:"required,min=1"`
+}
+
+type KOReaderAccountResponse struct {
+	Username   string    `json:"username"`
+	LinkedAt   time.Time `json:"linked_at"`
+	ServerPath string    `json:"server_path"`
+}
+
+func (h ProgressHandler) ListProgress(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+
+	list, err := h.progressUsecase.ListProgress(request.Context(), userID)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to list reading progress", err, slog.String("user_id", userID.String()))
+		return
+	}
+	response := ListProgressResponse{Progress: make([]ProgressResponse, len(list))}
+	for i, entry := range list {
+		response.Progress[i] = getProgressResponse(entry)
+	}
+	sendOkJSON(writer, response)
+}
+
+func (h ProgressHandler) GetBookProgress(writer http.ResponseWriter, request *http.Request) {
+	userID, bookID, ok := h.bookFromRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	entry, err := h.progressUsecase.GetBookProgress(request.Context(), userID, bookID)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to get reading progress", err, slog.String("book_id", bookID.String()))
+		return
+	}
+	sendOkJSON(writer, getProgressResponse(entry))
+}
+
+func (h ProgressHandler) SetBookProgress(writer http.ResponseWriter, request *http.Request) {
+	userID, bookID, ok := h.bookFromRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	var req ProgressRequest
+	if !decode(writer, request, &req) {
+		return
+	}
+	if validationErr(writer, h.validate, req) {
+		return
+	}
+
+	entry, err := h.progressUsecase.SetBookProgress(
+		request.Context(), userID, bookID, req.KOReaderDocument, progress.ProgressInput{
+			Percentage: *req.Percentage,
+			Page:       req.Page,
+			Position:   req.Position,
+			Device:     req.Device,
+		},
+	)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to set reading progress", err, slog.String("book_id", bookID.String()))
+		return
+	}
+	sendOkJSON(writer, getProgressResponse(entry))
+}
+
+func (h ProgressHandler) RemoveBookProgress(writer http.ResponseWriter, request *http.Request) {
+	userID, bookID, ok := h.bookFromRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	if err := h.progressUsecase.RemoveBookProgress(request.Context(), userID, bookID); err != nil {
+		SendError(writer, err)
+		logs.Error("failed to remove reading progress", err, slog.String("book_id", bookID.String()))
+		return
+	}
+	sendOk(writer)
+}
+
+func (h ProgressHandler) GetKOReaderAccount(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+
+	account, err := h.progressUsecase.GetKOReaderAccount(request.Context(), userID)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to get koreader account", err, slog.String("user_id", userID.String()))
+		return
+	}
+	sendOkJSON(
+		writer, KOReaderAccountResponse{
+			Username:   account.Username,
+			LinkedAt:   account.CreatedAt,
+			ServerPath: KOReaderRoot,
+		},
+	)
+}
+
+// LinkKOReader sets the credentials to enter in KOReader, they are separate
+// from the book-shelf password.
+func (h ProgressHandler) LinkKOReader(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+
+	var req KOReaderAccountRequest
+	if !decode(writer, request, &req) {
+		return
+	}
+	if validationErr(writer, h.validate, req) {
+		return
+	}
+
+	if err := h.progressUsecase.LinkKOReader(request.Context(), userID, req.Username, req.Password); err != nil {
+		SendError(writer, err)
+		logs.Error("failed to link koreader account", err, slog.String("user_id", userID.String()))
+		return
+	}
+	sendOk(writer)
+}
+
+func (h ProgressHandler) UnlinkKOReader(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+
+	if err := h.progressUsecase.UnlinkKOReader(request.Context(), userID); err != nil {
+		SendError(writer, err)
+		logs.Error("failed to unlink koreader account", err, slog.String("user_id", userID.String()))
+		return
+	}
+	sendOk(writer)
+}
+
+func (h ProgressHandler) bookFromRequest(
+	writer http.ResponseWriter,
+	request *http.Request,
+) (uuid.UUID, uuid.UUID, bool) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+	bookID, err := uuid.Parse(mux.Vars(request)[VarBookID])
+	if err != nil {
+		sendBadRequest(writer, InvalidBookID)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, bookID, true
+}
+
+func getProgressResponse(entry model.ReadingProgress) ProgressResponse {
+	return ProgressResponse{
+		BookID:           entry.BookID,
+		KOReaderDocument: entry.Document,
+		Percentage:       math.Round(entry.Fraction*10000) / 100,
+		Page:             entry.Page,
+		Position:         entry.Position,
+		Device:           entry.Device,
+		UpdatedAt:        entry.UpdatedAt,
+	}
+}
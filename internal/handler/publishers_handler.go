@@ -20,7 +20,7 @@ type PublisherUsecase interface {
 	AddPublisher(ctx context.Context, name string) (uuid.UUID, error)
 	GetPublisher(ctx context.Context, id uuid.UUID) (model.Publisher, error)
 	UpdatePublisher(ctx context.Context, id uuid.UUID, name string) error
-	RemovePublisher(ctx context.Context, id uuid.UUID) error
+	RemovePublisher(ctx context.Context, id uuid.UUID, policy model.RemovePolicy) error
 	ListPublishers(ctx context.Context) ([]model.Publisher, error)
 }
 
@@ -86,7 +86,7 @@ func (p PublisherHandler) GetPublisher(writer http.ResponseWriter, request *http
 		ID:   publisher.ID,
 		Name: publisher.Name,
 	}
-	sendOkJSON(writer, response)
+	sendRepresentation(writer, request, negotiateRepresentation(request), response, response)
 }
 
 func (p PublisherHandler) RemovePublisher(writer http.ResponseWriter, request *http.Request) {
@@ -102,7 +102,13 @@ func (p PublisherHandler) RemovePublisher(writer http.ResponseWriter, request *h
 		return
 	}
 
-	if err = p.publisherUsecase.RemovePublisher(request.Context(), id); err != nil {
+	policy, err := parseRemovePolicy(request)
+	if err != nil {
+		SendError(writer, err)
+		return
+	}
+
+	if err = p.publisherUsecase.RemovePublisher(request.Context(), id, policy); err != nil {
 		SendError(writer, err)
 		logs.Error("failed to remove publisher", err, slog.String("publisher_id", idStr))
 		return
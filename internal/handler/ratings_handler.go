@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"context"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/pkg/logs"
+	"log/slog"
+	"math"
+	"net/http"
+)
+
+type RatingsUsecase interface {
+	SetRating(ctx context.Context, userID uuid.UUID, bookID uuid.UUID, value int16) (model.RatingSummary, error)
+	RemoveRating(ctx context.Context, userID uuid.UUID, bookID uuid.UUID) (model.RatingSummary, error)
+	UserRatingsReader
+}
+
+// UserRatingsReader is what the handlers listing books need to show the
+// current user's own ratings.
+type UserRatingsReader interface {
+	UserRatings(ctx context.Context, userID uuid.UUID, bookIDs []uuid.UUID) (map[uuid.UUID]int16, error)
+}
+
+type RatingHandler struct {
+	ratingsUsecase    RatingsUsecase
+	userIDFromContext UserIDFromContext
+	validate          *validator.Validate
+}
+
+func NewRatingHandler(usecase RatingsUsecase, userIDFromContext UserIDFromContext) *RatingHandler {
+	return &RatingHandler{
+		ratingsUsecase:    usecase,
+		userIDFromContext: userIDFromContext,
+		validate:          validator.New(),
+	}
+}
+
+type SetRatingRequest struct {
+	Value *int16 `json:"value" validate:"required,min=0,max=10"`
+}
+
+type RatingResponse struct {
+	Average float64 `json:"average"`
+	Count   int     `json:"count"`
+	// Histogram holds the number of ratings for every value from 0 to 10.
+	Histogram []int32 `json:"histogram"`
+	Mine      *int16  `json:"mine,omitempty"`
+}
+
+func (h RatingHandler) SetRating(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+	bookID, err := uuid.Parse(mux.Vars(request)[VarID])
+	if err != nil {
+		sendBadRequest(writer, InvalidBookID)
+		return
+	}
+
+	var req SetRatingRequest
+	if !decode(writer, request, &req) {
+		return
+	}
+	if validationErr(writer, h.validate, req) {
+		return
+	}
+
+	summary, err := h.ratingsUsecase.SetRating(request.Context(), userID, bookID, *req.Value)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error(
+			"failed to rate book", err,
+			slog.String("user_id", userID.String()), slog.String("book_id", bookID.String()),
+		)
+		return
+	}
+	sendOkJSON(writer, getRatingResponse(summary, req.Value))
+}
+
+func (h RatingHandler) RemoveRating(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+	bookID, err := uuid.Parse(mux.Vars(request)[VarID])
+	if err != nil {
+		sendBadRequest(writer, InvalidBookID)
+		return
+	}
+
+	summary, err := h.ratingsUsecase.RemoveRating(request.Context(), userID, bookID)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error(
+			"failed to remove book rating", err,
+			slog.String("user_id", userID.String()), slog.String("book_id", bookID.String()),
+		)
+		return
+	}
+	sendOkJSON(writer, getRatingResponse(summary, nil))
+}
+
+func getRatingResponse(summary model.RatingSummary, mine *int16) RatingResponse {
+	histogram := summary.Histogram
+	if histogram == nil {
+		histogram = make([]int32, model.RatingMax-model.RatingMin+1)
+	}
+	return RatingResponse{
+		Average:   math.Round(summary.Average()*100) / 100,
+		Count:     summary.Count,
+		Histogram: histogram,
+		Mine:      mine,
+	}
+}
+
+// userRatings looks up the ratings the current user gave to the books, an
+// anonymous request has none.
+func userRatings(
+	request *http.Request,
+	userIDFromContext UserIDFromContext,
+	ratingsReader UserRatingsReader,
+	books []model.Book,
+) (map[uuid.UUID]int16, error) {
+	userID, ok := userIDFromContext(request.Context())
+	if !ok || len(books) == 0 {
+		return nil, nil
+	}
+	ids := make([]uuid.UUID, len(books))
+	for i, book := range books {
+		ids[i] = book.ID
+	}
+	return ratingsReader.UserRatings(request.Context(), userID, ids)
+}
+
+func setMineRating(response *BookResponse, mine map[uuid.UUID]int16) {
+	if value, ok := mine[response.ID]; ok {
+		response.Rating.Mine = &value
+	}
+}
@@ -1,12 +1,14 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/iamvkosarev/book-shelf/internal/model"
 	"github.com/iamvkosarev/book-shelf/pkg/logs"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
@@ -14,11 +16,28 @@ const (
 	VarID                   = "id"
 	VarExpend               = "expand"
 	VarAuthorID             = "author_id"
+	VarForce                = "force"
+	VarOnBooks              = "on_books"
+	VarReassignTo           = "reassign_to"
+	VarPage                 = "page"
 	VarExpendValueAuthors   = "authors"
 	VarExpendValuePublisher = "publisher"
 	VarExpendValueTags      = "tags"
 )
 
+// UserIDFromContext reads the id that the authentication middleware stored in
+// the request context.
+type UserIDFromContext func(ctx context.Context) (uuid.UUID, bool)
+
+func (f UserIDFromContext) require(writer http.ResponseWriter, request *http.Request) (uuid.UUID, bool) {
+	userID, ok := f(request.Context())
+	if !ok {
+		SendError(writer, model.ErrAuthenticationRequired)
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
 func decode[t interface{}](
 	writer http.ResponseWriter,
 	request *http.Request,
@@ -49,6 +68,19 @@ func parseQueryToUUIDs(request *http.Request, key string) ([]uuid.UUID, error) {
 	return result, nil
 }
 
+func queryPage(writer http.ResponseWriter, request *http.Request) (int, bool) {
+	raw := request.URL.Query().Get(VarPage)
+	if raw == "" {
+		return 1, true
+	}
+	page, err := strconv.Atoi(raw)
+	if err != nil || page < 1 {
+		sendBadRequest(writer, "page must be a positive integer")
+		return 0, false
+	}
+	return page, true
+}
+
 func parseQueryToStringMap(r *http.Request, key string) map[string]struct{} {
 	raw := r.URL.Query().Get(key)
 	result := make(map[string]struct{})
@@ -68,3 +100,29 @@ func hasKeyInMap(mp map[string]struct{}, key string) bool {
 	_, ok := mp[key]
 	return ok
 }
+
+func parseRemovePolicy(request *http.Request) (model.RemovePolicy, error) {
+	query := request.URL.Query()
+	mode := query.Get(VarOnBooks)
+	reassignTo := query.Get(VarReassignTo)
+	if target, ok := strings.CutPrefix(mode, VarReassignTo+"="); ok {
+		mode, reassignTo = string(model.OnBooksReassign), target
+	}
+
+	policy := model.RemovePolicy{
+		OnBooks: model.OnBooksMode(mode),
+	}
+	if policy.OnBooks == "" {
+		policy.OnBooks = model.OnBooksRestrict
+	}
+	if raw := reassignTo; raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return model.RemovePolicy{}, model.NewInternalError(
+				http.StatusBadRequest, fmt.Sprintf("invalid %s", VarReassignTo),
+			)
+		}
+		policy.ReassignTo = id
+	}
+	return policy, nil
+}
@@ -8,24 +8,31 @@ import (
 )
 
 func SendError(writer http.ResponseWriter, err error) {
-	var internalError *model.InternalError
-	code := http.StatusInternalServerError
-	message := "internal server error"
+	code, message, details := describeError(err)
+	sendErrorDetailsJSON(writer, code, message, details)
+}
 
+func describeError(err error) (int, string, any) {
+	var internalError *model.InternalError
 	if errors.As(err, &internalError) {
-		code = internalError.Code()
-		message = internalError.Error()
+		return internalError.Code(), internalError.Error(), internalError.Details()
 	}
-	sendErrorJSON(writer, code, message)
+	return http.StatusInternalServerError, InternalErrorResponseText, nil
 }
 
 func sendErrorJSON(writer http.ResponseWriter, code int, message string) {
+	sendErrorDetailsJSON(writer, code, message, nil)
+}
+
+func sendErrorDetailsJSON(writer http.ResponseWriter, code int, message string, details any) {
 	errorData := struct {
 		Code    int    `json:"code"`
 		Message string `json:"message"`
+		Details any    `json:"details,omitempty"`
 	}{
 		Code:    code,
 		Message: message,
+		Details: details,
 	}
 	sendJSON(writer, errorData, errorData.Code)
 }
@@ -52,3 +59,9 @@ func sendJSON(writer http.ResponseWriter, response any, status int) {
 	writer.WriteHeader(status)
 	writer.Write(body)
 }
+
+func sendOkBody(writer http.ResponseWriter, contentType string, body []byte) {
+	writer.Header().Set("Content-Type", contentType)
+	writer.WriteHeader(http.StatusOK)
+	writer.Write(body)
+}
@@ -0,0 +1,381 @@
+package handler
+
+import (
+	"context"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/reviews"
+	"github.com/iamvkosarev/book-shelf/pkg/logs"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	ReviewsPageSize = 20
+
+	InvalidReviewID = "invalid review id"
+
+	VarOrder  = "order"
+	VarStatus = "status"
+)
+
+type ReviewsUsecase interface {
+	AddReview(ctx context.Context, userID uuid.UUID, bookID uuid.UUID, input reviews.ReviewInput) (uuid.UUID, error)
+	GetReview(ctx context.Context, viewerID uuid.UUID, id uuid.UUID) (model.Review, error)
+	UpdateReview(ctx context.Context, userID uuid.UUID, id uuid.UUID, input reviews.ReviewInput) error
+	RemoveReview(ctx context.Context, userID uuid.UUID, id uuid.UUID) error
+	ListBookReviews(
+		ctx context.Context,
+		viewerID uuid.UUID,
+		bookID uuid.UUID,
+		parameters reviews.ListParameters,
+	) ([]model.Review, error)
+	ListUserReviews(ctx context.Context, userID uuid.UUID) ([]model.Review, error)
+	ListModerationQueue(ctx context.Context, status model.ReviewStatus, limit, offset uint64) ([]model.Review, error)
+	ApproveReview(ctx context.Context, moderatorID uuid.UUID, id uuid.UUID) error
+	RejectReview(ctx context.Context, moderatorID uuid.UUID, id uuid.UUID, reason *string) error
+	VoteHelpful(ctx context.Context, userID uuid.UUID, id uuid.UUID) error
+	RemoveHelpfulVote(ctx context.Context, userID uuid.UUID, id uuid.UUID) error
+}
+
+type ReviewHandler struct {
+	reviewsUsecase    ReviewsUsecase
+	userIDFromContext UserIDFromContext
+	validate          *validator.Validate
+}
+
+func NewReviewHandler(usecase ReviewsUsecase, userIDFromContext UserIDFromContext) *ReviewHandler {
+	return &ReviewHandler{
+		reviewsUsecase:    usecase,
+		userIDFromContext: userIDFromContext,
+		validate:          validator.New(),
+	}
+}
+
+type ReviewRequest struct {
+	Body    string `json:"body" validate:"required,max=10000"`
+	Spoiler bool   `json:"spoiler"`
+}
+
+type RejectReviewRequest struct {
+	Reason *string `json:"reason" validate:"omitempty,max=1000"`
+}
+
+type AddReviewResponse struct {
+	ID uuid.UUID `json:"id"`
+}
+
+type ReviewResponse struct {
+	ID              uuid.UUID          `json:"id"`
+	BookID          uuid.UUID          `json:"book_id"`
+	UserID          uuid.UUID          `json:"user_id"`
+	Body            string             `json:"body"`
+	Spoiler         bool               `json:"spoiler"`
+	Status          model.ReviewStatus `json:"status"`
+	HelpfulCount    int                `json:"helpful_count"`
+	VotedHelpful    bool               `json:"voted_helpful"`
+	RejectionReason *string            `json:"rejection_reason,omitempty"`
+	ModeratedAt     *time.Time         `json:"moderated_at,omitempty"`
+	CreatedAt       time.Time          `json:"created_at"`
+	UpdatedAt       time.Time          `json:"updated_at"`
+}
+
+type ListReviewsResponse struct {
+	Reviews []ReviewResponse `json:"reviews"`
+	Page    int              `json:"page,omitempty"`
+	HasNext bool             `json:"has_next,omitempty"`
+}
+
+func (h ReviewHandler) AddReview(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+	bookID, err := uuid.Parse(mux.Vars(request)[VarID])
+	if err != nil {
+		sendBadRequest(writer, InvalidBookID)
+		return
+	}
+
+	var req ReviewRequest
+	if !decode(writer, request, &req) {
+		return
+	}
+	if validationErr(writer, h.validate, req) {
+		return
+	}
+
+	id, err := h.reviewsUsecase.AddReview(
+		request.Context(), userID, bookID, reviews.ReviewInput{Body: req.Body, Spoiler: req.Spoiler},
+	)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error(
+			"failed to add review", err,
+			slog.String("user_id", userID.String()), slog.String("book_id", bookID.String()),
+		)
+		return
+	}
+	sendCreatedJSON(writer, AddReviewResponse{ID: id})
+}
+
+// ListBookReviews is public, so nobody is the viewer and no review is marked
+// as voted helpful.
+func (h ReviewHandler) ListBookReviews(writer http.ResponseWriter, request *http.Request) {
+	bookID, err := uuid.Parse(mux.Vars(request)[VarID])
+	if err != nil {
+		sendBadRequest(writer, InvalidBookID)
+		return
+	}
+	order, err := reviews.ParseReviewsOrder(request.URL.Query().Get(VarOrder))
+	if err != nil {
+		SendError(writer, err)
+		return
+	}
+	page, ok := queryPage(writer, request)
+	if !ok {
+		return
+	}
+
+	list, err := h.reviewsUsecase.ListBookReviews(
+		request.Context(), uuid.Nil, bookID, reviews.ListParameters{
+			Order:  order,
+			Limit:  ReviewsPageSize + 1,
+			Offset: uint64((page - 1) * ReviewsPageSize),
+		},
+	)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to list book reviews", err, slog.String("book_id", bookID.String()))
+		return
+	}
+	sendOkJSON(writer, getReviewsPageResponse(list, page))
+}
+
+func (h ReviewHandler) GetReview(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+	id, ok := reviewID(writer, request)
+	if !ok {
+		return
+	}
+
+	review, err := h.reviewsUsecase.GetReview(request.Context(), userID, id)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to get review", err, slog.String("review_id", id.String()))
+		return
+	}
+	sendOkJSON(writer, getReviewResponse(review))
+}
+
+func (h ReviewHandler) UpdateReview(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+	id, ok := reviewID(writer, request)
+	if !ok {
+		return
+	}
+
+	var req ReviewRequest
+	if !decode(writer, request, &req) {
+		return
+	}
+	if validationErr(writer, h.validate, req) {
+		return
+	}
+
+	err := h.reviewsUsecase.UpdateReview(
+		request.Context(), userID, id, reviews.ReviewInput{Body: req.Body, Spoiler: req.Spoiler},
+	)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to update review", err, slog.String("review_id", id.String()))
+		return
+	}
+	sendOk(writer)
+}
+
+func (h ReviewHandler) RemoveReview(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+	id, ok := reviewID(writer, request)
+	if !ok {
+		return
+	}
+
+	if err := h.reviewsUsecase.RemoveReview(request.Context(), userID, id); err != nil {
+		SendError(writer, err)
+		logs.Error("failed to remove review", err, slog.String("review_id", id.String()))
+		return
+	}
+	sendOk(writer)
+}
+
+func (h ReviewHandler) ListMyReviews(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+
+	list, err := h.reviewsUsecase.ListUserReviews(request.Context(), userID)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to list user reviews", err, slog.String("user_id", userID.String()))
+		return
+	}
+	response := ListReviewsResponse{Reviews: make([]ReviewResponse, len(list))}
+	for i, review := range list {
+		response.Reviews[i] = getReviewResponse(review)
+	}
+	sendOkJSON(writer, response)
+}
+
+func (h ReviewHandler) VoteHelpful(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+	id, ok := reviewID(writer, request)
+	if !ok {
+		return
+	}
+
+	if err := h.reviewsUsecase.VoteHelpful(request.Context(), userID, id); err != nil {
+		SendError(writer, err)
+		logs.Error("failed to vote review helpful", err, slog.String("review_id", id.String()))
+		return
+	}
+	sendOk(writer)
+}
+
+func (h ReviewHandler) RemoveHelpfulVote(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+	id, ok := reviewID(writer, request)
+	if !ok {
+		return
+	}
+
+	if err := h.reviewsUsecase.RemoveHelpfulVote(request.Context(), userID, id); err != nil {
+		SendError(writer, err)
+		logs.Error("failed to remove helpful vote", err, slog.String("review_id", id.String()))
+		return
+	}
+	sendOk(writer)
+}
+
+func (h ReviewHandler) ListModerationQueue(writer http.ResponseWriter, request *http.Request) {
+	status, err := reviews.ParseReviewStatus(request.URL.Query().Get(VarStatus))
+	if err != nil {
+		SendError(writer, err)
+		return
+	}
+	page, ok := queryPage(writer, request)
+	if !ok {
+		return
+	}
+
+	list, err := h.reviewsUsecase.ListModerationQueue(
+		request.Context(), status, ReviewsPageSize+1, uint64((page-1)*ReviewsPageSize),
+	)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to list moderation queue", err, slog.String("status", string(status)))
+		return
+	}
+	sendOkJSON(writer, getReviewsPageResponse(list, page))
+}
+
+func (h ReviewHandler) ApproveReview(writer http.ResponseWriter, request *http.Request) {
+	moderatorID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+	id, ok := reviewID(writer, request)
+	if !ok {
+		return
+	}
+
+	if err := h.reviewsUsecase.ApproveReview(request.Context(), moderatorID, id); err != nil {
+		SendError(writer, err)
+		logs.Error("failed to approve review", err, slog.String("review_id", id.String()))
+		return
+	}
+	sendOk(writer)
+}
+
+func (h ReviewHandler) RejectReview(writer http.ResponseWriter, request *http.Request) {
+	moderatorID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+	id, ok := reviewID(writer, request)
+	if !ok {
+		return
+	}
+
+	var req RejectReviewRequest
+	if !decode(writer, request, &req) {
+		return
+	}
+	if validationErr(writer, h.validate, req) {
+		return
+	}
+
+	if err := h.reviewsUsecase.RejectReview(request.Context(), moderatorID, id, req.Reason); err != nil {
+		SendError(writer, err)
+		logs.Error("failed to reject review", err, slog.String("review_id", id.String()))
+		return
+	}
+	sendOk(writer)
+}
+
+func reviewID(writer http.ResponseWriter, request *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(request)[VarID])
+	if err != nil {
+		sendBadRequest(writer, InvalidReviewID)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func getReviewsPageResponse(list []model.Review, page int) ListReviewsResponse {
+	response := ListReviewsResponse{
+		Reviews: make([]ReviewResponse, 0, min(len(list), ReviewsPageSize)),
+		Page:    page,
+		HasNext: len(list) > ReviewsPageSize,
+	}
+	for _, review := range list[:min(len(list), ReviewsPageSize)] {
+		response.Reviews = append(response.Reviews, getReviewResponse(review))
+	}
+	return response
+}
+
+func getReviewResponse(review model.Review) ReviewResponse {
+	return ReviewResponse{
+		ID:              review.ID,
+		BookID:          review.BookID,
+		UserID:          review.UserID,
+		Body:            review.Body,
+		Spoiler:         review.Spoiler,
+		Status:          review.Status,
+		HelpfulCount:    review.HelpfulCount,
+		VotedHelpful:    review.VotedHelpful,
+		RejectionReason: review.RejectionReason,
+		ModeratedAt:     review.ModeratedAt,
+		CreatedAt:       review.CreatedAt,
+		UpdatedAt:       review.UpdatedAt,
+	}
+}
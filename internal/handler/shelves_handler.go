@@ -0,0 +1,453 @@
+package handler
+
+import (
+	"context"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/shelves"
+	"github.com/iamvkosarev/book-shelf/pkg/logs"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	VarShelf  = "shelf"
+	VarBookID = "book_id"
+	VarToken  = "token"
+
+	SharedShelfPath = "/shared/"
+
+	InvalidShareID = "invalid share id"
+)
+
+type ShelvesUsecase interface {
+	ListShelves(ctx context.Context, userID uuid.UUID) ([]model.Shelf, error)
+	AddShelf(ctx context.Context, userID uuid.UUID, name string) (uuid.UUID, error)
+	RenameShelf(ctx context.Context, userID uuid.UUID, ref shelves.ShelfRef, name string) error
+	RemoveShelf(ctx context.Context, userID uuid.UUID, ref shelves.ShelfRef) error
+	AddShelfBook(ctx context.Context, userID uuid.UUID, ref shelves.ShelfRef, bookID uuid.UUID) error
+	RemoveShelfBook(ctx context.Context, userID uuid.UUID, ref shelves.ShelfRef, bookID uuid.UUID) error
+	ListShelfBooks(
+		ctx context.Context,
+		userID uuid.UUID,
+		ref shelves.ShelfRef,
+		expandAuthors, expandTags, expandPublisher bool,
+	) (model.Shelf, []shelves.ShelfEntry, error)
+	CreateShare(
+		ctx context.Context,
+		userID uuid.UUID,
+		ref shelves.ShelfRef,
+		expiresAt *time.Time,
+	) (model.ShelfShare, error)
+	ListShares(ctx context.Context, userID uuid.UUID, ref shelves.ShelfRef) ([]model.ShelfShare, error)
+	RevokeShare(ctx context.Context, userID uuid.UUID, ref shelves.ShelfRef, id uuid.UUID) error
+	ViewSharedShelf(
+		ctx context.Context,
+		token string,
+		expandAuthors, expandTags, expandPublisher bool,
+	) (model.Shelf, []shelves.ShelfEntry, error)
+}
+
+type ShelfHandler struct {
+	shelvesUsecase    ShelvesUsecase
+	ratingsReader     UserRatingsReader
+	userIDFromContext UserIDFromContext
+	validate          *validator.Validate
+}
+
+func NewShelfHandler(
+	usecase ShelvesUsecase,
+	ratingsReader UserRatingsReader,
+	userIDFromContext UserIDFromContext,
+) *ShelfHandler {
+	return &ShelfHandler{
+		shelvesUsecase:    usecase,
+		ratingsReader:     ratingsReader,
+		userIDFromContext: userIDFromContext,
+		validate:          validator.New(),
+	}
+}
+
+type ShelfRequest struct {
+	Name string `json:"name" validate:"required,min=1,max=50"`
+}
+
+type AddShelfResponse struct {
+	ID uuid.UUID `json:"id"`
+}
+
+type ShelfResponse struct {
+	ID         uuid.UUID       `json:"id"`
+	Kind       model.ShelfKind `json:"kind"`
+	Name       string          `json:"name"`
+	BooksCount int             `json:"books_count"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+type ListShelvesResponse struct {
+	Shelves []ShelfResponse `json:"shelves"`
+}
+
+type ShelfBookResponse struct {
+	BookResponse
+	AddedAt time.Time `json:"added_at"`
+}
+
+type ShelfBooksResponse struct {
+	Shelf ShelfResponse       `json:"shelf"`
+	Books []ShelfBookResponse `json:"books"`
+}
+
+type ShareRequest struct {
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type ShareResponse struct {
+	ID           uuid.UUID  `json:"id"`
+	Token        string     `json:"token"`
+	Path         string     `json:"path"`
+	Active       bool       `json:"active"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	Views        int        `json:"views"`
+	LastViewedAt *time.Time `json:"last_viewed_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type ListSharesResponse struct {
+	Shares []ShareResponse `json:"shares"`
+}
+
+// SharedShelfResponse leaves the owner and the shelf id out, the token is
+// all a visitor knows about the shelf.
+type SharedShelfResponse struct {
+	Shelf SharedShelfInfoResponse `json:"shelf"`
+	Books []ShelfBookResponse     `json:"books"`
+}
+
+type SharedShelfInfoResponse struct {
+	Kind       model.ShelfKind `json:"kind"`
+	Name       string          `json:"name"`
+	BooksCount int             `json:"books_count"`
+}
+
+func (h ShelfHandler) ListShelves(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+
+	list, err := h.shelvesUsecase.ListShelves(request.Context(), userID)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to list shelves", err, slog.String("user_id", userID.String()))
+		return
+	}
+	response := ListShelvesResponse{Shelves: make([]ShelfResponse, len(list))}
+	for i, shelf := range list {
+		response.Shelves[i] = getShelfResponse(shelf)
+	}
+	sendOkJSON(writer, response)
+}
+
+func (h ShelfHandler) AddShelf(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+
+	var req ShelfRequest
+	if !decode(writer, request, &req) {
+		return
+	}
+	if validationErr(writer, h.validate, req) {
+		return
+	}
+
+	id, err := h.shelvesUsecase.AddShelf(request.Context(), userID, req.Name)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to add shelf", err, slog.String("user_id", userID.String()))
+		return
+	}
+	sendCreatedJSON(writer, AddShelfResponse{ID: id})
+}
+
+func (h ShelfHandler) RenameShelf(writer http.ResponseWriter, request *http.Request) {
+	userID, ref, ok := h.shelfFromRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	var req ShelfRequest
+	if !decode(writer, request, &req) {
+		return
+	}
+	if validationErr(writer, h.validate, req) {
+		return
+	}
+
+	if err := h.shelvesUsecase.RenameShelf(request.Context(), userID, ref, req.Name); err != nil {
+		SendError(writer, err)
+		logs.Error("failed to rename shelf", err, slog.String("user_id", userID.String()))
+		return
+	}
+	sendOk(writer)
+}
+
+func (h ShelfHandler) RemoveShelf(writer http.ResponseWriter, request *http.Request) {
+	userID, ref, ok := h.shelfFromRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	if err := h.shelvesUsecase.RemoveShelf(request.Context(), userID, ref); err != nil {
+		SendError(writer, err)
+		logs.Error("failed to remove shelf", err, slog.String("user_id", userID.String()))
+		return
+	}
+	sendOk(writer)
+}
+
+func (h ShelfHandler) ListShelfBooks(writer http.ResponseWriter, request *http.Request) {
+	userID, ref, ok := h.shelfFromRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	expend := parseQueryToStringMap(request, VarExpend)
+	expendAuthorsData := hasKeyInMap(expend, VarExpendValueAuthors)
+	expendTagsData := hasKeyInMap(expend, VarExpendValueTags)
+	expendPublisherData := hasKeyInMap(expend, VarExpendValuePublisher)
+
+	shelf, entries, err := h.shelvesUsecase.ListShelfBooks(
+		request.Context(), userID, ref,
+		expendAuthorsData, expendTagsData, expendPublisherData,
+	)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to list shelf books", err, slog.String("user_id", userID.String()))
+		return
+	}
+	mine, err := h.userRatings(request, entries)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to list user ratings", err, slog.String("user_id", userID.String()))
+		return
+	}
+	sendOkJSON(
+		writer, ShelfBooksResponse{
+			Shelf: getShelfResponse(shelf),
+			Books: getShelfBooksResponse(entries, mine, expendAuthorsData, expendTagsData, expendPublisherData),
+		},
+	)
+}
+
+// GetSharedShelf is public, the token in the path is the only credential. A
+// signed-in viewer also gets their own ratings of the books.
+func (h ShelfHandler) GetSharedShelf(writer http.ResponseWriter, request *http.Request) {
+	token := mux.Vars(request)[VarToken]
+
+	expend := parseQueryToStringMap(request, VarExpend)
+	expendAuthorsData := hasKeyInMap(expend, VarExpendValueAuthors)
+	expendTagsData := hasKeyInMap(expend, VarExpendValueTags)
+	expendPublisherData := hasKeyInMap(expend, VarExpendValuePublisher)
+
+	shelf, entries, err := h.shelvesUsecase.ViewSharedShelf(
+		request.Context(), token,
+		expendAuthorsData, expendTagsData, expendPublisherData,
+	)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to get shared shelf", err)
+		return
+	}
+	mine, err := h.userRatings(request, entries)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to list user ratings", err)
+		return
+	}
+	sendOkJSON(
+		writer, SharedShelfResponse{
+			Shelf: SharedShelfInfoResponse{Kind: shelf.Kind, Name: shelf.Name, BooksCount: shelf.BooksCount},
+			Books: getShelfBooksResponse(entries, mine, expendAuthorsData, expendTagsData, expendPublisherData),
+		},
+	)
+}
+
+func (h ShelfHandler) userRatings(request *http.Request, entries []shelves.ShelfEntry) (map[uuid.UUID]int16, error) {
+	books := make([]model.Book, len(entries))
+	for i, entry := range entries {
+		books[i] = entry.Book
+	}
+	return userRatings(request, h.userIDFromContext, h.ratingsReader, books)
+}
+
+func (h ShelfHandler) ListShares(writer http.ResponseWriter, request *http.Request) {
+	userID, ref, ok := h.shelfFromRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	list, err := h.shelvesUsecase.ListShares(request.Context(), userID, ref)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to list shelf shares", err, slog.String("user_id", userID.String()))
+		return
+	}
+	now := time.Now()
+	response := ListSharesResponse{Shares: make([]ShareResponse, len(list))}
+	for i, share := range list {
+		response.Shares[i] = getShareResponse(share, now)
+	}
+	sendOkJSON(writer, response)
+}
+
+func (h ShelfHandler) CreateShare(writer http.ResponseWriter, request *http.Request) {
+	userID, ref, ok := h.shelfFromRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	var req ShareRequest
+	if !decode(writer, request, &req) {
+		return
+	}
+
+	share, err := h.shelvesUsecase.CreateShare(request.Context(), userID, ref, req.ExpiresAt)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to create shelf share", err, slog.String("user_id", userID.String()))
+		return
+	}
+	sendCreatedJSON(writer, getShareResponse(share, time.Now()))
+}
+
+func (h ShelfHandler) RevokeShare(writer http.ResponseWriter, request *http.Request) {
+	userID, ref, ok := h.shelfFromRequest(writer, request)
+	if !ok {
+		return
+	}
+	id, err := uuid.Parse(mux.Vars(request)[VarID])
+	if err != nil {
+		sendBadRequest(writer, InvalidShareID)
+		return
+	}
+
+	if err = h.shelvesUsecase.RevokeShare(request.Context(), userID, ref, id); err != nil {
+		SendError(writer, err)
+		logs.Error("failed to revoke shelf share", err, slog.String("share_id", id.String()))
+		return
+	}
+	sendOk(writer)
+}
+
+func (h ShelfHandler) AddShelfBook(writer http.ResponseWriter, request *http.Request) {
+	userID, ref, bookID, ok := h.shelfBookFromRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	if err := h.shelvesUsecase.AddShelfBook(request.Context(), userID, ref, bookID); err != nil {
+		SendError(writer, err)
+		logs.Error(
+			"failed to add book to shelf", err,
+			slog.String("user_id", userID.String()), slog.String("book_id", bookID.String()),
+		)
+		return
+	}
+	sendOk(writer)
+}
+
+func (h ShelfHandler) RemoveShelfBook(writer http.ResponseWriter, request *http.Request) {
+	userID, ref, bookID, ok := h.shelfBookFromRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	if err := h.shelvesUsecase.RemoveShelfBook(request.Context(), userID, ref, bookID); err != nil {
+		SendError(writer, err)
+		logs.Error(
+			"failed to remove book from shelf", err,
+			slog.String("user_id", userID.String()), slog.String("book_id", bookID.String()),
+		)
+		return
+	}
+	sendOk(writer)
+}
+
+func (h ShelfHandler) shelfFromRequest(
+	writer http.ResponseWriter,
+	request *http.Request,
+) (uuid.UUID, shelves.ShelfRef, bool) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return uuid.Nil, shelves.ShelfRef{}, false
+	}
+	ref, err := shelves.ParseShelfRef(mux.Vars(request)[VarShelf])
+	if err != nil {
+		SendError(writer, err)
+		return uuid.Nil, shelves.ShelfRef{}, false
+	}
+	return userID, ref, true
+}
+
+func (h ShelfHandler) shelfBookFromRequest(
+	writer http.ResponseWriter,
+	request *http.Request,
+) (uuid.UUID, shelves.ShelfRef, uuid.UUID, bool) {
+	userID, ref, ok := h.shelfFromRequest(writer, request)
+	if !ok {
+		return uuid.Nil, shelves.ShelfRef{}, uuid.Nil, false
+	}
+	bookID, err := uuid.Parse(mux.Vars(request)[VarBookID])
+	if err != nil {
+		sendBadRequest(writer, InvalidBookID)
+		return uuid.Nil, shelves.ShelfRef{}, uuid.Nil, false
+	}
+	return userID, ref, bookID, true
+}
+
+func getShelfResponse(shelf model.Shelf) ShelfResponse {
+	return ShelfResponse{
+		ID:         shelf.ID,
+		Kind:       shelf.Kind,
+		Name:       shelf.Name,
+		BooksCount: shelf.BooksCount,
+		CreatedAt:  shelf.CreatedAt,
+	}
+}
+
+func getShelfBooksResponse(
+	entries []shelves.ShelfEntry,
+	mine map[uuid.UUID]int16,
+	expandAuthors, expandTags, expandPublisher bool,
+) []ShelfBookResponse {
+	response := make([]ShelfBookResponse, len(entries))
+	for i, entry := range entries {
+		response[i] = ShelfBookResponse{
+			BookResponse: getBookResponse(entry.Book, expandAuthors, expandTags, expandPublisher),
+			AddedAt:      entry.AddedAt,
+		}
+		setMineRating(&response[i].BookResponse, mine)
+	}
+	return response
+}
+
+func getShareResponse(share model.ShelfShare, now time.Time) ShareResponse {
+	return ShareResponse{
+		ID:           share.ID,
+		Token:        share.Token,
+		Path:         SharedShelfPath + share.Token,
+		Active:       share.Active(now),
+		ExpiresAt:    share.ExpiresAt,
+		RevokedAt:    share.RevokedAt,
+		Views:        share.Views,
+		LastViewedAt: share.LastViewedAt,
+		CreatedAt:    share.CreatedAt,
+	}
+}
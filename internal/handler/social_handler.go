@@ -0,0 +1,358 @@
+package handler
+
+import (
+	"context"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/social"
+	"github.com/iamvkosarev/book-shelf/pkg/logs"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	FollowsPageSize = 50
+	FeedPageSize    = 30
+
+	VarCursor = "cursor"
+
+	InvalidUserID = "invalid user id"
+)
+
+type SocialUsecase interface {
+	GetProfile(ctx context.Context, userID uuid.UUID) (model.Profile, error)
+	SetProfilePublic(ctx context.Context, userID uuid.UUID, public bool) (model.Profile, error)
+	Follow(ctx context.Context, followerID uuid.UUID, followeeID uuid.UUID) (model.Follow, error)
+	Unfollow(ctx context.Context, followerID uuid.UUID, followeeID uuid.UUID) error
+	AcceptFollower(ctx context.Context, userID uuid.UUID, followerID uuid.UUID) error
+	RemoveFollower(ctx context.Context, userID uuid.UUID, followerID uuid.UUID) error
+	ListFollowers(ctx context.Context, userID uuid.UUID, parameters social.ListFollowsParameters) ([]model.Follow, error)
+	ListFollowing(ctx context.Context, userID uuid.UUID, parameters social.ListFollowsParameters) ([]model.Follow, error)
+	Feed(
+		ctx context.Context,
+		userID uuid.UUID,
+		cursor *social.FeedCursor,
+		limit uint64,
+		expandAuthors, expandTags, expandPublisher bool,
+	) (social.FeedPage, error)
+}
+
+type SocialHandler struct {
+	socialUsecase     SocialUsecase
+	ratingsReader     UserRatingsReader
+	userIDFromContext UserIDFromContext
+	validate          *validator.Validate
+}
+
+func NewSocialHandler(
+	usecase SocialUsecase,
+	ratingsReader UserRatingsReader,
+	userIDFromContext UserIDFromContext,
+) *SocialHandler {
+	return &SocialHandler{
+		socialUsecase:     usecase,
+		ratingsReader:     ratingsReader,
+		userIDFromContext: userIDFromContext,
+		validate:          validator.New(),
+	}
+}
+
+type ProfileRequest struct {
+	Public *bool `json:"public" validate:"required"`
+}
+
+type ProfileResponse struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Public    bool      `json:"public"`
+	Followers int       `json:"followers"`
+	Following int       `json:"following"`
+}
+
+type FollowResponse struct {
+	FollowerID uuid.UUID          `json:"follower_id"`
+	FolloweeID uuid.UUID          `json:"followee_id"`
+	Status     model.FollowStatus `json:"status"`
+	CreatedAt  time.Time          `json:"created_at"`
+	AcceptedAt *time.Time         `json:"accepted_at,omitempty"`
+}
+
+type ListFollowsResponse struct {
+	Follows []FollowResponse `json:"follows"`
+	Page    int              `json:"page"`
+	HasNext bool             `json:"has_next"`
+}
+
+type ActivityShelfResponse struct {
+	ID   uuid.UUID       `json:"id"`
+	Kind model.ShelfKind `json:"kind"`
+	Name *string         `json:"name,omitempty"`
+}
+
+type ActivityResponse struct {
+	ID        uuid.UUID              `json:"id"`
+	UserID    uuid.UUID              `json:"user_id"`
+	Kind      model.ActivityKind     `json:"kind"`
+	Book      BookResponse           `json:"book"`
+	Shelf     *ActivityShelfResponse `json:"shelf,omitempty"`
+	Rating    *int16                 `json:"rating,omitempty"`
+	ReviewID  *uuid.UUID             `json:"review_id,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+type FeedResponse struct {
+	Events     []ActivityResponse `json:"events"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+func (h SocialHandler) GetProfile(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+
+	profile, err := h.socialUsecase.GetProfile(request.Context(), userID)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to get profile", err, slog.String("user_id", userID.String()))
+		return
+	}
+	sendOkJSON(writer, ProfileResponse(profile))
+}
+
+// GetUserProfile shows whether following the user needs their approval.
+func (h SocialHandler) GetUserProfile(writer http.ResponseWriter, request *http.Request) {
+	id, ok := userIDFromRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	profile, err := h.socialUsecase.GetProfile(request.Context(), id)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to get profile", err, slog.String("user_id", id.String()))
+		return
+	}
+	sendOkJSON(writer, ProfileResponse(profile))
+}
+
+func (h SocialHandler) SetProfile(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+
+	var req ProfileRequest
+	if !decode(writer, request, &req) {
+		return
+	}
+	if validationErr(writer, h.validate, req) {
+		return
+	}
+
+	profile, err := h.socialUsecase.SetProfilePublic(request.Context(), userID, *req.Public)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to set profile", err, slog.String("user_id", userID.String()))
+		return
+	}
+	sendOkJSON(writer, ProfileResponse(profile))
+}
+
+func (h SocialHandler) Follow(writer http.ResponseWriter, request *http.Request) {
+	userID, followeeID, ok := h.usersFromRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	follow, err := h.socialUsecase.Follow(request.Context(), userID, followeeID)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to follow user", err, slog.String("followee_id", followeeID.String()))
+		return
+	}
+	sendOkJSON(writer, FollowResponse(follow))
+}
+
+func (h SocialHandler) Unfollow(writer http.ResponseWriter, request *http.Request) {
+	userID, followeeID, ok := h.usersFromRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	if err := h.socialUsecase.Unfollow(request.Context(), userID, followeeID); err != nil {
+		SendError(writer, err)
+		logs.Error("failed to unfollow user", err, slog.String("followee_id", followeeID.String()))
+		return
+	}
+	sendOk(writer)
+}
+
+func (h SocialHandler) AcceptFollower(writer http.ResponseWriter, request *http.Request) {
+	userID, followerID, ok := h.usersFromRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	if err := h.socialUsecase.AcceptFollower(request.Context(), userID, followerID); err != nil {
+		SendError(writer, err)
+		logs.Error("failed to accept follower", err, slog.String("follower_id", followerID.String()))
+		return
+	}
+	sendOk(writer)
+}
+
+func (h SocialHandler) RemoveFollower(writer http.ResponseWriter, request *http.Request) {
+	userID, followerID, ok := h.usersFromRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	if err := h.socialUsecase.RemoveFollower(request.Context(), userID, followerID); err != nil {
+		SendError(writer, err)
+		logs.Error("failed to remove follower", err, slog.String("follower_id", followerID.String()))
+		return
+	}
+	sendOk(writer)
+}
+
+// ListFollowers takes the status query parameter, pending lists the requests
+// waiting for approval.
+func (h SocialHandler) ListFollowers(writer http.ResponseWriter, request *http.Request) {
+	h.listFollows(writer, request, h.socialUsecase.ListFollowers)
+}
+
+func (h SocialHandler) ListFollowing(writer http.ResponseWriter, request *http.Request) {
+	h.listFollows(writer, request, h.socialUsecase.ListFollowing)
+}
+
+func (h SocialHandler) listFollows(
+	writer http.ResponseWriter,
+	request *http.Request,
+	list func(context.Context, uuid.UUID, social.ListFollowsParameters) ([]model.Follow, error),
+) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+	status, err := social.ParseFollowStatus(request.URL.Query().Get(VarStatus))
+	if err != nil {
+		SendError(writer, err)
+		return
+	}
+	page, ok := queryPage(writer, request)
+	if !ok {
+		return
+	}
+
+	follows, err := list(
+		request.Context(), userID, social.ListFollowsParameters{
+			Status: status,
+			Limit:  FollowsPageSize + 1,
+			Offset: uint64((page - 1) * FollowsPageSize),
+		},
+	)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to list follows", err, slog.String("user_id", userID.String()))
+		return
+	}
+	response := ListFollowsResponse{
+		Follows: make([]FollowResponse, 0, min(len(follows), FollowsPageSize)),
+		Page:    page,
+		HasNext: len(follows) > FollowsPageSize,
+	}
+	for _, follow := range follows[:min(len(follows), FollowsPageSize)] {
+		response.Follows = append(response.Follows, FollowResponse(follow))
+	}
+	sendOkJSON(writer, response)
+}
+
+// Feed pages by cursor: next_cursor of a response is passed back as the
+// cursor query parameter, and it is missing on the last page.
+func (h SocialHandler) Feed(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+	cursor, err := social.ParseFeedCursor(request.URL.Query().Get(VarCursor))
+	if err != nil {
+		SendError(writer, err)
+		return
+	}
+
+	expend := parseQueryToStringMap(request, VarExpend)
+	expendAuthorsData := hasKeyInMap(expend, VarExpendValueAuthors)
+	expendTagsData := hasKeyInMap(expend, VarExpendValueTags)
+	expendPublisherData := hasKeyInMap(expend, VarExpendValuePublisher)
+
+	page, err := h.socialUsecase.Feed(
+		request.Context(), userID, cursor, FeedPageSize,
+		expendAuthorsData, expendTagsData, expendPublisherData,
+	)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to get feed", err, slog.String("user_id", userID.String()))
+		return
+	}
+	books := make([]model.Book, len(page.Entries))
+	for i, entry := range page.Entries {
+		books[i] = entry.Book
+	}
+	mine, err := userRatings(request, h.userIDFromContext, h.ratingsReader, books)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to list user ratings", err, slog.String("user_id", userID.String()))
+		return
+	}
+	response := FeedResponse{
+		Events:     make([]ActivityResponse, len(page.Entries)),
+		NextCursor: page.Next,
+	}
+	for i, entry := range page.Entries {
+		response.Events[i] = ActivityResponse{
+			ID:        entry.ID,
+			UserID:    entry.UserID,
+			Kind:      entry.Kind,
+			Book:      getBookResponse(entry.Book, expendAuthorsData, expendTagsData, expendPublisherData),
+			Rating:    entry.Rating,
+			ReviewID:  entry.ReviewID,
+			CreatedAt: entry.CreatedAt,
+		}
+		setMineRating(&response.Events[i].Book, mine)
+		if entry.ShelfID != nil && entry.ShelfKind != nil {
+			response.Events[i].Shelf = &ActivityShelfResponse{
+				ID:   *entry.ShelfID,
+				Kind: *entry.ShelfKind,
+				Name: entry.ShelfName,
+			}
+		}
+	}
+	sendOkJSON(writer, response)
+}
+
+func (h SocialHandler) usersFromRequest(
+	writer http.ResponseWriter,
+	request *http.Request,
+) (uuid.UUID, uuid.UUID, bool) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+	otherID, ok := userIDFromRequest(writer, request)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, otherID, true
+}
+
+func userIDFromRequest(writer http.ResponseWriter, request *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(request)[VarID])
+	if err != nil {
+		sendBadRequest(writer, InvalidUserID)
+		return uuid.Nil, false
+	}
+	return id, true
+}
@@ -0,0 +1,274 @@
+package handler
+
+import (
+	"context"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/stats"
+	"github.com/iamvkosarev/book-shelf/pkg/logs"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	VarYear = "year"
+
+	InvalidYear = "year must be an integer"
+)
+
+type StatsUsecase interface {
+	GetStats(ctx context.Context, userID uuid.UUID, year *int) (model.ReadingStats, error)
+	SetGoal(ctx context.Context, userID uuid.UUID, year int, input stats.GoalInput) (model.ReadingGoalProgress, error)
+	GetGoal(ctx context.Context, userID uuid.UUID, year int) (model.ReadingGoalProgress, error)
+	ListGoals(ctx context.Context, userID uuid.UUID) ([]model.ReadingGoalProgress, error)
+	RemoveGoal(ctx context.Context, userID uuid.UUID, year int) error
+}
+
+type StatsHandler struct {
+	statsUsecase      StatsUsecase
+	userIDFromContext UserIDFromContext
+	validate          *validator.Validate
+}
+
+func NewStatsHandler(usecase StatsUsecase, userIDFromContext UserIDFromContext) *StatsHandler {
+	return &StatsHandler{
+		statsUsecase:      usecase,
+		userIDFromContext: userIDFromContext,
+		validate:          validator.New(),
+	}
+}
+
+type ReadingPeriodResponse struct {
+	Year  int  `json:"year"`
+	Month *int `json:"month,omitempty"`
+	Books int  `json:"books"`
+	Pages int  `json:"pages"`
+}
+
+type FavouriteTagResponse struct {
+	Tag   TagResponse `json:"tag"`
+	Books int         `json:"books"`
+}
+
+type FavouriteAuthorResponse struct {
+	Author AuthorResponse `json:"author"`
+	Books  int            `json:"books"`
+}
+
+type RatingsGivenResponse struct {
+	Count   int      `json:"count"`
+	Average *float64 `json:"average"`
+}
+
+type StatsResponse struct {
+	Months           []ReadingPeriodResponse   `json:"months"`
+	Years            []ReadingPeriodResponse   `json:"years"`
+	FavouriteTags    []FavouriteTagResponse    `json:"favourite_tags"`
+	FavouriteAuthors []FavouriteAuthorResponse `json:"favourite_authors"`
+	Ratings          RatingsGivenResponse      `json:"ratings"`
+	Goal             *GoalResponse             `json:"goal,omitempty"`
+}
+
+type GoalRequest struct {
+	Books *int32 `json:"books" validate:"required,min=1"`
+	Pages *int32 `json:"pages" validate:"omitempty,min=1"`
+}
+
+type GoalResponse struct {
+	Year            int       `json:"year"`
+	Books           int32     `json:"books"`
+	Pages           *int32    `json:"pages,omitempty"`
+	BooksFinished   int       `json:"books_finished"`
+	PagesFinished   int       `json:"pages_finished"`
+	BooksPercentage float64   `json:"books_percentage"`
+	PagesPercentage *float64  `json:"pages_percentage,omitempty"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type ListGoalsResponse struct {
+	Goals []GoalResponse `json:"goals"`
+}
+
+// GetStats covers every year unless the year query parameter narrows it.
+func (h StatsHandler) GetStats(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+	var year *int
+	if raw := request.URL.Query().Get(VarYear); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			sendBadRequest(writer, InvalidYear)
+			return
+		}
+		year = &parsed
+	}
+
+	readingStats, err := h.statsUsecase.GetStats(request.Context(), userID, year)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to get reading stats", err, slog.String("user_id", userID.String()))
+		return
+	}
+	sendOkJSON(writer, getStatsResponse(readingStats))
+}
+
+func (h StatsHandler) ListGoals(writer http.ResponseWriter, request *http.Request) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return
+	}
+
+	list, err := h.statsUsecase.ListGoals(request.Context(), userID)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to list reading goals", err, slog.String("user_id", userID.String()))
+		return
+	}
+	response := ListGoalsResponse{Goals: make([]GoalResponse, len(list))}
+	for i, goal := range list {
+		response.Goals[i] = getGoalResponse(goal)
+	}
+	sendOkJSON(writer, response)
+}
+
+func (h StatsHandler) GetGoal(writer http.ResponseWriter, request *http.Request) {
+	userID, year, ok := h.goalFromRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	goal, err := h.statsUsecase.GetGoal(request.Context(), userID, year)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to get reading goal", err, slog.Int("year", year))
+		return
+	}
+	sendOkJSON(writer, getGoalResponse(goal))
+}
+
+func (h StatsHandler) SetGoal(writer http.ResponseWriter, request *http.Request) {
+	userID, year, ok := h.goalFromRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	var req GoalRequest
+	if !decode(writer, request, &req) {
+		return
+	}
+	if validationErr(writer, h.validate, req) {
+		return
+	}
+
+	goal, err := h.statsUsecase.SetGoal(
+		request.Context(), userID, year, stats.GoalInput{Books: *req.Books, Pages: req.Pages},
+	)
+	if err != nil {
+		SendError(writer, err)
+		logs.Error("failed to set reading goal", err, slog.Int("year", year))
+		return
+	}
+	sendOkJSON(writer, getGoalResponse(goal))
+}
+
+func (h StatsHandler) RemoveGoal(writer http.ResponseWriter, request *http.Request) {
+	userID, year, ok := h.goalFromRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	if err := h.statsUsecase.RemoveGoal(request.Context(), userID, year); err != nil {
+		SendError(writer, err)
+		logs.Error("failed to remove reading goal", err, slog.Int("year", year))
+		return
+	}
+	sendOk(writer)
+}
+
+func (h StatsHandler) goalFromRequest(writer http.ResponseWriter, request *http.Request) (uuid.UUID, int, bool) {
+	userID, ok := h.userIDFromContext.require(writer, request)
+	if !ok {
+		return uuid.Nil, 0, false
+	}
+	year, err := strconv.Atoi(mux.Vars(request)[VarYear])
+	if err != nil {
+		sendBadRequest(writer, InvalidYear)
+		return uuid.Nil, 0, false
+	}
+	return userID, year, true
+}
+
+func getStatsResponse(readingStats model.ReadingStats) StatsResponse {
+	response := StatsResponse{
+		Months:           make([]ReadingPeriodResponse, len(readingStats.Months)),
+		Years:            make([]ReadingPeriodResponse, len(readingStats.Years)),
+		FavouriteTags:    make([]FavouriteTagResponse, len(readingStats.FavouriteTags)),
+		FavouriteAuthors: make([]FavouriteAuthorResponse, len(readingStats.FavouriteAuthors)),
+		Ratings: RatingsGivenResponse{
+			Count:   readingStats.RatingsCount,
+			Average: readingStats.AverageRating,
+		},
+	}
+	for i, period := range readingStats.Months {
+		response.Months[i] = ReadingPeriodResponse(period)
+	}
+	for i, period := range readingStats.Years {
+		response.Years[i] = ReadingPeriodResponse(period)
+	}
+	for i, count := range readingStats.FavouriteTags {
+		response.FavouriteTags[i] = FavouriteTagResponse{
+			Tag:   TagResponse{ID: count.Tag.ID, Name: count.Tag.Name},
+			Books: count.Books,
+		}
+	}
+	for i, count := range readingStats.FavouriteAuthors {
+		response.FavouriteAuthors[i] = FavouriteAuthorResponse{
+			Author: AuthorResponse{
+				ID:         count.Author.ID,
+				FirstName:  count.Author.FirstName,
+				LastName:   count.Author.LastName,
+				MiddleName: count.Author.MiddleName,
+				Pseudonym:  count.Author.Pseudonym,
+			},
+			Books: count.Books,
+		}
+	}
+	if readingStats.AverageRating != nil {
+		average := math.Round(*readingStats.AverageRating*100) / 100
+		response.Ratings.Average = &average
+	}
+	if readingStats.Goal != nil {
+		goal := getGoalResponse(*readingStats.Goal)
+		response.Goal = &goal
+	}
+	return response
+}
+
+func getGoalResponse(progress model.ReadingGoalProgress) GoalResponse {
+	response := GoalResponse{
+		Year:            progress.Goal.Year,
+		Books:           progress.Goal.Books,
+		Pages:           progress.Goal.Pages,
+		BooksFinished:   progress.BooksFinished,
+		PagesFinished:   progress.PagesFinished,
+		BooksPercentage: percentage(progress.BooksFinished, progress.Goal.Books),
+		UpdatedAt:       progress.Goal.UpdatedAt,
+	}
+	if progress.Goal.Pages != nil {
+		pages := percentage(progress.PagesFinished, *progress.Goal.Pages)
+		response.PagesPercentage = &pages
+	}
+	return response
+}
+
+// percentage may go over 100 once the goal is beaten.
+func percentage(finished int, target int32) float64 {
+	return math.Round(float64(finished)*1000/float64(target)) / 10
+}
@@ -20,7 +20,7 @@ type TagUsecase interface {
 	AddTag(ctx context.Context, name string) (uuid.UUID, error)
 	GetTag(ctx context.Context, id uuid.UUID) (model.Tag, error)
 	UpdateTag(ctx context.Context, id uuid.UUID, name string) error
-	RemoveTag(ctx context.Context, id uuid.UUID) error
+	RemoveTag(ctx context.Context, id uuid.UUID, policy model.RemovePolicy) error
 	ListTags(ctx context.Context) ([]model.Tag, error)
 }
 
@@ -102,7 +102,13 @@ func (p TagHandler) RemoveTag(writer http.ResponseWriter, request *http.Request)
 		return
 	}
 
-	if err = p.tagUsecase.RemoveTag(request.Context(), id); err != nil {
+	policy, err := parseRemovePolicy(request)
+	if err != nil {
+		SendError(writer, err)
+		return
+	}
+
+	if err = p.tagUsecase.RemoveTag(request.Context(), id, policy); err != nil {
 		SendError(writer, err)
 		logs.Error("failed to remove tag", err, slog.String("tag_id", idStr))
 		return
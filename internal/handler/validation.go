@@ -13,30 +13,33 @@ const InternalErrorResponseText string = "internal server error"
 
 func validationErr(w http.ResponseWriter, validate *validator.Validate, req interface{}) bool {
 	if err := validate.Struct(req); err != nil {
-		var validatorErr validator.ValidationErrors
-		if errors.As(err, &validatorErr) {
-			errs := make([]string, len(validatorErr))
-			for i, fieldError := range validatorErr {
-				if fieldError.Param() != "" {
-					errs[i] = fmt.Sprintf(
-						"failed to validate field '%s', because of tag '%s:%s'",
-						strings.ToLower(fieldError.Field()),
-						fieldError.Tag(), fieldError.Param(),
-					)
-				} else {
-					errs[i] = fmt.Sprintf(
-						"failed to validate field '%s', because of tag '%s'", strings.ToLower(fieldError.Field()),
-						fieldError.Tag(),
-					)
-				}
-				break
-			}
-			sendBadRequest(w, strings.Join(errs, "\n"))
-		} else {
-			sendBadRequest(w, err.Error())
-		}
+		sendBadRequest(w, validationMessage(err))
 		logs.Error("failed to validate the request", err)
 		return true
 	}
 	return false
 }
+
+func validationMessage(err error) string {
+	var validatorErr validator.ValidationErrors
+	if !errors.As(err, &validatorErr) {
+		return err.Error()
+	}
+	errs := make([]string, len(validatorErr))
+	for i, fieldError := range validatorErr {
+		if fieldError.Param() != "" {
+			errs[i] = fmt.Sprintf(
+				"failed to validate field '%s', because of tag '%s:%s'",
+				strings.ToLower(fieldError.Field()),
+				fieldError.Tag(), fieldError.Param(),
+			)
+		} else {
+			errs[i] = fmt.Sprintf(
+				"failed to validate field '%s', because of tag '%s'", strings.ToLower(fieldError.Field()),
+				fieldError.Tag(),
+			)
+		}
+		break
+	}
+	return strings.Join(errs, "\n")
+}
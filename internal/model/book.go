@@ -14,7 +14,12 @@ type Book struct {
 	Title       string
 	Description *string
 	Price       *float64
+	Pages       *int32
 	Mark        *int16
+	Rating      RatingSummary
+	ISBN        *string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
 	Publisher   *Publisher
 	Authors     []Author
 	Tags        []Tag
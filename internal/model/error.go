@@ -7,19 +7,35 @@ import (
 var (
 	ErrPublisherAlreadyExists = NewInternalError(http.StatusConflict, "publisher already exists")
 	ErrPublisherNotFound      = NewInternalError(http.StatusNotFound, "publisher not found")
+	ErrPublisherNameRequired  = NewInternalError(http.StatusBadRequest, "publisher must have name")
+	ErrPublisherHasBooks      = NewInternalError(http.StatusConflict, "publisher is referenced by books")
 
 	ErrAuthorInvalidFields = NewInternalError(
 		http.StatusBadRequest, "author must have first_name, last_name or pseudonym",
 	)
 	ErrAuthorAlreadyExists = NewInternalError(http.StatusConflict, "author already exists")
 	ErrAuthorNotFound      = NewInternalError(http.StatusNotFound, "author not found")
+	ErrAuthorHasBooks      = NewInternalError(http.StatusConflict, "author is referenced by books")
 
 	ErrTagAlreadyExists = NewInternalError(http.StatusConflict, "tag already exists")
 	ErrTagNotFound      = NewInternalError(http.StatusNotFound, "tag not found")
+	ErrTagNameRequired  = NewInternalError(http.StatusBadRequest, "tag must have name")
+	ErrTagHasBooks      = NewInternalError(http.StatusConflict, "tag is referenced by books")
 
-	ErrBookAlreadyExists = NewInternalError(http.StatusConflict, "book already exists")
-	ErrBookNotFound      = NewInternalError(http.StatusNotFound, "book not found")
-	ErrBookInvalidFields = NewInternalError(http.StatusBadRequest, "book invalid fields")
+	ErrBookAlreadyExists   = NewInternalError(http.StatusConflict, "book already exists")
+	ErrBookNotFound        = NewInternalError(http.StatusNotFound, "book not found")
+	ErrBookInvalidFields   = NewInternalError(http.StatusBadRequest, "book invalid fields")
+	ErrBookInvalidMark     = NewInternalError(http.StatusBadRequest, "book mark must be between 0 and 10")
+	ErrBookInvalidPrice    = NewInternalError(http.StatusBadRequest, "book price must not be negative")
+	ErrBookInvalidPages    = NewInternalError(http.StatusBadRequest, "book pages must be a positive number")
+	ErrBookTitleRequired   = NewInternalError(http.StatusBadRequest, "book must have title")
+	ErrBookRepeatedAuthors = NewInternalError(http.StatusBadRequest, "book authors must not repeat")
+	ErrBookRepeatedTags    = NewInternalError(http.StatusBadRequest, "book tags must not repeat")
+	ErrBookInvalidISBN     = NewInternalError(http.StatusBadRequest, "book isbn is not a valid ISBN-10 or ISBN-13")
+	ErrBookIDRequired      = NewInternalError(http.StatusBadRequest, "book id is required")
+	ErrBookLikelyDuplicate = NewInternalError(
+		http.StatusConflict, "book is likely a duplicate, pass force=true to create it anyway",
+	)
 
 	ErrPasswordTooShort = NewInternalError(http.StatusBadRequest, "password is too short")
 	ErrUserNotExists    = NewInternalError(
@@ -52,6 +68,10 @@ var (
 	ErrUserAlreadyExists = NewInternalError(http.StatusConflict, "user already exists")
 	ErrUserNotFound      = NewInternalError(http.StatusConflict, "user not found")
 
+	ErrAuthenticationRequired = NewInternalError(
+		http.StatusUnauthorized,
+		"authentication required",
+	)
 	ErrTokenNotFound = NewInternalError(
 		http.StatusBadRequest,
 		"token not found",
@@ -76,11 +96,119 @@ var (
 		http.StatusInternalServerError,
 		"failed to parse claims",
 	)
+
+	ErrBatchUnknownOperation = NewInternalError(
+		http.StatusBadRequest, "batch operation must be one of create, update or delete",
+	)
+	ErrBatchRolledBack = NewInternalError(
+		http.StatusConflict, "operation was rolled back because another operation of the batch failed",
+	)
+	ErrBatchTooLarge = NewInternalError(http.StatusRequestEntityTooLarge, "batch has too many operations")
+
+	ErrImportCSVEmpty          = NewInternalError(http.StatusBadRequest, "csv has no header row")
+	ErrImportCSVTitleNotMapped = NewInternalError(http.StatusBadRequest, "csv has no column mapped to title")
+	ErrInvalidCitationFormat   = NewInternalError(http.StatusBadRequest, "citation format must be bibtex, ris or csl-json")
+	ErrImportMARCEmpty         = NewInternalError(http.StatusBadRequest, "marc file has no records")
+	ErrImportONIXEmpty         = NewInternalError(http.StatusBadRequest, "onix message has no products")
+	ErrImportCalibreInvalid    = NewInternalError(http.StatusBadRequest, "file is not a calibre metadata.db library")
+	ErrImportEPUBInvalid       = NewInternalError(http.StatusBadRequest, "epub metadata cannot be imported")
+
+	ErrHarvestBadResumptionToken = NewInternalError(http.StatusBadRequest, "resumption token is invalid or expired")
+	ErrHarvestUnknownSet         = NewInternalError(http.StatusBadRequest, "set does not exist")
+	ErrHarvestNoRecordsMatch     = NewInternalError(http.StatusNotFound, "no records match the request")
+
+	ErrBackupInvalidArchive     = NewInternalError(http.StatusBadRequest, "file is not a book-shelf backup archive")
+	ErrBackupUnsupportedVersion = NewInternalError(http.StatusBadRequest, "backup archive version is not supported")
+	ErrBackupInvalidConflict    = NewInternalError(
+		http.StatusBadRequest, "conflict strategy must be fail, skip or overwrite",
+	)
+	ErrBackupTargetNotEmpty = NewInternalError(
+		http.StatusConflict, "catalog is not empty, choose the skip or overwrite conflict strategy",
+	)
+
+	ErrHighlightsNoClippings = NewInternalError(http.StatusBadRequest, "file contains no kindle clippings")
+	ErrHighlightNotFound     = NewInternalError(http.StatusNotFound, "highlight not found")
+	ErrHighlightInvalidKind  = NewInternalError(
+		http.StatusBadRequest, "kind must be one of highlight, quote, note or bookmark",
+	)
+	ErrHighlightInvalidColour = NewInternalError(
+		http.StatusBadRequest, "colour must be one of yellow, green, blue, pink, orange or purple",
+	)
+	ErrHighlightTextRequired    = NewInternalError(http.StatusBadRequest, "highlight text must not be empty")
+	ErrHighlightInvalidLocation = NewInternalError(
+		http.StatusBadRequest, "location end must not be before the location start",
+	)
+	ErrHighlightInvalidTags = NewInternalError(
+		http.StatusBadRequest, "highlight tags must be at most 20 labels of up to 50 characters",
+	)
+
+	ErrShelfNotFound      = NewInternalError(http.StatusNotFound, "shelf not found")
+	ErrShelfAlreadyExists = NewInternalError(http.StatusConflict, "shelf with this name already exists")
+	ErrShelfBuiltin       = NewInternalError(
+		http.StatusBadRequest, "built-in shelves cannot be renamed or removed",
+	)
+	ErrShelfBookNotFound = NewInternalError(http.StatusNotFound, "book is not on the shelf")
+
+	ErrShareNotFound      = NewInternalError(http.StatusNotFound, "shared shelf not found")
+	ErrShareInvalidExpiry = NewInternalError(http.StatusBadRequest, "share expiry must be in the future")
+
+	ErrRatingNotFound     = NewInternalError(http.StatusNotFound, "book is not rated by the user")
+	ErrRatingInvalidValue = NewInternalError(http.StatusBadRequest, "rating must be between 0 and 10")
+
+	ErrReviewNotFound      = NewInternalError(http.StatusNotFound, "review not found")
+	ErrReviewAlreadyExists = NewInternalError(http.StatusConflict, "book is already reviewed by the user")
+	ErrReviewInvalidBody   = NewInternalError(http.StatusBadRequest, "review text must not be empty")
+	ErrReviewInvalidOrder  = NewInternalError(http.StatusBadRequest, "order must be one of newest or helpful")
+	ErrReviewInvalidStatus = NewInternalError(
+		http.StatusBadRequest, "status must be one of pending, approved or rejected",
+	)
+	ErrReviewOwnVote      = NewInternalError(http.StatusBadRequest, "own review cannot be voted helpful")
+	ErrReviewVoteNotFound = NewInternalError(http.StatusNotFound, "review is not voted helpful by the user")
+
+	ErrProgressNotFound          = NewInternalError(http.StatusNotFound, "reading progress not found")
+	ErrProgressInvalidPercentage = NewInternalError(http.StatusBadRequest, "percentage must be between 0 and 100")
+	ErrProgressInvalidPage       = NewInternalError(http.StatusBadRequest, "page must be a positive number")
+	ErrProgressDocumentRequired  = NewInternalError(http.StatusBadRequest, "document is required")
+
+	ErrKOReaderNotLinked = NewInternalError(
+		http.StatusNotFound, "KOReader account is not linked, link it from your book-shelf profile first",
+	)
+	ErrKOReaderUsernameTaken = NewInternalError(http.StatusConflict, "KOReader username is already registered")
+	ErrKOReaderUnauthorized  = NewInternalError(http.StatusUnauthorized, "KOReader credentials are not valid")
+
+	ErrGoalNotFound     = NewInternalError(http.StatusNotFound, "reading goal not found")
+	ErrGoalInvalidYear  = NewInternalError(http.StatusBadRequest, "year must be between 1900 and 9999")
+	ErrGoalInvalidBooks = NewInternalError(http.StatusBadRequest, "books goal must be a positive number")
+	ErrGoalInvalidPages = NewInternalError(http.StatusBadRequest, "pages goal must be a positive number")
+
+	ErrProfileNotFound     = NewInternalError(http.StatusNotFound, "user profile not found")
+	ErrFollowNotFound      = NewInternalError(http.StatusNotFound, "follow not found")
+	ErrFollowSelf          = NewInternalError(http.StatusBadRequest, "users cannot follow themselves")
+	ErrFollowInvalidStatus = NewInternalError(http.StatusBadRequest, "status must be one of pending or accepted")
+	ErrFeedInvalidCursor   = NewInternalError(http.StatusBadRequest, "feed cursor is invalid")
+
+	ErrInvalidOnBooksMode = NewInternalError(
+		http.StatusBadRequest, "on_books must be one of restrict, detach or reassign",
+	)
+	ErrInvalidReassignTarget = NewInternalError(
+		http.StatusBadRequest, "reassign_to must be set and differ from the removed entity",
+	)
+
+	ErrRequiredFieldMissing = NewInternalError(http.StatusBadRequest, "required field is missing")
+	ErrConstraintViolation  = NewInternalError(http.StatusBadRequest, "field value violates constraint")
+	ErrReferenceNotFound    = NewInternalError(http.StatusBadRequest, "referenced entity not found")
+	ErrEntityReferenced     = NewInternalError(http.StatusConflict, "entity is referenced by other entities")
+	ErrEntityAlreadyExists  = NewInternalError(http.StatusConflict, "entity already exists")
 )
 
+type FieldsErrorDetails struct {
+	Fields []string `json:"fields"`
+}
+
 type InternalError struct {
 	code    int
 	message string
+	details any
 }
 
 func (r *InternalError) Error() string {
@@ -91,6 +219,26 @@ func (r *InternalError) Code() int {
 	return r.code
 }
 
+func (r *InternalError) Details() any {
+	return r.details
+}
+
+func (r *InternalError) WithDetails(details any) *InternalError {
+	return &InternalError{r.code, r.message, details}
+}
+
+func (r *InternalError) WithFields(fields ...string) *InternalError {
+	return r.WithDetails(FieldsErrorDetails{Fields: fields})
+}
+
+func (r *InternalError) Is(target error) bool {
+	t, ok := target.(*InternalError)
+	if !ok {
+		return false
+	}
+	return t.code == r.code && t.message == r.message
+}
+
 func NewInternalError(code int, message string) *InternalError {
-	return &InternalError{code, message}
+	return &InternalError{code: code, message: message}
 }
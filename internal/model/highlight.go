@@ -0,0 +1,46 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type HighlightKind string
+
+const (
+	HighlightKindHighlight HighlightKind = "highlight"
+	HighlightKindQuote     HighlightKind = "quote"
+	HighlightKindNote      HighlightKind = "note"
+	HighlightKindBookmark  HighlightKind = "bookmark"
+)
+
+type HighlightColour string
+
+const (
+	HighlightYellow HighlightColour = "yellow"
+	HighlightGreen  HighlightColour = "green"
+	HighlightBlue   HighlightColour = "blue"
+	HighlightPink   HighlightColour = "pink"
+	HighlightOrange HighlightColour = "orange"
+	HighlightPurple HighlightColour = "purple"
+)
+
+// Highlight is a quote, highlight, note or bookmark of a user. It is private,
+// nobody but the owner reads it.
+type Highlight struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	BookID        *uuid.UUID
+	Kind          HighlightKind
+	Text          string
+	Page          *int32
+	LocationStart *int32
+	LocationEnd   *int32
+	Colour        *HighlightColour
+	Tags          []string
+	ClippedAt     *time.Time
+	SourceTitle   *string
+	SourceAuthor  *string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// ReadingProgress is where the user stopped reading. It belongs to a book, to
+// a KOReader document hash, or to both once the document is linked to a book.
+type ReadingProgress struct {
+	UserID   uuid.UUID
+	BookID   *uuid.UUID
+	Document *string
+	// Fraction is the read part of the book, from 0 to 1.
+	Fraction  float64
+	Page      *int32
+	Position  *string
+	Device    *string
+	DeviceID  *string
+	UpdatedAt time.Time
+}
+
+type KOReaderAccount struct {
+	UserID    uuid.UUID
+	Username  string
+	CreatedAt time.Time
+}
@@ -0,0 +1,22 @@
+package model
+
+const (
+	RatingMin = 0
+	RatingMax = 10
+)
+
+// RatingSummary aggregates the ratings users gave a book. The legacy rating
+// kept in Book.Mark from before users rated books themselves is not counted.
+// Histogram[v] is the number of ratings with value v.
+type RatingSummary struct {
+	Count     int
+	Sum       int
+	Histogram []int32
+}
+
+func (s RatingSummary) Average() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return float64(s.Sum) / float64(s.Count)
+}
@@ -0,0 +1,22 @@
+package model
+
+import (
+	"github.com/google/uuid"
+)
+
+type OnBooksMode string
+
+const (
+	OnBooksRestrict OnBooksMode = "restrict"
+	OnBooksDetach   OnBooksMode = "detach"
+	OnBooksReassign OnBooksMode = "reassign"
+)
+
+type RemovePolicy struct {
+	OnBooks    OnBooksMode
+	ReassignTo uuid.UUID
+}
+
+type AffectedBooksDetails struct {
+	BooksIDs []uuid.UUID `json:"books_ids"`
+}
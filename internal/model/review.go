@@ -0,0 +1,30 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type ReviewStatus string
+
+const (
+	ReviewPending  ReviewStatus = "pending"
+	ReviewApproved ReviewStatus = "approved"
+	ReviewRejected ReviewStatus = "rejected"
+)
+
+type Review struct {
+	ID              uuid.UUID
+	BookID          uuid.UUID
+	UserID          uuid.UUID
+	Body            string
+	Spoiler         bool
+	Status          ReviewStatus
+	HelpfulCount    int
+	RejectionReason *string
+	ModeratedAt     *time.Time
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	// VotedHelpful tells whether the user reading the review marked it helpful.
+	VotedHelpful bool
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type ShelfKind string
+
+const (
+	ShelfWantToRead ShelfKind = "want-to-read"
+	ShelfReading    ShelfKind = "reading"
+	ShelfRead       ShelfKind = "read"
+	ShelfCustom     ShelfKind = "custom"
+)
+
+// BuiltinShelves are the reading states every user has. A book is on at most
+// one of them at a time.
+var BuiltinShelves = []ShelfKind{ShelfWantToRead, ShelfReading, ShelfRead}
+
+type Shelf struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Kind       ShelfKind
+	Name       string
+	BooksCount int
+	CreatedAt  time.Time
+}
+
+type ShelfBook struct {
+	BookID  uuid.UUID
+	AddedAt time.Time
+}
+
+// ShelfShare is a link that shows a shelf to anyone holding the token, until
+// it expires or the owner revokes it.
+type ShelfShare struct {
+	ID           uuid.UUID
+	ShelfID      uuid.UUID
+	Token        string
+	ExpiresAt    *time.Time
+	RevokedAt    *time.Time
+	Views        int
+	LastViewedAt *time.Time
+	CreatedAt    time.Time
+}
+
+func (s ShelfShare) Active(now time.Time) bool {
+	return s.RevokedAt == nil && (s.ExpiresAt == nil || s.ExpiresAt.After(now))
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type FollowStatus string
+
+const (
+	// FollowPending waits for the approval of a user with a private profile.
+	FollowPending  FollowStatus = "pending"
+	FollowAccepted FollowStatus = "accepted"
+)
+
+type ActivityKind string
+
+const (
+	ActivityShelved  ActivityKind = "shelved"
+	ActivityFinished ActivityKind = "finished"
+	ActivityRated    ActivityKind = "rated"
+	ActivityReviewed ActivityKind = "reviewed"
+)
+
+// Profile is what other users see of the user. Only accepted follows count.
+type Profile struct {
+	UserID    uuid.UUID
+	Public    bool
+	Followers int
+	Following int
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	Status     FollowStatus
+	CreatedAt  time.Time
+	AcceptedAt *time.Time
+}
+
+// ActivityEvent is recorded by the database as the user shelves, finishes,
+// rates or reviews a book. The shelf is set for shelved and finished events
+// that come from a shelf, the rating and the review for their own kinds.
+type ActivityEvent struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Kind      ActivityKind
+	BookID    uuid.UUID
+	ShelfID   *uuid.UUID
+	ShelfKind *ShelfKind
+	ShelfName *string
+	Rating    *int16
+	ReviewID  *uuid.UUID
+	CreatedAt time.Time
+}
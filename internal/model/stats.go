@@ -0,0 +1,56 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	ReadingGoalYearMin = 1900
+	ReadingGoalYearMax = 9999
+)
+
+// ReadingPeriod sums up the books finished in a year, or in one month of it
+// when Month is set. Pages count only the books with a known length.
+type ReadingPeriod struct {
+	Year  int
+	Month *int
+	Books int
+	Pages int
+}
+
+type TagCount struct {
+	Tag   Tag
+	Books int
+}
+
+type AuthorCount struct {
+	Author Author
+	Books  int
+}
+
+type ReadingStats struct {
+	Months           []ReadingPeriod
+	Years            []ReadingPeriod
+	FavouriteTags    []TagCount
+	FavouriteAuthors []AuthorCount
+	RatingsCount     int
+	// AverageRating is nil until the user rates a book.
+	AverageRating *float64
+	Goal          *ReadingGoalProgress
+}
+
+type ReadingGoal struct {
+	UserID    uuid.UUID
+	Year      int
+	Books     int32
+	Pages     *int32
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type ReadingGoalProgress struct {
+	Goal          ReadingGoal
+	BooksFinished int
+	PagesFinished int
+}
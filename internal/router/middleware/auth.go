@@ -2,7 +2,9 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"github.com/iamvkosarev/book-shelf/internal/handler"
+	"github.com/iamvkosarev/book-shelf/internal/model"
 	"net/http"
 
 	"github.com/google/uuid"
@@ -38,3 +40,63 @@ func RequireAuth(extractor UserIDExtractor) func(http.Handler) http.Handler {
 		)
 	}
 }
+
+// OptionalAuth identifies the user of a request with a valid token and serves
+// the others anonymously.
+func OptionalAuth(extractor UserIDExtractor) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(writer http.ResponseWriter, request *http.Request) {
+				userID, err := extractor.GetVerifiedUserIDFromRequest(request)
+				if err != nil {
+					next.ServeHTTP(writer, request)
+					return
+				}
+
+				ctx := context.WithValue(request.Context(), userIDKey, userID)
+				next.ServeHTTP(writer, request.WithContext(ctx))
+			},
+		)
+	}
+}
+
+type CredentialsVerifier interface {
+	VerifyEmailPassword(ctx context.Context, email, password string) (uuid.UUID, error)
+}
+
+// RequireAuthOrBasic accepts bearer tokens and falls back to HTTP Basic
+// credentials for clients, such as e-reader apps, that cannot send tokens.
+func RequireAuthOrBasic(
+	extractor UserIDExtractor,
+	verifier CredentialsVerifier,
+	realm string,
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(writer http.ResponseWriter, request *http.Request) {
+				var (
+					userID uuid.UUID
+					err    error
+				)
+				if email, password, ok := request.BasicAuth(); ok {
+					userID, err = verifier.VerifyEmailPassword(request.Context(), email, password)
+				} else {
+					userID, err = extractor.GetVerifiedUserIDFromRequest(request)
+				}
+				var internalError *model.InternalError
+				if errors.As(err, &internalError) {
+					writer.Header().Set("WWW-Authenticate", `Basic realm="`+realm+`", charset="UTF-8"`)
+					handler.SendError(writer, model.ErrAuthenticationRequired)
+					return
+				}
+				if err != nil {
+					handler.SendError(writer, err)
+					return
+				}
+
+				ctx := context.WithValue(request.Context(), userIDKey, userID)
+				next.ServeHTTP(writer, request.WithContext(ctx))
+			},
+		)
+	}
+}
@@ -19,8 +19,22 @@ type Deps struct {
 	TagsHandler       *handler.TagHandler
 	BooksHandler      *handler.BookHandler
 	UserHandler       *handler.UserHandler
+	ImportHandler     *handler.ImportHandler
+	ExportHandler     *handler.ExportHandler
+	OPDSHandler       *handler.OPDSHandler
+	OAIHandler        *handler.OAIHandler
+	BackupHandler     *handler.BackupHandler
+	HighlightHandler  *handler.HighlightHandler
+	ShelfHandler      *handler.ShelfHandler
+	RatingHandler     *handler.RatingHandler
+	ReviewHandler     *handler.ReviewHandler
+	ProgressHandler   *handler.ProgressHandler
+	KOReaderHandler   *handler.KOReaderHandler
+	StatsHandler      *handler.StatsHandler
+	SocialHandler     *handler.SocialHandler
 	UserIDExtractor   middleware.UserIDExtractor
 	UserRoleChecker   middleware.RoleChecker
+	UserCredentials   middleware.CredentialsVerifier
 
 	UserUsecase interface {
 		CheckUserAnyRole(ctx context.Context, userID uuid.UUID, needRoleList []model.Role) error
@@ -42,6 +56,23 @@ func Setup(rt *mux.Router, cfg config.Router, deps Deps) (http.Handler, error) {
 	rt.HandleFunc("/user/register/email", deps.UserHandler.RegisterUserByEmail).Methods(http.MethodPost)
 	rt.HandleFunc("/user/token/email", deps.UserHandler.GetUserTokenByEmail).Methods(http.MethodPost)
 
+	rt.HandleFunc(handler.OAIPath, deps.OAIHandler.Handle).Methods(http.MethodGet, http.MethodPost)
+
+	// Anyone holding the token sees the shared shelf.
+	rt.Handle(
+		handler.SharedShelfPath+"{token}",
+		middleware.OptionalAuth(deps.UserIDExtractor)(http.HandlerFunc(deps.ShelfHandler.GetSharedShelf)),
+	).Methods(http.MethodGet)
+
+	rt.HandleFunc("/books/{id}/reviews", deps.ReviewHandler.ListBookReviews).Methods(http.MethodGet)
+
+	// KOReader authenticates every request with its own headers.
+	koreader := rt.PathPrefix(handler.KOReaderRoot).Subrouter()
+	koreader.HandleFunc("/users/create", deps.KOReaderHandler.CreateUser).Methods(http.MethodPost)
+	koreader.HandleFunc("/users/auth", deps.KOReaderHandler.Authorize).Methods(http.MethodGet)
+	koreader.HandleFunc("/syncs/progress", deps.KOReaderHandler.UpdateProgress).Methods(http.MethodPut)
+	koreader.HandleFunc("/syncs/progress/{document}", deps.KOReaderHandler.GetProgress).Methods(http.MethodGet)
+
 	private := rt.NewRoute().Subrouter()
 	private.Use(middleware.RequireAuth(deps.UserIDExtractor))
 	private.Use(middleware.RequireAnyRole(deps.UserRoleChecker, []model.Role{model.RoleAdmin}))
@@ -56,7 +87,13 @@ func Setup(rt *mux.Router, cfg config.Router, deps Deps) (http.Handler, error) {
 	private.HandleFunc("/tags/{id}", deps.TagsHandler.GetTag).Methods(http.MethodGet)
 
 	private.HandleFunc("/books", deps.BooksHandler.ListBooks).Methods(http.MethodGet)
+	private.HandleFunc("/books/duplicates", deps.BooksHandler.ListDuplicates).Methods(http.MethodGet)
+	private.HandleFunc("/books/cite", deps.ExportHandler.CiteBooks).Methods(http.MethodGet)
 	private.HandleFunc("/books/{id}", deps.BooksHandler.GetBook).Methods(http.MethodGet)
+	private.HandleFunc("/books/{id}/marc", deps.ExportHandler.BookMARC).Methods(http.MethodGet)
+	private.HandleFunc("/books/{id}/marcxml", deps.ExportHandler.BookMARCXML).Methods(http.MethodGet)
+	private.HandleFunc("/books/{id}/cite", deps.ExportHandler.CiteBook).Methods(http.MethodGet)
+	private.HandleFunc("/export/onix", deps.ExportHandler.ONIXFeed).Methods(http.MethodGet)
 
 	private.HandleFunc("/publishers", deps.PublishersHandler.AddPublisher).Methods(http.MethodPost)
 	private.HandleFunc("/publishers/{id}", deps.PublishersHandler.UpdatePublisher).Methods(http.MethodPut)
@@ -64,6 +101,7 @@ func Setup(rt *mux.Router, cfg config.Router, deps Deps) (http.Handler, error) {
 
 	private.HandleFunc("/authors", deps.AuthorsHandler.AddAuthor).Methods(http.MethodPost)
 	private.HandleFunc("/authors/{id}", deps.AuthorsHandler.UpdateAuthor).Methods(http.MethodPut)
+	private.HandleFunc("/authors/{id}", deps.AuthorsHandler.PatchAuthor).Methods(http.MethodPatch)
 	private.HandleFunc("/authors/{id}", deps.AuthorsHandler.RemoveAuthor).Methods(http.MethodDelete)
 
 	private.HandleFunc("/tags", deps.TagsHandler.AddTag).Methods(http.MethodPost)
@@ -71,9 +109,112 @@ func Setup(rt *mux.Router, cfg config.Router, deps Deps) (http.Handler, error) {
 	private.HandleFunc("/tags/{id}", deps.TagsHandler.RemoveTag).Methods(http.MethodDelete)
 
 	private.HandleFunc("/books", deps.BooksHandler.AddBook).Methods(http.MethodPost)
+	private.HandleFunc("/books/batch", deps.BooksHandler.BatchBooks).Methods(http.MethodPost)
+	private.HandleFunc("/books/from-epub", deps.ImportHandler.BookFromEPUB).Methods(http.MethodPost)
 	private.HandleFunc("/books/{id}", deps.BooksHandler.UpdateBook).Methods(http.MethodPut)
+	private.HandleFunc("/books/{id}", deps.BooksHandler.PatchBook).Methods(http.MethodPatch)
 	private.HandleFunc("/books/{id}", deps.BooksHandler.RemoveBook).Methods(http.MethodDelete)
 
+	private.HandleFunc("/import/books", deps.ImportHandler.ImportBooksCSV).Methods(http.MethodPost)
+	private.HandleFunc("/import/goodreads", deps.ImportHandler.ImportGoodreadsCSV).Methods(http.MethodPost)
+	private.HandleFunc("/import/marc", deps.ImportHandler.ImportMARC).Methods(http.MethodPost)
+	private.HandleFunc("/import/onix", deps.ImportHandler.ImportONIX).Methods(http.MethodPost)
+	private.HandleFunc("/import/calibre", deps.ImportHandler.ImportCalibre).Methods(http.MethodPost)
+
+	private.HandleFunc("/admin/backup", deps.BackupHandler.Backup).Methods(http.MethodGet)
+	private.HandleFunc("/admin/restore", deps.BackupHandler.Restore).Methods(http.MethodPost)
+
+	private.HandleFunc("/admin/reviews", deps.ReviewHandler.ListModerationQueue).Methods(http.MethodGet)
+	private.HandleFunc("/admin/reviews/{id}/approve", deps.ReviewHandler.ApproveReview).Methods(http.MethodPost)
+	private.HandleFunc("/admin/reviews/{id}/reject", deps.ReviewHandler.RejectReview).Methods(http.MethodPost)
+
+	personal := rt.NewRoute().Subrouter()
+	personal.Use(middleware.RequireAuth(deps.UserIDExtractor))
+	personal.Use(middleware.RequireAnyRole(deps.UserRoleChecker, []model.Role{model.RoleUser, model.RoleAdmin}))
+
+	personal.HandleFunc("/me/highlights/kindle", deps.HighlightHandler.ImportKindleClippings).Methods(http.MethodPost)
+	personal.HandleFunc(
+		"/me/highlights/kindle/unmatched", deps.HighlightHandler.ListUnmatchedSources,
+	).Methods(http.MethodGet)
+	personal.HandleFunc("/me/highlights/kindle/resolve", deps.HighlightHandler.ResolveSource).Methods(http.MethodPost)
+	personal.HandleFunc("/me/highlights", deps.HighlightHandler.ListHighlights).Methods(http.MethodGet)
+	personal.HandleFunc("/books/{id}/highlights", deps.HighlightHandler.ListBookHighlights).Methods(http.MethodGet)
+	personal.HandleFunc("/books/{id}/highlights", deps.HighlightHandler.AddHighlight).Methods(http.MethodPost)
+	personal.HandleFunc(
+		"/books/{id}/highlights/export", deps.HighlightHandler.ExportMarkdown,
+	).Methods(http.MethodGet)
+	personal.HandleFunc("/highlights/{id}", deps.HighlightHandler.GetHighlight).Methods(http.MethodGet)
+	personal.HandleFunc("/highlights/{id}", deps.HighlightHandler.UpdateHighlight).Methods(http.MethodPut)
+	personal.HandleFunc("/highlights/{id}", deps.HighlightHandler.RemoveHighlight).Methods(http.MethodDelete)
+
+	personal.HandleFunc("/me/shelves", deps.ShelfHandler.ListShelves).Methods(http.MethodGet)
+	personal.HandleFunc("/me/shelves", deps.ShelfHandler.AddShelf).Methods(http.MethodPost)
+	personal.HandleFunc("/me/shelves/{shelf}", deps.ShelfHandler.RenameShelf).Methods(http.MethodPut)
+	personal.HandleFunc("/me/shelves/{shelf}", deps.ShelfHandler.RemoveShelf).Methods(http.MethodDelete)
+	personal.HandleFunc("/me/shelves/{shelf}/books", deps.ShelfHandler.ListShelfBooks).Methods(http.MethodGet)
+	personal.HandleFunc("/me/shelves/{shelf}/books/{book_id}", deps.ShelfHandler.AddShelfBook).Methods(http.MethodPut)
+	personal.HandleFunc(
+		"/me/shelves/{shelf}/books/{book_id}", deps.ShelfHandler.RemoveShelfBook,
+	).Methods(http.MethodDelete)
+	personal.HandleFunc("/me/shelves/{shelf}/shares", deps.ShelfHandler.ListShares).Methods(http.MethodGet)
+	personal.HandleFunc("/me/shelves/{shelf}/shares", deps.ShelfHandler.CreateShare).Methods(http.MethodPost)
+	personal.HandleFunc("/me/shelves/{shelf}/shares/{id}", deps.ShelfHandler.RevokeShare).Methods(http.MethodDelete)
+
+	personal.HandleFunc("/books/{id}/rating", deps.RatingHandler.SetRating).Methods(http.MethodPut)
+	personal.HandleFunc("/books/{id}/rating", deps.RatingHandler.RemoveRating).Methods(http.MethodDelete)
+
+	personal.HandleFunc("/books/{id}/reviews", deps.ReviewHandler.AddReview).Methods(http.MethodPost)
+	personal.HandleFunc("/reviews/{id}", deps.ReviewHandler.GetReview).Methods(http.MethodGet)
+	personal.HandleFunc("/reviews/{id}", deps.ReviewHandler.UpdateReview).Methods(http.MethodPut)
+	personal.HandleFunc("/reviews/{id}", deps.ReviewHandler.RemoveReview).Methods(http.MethodDelete)
+	personal.HandleFunc("/reviews/{id}/helpful", deps.ReviewHandler.VoteHelpful).Methods(http.MethodPut)
+	personal.HandleFunc("/reviews/{id}/helpful", deps.ReviewHandler.RemoveHelpfulVote).Methods(http.MethodDelete)
+	personal.HandleFunc("/me/reviews", deps.ReviewHandler.ListMyReviews).Methods(http.MethodGet)
+
+	personal.HandleFunc("/me/progress", deps.ProgressHandler.ListProgress).Methods(http.MethodGet)
+	personal.HandleFunc("/me/progress/{book_id}", deps.ProgressHandler.GetBookProgress).Methods(http.MethodGet)
+	personal.HandleFunc("/me/progress/{book_id}", deps.ProgressHandler.SetBookProgress).Methods(http.MethodPut)
+	personal.HandleFunc(
+		"/me/progress/{book_id}", deps.ProgressHandler.RemoveBookProgress,
+	).Methods(http.MethodDelete)
+	personal.HandleFunc("/me/koreader", deps.ProgressHandler.GetKOReaderAccount).Methods(http.MethodGet)
+	personal.HandleFunc("/me/koreader", deps.ProgressHandler.LinkKOReader).Methods(http.MethodPut)
+	personal.HandleFunc("/me/koreader", deps.ProgressHandler.UnlinkKOReader).Methods(http.MethodDelete)
+
+	personal.HandleFunc("/me/stats", deps.StatsHandler.GetStats).Methods(http.MethodGet)
+	personal.HandleFunc("/me/goals", deps.StatsHandler.ListGoals).Methods(http.MethodGet)
+	personal.HandleFunc("/me/goals/{year}", deps.StatsHandler.GetGoal).Methods(http.MethodGet)
+	personal.HandleFunc("/me/goals/{year}", deps.StatsHandler.SetGoal).Methods(http.MethodPut)
+	personal.HandleFunc("/me/goals/{year}", deps.StatsHandler.RemoveGoal).Methods(http.MethodDelete)
+
+	personal.HandleFunc("/me/profile", deps.SocialHandler.GetProfile).Methods(http.MethodGet)
+	personal.HandleFunc("/me/profile", deps.SocialHandler.SetProfile).Methods(http.MethodPut)
+	personal.HandleFunc("/me/feed", deps.SocialHandler.Feed).Methods(http.MethodGet)
+	personal.HandleFunc("/me/following", deps.SocialHandler.ListFollowing).Methods(http.MethodGet)
+	personal.HandleFunc("/me/followers", deps.SocialHandler.ListFollowers).Methods(http.MethodGet)
+	personal.HandleFunc("/me/followers/{id}", deps.SocialHandler.AcceptFollower).Methods(http.MethodPut)
+	personal.HandleFunc("/me/followers/{id}", deps.SocialHandler.RemoveFollower).Methods(http.MethodDelete)
+	personal.HandleFunc("/users/{id}/profile", deps.SocialHandler.GetUserProfile).Methods(http.MethodGet)
+	personal.HandleFunc("/users/{id}/follow", deps.SocialHandler.Follow).Methods(http.MethodPut)
+	personal.HandleFunc("/users/{id}/follow", deps.SocialHandler.Unfollow).Methods(http.MethodDelete)
+
+	for _, root := range []string{handler.OPDSRootV2, handler.OPDSRoot} {
+		opds := rt.PathPrefix(root).Subrouter()
+		opds.Use(middleware.RequireAuthOrBasic(deps.UserIDExtractor, deps.UserCredentials, handler.OPDSRealm))
+		opds.Use(middleware.RequireAnyRole(deps.UserRoleChecker, []model.Role{model.RoleAdmin}))
+
+		opds.HandleFunc("", deps.OPDSHandler.Root).Methods(http.MethodGet)
+		opds.HandleFunc("/search.xml", deps.OPDSHandler.SearchDescription).Methods(http.MethodGet)
+		opds.HandleFunc("/new", deps.OPDSHandler.NewBooks).Methods(http.MethodGet)
+		opds.HandleFunc("/books", deps.OPDSHandler.Books).Methods(http.MethodGet)
+		opds.HandleFunc("/authors", deps.OPDSHandler.Authors).Methods(http.MethodGet)
+		opds.HandleFunc("/authors/{id}", deps.OPDSHandler.AuthorBooks).Methods(http.MethodGet)
+		opds.HandleFunc("/tags", deps.OPDSHandler.Tags).Methods(http.MethodGet)
+		opds.HandleFunc("/tags/{id}", deps.OPDSHandler.TagBooks).Methods(http.MethodGet)
+		opds.HandleFunc("/publishers", deps.OPDSHandler.Publishers).Methods(http.MethodGet)
+		opds.HandleFunc("/publishers/{id}", deps.OPDSHandler.PublisherBooks).Methods(http.MethodGet)
+	}
+
 	return rt, nil
 }
 
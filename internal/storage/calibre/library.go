@@ -0,0 +1,145 @@
+package calibre
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/imports"
+	_ "modernc.org/sqlite"
+	"net/url"
+	"time"
+)
+
+const (
+	driverName = "sqlite"
+	dateLayout = "2006-01-02"
+
+	// Calibre stores an unknown publication date as 0101-01-01.
+	undefinedYear = 101
+)
+
+type Opener struct{}
+
+func NewOpener() Opener {
+	return Opener{}
+}
+
+func (Opener) OpenCalibreLibrary(path string) (imports.CalibreLibrary, error) {
+	dsn := (&url.URL{Scheme: "file", Opaque: path, RawQuery: "mode=ro"}).String()
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &Library{db: db}, nil
+}
+
+type Library struct {
+	db *sql.DB
+}
+
+func (l *Library) Close() error {
+	return l.db.Close()
+}
+
+func (l *Library) LibraryID(ctx context.Context) (string, error) {
+	var id string
+	if err := l.db.QueryRowContext(ctx, "SELECT uuid FROM library_id LIMIT 1").Scan(&id); err != nil {
+		return "", fmt.Errorf("%w: %s", model.ErrImportCalibreInvalid, err)
+	}
+	return id, nil
+}
+
+func (l *Library) ListBooks(ctx context.Context) ([]imports.CalibreBook, error) {
+	rows, err := l.db.QueryContext(
+		ctx, `SELECT b.id, b.title, COALESCE(strftime('%Y-%m-%d', b.pubdate), ''), COALESCE(b.isbn, ''),
+			COALESCE(c.text, '')
+		FROM books b
+		LEFT JOIN comments c ON c.book = b.id
+		ORDER BY b.id`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", model.ErrImportCalibreInvalid, err)
+	}
+	defer rows.Close()
+
+	var (
+		books   []imports.CalibreBook
+		indexes = make(map[int64]int)
+	)
+	for rows.Next() {
+		var (
+			book      imports.CalibreBook
+			published string
+		)
+		if err = rows.Scan(
+			&book.ID, &book.Title, &published, &book.ISBN, &book.Comments,
+		); err != nil {
+			return nil, err
+		}
+		if publishedAt, err := time.Parse(dateLayout, published); err == nil && publishedAt.Year() > undefinedYear {
+			book.PublishedAt = &publishedAt
+		}
+		book.Identifiers = make(map[string]string)
+		indexes[book.ID] = len(books)
+		books = append(books, book)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	links := []struct {
+		query string
+		apply func(book *imports.CalibreBook, value, extra string)
+	}{
+		{
+			query: `SELECT l.book, a.name, '' FROM books_authors_link l JOIN authors a ON a.id = l.author ORDER BY l.id`,
+			apply: func(book *imports.CalibreBook, value, _ string) { book.Authors = append(book.Authors, value) },
+		},
+		{
+			query: `SELECT l.book, t.name, '' FROM books_tags_link l JOIN tags t ON t.id = l.tag ORDER BY l.id`,
+			apply: func(book *imports.CalibreBook, value, _ string) { book.Tags = append(book.Tags, value) },
+		},
+		{
+			query: `SELECT l.book, p.name, '' FROM books_publishers_link l JOIN publishers p ON p.id = l.publisher`,
+			apply: func(book *imports.CalibreBook, value, _ string) { book.Publisher = value },
+		},
+		{
+			query: `SELECT l.book, s.name, '' FROM books_series_link l JOIN series s ON s.id = l.series`,
+			apply: func(book *imports.CalibreBook, value, _ string) { book.Series = value },
+		},
+		{
+			query: `SELECT book, type, val FROM identifiers`,
+			apply: func(book *imports.CalibreBook, value, extra string) { book.Identifiers[value] = extra },
+		},
+	}
+	for _, link := range links {
+		if err = l.queryLinks(ctx, link.query, func(bookID int64, value, extra string) {
+			if index, ok := indexes[bookID]; ok {
+				link.apply(&books[index], value, extra)
+			}
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return books, nil
+}
+
+func (l *Library) queryLinks(ctx context.Context, query string, apply func(bookID int64, value, extra string)) error {
+	rows, err := l.db.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("%w: %s", model.ErrImportCalibreInvalid, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			bookID       int64
+			value, extra string
+		)
+		if err = rows.Scan(&bookID, &value, &extra); err != nil {
+			return err
+		}
+		apply(bookID, value, extra)
+	}
+	return rows.Err()
+}
@@ -8,7 +8,6 @@ import (
 	"github.com/iamvkosarev/book-shelf/internal/model"
 	"github.com/iamvkosarev/book-shelf/internal/usecase"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -60,16 +59,7 @@ func (p *AuthorsStorage) AddAuthor(ctx context.Context, input usecase.AddAuthorI
 
 	var id uuid.UUID
 	if err = p.pool.QueryRow(ctx, sql, args...).Scan(&id); err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			switch pgErr.Code {
-			case "23505":
-				return uuid.Nil, model.ErrAuthorAlreadyExists
-			case "23514":
-				return uuid.Nil, model.ErrAuthorInvalidFields
-			}
-		}
-		return uuid.Nil, err
+		return uuid.Nil, translateError(err)
 	}
 
 	return id, nil
@@ -128,6 +118,22 @@ func (p *AuthorsStorage) UpdateAuthor(ctx context.Context, id uuid.UUID, input u
 		q = q.Set(columnMiddleName, strPrtToAny(input.MiddleName))
 		sets++
 	}
+	if input.Clear.FirstName {
+		q = q.Set(columnFirstName, nil)
+		sets++
+	}
+	if input.Clear.LastName {
+		q = q.Set(columnLastName, nil)
+		sets++
+	}
+	if input.Clear.Pseudonym {
+		q = q.Set(columnPseudonym, nil)
+		sets++
+	}
+	if input.Clear.MiddleName {
+		q = q.Set(columnMiddleName, nil)
+		sets++
+	}
 
 	if sets == 0 {
 		return nil
@@ -140,16 +146,7 @@ func (p *AuthorsStorage) UpdateAuthor(ctx context.Context, id uuid.UUID, input u
 
 	tag, err := p.pool.Exec(ctx, sql, args...)
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			switch pgErr.Code {
-			case "23505":
-				return model.ErrAuthorAlreadyExists
-			case "23514":
-				return model.ErrAuthorInvalidFields
-			}
-		}
-		return err
+		return translateError(err)
 	}
 
 	if tag.RowsAffected() == 0 {
@@ -159,31 +156,42 @@ func (p *AuthorsStorage) UpdateAuthor(ctx context.Context, id uuid.UUID, input u
 	return nil
 }
 
-func (p *AuthorsStorage) RemoveAuthor(ctx context.Context, id uuid.UUID) error {
-	sql, args, err := p.psql.
-		Delete(tableAuthors).
-		Where(squirrel.Eq{columnID: id}).
-		ToSql()
+func (p *AuthorsStorage) RemoveAuthor(ctx context.Context, id uuid.UUID, policy model.RemovePolicy) error {
+	tx, err := p.pool.Begin(ctx)
 	if err != nil {
 		return err
 	}
+	defer func() { _ = tx.Rollback(ctx) }()
 
-	tag, err := p.pool.Exec(ctx, sql, args...)
-	if err != nil {
+	if err = removeWithLinkTablePolicy(
+		ctx, tx, tableAuthors, tableBooksAuthors, columnAuthorID, id, policy,
+		model.ErrAuthorNotFound, model.ErrAuthorHasBooks,
+	); err != nil {
 		return err
 	}
-	if tag.RowsAffected() == 0 {
-		return model.ErrAuthorNotFound
-	}
 
-	return nil
+	return tx.Commit(ctx)
 }
 
 func (p *AuthorsStorage) ListAuthors(ctx context.Context) ([]model.Author, error) {
-	sql, args, err := p.psql.
+	return p.queryAuthors(ctx, p.selectAuthors())
+}
+
+func (p *AuthorsStorage) ListAuthorsByIDs(ctx context.Context, ids []uuid.UUID) ([]model.Author, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return p.queryAuthors(ctx, p.selectAuthors().Where(squirrel.Eq{columnID: ids}))
+}
+
+func (p *AuthorsStorage) selectAuthors() squirrel.SelectBuilder {
+	return p.psql.
 		Select(columnID, columnFirstName, columnLastName, columnMiddleName, columnPseudonym).
-		From(tableAuthors).
-		ToSql()
+		From(tableAuthors)
+}
+
+func (p *AuthorsStorage) queryAuthors(ctx context.Context, q squirrel.SelectBuilder) ([]model.Author, error) {
+	sql, args, err := q.ToSql()
 	if err != nil {
 		return nil, err
 	}
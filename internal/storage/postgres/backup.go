@@ -0,0 +1,713 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/backup"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/books"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	tableSchemaMigrations = "schema_migrations"
+	tableRoles            = "roles"
+	tableGrantedRoles     = "granted_roles"
+
+	columnVersion = "version"
+	columnRoleID  = "role_id"
+
+	pgCodeUndefinedTable = "42P01"
+)
+
+type BackupStorage struct {
+	pool *pgxpool.Pool
+	psql squirrel.StatementBuilderType
+}
+
+func NewBackupStorage(pool *pgxpool.Pool) *BackupStorage {
+	return &BackupStorage{
+		pool: pool,
+		psql: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+func (p *BackupStorage) SchemaVersion(ctx context.Context) (uint, error) {
+	sql, args, err := p.psql.Select(columnVersion).From(tableSchemaMigrations).Limit(1).ToSql()
+	if err != nil {
+		return 0, err
+	}
+	var version int64
+	if err = p.pool.QueryRow(ctx, sql, args...).Scan(&version); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.Is(err, pgx.ErrNoRows) || errors.As(err, &pgErr) && pgErr.Code == pgCodeUndefinedTable {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return uint(version), nil
+}
+
+func (p *BackupStorage) Snapshot(ctx context.Context, includeUsers bool) (backup.Snapshot, error) {
+	tx, err := p.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return backup.Snapshot{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var snapshot backup.Snapshot
+	if snapshot.Publishers, err = p.snapshotPublishers(ctx, tx); err != nil {
+		return backup.Snapshot{}, err
+	}
+	if snapshot.Authors, err = p.snapshotAuthors(ctx, tx); err != nil {
+		return backup.Snapshot{}, err
+	}
+	if snapshot.Tags, err = p.snapshotTags(ctx, tx); err != nil {
+		return backup.Snapshot{}, err
+	}
+	if snapshot.Books, err = p.snapshotBooks(ctx, tx); err != nil {
+		return backup.Snapshot{}, err
+	}
+	if includeUsers {
+		if snapshot.Users, err = p.snapshotUsers(ctx, tx); err != nil {
+			return backup.Snapshot{}, err
+		}
+		if snapshot.Ratings, err = p.snapshotRatings(ctx, tx); err != nil {
+			return backup.Snapshot{}, err
+		}
+	}
+	return snapshot, nil
+}
+
+func (p *BackupStorage) snapshotPublishers(ctx context.Context, tx pgx.Tx) ([]backup.Publisher, error) {
+	sql, args, err := p.psql.Select(columnID, columnName).From(tablePublishers).OrderBy(columnID).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	publishers := make([]backup.Publisher, 0)
+	for rows.Next() {
+		var publisher backup.Publisher
+		if err = rows.Scan(&publisher.ID, &publisher.Name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		publishers = append(publishers, publisher)
+	}
+	return publishers, rows.Err()
+}
+
+func (p *BackupStorage) snapshotAuthors(ctx context.Context, tx pgx.Tx) ([]backup.Author, error) {
+	sql, args, err := p.psql.Select(columnID, columnFirstName, columnMiddleName, columnLastName, columnPseudonym).
+		From(tableAuthors).
+		OrderBy(columnID).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	authors := make([]backup.Author, 0)
+	for rows.Next() {
+		var author backup.Author
+		if err = rows.Scan(
+			&author.ID, &author.FirstName, &author.MiddleName, &author.LastName, &author.Pseudonym,
+		); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		authors = append(authors, author)
+	}
+	return authors, rows.Err()
+}
+
+func (p *BackupStorage) snapshotTags(ctx context.Context, tx pgx.Tx) ([]backup.Tag, error) {
+	sql, args, err := p.psql.Select(columnID, columnName).From(tableTags).OrderBy(columnID).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]backup.Tag, 0)
+	for rows.Next() {
+		var tag backup.Tag
+		if err = rows.Scan(&tag.ID, &tag.Name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+func (p *BackupStorage) snapshotBooks(ctx context.Context, tx pgx.Tx) ([]backup.Book, error) {
+	authors := subquery.Select("COALESCE(array_agg(" + columnAuthorID + " ORDER BY " + columnAuthorID + "), '{}')").
+		From(tableBooksAuthors).
+		Where(columnBookID + " = b." + columnID)
+	tags := subquery.Select("COALESCE(array_agg(" + columnTagID + " ORDER BY " + columnTagID + "), '{}')").
+		From(tableBooksTags).
+		Where(columnBookID + " = b." + columnID)
+	sql, args, err := p.psql.Select(
+		"b."+columnID,
+		"b."+columnPublisherID,
+		"to_char(b."+columnPublishedAt+", 'YYYY-MM-DD')",
+		"b."+columnTitle,
+		"b."+columnDescription,
+		"b."+columnPrice,
+		"b."+columnPages,
+		legacyMarkExpr("b"),
+		"b."+columnISBN,
+		"b."+columnCreatedAt,
+		"b."+columnUpdatedAt,
+	).
+		Column(squirrel.Alias(authors, fieldAuthorsIDs)).
+		Column(squirrel.Alias(tags, fieldTagsIDs)).
+		From(tableBooks + " b").
+		OrderBy("b." + columnID).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	books := make([]backup.Book, 0)
+	for rows.Next() {
+		var book backup.Book
+		if err = rows.Scan(
+			&book.ID, &book.PublisherID, &book.PublishedAt, &book.Title, &book.Description, &book.Price,
+			&book.Pages, &book.Mark, &book.ISBN, &book.CreatedAt, &book.UpdatedAt, &book.AuthorsIDs, &book.TagsIDs,
+		); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		books = append(books, book)
+	}
+	return books, rows.Err()
+}
+
+func (p *BackupStorage) snapshotUsers(ctx context.Context, tx pgx.Tx) ([]backup.User, error) {
+	roles := subquery.Select("COALESCE(array_agg(r." + columnName + " ORDER BY r." + columnRoleID + "), '{}')").
+		From(tableGrantedRoles + " gr").
+		Join(tableRoles + " r ON r." + columnRoleID + " = gr." + columnRoleID).
+		Where("gr." + columnUserID + " = u." + columnUserID)
+	sql, args, err := p.psql.Select("u."+columnUserID, "u."+columnCreatedAt, "ep."+columnEmail, "ep."+columnPassHash).
+		Column(squirrel.Alias(roles, "roles")).
+		From(tableUsers + " u").
+		LeftJoin(tableEmailPassed + " ep ON ep." + columnUserID + " = u." + columnUserID).
+		OrderBy("u." + columnUserID).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	users := make([]backup.User, 0)
+	for rows.Next() {
+		var user backup.User
+		if err = rows.Scan(&user.ID, &user.CreatedAt, &user.Email, &user.PassHash, &user.Roles); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// snapshotRatings leaves out the legacy ratings, they are archived as the mark
+// of their book.
+func (p *BackupStorage) snapshotRatings(ctx context.Context, tx pgx.Tx) ([]backup.Rating, error) {
+	sql, args, err := p.psql.Select(columnBookID, columnUserID, columnValue, columnCreatedAt, columnUpdatedAt).
+		From(tableBookRatings).
+		Where(squirrel.NotEq{columnUserID: nil}).
+		OrderBy(columnBookID, columnUserID).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	ratings := make([]backup.Rating, 0)
+	for rows.Next() {
+		var rating backup.Rating
+		if err = rows.Scan(
+			&rating.BookID, &rating.UserID, &rating.Value, &rating.CreatedAt, &rating.UpdatedAt,
+		); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ratings = append(ratings, rating)
+	}
+	return ratings, rows.Err()
+}
+
+func (p *BackupStorage) Restore(
+	ctx context.Context,
+	snapshot backup.Snapshot,
+	strategy backup.ConflictStrategy,
+	callerID uuid.UUID,
+) (backup.Counts, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return backup.Counts{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if strategy == backup.ConflictFail {
+		empty, err := p.isEmpty(ctx, tx, len(snapshot.Users) > 0, callerID)
+		if err != nil {
+			return backup.Counts{}, err
+		}
+		if !empty {
+			return backup.Counts{}, model.ErrBackupTargetNotEmpty
+		}
+	}
+
+	restorer := backupRestorer{
+		psql:     p.psql,
+		tx:       tx,
+		strategy: strategy,
+		callerID: callerID,
+		ids:      make(map[uuid.UUID]uuid.UUID),
+	}
+	if callerID != uuid.Nil {
+		if restorer.callerEmail, err = p.userEmail(ctx, tx, callerID); err != nil {
+			return backup.Counts{}, err
+		}
+	}
+	if err = restorer.restore(ctx, snapshot); err != nil {
+		return backup.Counts{}, translateError(err)
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return backup.Counts{}, translateError(err)
+	}
+	return restorer.counts, nil
+}
+
+// isEmpty does not count the caller among the users.
+func (p *BackupStorage) isEmpty(ctx context.Context, tx pgx.Tx, withUsers bool, callerID uuid.UUID) (bool, error) {
+	queries := []squirrel.SelectBuilder{
+		subquery.Select("1").From(tablePublishers),
+		subquery.Select("1").From(tableAuthors),
+		subquery.Select("1").From(tableTags),
+		subquery.Select("1").From(tableBooks),
+	}
+	if withUsers {
+		queries = append(queries, subquery.Select("1").From(tableUsers).Where(squirrel.NotEq{columnUserID: callerID}))
+	}
+	for _, query := range queries {
+		sql, args, err := p.psql.Select().Column(squirrel.Expr("EXISTS (?)", query)).ToSql()
+		if err != nil {
+			return false, err
+		}
+		var exists bool
+		if err = tx.QueryRow(ctx, sql, args...).Scan(&exists); err != nil {
+			return false, err
+		}
+		if exists {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (p *BackupStorage) userEmail(ctx context.Context, tx pgx.Tx, userID uuid.UUID) (*string, error) {
+	sql, args, err := p.psql.Select(columnEmail).From(tableEmailPassed).Where(squirrel.Eq{columnUserID: userID}).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var email string
+	if err = tx.QueryRow(ctx, sql, args...).Scan(&email); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &email, nil
+}
+
+// backupRestorer inserts a snapshot row by row inside one transaction. With
+// the skip strategy rows that are already present are left as they are, so
+// links are only written for books the restore actually inserted.
+//
+// Publishers and tags are matched by name and books by ISBN too: an archive
+// row whose natural key belongs to another row is taken for that row, and ids
+// maps its archive id to the id in the database.
+type backupRestorer struct {
+	psql        squirrel.StatementBuilderType
+	tx          pgx.Tx
+	strategy    backup.ConflictStrategy
+	callerID    uuid.UUID
+	callerEmail *string
+	ids         map[uuid.UUID]uuid.UUID
+	counts      backup.Counts
+}
+
+func (r *backupRestorer) restore(ctx context.Context, snapshot backup.Snapshot) error {
+	for _, publisher := range snapshot.Publishers {
+		matched, err := r.match(ctx, tablePublishers, columnID, columnName, publisher.Name, publisher.ID)
+		if err != nil {
+			return err
+		}
+		if matched {
+			continue
+		}
+		ok, err := r.upsert(
+			ctx, tablePublishers, columnID,
+			[]string{columnID, columnName},
+			[]any{publisher.ID, publisher.Name},
+		)
+		if err != nil {
+			return err
+		}
+		r.counts.Publishers += count(ok)
+	}
+	for _, author := range snapshot.Authors {
+		ok, err := r.upsert(
+			ctx, tableAuthors, columnID,
+			[]string{columnID, columnFirstName, columnMiddleName, columnLastName, columnPseudonym},
+			[]any{author.ID, author.FirstName, author.MiddleName, author.LastName, author.Pseudonym},
+		)
+		if err != nil {
+			return err
+		}
+		r.counts.Authors += count(ok)
+	}
+	for _, tag := range snapshot.Tags {
+		matched, err := r.match(ctx, tableTags, columnID, columnName, tag.Name, tag.ID)
+		if err != nil {
+			return err
+		}
+		if matched {
+			continue
+		}
+		ok, err := r.upsert(ctx, tableTags, columnID, []string{columnID, columnName}, []any{tag.ID, tag.Name})
+		if err != nil {
+			return err
+		}
+		r.counts.Tags += count(ok)
+	}
+
+	restored := make([]backup.Book, 0, len(snapshot.Books))
+	for _, book := range snapshot.Books {
+		if book.ISBN != nil {
+			if _, err := r.match(ctx, tableBooks, columnID, columnISBN, *book.ISBN, book.ID); err != nil {
+				return err
+			}
+			book.ID = r.resolve(book.ID)
+		}
+		ok, err := r.restoreBook(ctx, book)
+		if err != nil {
+			return err
+		}
+		if ok {
+			restored = append(restored, book)
+		}
+	}
+	r.counts.Books = len(restored)
+	for _, book := range restored {
+		if err := r.restoreBookLinks(ctx, book); err != nil {
+			return err
+		}
+	}
+	// Linking touches updated_at, so the archived value is written back last.
+	for _, book := range restored {
+		if _, err := r.exec(
+			ctx, r.psql.Update(tableBooks).Set(columnUpdatedAt, book.UpdatedAt).Where(squirrel.Eq{columnID: book.ID}),
+		); err != nil {
+			return err
+		}
+	}
+
+	for _, user := range snapshot.Users {
+		if err := r.restoreUser(ctx, user); err != nil {
+			return err
+		}
+	}
+	// The stats of the books follow the ratings through their trigger.
+	for _, rating := range snapshot.Ratings {
+		if err := r.restoreRating(ctx, rating); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *backupRestorer) restoreBook(ctx context.Context, book backup.Book) (bool, error) {
+	var publisherID any
+	if book.PublisherID != nil {
+		publisherID = r.resolve(*book.PublisherID)
+		if r.strategy == backup.ConflictSkip {
+			publisherID = squirrel.Expr(
+				"(SELECT "+columnID+" FROM "+tablePublishers+" WHERE "+columnID+" = ?)", publisherID,
+			)
+		}
+	}
+	var publishedAt any
+	if book.PublishedAt != nil {
+		publishedAt = squirrel.Expr("?::date", *book.PublishedAt)
+	}
+	ok, err := r.upsert(
+		ctx, tableBooks, columnID,
+		[]string{
+			columnID, columnPublisherID, columnPublishedAt, columnTitle, columnNormalizedTitle,
+			columnDescription, columnPrice, columnPages, columnISBN, columnCreatedAt,
+		},
+		[]any{
+			book.ID, publisherID, publishedAt, book.Title, books.NormalizeTitle(book.Title),
+			book.Description, book.Price, book.Pages, book.ISBN, book.CreatedAt,
+		},
+	)
+	if err != nil || !ok {
+		return ok, err
+	}
+	legacy := legacyRatingStatement(r.psql, book.ID, book.Mark, r.strategy == backup.ConflictOverwrite)
+	if legacy != nil {
+		if _, err = r.exec(ctx, legacy); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (r *backupRestorer) restoreBookLinks(ctx context.Context, book backup.Book) error {
+	links := []struct {
+		table  string
+		column string
+		target string
+		ids    []uuid.UUID
+	}{
+		{tableBooksAuthors, columnAuthorID, tableAuthors, book.AuthorsIDs},
+		{tableBooksTags, columnTagID, tableTags, book.TagsIDs},
+	}
+	for _, link := range links {
+		if r.strategy == backup.ConflictOverwrite {
+			if _, err := r.exec(ctx, r.psql.Delete(link.table).Where(squirrel.Eq{columnBookID: book.ID})); err != nil {
+				return err
+			}
+		}
+		for _, id := range link.ids {
+			id = r.resolve(id)
+			insert := r.psql.Insert(link.table).Columns(columnBookID, link.column)
+			if r.strategy == backup.ConflictFail {
+				insert = insert.Values(book.ID, id)
+			} else {
+				insert = insert.Select(
+					subquery.Select().
+						Column("?::uuid", book.ID).
+						Column("?::uuid", id).
+						Where(squirrel.Expr("EXISTS (SELECT 1 FROM "+link.target+" WHERE "+columnID+" = ?)", id)),
+				).Suffix("ON CONFLICT DO NOTHING")
+			}
+			tag, err := r.exec(ctx, insert)
+			if err != nil {
+				return err
+			}
+			// The book is new or its links were just removed, so a link that
+			// is not written has no target.
+			if tag.RowsAffected() == 0 {
+				r.counts.SkippedLinks++
+			}
+		}
+	}
+	return nil
+}
+
+// restoreUser leaves out the caller's own account, the archived one with the
+// caller's email is taken for it. With the skip strategy a user whose email
+// already signs in another account is left out as well.
+func (r *backupRestorer) restoreUser(ctx context.Context, user backup.User) error {
+	if user.ID == r.callerID && r.callerID != uuid.Nil {
+		return nil
+	}
+	if user.Email != nil && r.callerEmail != nil && *user.Email == *r.callerEmail {
+		r.ids[user.ID] = r.callerID
+		return nil
+	}
+	if user.Email != nil && r.strategy == backup.ConflictSkip {
+		matched, err := r.match(ctx, tableEmailPassed, columnUserID, columnEmail, *user.Email, user.ID)
+		if err != nil || matched {
+			return err
+		}
+	}
+	ok, err := r.upsert(
+		ctx, tableUsers, columnUserID,
+		[]string{columnUserID, columnCreatedAt},
+		[]any{user.ID, user.CreatedAt},
+	)
+	if err != nil || !ok {
+		return err
+	}
+	r.counts.Users++
+
+	if user.Email != nil {
+		insert := r.psql.Insert(tableEmailPassed).
+			Columns(columnUserID, columnEmail, columnPassHash).
+			Values(user.ID, *user.Email, user.PassHash)
+		if r.strategy == backup.ConflictOverwrite {
+			if _, err = r.exec(ctx, r.psql.Delete(tableEmailPassed).Where(squirrel.Eq{columnUserID: user.ID})); err != nil {
+				return err
+			}
+			insert = insert.Suffix(
+				"ON CONFLICT (" + columnEmail + ") DO UPDATE SET " +
+					columnUserID + " = EXCLUDED." + columnUserID + ", " +
+					columnPassHash + " = EXCLUDED." + columnPassHash,
+			)
+		}
+		if _, err = r.exec(ctx, insert); err != nil {
+			return err
+		}
+	}
+
+	if r.strategy == backup.ConflictOverwrite {
+		if _, err = r.exec(ctx, r.psql.Delete(tableGrantedRoles).Where(squirrel.Eq{columnUserID: user.ID})); err != nil {
+			return err
+		}
+	}
+	for _, role := range user.Roles {
+		insert := r.psql.Insert(tableGrantedRoles).Columns(columnUserID, columnRoleID).Select(
+			subquery.Select().
+				Column("?::uuid", user.ID).
+				Column(columnRoleID).
+				From(tableRoles).
+				Where(squirrel.Eq{columnName: role}),
+		).Suffix("ON CONFLICT DO NOTHING")
+		if _, err = r.exec(ctx, insert); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreRating writes the rating on the book and user the archive ids were
+// restored as. With the skip strategy the rating a user already gave the book
+// is kept.
+func (r *backupRestorer) restoreRating(ctx context.Context, rating backup.Rating) error {
+	bookID, userID := r.resolve(rating.BookID), r.resolve(rating.UserID)
+	insert := r.psql.Insert(tableBookRatings).
+		Columns(columnBookID, columnUserID, columnValue, columnCreatedAt, columnUpdatedAt)
+	if r.strategy == backup.ConflictFail {
+		insert = insert.Values(bookID, userID, rating.Value, rating.CreatedAt, rating.UpdatedAt)
+	} else {
+		insert = insert.Select(
+			subquery.Select().
+				Column("?::uuid", bookID).
+				Column("?::uuid", userID).
+				Column("?::smallint", rating.Value).
+				Column("?::timestamp", rating.CreatedAt).
+				Column("?::timestamp", rating.UpdatedAt).
+				Where(squirrel.Expr("EXISTS (SELECT 1 FROM "+tableBooks+" WHERE "+columnID+" = ?)", bookID)).
+				Where(squirrel.Expr("EXISTS (SELECT 1 FROM "+tableUsers+" WHERE "+columnUserID+" = ?)", userID)),
+		)
+		conflict := "ON CONFLICT (" + columnBookID + ", " + columnUserID + ") WHERE " + columnUserID + " IS NOT NULL "
+		if r.strategy == backup.ConflictOverwrite {
+			insert = insert.Suffix(
+				conflict + "DO UPDATE SET " +
+					columnValue + " = EXCLUDED." + columnValue + ", " +
+					columnCreatedAt + " = EXCLUDED." + columnCreatedAt + ", " +
+					columnUpdatedAt + " = EXCLUDED." + columnUpdatedAt,
+			)
+		} else {
+			insert = insert.Suffix(conflict + "DO NOTHING")
+		}
+	}
+	tag, err := r.exec(ctx, insert)
+	if err != nil {
+		return err
+	}
+	r.counts.Ratings += int(tag.RowsAffected())
+	return nil
+}
+
+// match looks for another row holding the natural key of an archive row and
+// maps the archive id to it.
+func (r *backupRestorer) match(ctx context.Context, table, key, column, value string, id uuid.UUID) (bool, error) {
+	sql, args, err := r.psql.Select(key).
+		From(table).
+		Where(squirrel.Eq{column: value}).
+		Where(squirrel.NotEq{key: id}).
+		ToSql()
+	if err != nil {
+		return false, err
+	}
+	var existing uuid.UUID
+	if err = r.tx.QueryRow(ctx, sql, args...).Scan(&existing); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	r.ids[id] = existing
+	return true, nil
+}
+
+func (r *backupRestorer) resolve(id uuid.UUID) uuid.UUID {
+	if existing, ok := r.ids[id]; ok {
+		return existing
+	}
+	return id
+}
+
+// upsert inserts one row and reports whether it was written. Conflicts are
+// resolved by the restorer strategy: fail lets the constraint error surface,
+// skip keeps the existing row and overwrite replaces it by key.
+func (r *backupRestorer) upsert(
+	ctx context.Context,
+	table, key string,
+	columns []string,
+	values []any,
+) (bool, error) {
+	insert := r.psql.Insert(table).Columns(columns...).Values(values...)
+	switch r.strategy {
+	case backup.ConflictSkip:
+		insert = insert.Suffix("ON CONFLICT DO NOTHING")
+	case backup.ConflictOverwrite:
+		set := ""
+		for _, column := range columns {
+			if column == key {
+				continue
+			}
+			if set != "" {
+				set += ", "
+			}
+			set += column + " = EXCLUDED." + column
+		}
+		insert = insert.Suffix("ON CONFLICT (" + key + ") DO UPDATE SET " + set)
+	}
+	tag, err := r.exec(ctx, insert)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *backupRestorer) exec(ctx context.Context, statement squirrel.Sqlizer) (pgconn.CommandTag, error) {
+	sql, args, err := statement.ToSql()
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return r.tx.Exec(ctx, sql, args...)
+}
+
+func count(ok bool) int {
+	if ok {
+		return 1
+	}
+	return 0
+}
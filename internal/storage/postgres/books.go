@@ -8,7 +8,6 @@ import (
 	"github.com/iamvkosarev/book-shelf/internal/model"
 	"github.com/iamvkosarev/book-shelf/internal/usecase/books"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"strings"
@@ -24,11 +23,20 @@ const (
 	columnTitle       = "title"
 	columnDescription = "description"
 	columnPrice       = "price"
+	columnPages       = "pages"
 	columnMark        = "mark"
+	columnISBN        = "isbn"
+	columnCreatedAt   = "created_at"
+	columnUpdatedAt   = "updated_at"
 
 	columnBookID   = "book_id"
 	columnAuthorID = "author_id"
 	columnTagID    = "tag_id"
+
+	// columnNormalizedTitle holds books.NormalizeTitle of the title, it is
+	// written next to the title so that the matching never depends on the
+	// database locale.
+	columnNormalizedTitle = "normalized_title"
 )
 
 type BooksStorage struct {
@@ -59,17 +67,21 @@ func (p *BooksStorage) AddBook(ctx context.Context, input books.CreateBookInput)
 			columnPublisherID,
 			columnPublishedAt,
 			columnTitle,
+			columnNormalizedTitle,
 			columnDescription,
 			columnPrice,
-			columnMark,
+			columnPages,
+			columnISBN,
 		).
 		Values(
 			toPostgresUUIDPtr(input.PublisherID),
 			toPostgresDatePtr(input.PublishedAt),
 			input.Title,
+			books.NormalizeTitle(input.Title),
 			toPostgresTextPtr(input.Description),
 			toPostgresFloat8Ptr(input.Price),
-			toPostgresInt2Ptr(input.Mark),
+			input.Pages,
+			toPostgresTextPtr(input.ISBN),
 		).
 		Suffix("RETURNING " + columnID).
 		ToSql()
@@ -79,7 +91,7 @@ func (p *BooksStorage) AddBook(ctx context.Context, input books.CreateBookInput)
 
 	var id uuid.UUID
 	if err = tx.QueryRow(ctx, sql, args...).Scan(&id); err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, translateError(err)
 	}
 
 	if err = p.replaceBookAuthorsTx(ctx, tx, id, input.AuthorsIDs, true); err != nil {
@@ -96,15 +108,7 @@ func (p *BooksStorage) AddBook(ctx context.Context, input books.CreateBookInput)
 }
 
 func (p *BooksStorage) GetBook(ctx context.Context, id uuid.UUID) (model.Book, error) {
-	sql, args, err := p.psql.Select(
-		columnID,
-		columnPublisherID,
-		columnPublishedAt,
-		columnTitle,
-		columnDescription,
-		columnPrice,
-		columnMark,
-	).From(tableBooks).Where(squirrel.Eq{columnID: id}).ToSql()
+	sql, args, err := p.selectBooks().Where(squirrel.Eq{columnID: id}).ToSql()
 	if err != nil {
 		return model.Book{}, err
 	}
@@ -116,6 +120,7 @@ func (p *BooksStorage) GetBook(ctx context.Context, id uuid.UUID) (model.Book, e
 		description pgtype.Text
 		price       pgtype.Float8
 		mark        pgtype.Int2
+		isbn        pgtype.Text
 	)
 
 	if err = p.pool.QueryRow(ctx, sql, args...).Scan(
@@ -125,7 +130,14 @@ func (p *BooksStorage) GetBook(ctx context.Context, id uuid.UUID) (model.Book, e
 		&book.Title,
 		&description,
 		&price,
+		&book.Pages,
 		&mark,
+		&isbn,
+		&book.CreatedAt,
+		&book.UpdatedAt,
+		&book.Rating.Count,
+		&book.Rating.Sum,
+		&book.Rating.Histogram,
 	); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return model.Book{}, model.ErrBookNotFound
@@ -168,6 +180,8 @@ func (p *BooksStorage) GetBook(ctx context.Context, id uuid.UUID) (model.Book, e
 		book.Mark = nil
 	}
 
+	book.ISBN = postgresTextToStrPtr(isbn)
+
 	authorsIDs, err := p.getBookAuthorIDs(ctx, id)
 	if err != nil {
 		return model.Book{}, err
@@ -189,9 +203,11 @@ func (p *BooksStorage) UpdateBook(ctx context.Context, id uuid.UUID, patch books
 		patch.Title == nil &&
 		patch.Description == nil &&
 		patch.Price == nil &&
-		patch.Mark == nil &&
+		patch.Pages == nil &&
+		patch.ISBN == nil &&
 		patch.AuthorsIDs == nil &&
-		patch.TagsIDs == nil {
+		patch.TagsIDs == nil &&
+		!patch.Clear.Any() {
 		return model.ErrBookInvalidFields
 	}
 
@@ -201,36 +217,9 @@ func (p *BooksStorage) UpdateBook(ctx context.Context, id uuid.UUID, patch books
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
-	upd := p.psql.Update(tableBooks).Where(squirrel.Eq{columnID: id})
-
-	changed := false
-
-	if patch.PublisherID != nil {
-		upd = upd.Set(columnPublisherID, toPostgresUUIDPtr(patch.PublisherID))
-		changed = true
-	}
-	if patch.PublishedAt != nil {
-		upd = upd.Set(columnPublishedAt, toPostgresDatePtr(patch.PublishedAt))
-		changed = true
-	}
-	if patch.Title != nil {
-		if strings.TrimSpace(*patch.Title) == "" {
-			return model.ErrBookInvalidFields
-		}
-		upd = upd.Set(columnTitle, *patch.Title)
-		changed = true
-	}
-	if patch.Description != nil {
-		upd = upd.Set(columnDescription, toPostgresTextPtr(patch.Description))
-		changed = true
-	}
-	if patch.Price != nil {
-		upd = upd.Set(columnPrice, toPostgresFloat8Ptr(patch.Price))
-		changed = true
-	}
-	if patch.Mark != nil {
-		upd = upd.Set(columnMark, toPostgresInt2Ptr(patch.Mark))
-		changed = true
+	upd, changed, err := p.buildBookUpdate(id, patch)
+	if err != nil {
+		return err
 	}
 
 	if changed {
@@ -241,11 +230,7 @@ func (p *BooksStorage) UpdateBook(ctx context.Context, id uuid.UUID, patch books
 
 		commandTag, err := tx.Exec(ctx, sql, args...)
 		if err != nil {
-			var pgErr *pgconn.PgError
-			if errors.As(err, &pgErr) && pgErr.Code == "23505" {
-				return model.ErrBookAlreadyExists
-			}
-			return err
+			return translateError(err)
 		}
 		if commandTag.RowsAffected() == 0 {
 			return model.ErrBookNotFound
@@ -273,6 +258,74 @@ func (p *BooksStorage) UpdateBook(ctx context.Context, id uuid.UUID, patch books
 	return nil
 }
 
+func (p *BooksStorage) buildBookUpdate(
+	id uuid.UUID,
+	patch books.UpdateBookPatch,
+) (squirrel.UpdateBuilder, bool, error) {
+	upd := p.psql.Update(tableBooks).Where(squirrel.Eq{columnID: id})
+
+	changed := false
+
+	if patch.PublisherID != nil {
+		upd = upd.Set(columnPublisherID, toPostgresUUIDPtr(patch.PublisherID))
+		changed = true
+	}
+	if patch.PublishedAt != nil {
+		upd = upd.Set(columnPublishedAt, toPostgresDatePtr(patch.PublishedAt))
+		changed = true
+	}
+	if patch.Title != nil {
+		if strings.TrimSpace(*patch.Title) == "" {
+			return squirrel.UpdateBuilder{}, false, model.ErrBookInvalidFields
+		}
+		upd = upd.Set(columnTitle, *patch.Title).Set(columnNormalizedTitle, books.NormalizeTitle(*patch.Title))
+		changed = true
+	}
+	if patch.Description != nil {
+		upd = upd.Set(columnDescription, toPostgresTextPtr(patch.Description))
+		changed = true
+	}
+	if patch.Price != nil {
+		upd = upd.Set(columnPrice, toPostgresFloat8Ptr(patch.Price))
+		changed = true
+	}
+	if patch.Pages != nil {
+		upd = upd.Set(columnPages, *patch.Pages)
+		changed = true
+	}
+	if patch.ISBN != nil {
+		upd = upd.Set(columnISBN, toPostgresTextPtr(patch.ISBN))
+		changed = true
+	}
+
+	if patch.Clear.PublisherID {
+		upd = upd.Set(columnPublisherID, nil)
+		changed = true
+	}
+	if patch.Clear.PublishedAt {
+		upd = upd.Set(columnPublishedAt, nil)
+		changed = true
+	}
+	if patch.Clear.Description {
+		upd = upd.Set(columnDescription, nil)
+		changed = true
+	}
+	if patch.Clear.Price {
+		upd = upd.Set(columnPrice, nil)
+		changed = true
+	}
+	if patch.Clear.Pages {
+		upd = upd.Set(columnPages, nil)
+		changed = true
+	}
+	if patch.Clear.ISBN {
+		upd = upd.Set(columnISBN, nil)
+		changed = true
+	}
+
+	return upd, changed, nil
+}
+
 func (p *BooksStorage) RemoveBook(ctx context.Context, id uuid.UUID) error {
 	tx, err := p.pool.Begin(ctx)
 	if err != nil {
@@ -302,18 +355,13 @@ func (p *BooksStorage) RemoveBook(ctx context.Context, id uuid.UUID) error {
 }
 
 func (p *BooksStorage) ListBooks(ctx context.Context, parameters books.ListBookParameters) ([]model.Book, error) {
-	q := p.psql.Select(
-		columnID,
-		columnPublisherID,
-		columnPublishedAt,
-		columnTitle,
-		columnDescription,
-		columnPrice,
-		columnMark,
-	).From(tableBooks)
+	q := p.selectBooks()
 
+	if parameters.IDs != nil {
+		q = q.Where(squirrel.Eq{columnID: parameters.IDs})
+	}
 	if parameters.AuthorsIDs != nil && len(parameters.AuthorsIDs) > 0 {
-		sub := p.psql.Select(columnBookID).
+		sub := subquery.Select(columnBookID).
 			From(tableBooksAuthors).
 			Where(squirrel.Eq{columnAuthorID: parameters.AuthorsIDs}).
 			GroupBy(columnBookID).
@@ -321,7 +369,145 @@ func (p *BooksStorage) ListBooks(ctx context.Context, parameters books.ListBookP
 
 		q = q.Where(squirrel.Expr(columnID+" IN (?)", sub))
 	}
+	if len(parameters.TagsIDs) > 0 {
+		sub := subquery.Select(columnBookID).
+			From(tableBooksTags).
+			Where(squirrel.Eq{columnTagID: parameters.TagsIDs}).
+			GroupBy(columnBookID).
+			Having("COUNT(DISTINCT "+columnTagID+") = ?", len(parameters.TagsIDs))
+
+		q = q.Where(squirrel.Expr(columnID+" IN (?)", sub))
+	}
+	if parameters.PublisherID != nil {
+		q = q.Where(squirrel.Eq{columnPublisherID: *parameters.PublisherID})
+	}
+	if len(parameters.ISBNs) > 0 {
+		q = q.Where(squirrel.Expr(columnISBN+" = ANY(?)", parameters.ISBNs))
+	}
+	if len(parameters.Titles) > 0 {
+		q = q.Where(squirrel.Expr(columnNormalizedTitle+" = ANY(?)", parameters.Titles))
+	}
+	if query := strings.TrimSpace(parameters.Query); query != "" {
+		pattern := "%" + escapeLike(query) + "%"
+		authors := subquery.Select(columnBookID).
+			From(tableBooksAuthors).
+			Join(tableAuthors + " ON " + tableAuthors + "." + columnID + " = " + tableBooksAuthors + "." + columnAuthorID).
+			Where(
+				squirrel.Expr(
+					"concat_ws(' ', "+columnFirstName+", "+columnMiddleName+", "+columnLastName+", "+
+						columnPseudonym+") ILIKE ?", pattern,
+				),
+			)
+		q = q.Where(
+			squirrel.Or{
+				squirrel.ILike{columnTitle: pattern},
+				squirrel.Eq{columnISBN: query},
+				squirrel.Expr(columnID+" IN (?)", authors),
+			},
+		)
+	}
+
+	switch parameters.Order {
+	case books.BooksOrderTitle:
+		q = q.OrderBy(columnTitle, columnID)
+	case books.BooksOrderNewest:
+		q = q.OrderBy(columnCreatedAt+" DESC", columnID)
+	}
+	if parameters.Limit > 0 {
+		q = q.Limit(parameters.Limit)
+	}
+	if parameters.Offset > 0 {
+		q = q.Offset(parameters.Offset)
+	}
+
+	return p.queryBooks(ctx, q)
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func (p *BooksStorage) ListBooksWithRepeatedTitles(ctx context.Context) ([]model.Book, error) {
+	sub := subquery.Select(columnNormalizedTitle).
+		From(tableBooks).
+		Where(squirrel.NotEq{columnNormalizedTitle: nil}).
+		GroupBy(columnNormalizedTitle).
+		Having("COUNT(*) > 1")
+
+	q := p.selectBooks().
+		Where(squirrel.Expr(columnNormalizedTitle+" IN (?)", sub)).
+		OrderBy(columnNormalizedTitle, columnID)
+	return p.queryBooks(ctx, q)
+}
+
+// FillNormalizedTitles writes the normalized title of the books stored before
+// the column existed and returns how many it filled.
+func (p *BooksStorage) FillNormalizedTitles(ctx context.Context) (int, error) {
+	sql, args, err := p.psql.Select(columnID, columnTitle).
+		From(tableBooks).
+		Where(squirrel.Eq{columnNormalizedTitle: nil}).
+		ToSql()
+	if err != nil {
+		return 0, err
+	}
+	rows, err := p.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
 
+	batch := &pgx.Batch{}
+	for rows.Next() {
+		var (
+			id    uuid.UUID
+			title string
+		)
+		if err = rows.Scan(&id, &title); err != nil {
+			return 0, err
+		}
+		sql, args, err = p.psql.Update(tableBooks).
+			Set(columnNormalizedTitle, books.NormalizeTitle(title)).
+			Where(squirrel.Eq{columnID: id, columnNormalizedTitle: nil}).
+			ToSql()
+		if err != nil {
+			return 0, err
+		}
+		batch.Queue(sql, args...)
+	}
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+	if batch.Len() == 0 {
+		return 0, nil
+	}
+
+	if err = p.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return 0, translateError(err)
+	}
+	return batch.Len(), nil
+}
+
+func (p *BooksStorage) selectBooks() squirrel.SelectBuilder {
+	q := p.psql.Select(
+		tableBooks+"."+columnID,
+		columnPublisherID,
+		columnPublishedAt,
+		columnTitle,
+		columnDescription,
+		columnPrice,
+		columnPages,
+		legacyMarkExpr(tableBooks)+" AS "+columnMark,
+		columnISBN,
+		columnCreatedAt,
+		columnUpdatedAt,
+	).
+		Columns(ratingSummaryColumns...).
+		From(tableBooks)
+	return joinRatingStats(q)
+}
+
+func (p *BooksStorage) queryBooks(ctx context.Context, q squirrel.SelectBuilder) ([]model.Book, error) {
 	sql, args, err := q.ToSql()
 	if err != nil {
 		return nil, err
@@ -344,6 +530,7 @@ func (p *BooksStorage) ListBooks(ctx context.Context, parameters books.ListBookP
 			description pgtype.Text
 			price       pgtype.Float8
 			mark        pgtype.Int2
+			isbn        pgtype.Text
 		)
 
 		if err := rows.Scan(
@@ -353,7 +540,14 @@ func (p *BooksStorage) ListBooks(ctx context.Context, parameters books.ListBookP
 			&book.Title,
 			&description,
 			&price,
+			&book.Pages,
 			&mark,
+			&isbn,
+			&book.CreatedAt,
+			&book.UpdatedAt,
+			&book.Rating.Count,
+			&book.Rating.Sum,
+			&book.Rating.Histogram,
 		); err != nil {
 			return nil, err
 		}
@@ -379,6 +573,7 @@ func (p *BooksStorage) ListBooks(ctx context.Context, parameters books.ListBookP
 			f := mark.Int16
 			book.Mark = &f
 		}
+		book.ISBN = postgresTextToStrPtr(isbn)
 
 		books = append(books, book)
 		ids = append(ids, book.ID)
@@ -491,7 +686,7 @@ func (p *BooksStorage) replaceBookAuthorsTx(
 		return err
 	}
 	if _, err := tx.Exec(ctx, sql, args...); err != nil {
-		return err
+		return translateError(err)
 	}
 	return nil
 }
@@ -522,7 +717,7 @@ func (p *BooksStorage) replaceBookTagsTx(
 		return err
 	}
 	if _, err := tx.Exec(ctx, sql, args...); err != nil {
-		return err
+		return translateError(err)
 	}
 	return nil
 }
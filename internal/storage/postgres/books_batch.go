@@ -0,0 +1,227 @@
+package postgres
+
+import (
+	"context"
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/books"
+	"github.com/jackc/pgx/v5"
+)
+
+type batchStatement struct {
+	index    int
+	sql      string
+	args     []any
+	notFound error
+}
+
+func (p *BooksStorage) ApplyBatch(
+	ctx context.Context,
+	operations []books.BatchOperation,
+	atomic bool,
+) ([]books.BatchItemResult, error) {
+	results := make([]books.BatchItemResult, len(operations))
+	statements := make([][]batchStatement, len(operations))
+	for i, operation := range operations {
+		results[i].ID = operation.ID
+		if operation.Kind == books.BatchCreate {
+			results[i].ID = uuid.New()
+		}
+		statements[i], results[i].Err = p.batchOperationStatements(i, results[i].ID, operation)
+	}
+
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if atomic {
+		var all []batchStatement
+		for i := range statements {
+			if results[i].Err != nil {
+				markRolledBack(results, i)
+				return forgetFailedCreates(operations, results), nil
+			}
+			all = append(all, statements[i]...)
+		}
+		if index, err := execBatchStatements(ctx, tx, all); err != nil {
+			results[index].Err = err
+			markRolledBack(results, index)
+			return forgetFailedCreates(operations, results), nil
+		}
+	} else {
+		for i := range statements {
+			if results[i].Err != nil {
+				continue
+			}
+			savepoint, err := tx.Begin(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if _, err = execBatchStatements(ctx, savepoint, statements[i]); err != nil {
+				results[i].Err = err
+				if rollbackErr := savepoint.Rollback(ctx); rollbackErr != nil {
+					return nil, rollbackErr
+				}
+				continue
+			}
+			if err = savepoint.Commit(ctx); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, translateError(err)
+	}
+	return forgetFailedCreates(operations, results), nil
+}
+
+func (p *BooksStorage) batchOperationStatements(
+	index int,
+	id uuid.UUID,
+	operation books.BatchOperation,
+) ([]batchStatement, error) {
+	var (
+		statements []batchStatement
+		authorsIDs []uuid.UUID
+		tagsIDs    []uuid.UUID
+	)
+	add := func(builder squirrel.Sqlizer, notFound error) error {
+		sql, args, err := builder.ToSql()
+		if err != nil {
+			return err
+		}
+		statements = append(statements, batchStatement{index: index, sql: sql, args: args, notFound: notFound})
+		return nil
+	}
+
+	switch operation.Kind {
+	case books.BatchCreate:
+		input := operation.Create
+		insert := p.psql.Insert(tableBooks).
+			Columns(
+				columnID,
+				columnPublisherID,
+				columnPublishedAt,
+				columnTitle,
+				columnNormalizedTitle,
+				columnDescription,
+				columnPrice,
+				columnPages,
+				columnISBN,
+			).
+			Values(
+				id,
+				toPostgresUUIDPtr(input.PublisherID),
+				toPostgresDatePtr(input.PublishedAt),
+				input.Title,
+				books.NormalizeTitle(input.Title),
+				toPostgresTextPtr(input.Description),
+				toPostgresFloat8Ptr(input.Price),
+				input.Pages,
+				toPostgresTextPtr(input.ISBN),
+			)
+		if err := add(insert, nil); err != nil {
+			return nil, err
+		}
+		authorsIDs, tagsIDs = input.AuthorsIDs, input.TagsIDs
+	case books.BatchUpdate:
+		upd, changed, err := p.buildBookUpdate(id, operation.Update)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			err = add(upd, model.ErrBookNotFound)
+		} else {
+			err = add(p.psql.Select(columnID).From(tableBooks).Where(squirrel.Eq{columnID: id}), model.ErrBookNotFound)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if operation.Update.AuthorsIDs != nil {
+			if err = add(p.psql.Delete(tableBooksAuthors).Where(squirrel.Eq{columnBookID: id}), nil); err != nil {
+				return nil, err
+			}
+		}
+		if operation.Update.TagsIDs != nil {
+			if err = add(p.psql.Delete(tableBooksTags).Where(squirrel.Eq{columnBookID: id}), nil); err != nil {
+				return nil, err
+			}
+		}
+		authorsIDs, tagsIDs = operation.Update.AuthorsIDs, operation.Update.TagsIDs
+	case books.BatchDelete:
+		if err := add(p.psql.Delete(tableBooks).Where(squirrel.Eq{columnID: id}), model.ErrBookNotFound); err != nil {
+			return nil, err
+		}
+		return statements, nil
+	default:
+		return nil, model.ErrBatchUnknownOperation
+	}
+
+	if len(authorsIDs) > 0 {
+		ins := p.psql.Insert(tableBooksAuthors).Columns(columnBookID, columnAuthorID)
+		for _, aID := range authorsIDs {
+			ins = ins.Values(id, aID)
+		}
+		if err := add(ins, nil); err != nil {
+			return nil, err
+		}
+	}
+	if len(tagsIDs) > 0 {
+		ins := p.psql.Insert(tableBooksTags).Columns(columnBookID, columnTagID)
+		for _, tID := range tagsIDs {
+			ins = ins.Values(id, tID)
+		}
+		if err := add(ins, nil); err != nil {
+			return nil, err
+		}
+	}
+	return statements, nil
+}
+
+func execBatchStatements(ctx context.Context, tx pgx.Tx, statements []batchStatement) (int, error) {
+	if len(statements) == 0 {
+		return 0, nil
+	}
+	batch := &pgx.Batch{}
+	for _, statement := range statements {
+		batch.Queue(statement.sql, statement.args...)
+	}
+
+	results := tx.SendBatch(ctx, batch)
+	for _, statement := range statements {
+		commandTag, err := results.Exec()
+		if err != nil {
+			_ = results.Close()
+			return statement.index, translateError(err)
+		}
+		if statement.notFound != nil && commandTag.RowsAffected() == 0 {
+			_ = results.Close()
+			return statement.index, statement.notFound
+		}
+	}
+	if err := results.Close(); err != nil {
+		return statements[len(statements)-1].index, translateError(err)
+	}
+	return 0, nil
+}
+
+func markRolledBack(results []books.BatchItemResult, failedIndex int) {
+	for i := range results {
+		if i != failedIndex && results[i].Err == nil {
+			results[i].Err = model.ErrBatchRolledBack
+		}
+	}
+}
+
+func forgetFailedCreates(operations []books.BatchOperation, results []books.BatchItemResult) []books.BatchItemResult {
+	for i, operation := range operations {
+		if operation.Kind == books.BatchCreate && results[i].Err != nil {
+			results[i].ID = uuid.Nil
+		}
+	}
+	return results
+}
@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	tableCalibreImportedBooks = "calibre_imported_books"
+
+	columnLibraryID   = "library_id"
+	columnCalibreID   = "calibre_id"
+	columnIdentifiers = "identifiers"
+)
+
+type CalibreImportsStorage struct {
+	pool *pgxpool.Pool
+	psql squirrel.StatementBuilderType
+}
+
+func NewCalibreImportsStorage(pool *pgxpool.Pool) *CalibreImportsStorage {
+	return &CalibreImportsStorage{
+		pool: pool,
+		psql: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+func (p *CalibreImportsStorage) ListImportedCalibreBooks(
+	ctx context.Context,
+	libraryID string,
+) (map[int64]uuid.UUID, error) {
+	sql, args, err := p.psql.Select(columnCalibreID, columnBookID).
+		From(tableCalibreImportedBooks).
+		Where(squirrel.Eq{columnLibraryID: libraryID}).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	imported := make(map[int64]uuid.UUID)
+	for rows.Next() {
+		var (
+			calibreID int64
+			bookID    pgtype.UUID
+		)
+		if err = rows.Scan(&calibreID, &bookID); err != nil {
+			return nil, err
+		}
+		imported[calibreID] = uuid.Nil
+		if bookID.Valid {
+			imported[calibreID] = bookID.Bytes
+		}
+	}
+	return imported, rows.Err()
+}
+
+func (p *CalibreImportsStorage) SaveImportedCalibreBook(
+	ctx context.Context,
+	libraryID string,
+	calibreID int64,
+	bookID uuid.UUID,
+	identifiers map[string]string,
+) error {
+	sql, args, err := p.psql.Insert(tableCalibreImportedBooks).
+		Columns(columnLibraryID, columnCalibreID, columnBookID, columnIdentifiers).
+		Values(libraryID, calibreID, bookID, identifiers).
+		Suffix(
+			"ON CONFLICT (" + columnLibraryID + ", " + columnCalibreID + ") DO UPDATE SET " +
+				columnBookID + " = EXCLUDED." + columnBookID + ", " +
+				columnIdentifiers + " = EXCLUDED." + columnIdentifiers,
+		).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err = p.pool.Exec(ctx, sql, args...); err != nil {
+		return translateError(err)
+	}
+	return nil
+}
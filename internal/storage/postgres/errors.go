@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"errors"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	pgCodeNotNullViolation    = "23502"
+	pgCodeForeignKeyViolation = "23503"
+	pgCodeUniqueViolation     = "23505"
+	pgCodeCheckViolation      = "23514"
+
+	fieldAuthorsIDs = "authors_ids"
+	fieldTagsIDs    = "tags_ids"
+	fieldReassignTo = "reassign_to"
+	fieldPercentage = "percentage"
+)
+
+type constraintViolation struct {
+	err    *model.InternalError
+	fields []string
+}
+
+var constraintViolations = map[string]constraintViolation{
+	"publishers_name_key": {model.ErrPublisherAlreadyExists, []string{columnName}},
+
+	"tags_name_key": {model.ErrTagAlreadyExists, []string{columnName}},
+
+	"chk_authors_identity": {
+		model.ErrAuthorInvalidFields,
+		[]string{columnFirstName, columnLastName, columnPseudonym},
+	},
+
+	"books_price_check":            {model.ErrBookInvalidPrice, []string{columnPrice}},
+	"books_pages_chk":              {model.ErrBookInvalidPages, []string{columnPages}},
+	"uq_books_isbn":                {model.ErrBookAlreadyExists, []string{columnISBN}},
+	"books_publisher_id_fkey":      {model.ErrPublisherNotFound, []string{columnPublisherID}},
+	"books_authors_pkey":           {model.ErrBookRepeatedAuthors, []string{fieldAuthorsIDs}},
+	"books_authors_book_id_fkey":   {model.ErrBookNotFound, []string{columnID}},
+	"books_authors_author_id_fkey": {model.ErrAuthorNotFound, []string{fieldAuthorsIDs}},
+	"books_tags_pkey":              {model.ErrBookRepeatedTags, []string{fieldTagsIDs}},
+	"books_tags_book_id_fkey":      {model.ErrBookNotFound, []string{columnID}},
+	"books_tags_tag_id_fkey":       {model.ErrTagNotFound, []string{fieldTagsIDs}},
+
+	"email_passes_pkey":          {model.ErrUserAlreadyExists, []string{columnEmail}},
+	"uq_email_passes_user_id":    {model.ErrUserAlreadyExists, []string{columnUserID}},
+	"granted_roles_user_id_fkey": {model.ErrUserNotFound, []string{columnUserID}},
+
+	"highlights_book_id_fkey": {model.ErrBookNotFound, []string{columnBookID}},
+	"highlights_kind_chk":     {model.ErrHighlightInvalidKind, []string{columnKind}},
+	"highlights_colour_chk":   {model.ErrHighlightInvalidColour, []string{columnColour}},
+
+	"book_ratings_value_chk":    {model.ErrBookInvalidMark, []string{columnMark}},
+	"book_ratings_book_id_fkey": {model.ErrBookNotFound, []string{columnBookID}},
+
+	"uq_reviews_book_user":        {model.ErrReviewAlreadyExists, []string{columnBookID}},
+	"reviews_book_id_fkey":        {model.ErrBookNotFound, []string{columnBookID}},
+	"reviews_body_chk":            {model.ErrReviewInvalidBody, []string{columnBody}},
+	"review_votes_review_id_fkey": {model.ErrReviewNotFound, []string{columnReviewID}},
+
+	"reading_progress_book_id_fkey":  {model.ErrBookNotFound, []string{columnBookID}},
+	"reading_progress_fraction_chk":  {model.ErrProgressInvalidPercentage, []string{fieldPercentage}},
+	"reading_progress_page_chk":      {model.ErrProgressInvalidPage, []string{columnPage}},
+	"koreader_accounts_username_key": {model.ErrKOReaderUsernameTaken, []string{columnUsername}},
+
+	"reading_goals_books_chk": {model.ErrGoalInvalidBooks, []string{columnBooks}},
+	"reading_goals_pages_chk": {model.ErrGoalInvalidPages, []string{columnPages}},
+
+	"uq_shelves_user_name":       {model.ErrShelfAlreadyExists, []string{columnName}},
+	"shelves_books_book_id_fkey": {model.ErrBookNotFound, []string{columnBookID}},
+
+	"follows_self_chk":         {model.ErrFollowSelf, []string{columnFolloweeID}},
+	"follows_followee_id_fkey": {model.ErrProfileNotFound, []string{columnFolloweeID}},
+}
+
+var referencedConstraintViolations = map[string]constraintViolation{
+	"books_authors_author_id_fkey": {model.ErrAuthorHasBooks, []string{columnID}},
+}
+
+var notNullViolations = map[string]*model.InternalError{
+	tableBooks + "." + columnTitle:     model.ErrBookTitleRequired,
+	tablePublishers + "." + columnName: model.ErrPublisherNameRequired,
+	tableTags + "." + columnName:       model.ErrTagNameRequired,
+}
+
+func translateError(err error) error {
+	return translatePgError(err, false)
+}
+
+func translateRemoveError(err error) error {
+	return translatePgError(err, true)
+}
+
+func translatePgError(err error, removing bool) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	violations := constraintViolations
+	if removing && pgErr.Code == pgCodeForeignKeyViolation {
+		violations = referencedConstraintViolations
+	}
+	if violation, ok := violations[pgErr.ConstraintName]; ok {
+		return violation.err.WithFields(violation.fields...)
+	}
+
+	switch pgErr.Code {
+	case pgCodeNotNullViolation:
+		if mapped, ok := notNullViolations[pgErr.TableName+"."+pgErr.ColumnName]; ok {
+			return mapped.WithFields(pgErr.ColumnName)
+		}
+		return model.ErrRequiredFieldMissing.WithFields(pgErr.ColumnName)
+	case pgCodeCheckViolation:
+		return model.ErrConstraintViolation.WithFields(fieldsOf(pgErr)...)
+	case pgCodeForeignKeyViolation:
+		if removing {
+			return model.ErrEntityReferenced.WithFields(fieldsOf(pgErr)...)
+		}
+		return model.ErrReferenceNotFound.WithFields(fieldsOf(pgErr)...)
+	case pgCodeUniqueViolation:
+		return model.ErrEntityAlreadyExists.WithFields(fieldsOf(pgErr)...)
+	}
+	return err
+}
+
+func fieldsOf(pgErr *pgconn.PgError) []string {
+	if pgErr.ColumnName == "" {
+		return nil
+	}
+	return []string{pgErr.ColumnName}
+}
@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/harvest"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+const (
+	tableDeletedBooks = "deleted_books"
+
+	columnDeletedAt = "deleted_at"
+	columnDatestamp = "datestamp"
+	columnDeleted   = "deleted"
+)
+
+type HarvestStorage struct {
+	pool *pgxpool.Pool
+	psql squirrel.StatementBuilderType
+}
+
+func NewHarvestStorage(pool *pgxpool.Pool) *HarvestStorage {
+	return &HarvestStorage{
+		pool: pool,
+		psql: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+func (p *HarvestStorage) ListHeaders(
+	ctx context.Context,
+	parameters harvest.HeaderParameters,
+) ([]harvest.Header, error) {
+	existing := subquery.Select(
+		columnID,
+		columnUpdatedAt+" AS "+columnDatestamp,
+		"FALSE AS "+columnDeleted,
+	).From(tableBooks)
+	if set := parameters.Set; set != nil {
+		switch {
+		case set.Kind == harvest.SetPublisher && set.ID != nil:
+			existing = existing.Where(squirrel.Eq{columnPublisherID: *set.ID})
+		case set.Kind == harvest.SetPublisher:
+			existing = existing.Where(squirrel.NotEq{columnPublisherID: nil})
+		case set.ID != nil:
+			sub := subquery.Select(columnBookID).From(tableBooksTags).Where(squirrel.Eq{columnTagID: *set.ID})
+			existing = existing.Where(squirrel.Expr(columnID+" IN (?)", sub))
+		default:
+			existing = existing.Where(squirrel.Expr(columnID + " IN (SELECT " + columnBookID + " FROM " + tableBooksTags + ")"))
+		}
+	}
+	// Set membership is not kept for deleted books, so their tombstones are
+	// reported to every harvest and harvesters drop the ones they never had.
+	deleted := subquery.Select(
+		columnID,
+		columnDeletedAt+" AS "+columnDatestamp,
+		"TRUE AS "+columnDeleted,
+	).From(tableDeletedBooks)
+
+	q := p.psql.Select(columnID, columnDatestamp, columnDeleted).
+		FromSelect(existing.Suffix("UNION ALL ?", deleted), "records").
+		OrderBy(columnDatestamp, columnID)
+	if parameters.From != nil {
+		q = q.Where(squirrel.GtOrEq{columnDatestamp: parameters.From.UTC()})
+	}
+	if parameters.Until != nil {
+		q = q.Where(squirrel.Lt{columnDatestamp: parameters.Until.UTC()})
+	}
+	if parameters.AfterDatestamp != nil {
+		q = q.Where(
+			squirrel.Expr(
+				"("+columnDatestamp+", "+columnID+") > (?, ?)",
+				parameters.AfterDatestamp.UTC(), parameters.AfterID,
+			),
+		)
+	}
+	if parameters.Limit > 0 {
+		q = q.Limit(parameters.Limit)
+	}
+
+	sql, args, err := q.ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var headers []harvest.Header
+	for rows.Next() {
+		var header harvest.Header
+		if err = rows.Scan(&header.ID, &header.Datestamp, &header.Deleted); err != nil {
+			return nil, err
+		}
+		headers = append(headers, header)
+	}
+	return headers, rows.Err()
+}
+
+func (p *HarvestStorage) GetDeletedBook(ctx context.Context, id uuid.UUID) (harvest.Header, error) {
+	sql, args, err := p.psql.Select(columnID, columnDeletedAt).
+		From(tableDeletedBooks).
+		Where(squirrel.Eq{columnID: id}).
+		ToSql()
+	if err != nil {
+		return harvest.Header{}, err
+	}
+	header := harvest.Header{Deleted: true}
+	if err = p.pool.QueryRow(ctx, sql, args...).Scan(&header.ID, &header.Datestamp); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return harvest.Header{}, model.ErrBookNotFound
+		}
+		return harvest.Header{}, err
+	}
+	return header, nil
+}
+
+func (p *HarvestStorage) EarliestDatestamp(ctx context.Context) (time.Time, error) {
+	var earliest pgtype.Timestamp
+	err := p.pool.QueryRow(
+		ctx,
+		"SELECT LEAST((SELECT MIN("+columnUpdatedAt+") FROM "+tableBooks+"), "+
+			"(SELECT MIN("+columnDeletedAt+") FROM "+tableDeletedBooks+"))",
+	).Scan(&earliest)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !earliest.Valid {
+		return time.Now().UTC(), nil
+	}
+	return earliest.Time, nil
+}
@@ -0,0 +1,333 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/highlights"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	tableHighlights = "highlights"
+
+	columnKind          = "kind"
+	columnText          = "text"
+	columnPage          = "page"
+	columnLocationStart = "location_start"
+	columnLocationEnd   = "location_end"
+	columnClippedAt     = "clipped_at"
+	columnSourceTitle   = "source_title"
+	columnSourceAuthor  = "source_author"
+	columnFingerprint   = "fingerprint"
+	columnColour        = "colour"
+	columnTags          = "tags"
+	columnSearch        = "search"
+
+	highlightsSearchConfig = "'simple'"
+
+	highlightsInsertChunk = 500
+)
+
+type HighlightsStorage struct {
+	pool *pgxpool.Pool
+	psql squirrel.StatementBuilderType
+}
+
+func NewHighlightsStorage(pool *pgxpool.Pool) *HighlightsStorage {
+	return &HighlightsStorage{
+		pool: pool,
+		psql: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+func (p *HighlightsStorage) AddImportedHighlights(
+	ctx context.Context,
+	imported []highlights.ImportedHighlight,
+) (int, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	inserted := 0
+	for start := 0; start < len(imported); start += highlightsInsertChunk {
+		insert := p.psql.Insert(tableHighlights).Columns(
+			columnUserID, columnBookID, columnKind, columnText, columnPage, columnLocationStart,
+			columnLocationEnd, columnClippedAt, columnSourceTitle, columnSourceAuthor, columnFingerprint,
+		)
+		for _, row := range imported[start:min(start+highlightsInsertChunk, len(imported))] {
+			h := row.Highlight
+			insert = insert.Values(
+				h.UserID, toPostgresUUIDPtr(h.BookID), string(h.Kind), h.Text, h.Page, h.LocationStart,
+				h.LocationEnd, h.ClippedAt, h.SourceTitle, h.SourceAuthor, row.Fingerprint,
+			)
+		}
+		sql, args, err := insert.Suffix("ON CONFLICT DO NOTHING").ToSql()
+		if err != nil {
+			return 0, err
+		}
+		tag, err := tx.Exec(ctx, sql, args...)
+		if err != nil {
+			return 0, translateError(err)
+		}
+		inserted += int(tag.RowsAffected())
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return inserted, nil
+}
+
+func (p *HighlightsStorage) ListUnmatchedSources(
+	ctx context.Context,
+	userID uuid.UUID,
+) ([]highlights.UnmatchedSource, error) {
+	sql, args, err := p.psql.Select(columnSourceTitle, "COALESCE("+columnSourceAuthor+", '')", "count(*)").
+		From(tableHighlights).
+		Where(squirrel.Eq{columnUserID: userID, columnBookID: nil}).
+		Where(squirrel.NotEq{columnSourceTitle: nil}).
+		GroupBy(columnSourceTitle, columnSourceAuthor).
+		OrderBy(columnSourceTitle, columnSourceAuthor).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sources := make([]highlights.UnmatchedSource, 0)
+	for rows.Next() {
+		var source highlights.UnmatchedSource
+		if err = rows.Scan(&source.Title, &source.Author, &source.Highlights); err != nil {
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+	return sources, rows.Err()
+}
+
+func (p *HighlightsStorage) AssignSource(
+	ctx context.Context,
+	userID uuid.UUID,
+	source highlights.Source,
+	bookID uuid.UUID,
+) (int, error) {
+	sql, args, err := p.psql.Update(tableHighlights).
+		Set(columnBookID, bookID).
+		Where(
+			squirrel.Eq{
+				columnUserID:      userID,
+				columnBookID:      nil,
+				columnSourceTitle: source.Title,
+			},
+		).
+		Where(squirrel.Expr("COALESCE("+columnSourceAuthor+", '') = ?", source.Author)).
+		ToSql()
+	if err != nil {
+		return 0, err
+	}
+	tag, err := p.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, translateError(err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+func (p *HighlightsStorage) AddHighlight(ctx context.Context, highlight model.Highlight) (uuid.UUID, error) {
+	sql, args, err := p.psql.Insert(tableHighlights).
+		Columns(
+			columnUserID, columnBookID, columnKind, columnText, columnPage, columnLocationStart, columnLocationEnd,
+			columnColour, columnTags,
+		).
+		Values(
+			highlight.UserID, toPostgresUUIDPtr(highlight.BookID), string(highlight.Kind), highlight.Text,
+			highlight.Page, highlight.LocationStart, highlight.LocationEnd, colourToAny(highlight.Colour),
+			highlight.Tags,
+		).
+		Suffix("RETURNING " + columnID).
+		ToSql()
+	if err != nil {
+		return uuid.Nil, err
+	}
+	var id uuid.UUID
+	if err = p.pool.QueryRow(ctx, sql, args...).Scan(&id); err != nil {
+		return uuid.Nil, translateError(err)
+	}
+	return id, nil
+}
+
+func (p *HighlightsStorage) GetHighlight(ctx context.Context, userID uuid.UUID, id uuid.UUID) (model.Highlight, error) {
+	sql, args, err := p.selectHighlights().Where(squirrel.Eq{columnID: id, columnUserID: userID}).ToSql()
+	if err != nil {
+		return model.Highlight{}, err
+	}
+	highlight, err := scanHighlight(p.pool.QueryRow(ctx, sql, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Highlight{}, model.ErrHighlightNotFound
+		}
+		return model.Highlight{}, err
+	}
+	return highlight, nil
+}
+
+func (p *HighlightsStorage) UpdateHighlight(ctx context.Context, highlight model.Highlight) error {
+	sql, args, err := p.psql.Update(tableHighlights).
+		Set(columnKind, string(highlight.Kind)).
+		Set(columnText, highlight.Text).
+		Set(columnPage, highlight.Page).
+		Set(columnLocationStart, highlight.LocationStart).
+		Set(columnLocationEnd, highlight.LocationEnd).
+		Set(columnColour, colourToAny(highlight.Colour)).
+		Set(columnTags, highlight.Tags).
+		Set(columnUpdatedAt, squirrel.Expr("CURRENT_TIMESTAMP")).
+		Where(squirrel.Eq{columnID: highlight.ID, columnUserID: highlight.UserID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	tag, err := p.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return translateError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrHighlightNotFound
+	}
+	return nil
+}
+
+func (p *HighlightsStorage) RemoveHighlight(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	sql, args, err := p.psql.Delete(tableHighlights).
+		Where(squirrel.Eq{columnID: id, columnUserID: userID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	tag, err := p.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrHighlightNotFound
+	}
+	return nil
+}
+
+// ListHighlights ranks the matches of a search first. Otherwise the
+// highlights of a book follow the reading order and the rest the newest first.
+func (p *HighlightsStorage) ListHighlights(
+	ctx context.Context,
+	userID uuid.UUID,
+	parameters highlights.ListParameters,
+) ([]model.Highlight, error) {
+	q := p.selectHighlights().Where(squirrel.Eq{columnUserID: userID})
+	if parameters.BookID != nil {
+		q = q.Where(squirrel.Eq{columnBookID: *parameters.BookID})
+	}
+	if parameters.Kind != "" {
+		q = q.Where(squirrel.Eq{columnKind: string(parameters.Kind)})
+	}
+	if parameters.Colour != "" {
+		q = q.Where(squirrel.Eq{columnColour: string(parameters.Colour)})
+	}
+	if parameters.Tag != "" {
+		q = q.Where(squirrel.Expr(columnTags+" @> ARRAY[?]::text[]", parameters.Tag))
+	}
+	switch {
+	case parameters.Query != "":
+		query := "websearch_to_tsquery(" + highlightsSearchConfig + ", ?)"
+		q = q.Where(squirrel.Expr(columnSearch+" @@ "+query, parameters.Query)).
+			OrderByClause("ts_rank("+columnSearch+", "+query+") DESC", parameters.Query).
+			OrderBy(columnCreatedAt+" DESC", columnID)
+	case parameters.BookID != nil:
+		q = q.OrderBy(
+			columnPage+" NULLS LAST", columnLocationStart+" NULLS LAST", columnCreatedAt, columnID,
+		)
+	default:
+		q = q.OrderBy(columnCreatedAt+" DESC", columnID)
+	}
+	if parameters.Limit > 0 {
+		q = q.Limit(parameters.Limit)
+	}
+	if parameters.Offset > 0 {
+		q = q.Offset(parameters.Offset)
+	}
+
+	sql, args, err := q.ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]model.Highlight, 0)
+	for rows.Next() {
+		highlight, err := scanHighlight(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, highlight)
+	}
+	return list, rows.Err()
+}
+
+func (p *HighlightsStorage) selectHighlights() squirrel.SelectBuilder {
+	return p.psql.Select(
+		columnID,
+		columnUserID,
+		columnBookID,
+		columnKind,
+		columnText,
+		columnPage,
+		columnLocationStart,
+		columnLocationEnd,
+		columnColour,
+		columnTags,
+		columnClippedAt,
+		columnSourceTitle,
+		columnSourceAuthor,
+		columnCreatedAt,
+		columnUpdatedAt,
+	).From(tableHighlights)
+}
+
+func scanHighlight(row pgx.Row) (model.Highlight, error) {
+	var highlight model.Highlight
+	err := row.Scan(
+		&highlight.ID,
+		&highlight.UserID,
+		&highlight.BookID,
+		&highlight.Kind,
+		&highlight.Text,
+		&highlight.Page,
+		&highlight.LocationStart,
+		&highlight.LocationEnd,
+		&highlight.Colour,
+		&highlight.Tags,
+		&highlight.ClippedAt,
+		&highlight.SourceTitle,
+		&highlight.SourceAuthor,
+		&highlight.CreatedAt,
+		&highlight.UpdatedAt,
+	)
+	return highlight, err
+}
+
+func colourToAny(colour *model.HighlightColour) any {
+	if colour == nil {
+		return nil
+	}
+	return string(*colour)
+}
@@ -2,10 +2,15 @@ package postgres
 
 import (
 	"context"
+	"github.com/Masterminds/squirrel"
 	"github.com/iamvkosarev/book-shelf/config"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// subquery builds nested statements with "?" placeholders so that the outer
+// Dollar builder numbers all arguments in a single pass.
+var subquery = squirrel.StatementBuilder.PlaceholderFormat(squirrel.Question)
+
 func NewPool(ctx context.Context, cfg config.Database) (*pgxpool.Pool, error) {
 	pool, err := pgxpool.New(ctx, cfg.URL)
 	if err != nil {
@@ -0,0 +1,321 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+const (
+	tableReadingProgress  = "reading_progress"
+	tableKOReaderAccounts = "koreader_accounts"
+
+	columnDocument = "document"
+	columnFraction = "fraction"
+	columnPosition = "position"
+	columnDevice   = "device"
+	columnDeviceID = "device_id"
+	columnUsername = "username"
+	columnKeyHash  = "key_hash"
+
+	progressUpdate = columnFraction + " = EXCLUDED." + columnFraction + ", " +
+		columnPage + " = EXCLUDED." + columnPage + ", " +
+		columnPosition + " = EXCLUDED." + columnPosition + ", " +
+		columnDevice + " = EXCLUDED." + columnDevice + ", " +
+		columnDeviceID + " = EXCLUDED." + columnDeviceID + ", " +
+		columnUpdatedAt + " = CURRENT_TIMESTAMP"
+)
+
+type ProgressStorage struct {
+	pool *pgxpool.Pool
+	psql squirrel.StatementBuilderType
+}
+
+func NewProgressStorage(pool *pgxpool.Pool) *ProgressStorage {
+	return &ProgressStorage{
+		pool: pool,
+		psql: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+// SetBookProgress moves a linked document away from any other row of the
+// user first, a document belongs to one book at a time.
+func (p *ProgressStorage) SetBookProgress(
+	ctx context.Context,
+	userID uuid.UUID,
+	bookID uuid.UUID,
+	document *string,
+	progress model.ReadingProgress,
+) error {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if document != nil {
+		statements := []squirrel.Sqlizer{
+			p.psql.Delete(tableReadingProgress).
+				Where(squirrel.Eq{columnUserID: userID, columnDocument: *document, columnBookID: nil}),
+			p.psql.Update(tableReadingProgress).
+				Set(columnDocument, nil).
+				Where(squirrel.Eq{columnUserID: userID, columnDocument: *document}).
+				Where(squirrel.NotEq{columnBookID: bookID}),
+		}
+		for _, statement := range statements {
+			sql, args, err := statement.ToSql()
+			if err != nil {
+				return err
+			}
+			if _, err = tx.Exec(ctx, sql, args...); err != nil {
+				return err
+			}
+		}
+	}
+
+	sql, args, err := p.psql.Insert(tableReadingProgress).
+		Columns(
+			columnUserID, columnBookID, columnDocument, columnFraction, columnPage, columnPosition,
+			columnDevice, columnDeviceID,
+		).
+		Values(
+			userID, bookID, toPostgresTextPtr(document), progress.Fraction, progress.Page, progress.Position,
+			progress.Device, progress.DeviceID,
+		).
+		Suffix(
+			"ON CONFLICT (" + columnUserID + ", " + columnBookID + ") WHERE " + columnBookID + " IS NOT NULL " +
+				"DO UPDATE SET " + progressUpdate + ", " + columnDocument + " = COALESCE(EXCLUDED." + columnDocument +
+				", " + tableReadingProgress + "." + columnDocument + ")",
+		).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err = tx.Exec(ctx, sql, args...); err != nil {
+		return translateError(err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+func (p *ProgressStorage) GetBookProgress(
+	ctx context.Context,
+	userID uuid.UUID,
+	bookID uuid.UUID,
+) (model.ReadingProgress, error) {
+	return p.getProgress(ctx, squirrel.Eq{columnUserID: userID, columnBookID: bookID})
+}
+
+func (p *ProgressStorage) RemoveBookProgress(ctx context.Context, userID uuid.UUID, bookID uuid.UUID) error {
+	sql, args, err := p.psql.Delete(tableReadingProgress).
+		Where(squirrel.Eq{columnUserID: userID, columnBookID: bookID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	tag, err := p.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrProgressNotFound
+	}
+	return nil
+}
+
+func (p *ProgressStorage) ListProgress(ctx context.Context, userID uuid.UUID) ([]model.ReadingProgress, error) {
+	sql, args, err := p.selectProgress().
+		Where(squirrel.Eq{columnUserID: userID}).
+		OrderBy(columnUpdatedAt+" DESC", columnID).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]model.ReadingProgress, 0)
+	for rows.Next() {
+		progress, err := scanProgress(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, progress)
+	}
+	return list, rows.Err()
+}
+
+func (p *ProgressStorage) SetDocumentProgress(
+	ctx context.Context,
+	userID uuid.UUID,
+	document string,
+	progress model.ReadingProgress,
+) (time.Time, error) {
+	sql, args, err := p.psql.Insert(tableReadingProgress).
+		Columns(
+			columnUserID, columnDocument, columnFraction, columnPage, columnPosition, columnDevice, columnDeviceID,
+		).
+		Values(
+			userID, document, progress.Fraction, progress.Page, progress.Position, progress.Device,
+			progress.DeviceID,
+		).
+		Suffix(
+			"ON CONFLICT (" + columnUserID + ", " + columnDocument + ") WHERE " + columnDocument + " IS NOT NULL " +
+				"DO UPDATE SET " + progressUpdate + " RETURNING " + columnUpdatedAt,
+		).
+		ToSql()
+	if err != nil {
+		return time.Time{}, err
+	}
+	var updatedAt time.Time
+	if err = p.pool.QueryRow(ctx, sql, args...).Scan(&updatedAt); err != nil {
+		return time.Time{}, translateError(err)
+	}
+	return updatedAt, nil
+}
+
+func (p *ProgressStorage) GetDocumentProgress(
+	ctx context.Context,
+	userID uuid.UUID,
+	document string,
+) (model.ReadingProgress, error) {
+	return p.getProgress(ctx, squirrel.Eq{columnUserID: userID, columnDocument: document})
+}
+
+func (p *ProgressStorage) SetKOReaderAccount(
+	ctx context.Context,
+	userID uuid.UUID,
+	username string,
+	keyHash []byte,
+) error {
+	sql, args, err := p.psql.Insert(tableKOReaderAccounts).
+		Columns(columnUserID, columnUsername, columnKeyHash).
+		Values(userID, username, keyHash).
+		Suffix(
+			"ON CONFLICT (" + columnUserID + ") DO UPDATE SET " +
+				columnUsername + " = EXCLUDED." + columnUsername + ", " +
+				columnKeyHash + " = EXCLUDED." + columnKeyHash,
+		).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err = p.pool.Exec(ctx, sql, args...); err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+func (p *ProgressStorage) GetKOReaderAccount(ctx context.Context, userID uuid.UUID) (model.KOReaderAccount, error) {
+	sql, args, err := p.psql.Select(columnUserID, columnUsername, columnCreatedAt).
+		From(tableKOReaderAccounts).
+		Where(squirrel.Eq{columnUserID: userID}).
+		ToSql()
+	if err != nil {
+		return model.KOReaderAccount{}, err
+	}
+	var account model.KOReaderAccount
+	if err = p.pool.QueryRow(ctx, sql, args...).Scan(
+		&account.UserID, &account.Username, &account.CreatedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.KOReaderAccount{}, model.ErrKOReaderNotLinked
+		}
+		return model.KOReaderAccount{}, err
+	}
+	return account, nil
+}
+
+func (p *ProgressStorage) RemoveKOReaderAccount(ctx context.Context, userID uuid.UUID) error {
+	sql, args, err := p.psql.Delete(tableKOReaderAccounts).
+		Where(squirrel.Eq{columnUserID: userID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	tag, err := p.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrKOReaderNotLinked
+	}
+	return nil
+}
+
+func (p *ProgressStorage) GetKOReaderKeyHash(ctx context.Context, username string) (uuid.UUID, []byte, error) {
+	sql, args, err := p.psql.Select(columnUserID, columnKeyHash).
+		From(tableKOReaderAccounts).
+		Where(squirrel.Eq{columnUsername: username}).
+		ToSql()
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	var (
+		userID  uuid.UUID
+		keyHash []byte
+	)
+	if err = p.pool.QueryRow(ctx, sql, args...).Scan(&userID, &keyHash); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, nil, model.ErrKOReaderNotLinked
+		}
+		return uuid.Nil, nil, err
+	}
+	return userID, keyHash, nil
+}
+
+func (p *ProgressStorage) getProgress(ctx context.Context, where squirrel.Eq) (model.ReadingProgress, error) {
+	sql, args, err := p.selectProgress().Where(where).ToSql()
+	if err != nil {
+		return model.ReadingProgress{}, err
+	}
+	progress, err := scanProgress(p.pool.QueryRow(ctx, sql, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.ReadingProgress{}, model.ErrProgressNotFound
+		}
+		return model.ReadingProgress{}, err
+	}
+	return progress, nil
+}
+
+func (p *ProgressStorage) selectProgress() squirrel.SelectBuilder {
+	return p.psql.Select(
+		columnUserID,
+		columnBookID,
+		columnDocument,
+		columnFraction,
+		columnPage,
+		columnPosition,
+		columnDevice,
+		columnDeviceID,
+		columnUpdatedAt,
+	).From(tableReadingProgress)
+}
+
+func scanProgress(row pgx.Row) (model.ReadingProgress, error) {
+	var progress model.ReadingProgress
+	err := row.Scan(
+		&progress.UserID,
+		&progress.BookID,
+		&progress.Document,
+		&progress.Fraction,
+		&progress.Page,
+		&progress.Position,
+		&progress.Device,
+		&progress.DeviceID,
+		&progress.UpdatedAt,
+	)
+	return progress, err
+}
@@ -7,7 +7,6 @@ import (
 	"github.com/google/uuid"
 	"github.com/iamvkosarev/book-shelf/internal/model"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -34,14 +33,7 @@ func (p *PublishersStorage) AddPublisher(ctx context.Context, name string) (uuid
 	}
 	var id uuid.UUID
 	if err = p.pool.QueryRow(ctx, sql, args...).Scan(&id); err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			switch pgErr.Code {
-			case "23505":
-				return uuid.Nil, model.ErrPublisherAlreadyExists
-			}
-		}
-		return uuid.Nil, err
+		return uuid.Nil, translateError(err)
 	}
 	return id, nil
 }
@@ -75,14 +67,7 @@ func (p *PublishersStorage) UpdatePublisher(ctx context.Context, id uuid.UUID, p
 
 	ct, err := p.pool.Exec(ctx, sql, args...)
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			switch pgErr.Code {
-			case "23505":
-				return model.ErrPublisherAlreadyExists
-			}
-		}
-		return err
+		return translateError(err)
 	}
 
 	if ct.RowsAffected() == 0 {
@@ -92,19 +77,70 @@ func (p *PublishersStorage) UpdatePublisher(ctx context.Context, id uuid.UUID, p
 	return nil
 }
 
-func (p *PublishersStorage) RemovePublisher(ctx context.Context, id uuid.UUID) error {
-	sql, args, err := p.psql.Delete(tablePublishers).Where(squirrel.Eq{columnID: id}).ToSql()
+func (p *PublishersStorage) RemovePublisher(ctx context.Context, id uuid.UUID, policy model.RemovePolicy) error {
+	tx, err := p.pool.Begin(ctx)
 	if err != nil {
 		return err
 	}
-	if _, err = p.pool.Exec(ctx, sql, args...); err != nil {
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err = lockRowTx(ctx, tx, tablePublishers, id, model.ErrPublisherNotFound); err != nil {
 		return err
 	}
-	return nil
+	if policy.OnBooks == model.OnBooksReassign {
+		notFound := model.ErrPublisherNotFound.WithFields(fieldReassignTo)
+		if err = lockRowTx(ctx, tx, tablePublishers, policy.ReassignTo, notFound); err != nil {
+			return err
+		}
+	}
+
+	booksIDs, err := listLinkedBookIDsTx(ctx, tx, tableBooks, columnID, columnPublisherID, id)
+	if err != nil {
+		return err
+	}
+	if len(booksIDs) > 0 {
+		upd := p.psql.Update(tableBooks).Where(squirrel.Eq{columnPublisherID: id})
+		switch policy.OnBooks {
+		case model.OnBooksRestrict:
+			return model.ErrPublisherHasBooks.WithDetails(model.AffectedBooksDetails{BooksIDs: booksIDs})
+		case model.OnBooksDetach:
+			upd = upd.Set(columnPublisherID, nil)
+		case model.OnBooksReassign:
+			upd = upd.Set(columnPublisherID, policy.ReassignTo)
+		}
+		sql, args, err := upd.ToSql()
+		if err != nil {
+			return err
+		}
+		if _, err = tx.Exec(ctx, sql, args...); err != nil {
+			return translateError(err)
+		}
+	}
+
+	sql, args, err := p.psql.Delete(tablePublishers).Where(squirrel.Eq{columnID: id}).ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err = tx.Exec(ctx, sql, args...); err != nil {
+		return translateRemoveError(err)
+	}
+
+	return tx.Commit(ctx)
 }
 
 func (p *PublishersStorage) ListPublishers(ctx context.Context) ([]model.Publisher, error) {
-	sql, args, err := p.psql.Select(columnID, columnName).From(tablePublishers).ToSql()
+	return p.queryPublishers(ctx, p.psql.Select(columnID, columnName).From(tablePublishers))
+}
+
+func (p *PublishersStorage) ListPublishersByIDs(ctx context.Context, ids []uuid.UUID) ([]model.Publisher, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return p.queryPublishers(ctx, p.psql.Select(columnID, columnName).From(tablePublishers).Where(squirrel.Eq{columnID: ids}))
+}
+
+func (p *PublishersStorage) queryPublishers(ctx context.Context, q squirrel.SelectBuilder) ([]model.Publisher, error) {
+	sql, args, err := q.ToSql()
 	if err != nil {
 		return nil, err
 	}
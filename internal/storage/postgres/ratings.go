@@ -0,0 +1,168 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	tableBookRatings     = "book_ratings"
+	tableBookRatingStats = "book_rating_stats"
+
+	columnValue        = "value"
+	columnRatingsCount = "ratings_count"
+	columnRatingsSum   = "ratings_sum"
+	columnHistogram    = "histogram"
+
+	legacyRatingConflict = "ON CONFLICT (" + columnBookID + ") WHERE " + columnUserID + " IS NULL " +
+		"DO UPDATE SET " + columnValue + " = EXCLUDED." + columnValue + ", " + columnUpdatedAt + " = CURRENT_TIMESTAMP"
+	userRatingConflict = "ON CONFLICT (" + columnBookID + ", " + columnUserID + ") WHERE " + columnUserID +
+		" IS NOT NULL DO UPDATE SET " + columnValue + " = EXCLUDED." + columnValue + ", " + columnUpdatedAt +
+		" = CURRENT_TIMESTAMP"
+)
+
+// legacyMarkExpr reads the rating without a user, the one that replaced the
+// mark column of the books table.
+func legacyMarkExpr(booksTable string) string {
+	return "(SELECT r." + columnValue + " FROM " + tableBookRatings + " r WHERE r." + columnBookID + " = " +
+		booksTable + "." + columnID + " AND r." + columnUserID + " IS NULL)"
+}
+
+// ratingSummaryColumns are read from book_rating_stats joined by
+// joinRatingStats, books no user rated have no stats row.
+var ratingSummaryColumns = []string{
+	"COALESCE(" + tableBookRatingStats + "." + columnRatingsCount + ", 0)",
+	"COALESCE(" + tableBookRatingStats + "." + columnRatingsSum + ", 0)",
+	"COALESCE(" + tableBookRatingStats + "." + columnHistogram + ", array_fill(0, ARRAY[11]))",
+}
+
+func joinRatingStats(q squirrel.SelectBuilder) squirrel.SelectBuilder {
+	return q.LeftJoin(
+		tableBookRatingStats + " ON " + tableBookRatingStats + "." + columnBookID + " = " + tableBooks + "." + columnID,
+	)
+}
+
+// legacyRatingStatement writes the mark of a restored book as its legacy
+// rating, it returns nil when the mark is left as it is. The API no longer
+// writes marks, only backups carry them. The stats trigger skips rows
+// without a user, so the mark never reaches book_rating_stats.
+func legacyRatingStatement(
+	psql squirrel.StatementBuilderType,
+	bookID uuid.UUID,
+	mark *int16,
+	clear bool,
+) squirrel.Sqlizer {
+	switch {
+	case mark != nil:
+		return psql.Insert(tableBookRatings).
+			Columns(columnBookID, columnValue).
+			Values(bookID, *mark).
+			Suffix(legacyRatingConflict)
+	case clear:
+		return psql.Delete(tableBookRatings).
+			Where(squirrel.Eq{columnBookID: bookID, columnUserID: nil})
+	}
+	return nil
+}
+
+type RatingsStorage struct {
+	pool *pgxpool.Pool
+	psql squirrel.StatementBuilderType
+}
+
+func NewRatingsStorage(pool *pgxpool.Pool) *RatingsStorage {
+	return &RatingsStorage{
+		pool: pool,
+		psql: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+func (p *RatingsStorage) SetRating(ctx context.Context, userID uuid.UUID, bookID uuid.UUID, value int16) error {
+	sql, args, err := p.psql.Insert(tableBookRatings).
+		Columns(columnBookID, columnUserID, columnValue).
+		Values(bookID, userID, value).
+		Suffix(userRatingConflict).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err = p.pool.Exec(ctx, sql, args...); err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+func (p *RatingsStorage) RemoveRating(ctx context.Context, userID uuid.UUID, bookID uuid.UUID) error {
+	sql, args, err := p.psql.Delete(tableBookRatings).
+		Where(squirrel.Eq{columnBookID: bookID, columnUserID: userID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	tag, err := p.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrRatingNotFound
+	}
+	return nil
+}
+
+func (p *RatingsStorage) GetRatingSummary(ctx context.Context, bookID uuid.UUID) (model.RatingSummary, error) {
+	sql, args, err := joinRatingStats(p.psql.Select(ratingSummaryColumns...).From(tableBooks)).
+		Where(squirrel.Eq{tableBooks + "." + columnID: bookID}).
+		ToSql()
+	if err != nil {
+		return model.RatingSummary{}, err
+	}
+	var summary model.RatingSummary
+	if err = p.pool.QueryRow(ctx, sql, args...).Scan(&summary.Count, &summary.Sum, &summary.Histogram); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.RatingSummary{}, model.ErrBookNotFound
+		}
+		return model.RatingSummary{}, err
+	}
+	return summary, nil
+}
+
+func (p *RatingsStorage) UserRatings(
+	ctx context.Context,
+	userID uuid.UUID,
+	bookIDs []uuid.UUID,
+) (map[uuid.UUID]int16, error) {
+	result := make(map[uuid.UUID]int16)
+	if len(bookIDs) == 0 {
+		return result, nil
+	}
+
+	sql, args, err := p.psql.Select(columnBookID, columnValue).
+		From(tableBookRatings).
+		Where(squirrel.Eq{columnUserID: userID, columnBookID: bookIDs}).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			bookID uuid.UUID
+			value  int16
+		)
+		if err = rows.Scan(&bookID, &value); err != nil {
+			return nil, err
+		}
+		result[bookID] = value
+	}
+	return result, rows.Err()
+}
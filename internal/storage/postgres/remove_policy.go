@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+func lockRowTx(ctx context.Context, tx pgx.Tx, table string, id uuid.UUID, notFound error) error {
+	var existing uuid.UUID
+	err := tx.QueryRow(
+		ctx, "SELECT "+columnID+" FROM "+table+" WHERE "+columnID+"=$1 FOR UPDATE", id,
+	).Scan(&existing)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return notFound
+		}
+		return err
+	}
+	return nil
+}
+
+func listLinkedBookIDsTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	table, bookColumn, linkColumn string,
+	id uuid.UUID,
+) ([]uuid.UUID, error) {
+	rows, err := tx.Query(
+		ctx,
+		"SELECT "+bookColumn+" FROM "+table+" WHERE "+linkColumn+"=$1 ORDER BY "+bookColumn,
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var bookID uuid.UUID
+		if err := rows.Scan(&bookID); err != nil {
+			return nil, err
+		}
+		ids = append(ids, bookID)
+	}
+	return ids, rows.Err()
+}
+
+func applyLinkTablePolicyTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	linkTable, linkColumn string,
+	id uuid.UUID,
+	policy model.RemovePolicy,
+) error {
+	if policy.OnBooks == model.OnBooksReassign {
+		if _, err := tx.Exec(
+			ctx,
+			"INSERT INTO "+linkTable+" ("+columnBookID+", "+linkColumn+") "+
+				"SELECT "+columnBookID+", $2 FROM "+linkTable+" WHERE "+linkColumn+"=$1 "+
+				"ON CONFLICT DO NOTHING",
+			id, policy.ReassignTo,
+		); err != nil {
+			return translateError(err)
+		}
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM "+linkTable+" WHERE "+linkColumn+"=$1", id); err != nil {
+		return err
+	}
+	return nil
+}
+
+func removeWithLinkTablePolicy(
+	ctx context.Context,
+	tx pgx.Tx,
+	table, linkTable, linkColumn string,
+	id uuid.UUID,
+	policy model.RemovePolicy,
+	notFound, hasBooks *model.InternalError,
+) error {
+	if err := lockRowTx(ctx, tx, table, id, notFound); err != nil {
+		return err
+	}
+	if policy.OnBooks == model.OnBooksReassign {
+		if err := lockRowTx(ctx, tx, table, policy.ReassignTo, notFound.WithFields(fieldReassignTo)); err != nil {
+			return err
+		}
+	}
+
+	booksIDs, err := listLinkedBookIDsTx(ctx, tx, linkTable, columnBookID, linkColumn, id)
+	if err != nil {
+		return err
+	}
+	if len(booksIDs) > 0 {
+		if policy.OnBooks == model.OnBooksRestrict {
+			return hasBooks.WithDetails(model.AffectedBooksDetails{BooksIDs: booksIDs})
+		}
+		if err = applyLinkTablePolicyTx(ctx, tx, linkTable, linkColumn, id, policy); err != nil {
+			return err
+		}
+	}
+
+	if _, err = tx.Exec(ctx, "DELETE FROM "+table+" WHERE "+columnID+"=$1", id); err != nil {
+		return translateRemoveError(err)
+	}
+	return nil
+}
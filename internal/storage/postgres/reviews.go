@@ -0,0 +1,291 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/reviews"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	tableReviews     = "reviews"
+	tableReviewVotes = "review_votes"
+
+	columnBody            = "body"
+	columnSpoiler         = "spoiler"
+	columnStatus          = "status"
+	columnHelpfulCount    = "helpful_count"
+	columnModeratedBy     = "moderated_by"
+	columnModeratedAt     = "moderated_at"
+	columnRejectionReason = "rejection_reason"
+	columnReviewID        = "review_id"
+)
+
+type ReviewsStorage struct {
+	pool *pgxpool.Pool
+	psql squirrel.StatementBuilderType
+}
+
+func NewReviewsStorage(pool *pgxpool.Pool) *ReviewsStorage {
+	return &ReviewsStorage{
+		pool: pool,
+		psql: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+func (p *ReviewsStorage) AddReview(
+	ctx context.Context,
+	userID uuid.UUID,
+	bookID uuid.UUID,
+	input reviews.ReviewInput,
+) (uuid.UUID, error) {
+	sql, args, err := p.psql.Insert(tableReviews).
+		Columns(columnBookID, columnUserID, columnBody, columnSpoiler).
+		Values(bookID, userID, input.Body, input.Spoiler).
+		Suffix("RETURNING " + columnID).
+		ToSql()
+	if err != nil {
+		return uuid.Nil, err
+	}
+	var id uuid.UUID
+	if err = p.pool.QueryRow(ctx, sql, args...).Scan(&id); err != nil {
+		return uuid.Nil, translateError(err)
+	}
+	return id, nil
+}
+
+func (p *ReviewsStorage) GetReview(ctx context.Context, viewerID uuid.UUID, id uuid.UUID) (model.Review, error) {
+	sql, args, err := p.selectReviews(viewerID).Where(squirrel.Eq{columnID: id}).ToSql()
+	if err != nil {
+		return model.Review{}, err
+	}
+	review, err := scanReview(p.pool.QueryRow(ctx, sql, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Review{}, model.ErrReviewNotFound
+		}
+		return model.Review{}, err
+	}
+	return review, nil
+}
+
+func (p *ReviewsStorage) UpdateReview(
+	ctx context.Context,
+	userID uuid.UUID,
+	id uuid.UUID,
+	input reviews.ReviewInput,
+) error {
+	sql, args, err := p.psql.Update(tableReviews).
+		Set(columnBody, input.Body).
+		Set(columnSpoiler, input.Spoiler).
+		Set(columnStatus, string(model.ReviewPending)).
+		Set(columnModeratedBy, nil).
+		Set(columnModeratedAt, nil).
+		Set(columnRejectionReason, nil).
+		Set(columnUpdatedAt, squirrel.Expr("CURRENT_TIMESTAMP")).
+		Where(squirrel.Eq{columnID: id, columnUserID: userID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	tag, err := p.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return translateError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrReviewNotFound
+	}
+	return nil
+}
+
+func (p *ReviewsStorage) RemoveReview(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	sql, args, err := p.psql.Delete(tableReviews).
+		Where(squirrel.Eq{columnID: id, columnUserID: userID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	tag, err := p.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrReviewNotFound
+	}
+	return nil
+}
+
+func (p *ReviewsStorage) ListBookReviews(
+	ctx context.Context,
+	viewerID uuid.UUID,
+	bookID uuid.UUID,
+	parameters reviews.ListParameters,
+) ([]model.Review, error) {
+	q := p.selectReviews(viewerID).
+		Where(squirrel.Eq{columnBookID: bookID, columnStatus: string(model.ReviewApproved)})
+	switch parameters.Order {
+	case reviews.ReviewsOrderHelpful:
+		q = q.OrderBy(columnHelpfulCount+" DESC", columnCreatedAt+" DESC", columnID)
+	default:
+		q = q.OrderBy(columnCreatedAt+" DESC", columnID)
+	}
+	if parameters.Limit > 0 {
+		q = q.Limit(parameters.Limit)
+	}
+	if parameters.Offset > 0 {
+		q = q.Offset(parameters.Offset)
+	}
+	return p.queryReviews(ctx, q)
+}
+
+func (p *ReviewsStorage) ListUserReviews(ctx context.Context, userID uuid.UUID) ([]model.Review, error) {
+	q := p.selectReviews(userID).
+		Where(squirrel.Eq{columnUserID: userID}).
+		OrderBy(columnCreatedAt+" DESC", columnID)
+	return p.queryReviews(ctx, q)
+}
+
+func (p *ReviewsStorage) ListReviewsByStatus(
+	ctx context.Context,
+	status model.ReviewStatus,
+	limit, offset uint64,
+) ([]model.Review, error) {
+	q := p.selectReviews(uuid.Nil).
+		Where(squirrel.Eq{columnStatus: string(status)}).
+		OrderBy(columnUpdatedAt, columnID)
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	if offset > 0 {
+		q = q.Offset(offset)
+	}
+	return p.queryReviews(ctx, q)
+}
+
+func (p *ReviewsStorage) ModerateReview(
+	ctx context.Context,
+	moderatorID uuid.UUID,
+	id uuid.UUID,
+	status model.ReviewStatus,
+	reason *string,
+) error {
+	sql, args, err := p.psql.Update(tableReviews).
+		Set(columnStatus, string(status)).
+		Set(columnModeratedBy, moderatorID).
+		Set(columnModeratedAt, squirrel.Expr("CURRENT_TIMESTAMP")).
+		Set(columnRejectionReason, toPostgresTextPtr(reason)).
+		Where(squirrel.Eq{columnID: id}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	tag, err := p.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return translateError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrReviewNotFound
+	}
+	return nil
+}
+
+func (p *ReviewsStorage) AddVote(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	sql, args, err := p.psql.Insert(tableReviewVotes).
+		Columns(columnReviewID, columnUserID).
+		Values(id, userID).
+		Suffix("ON CONFLICT DO NOTHING").
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err = p.pool.Exec(ctx, sql, args...); err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+func (p *ReviewsStorage) RemoveVote(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	sql, args, err := p.psql.Delete(tableReviewVotes).
+		Where(squirrel.Eq{columnReviewID: id, columnUserID: userID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	tag, err := p.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrReviewVoteNotFound
+	}
+	return nil
+}
+
+// selectReviews reads whether the viewer voted each review helpful, uuid.Nil
+// stands for nobody.
+func (p *ReviewsStorage) selectReviews(viewerID uuid.UUID) squirrel.SelectBuilder {
+	voted := subquery.Select("1").
+		From(tableReviewVotes + " v").
+		Where("v." + columnReviewID + " = " + tableReviews + "." + columnID).
+		Where(squirrel.Eq{"v." + columnUserID: viewerID})
+	return p.psql.Select(
+		columnID,
+		columnBookID,
+		columnUserID,
+		columnBody,
+		columnSpoiler,
+		columnStatus,
+		columnHelpfulCount,
+		columnRejectionReason,
+		columnModeratedAt,
+		columnCreatedAt,
+		columnUpdatedAt,
+	).
+		Column(squirrel.Expr("EXISTS (?)", voted)).
+		From(tableReviews)
+}
+
+func (p *ReviewsStorage) queryReviews(ctx context.Context, q squirrel.SelectBuilder) ([]model.Review, error) {
+	sql, args, err := q.ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]model.Review, 0)
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, review)
+	}
+	return list, rows.Err()
+}
+
+func scanReview(row pgx.Row) (model.Review, error) {
+	var review model.Review
+	err := row.Scan(
+		&review.ID,
+		&review.BookID,
+		&review.UserID,
+		&review.Body,
+		&review.Spoiler,
+		&review.Status,
+		&review.HelpfulCount,
+		&review.RejectionReason,
+		&review.ModeratedAt,
+		&review.CreatedAt,
+		&review.UpdatedAt,
+		&review.VotedHelpful,
+	)
+	return review, err
+}
@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+const (
+	tableShelfShares = "shelf_shares"
+
+	columnToken        = "token"
+	columnExpiresAt    = "expires_at"
+	columnRevokedAt    = "revoked_at"
+	columnViews        = "views"
+	columnLastViewedAt = "last_viewed_at"
+)
+
+func (p *ShelvesStorage) AddShare(ctx context.Context, share model.ShelfShare) (model.ShelfShare, error) {
+	sql, args, err := p.psql.Insert(tableShelfShares).
+		Columns(columnShelfID, columnToken, columnExpiresAt).
+		Values(share.ShelfID, share.Token, share.ExpiresAt).
+		Suffix("RETURNING " + columnID + ", " + columnCreatedAt).
+		ToSql()
+	if err != nil {
+		return model.ShelfShare{}, err
+	}
+	if err = p.pool.QueryRow(ctx, sql, args...).Scan(&share.ID, &share.CreatedAt); err != nil {
+		return model.ShelfShare{}, translateError(err)
+	}
+	return share, nil
+}
+
+func (p *ShelvesStorage) ListShares(ctx context.Context, shelfID uuid.UUID) ([]model.ShelfShare, error) {
+	sql, args, err := p.psql.Select(
+		columnID,
+		columnShelfID,
+		columnToken,
+		columnExpiresAt,
+		columnRevokedAt,
+		columnViews,
+		columnLastViewedAt,
+		columnCreatedAt,
+	).
+		From(tableShelfShares).
+		Where(squirrel.Eq{columnShelfID: shelfID}).
+		OrderBy(columnCreatedAt+" DESC", columnID).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]model.ShelfShare, 0)
+	for rows.Next() {
+		var share model.ShelfShare
+		if err = rows.Scan(
+			&share.ID,
+			&share.ShelfID,
+			&share.Token,
+			&share.ExpiresAt,
+			&share.RevokedAt,
+			&share.Views,
+			&share.LastViewedAt,
+			&share.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, share)
+	}
+	return list, rows.Err()
+}
+
+// RevokeShare keeps the time of the first revocation when the share is
+// revoked again.
+func (p *ShelvesStorage) RevokeShare(ctx context.Context, shelfID uuid.UUID, id uuid.UUID, now time.Time) error {
+	sql, args, err := p.psql.Update(tableShelfShares).
+		Set(columnRevokedAt, squirrel.Expr("COALESCE("+columnRevokedAt+", ?)", now)).
+		Where(squirrel.Eq{columnID: id, columnShelfID: shelfID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	tag, err := p.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrShareNotFound
+	}
+	return nil
+}
+
+// ViewShare counts the view of an active share and returns its shelf.
+// Revoked, expired and unknown tokens all come back as ErrShareNotFound.
+func (p *ShelvesStorage) ViewShare(ctx context.Context, token string, now time.Time) (model.Shelf, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return model.Shelf{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	sql, args, err := p.psql.Update(tableShelfShares).
+		Set(columnViews, squirrel.Expr(columnViews+" + 1")).
+		Set(columnLastViewedAt, now).
+		Where(squirrel.Eq{columnToken: token, columnRevokedAt: nil}).
+		Where(squirrel.Or{squirrel.Eq{columnExpiresAt: nil}, squirrel.Gt{columnExpiresAt: now}}).
+		Suffix("RETURNING " + columnShelfID).
+		ToSql()
+	if err != nil {
+		return model.Shelf{}, err
+	}
+	var shelfID uuid.UUID
+	if err = tx.QueryRow(ctx, sql, args...).Scan(&shelfID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Shelf{}, model.ErrShareNotFound
+		}
+		return model.Shelf{}, err
+	}
+	shelf, err := p.getShelfTx(ctx, tx, shelfID)
+	if err != nil {
+		return model.Shelf{}, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return model.Shelf{}, err
+	}
+	return shelf, nil
+}
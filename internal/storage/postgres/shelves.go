@@ -0,0 +1,347 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/shelves"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	tableShelves      = "shelves"
+	tableShelvesBooks = "shelves_books"
+
+	columnShelfID = "shelf_id"
+	columnAddedAt = "added_at"
+)
+
+type ShelvesStorage struct {
+	pool *pgxpool.Pool
+	psql squirrel.StatementBuilderType
+}
+
+func NewShelvesStorage(pool *pgxpool.Pool) *ShelvesStorage {
+	return &ShelvesStorage{
+		pool: pool,
+		psql: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+func (p *ShelvesStorage) ListShelves(ctx context.Context, userID uuid.UUID) ([]model.Shelf, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err = p.ensureBuiltinShelves(ctx, tx, userID); err != nil {
+		return nil, err
+	}
+
+	count := subquery.Select("count(*)").
+		From(tableShelvesBooks + " sb").
+		Where("sb." + columnShelfID + " = s." + columnID)
+	sql, args, err := p.psql.Select("s."+columnID, "s."+columnUserID, "s."+columnKind, "COALESCE(s."+columnName+", '')").
+		Column(squirrel.Alias(count, "books_count")).
+		Column("s."+columnCreatedAt).
+		From(tableShelves+" s").
+		Where(squirrel.Eq{"s." + columnUserID: userID}).
+		OrderBy(
+			"array_position(ARRAY['want-to-read', 'reading', 'read', 'custom']::varchar[], s."+columnKind+")",
+			"s."+columnCreatedAt,
+			"s."+columnID,
+		).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]model.Shelf, 0)
+	for rows.Next() {
+		var shelf model.Shelf
+		if err = rows.Scan(
+			&shelf.ID, &shelf.UserID, &shelf.Kind, &shelf.Name, &shelf.BooksCount, &shelf.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, shelf)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (p *ShelvesStorage) GetShelf(ctx context.Context, userID uuid.UUID, ref shelves.ShelfRef) (model.Shelf, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return model.Shelf{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	id, err := p.shelfID(ctx, tx, userID, ref)
+	if err != nil {
+		return model.Shelf{}, err
+	}
+	shelf, err := p.getShelfTx(ctx, tx, id)
+	if err != nil {
+		return model.Shelf{}, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return model.Shelf{}, err
+	}
+	return shelf, nil
+}
+
+func (p *ShelvesStorage) getShelfTx(ctx context.Context, tx pgx.Tx, id uuid.UUID) (model.Shelf, error) {
+	count := subquery.Select("count(*)").
+		From(tableShelvesBooks).
+		Where(columnShelfID + " = " + tableShelves + "." + columnID)
+	sql, args, err := p.psql.Select(columnID, columnUserID, columnKind, "COALESCE("+columnName+", '')").
+		Column(squirrel.Alias(count, "books_count")).
+		Column(columnCreatedAt).
+		From(tableShelves).
+		Where(squirrel.Eq{columnID: id}).
+		ToSql()
+	if err != nil {
+		return model.Shelf{}, err
+	}
+	var shelf model.Shelf
+	if err = tx.QueryRow(ctx, sql, args...).Scan(
+		&shelf.ID, &shelf.UserID, &shelf.Kind, &shelf.Name, &shelf.BooksCount, &shelf.CreatedAt,
+	); err != nil {
+		return model.Shelf{}, err
+	}
+	return shelf, nil
+}
+
+func (p *ShelvesStorage) AddShelf(ctx context.Context, userID uuid.UUID, name string) (uuid.UUID, error) {
+	sql, args, err := p.psql.Insert(tableShelves).
+		Columns(columnUserID, columnKind, columnName).
+		Values(userID, string(model.ShelfCustom), name).
+		Suffix("RETURNING " + columnID).
+		ToSql()
+	if err != nil {
+		return uuid.Nil, err
+	}
+	var id uuid.UUID
+	if err = p.pool.QueryRow(ctx, sql, args...).Scan(&id); err != nil {
+		return uuid.Nil, translateError(err)
+	}
+	return id, nil
+}
+
+func (p *ShelvesStorage) RenameShelf(ctx context.Context, userID uuid.UUID, id uuid.UUID, name string) error {
+	sql, args, err := p.psql.Update(tableShelves).
+		Set(columnName, name).
+		Where(squirrel.Eq{columnID: id, columnUserID: userID, columnKind: string(model.ShelfCustom)}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	tag, err := p.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return translateError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrShelfNotFound
+	}
+	return nil
+}
+
+func (p *ShelvesStorage) RemoveShelf(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	sql, args, err := p.psql.Delete(tableShelves).
+		Where(squirrel.Eq{columnID: id, columnUserID: userID, columnKind: string(model.ShelfCustom)}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	tag, err := p.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return translateError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrShelfNotFound
+	}
+	return nil
+}
+
+// AddShelfBook keeps the built-in shelves exclusive: moving a book to one of
+// them takes it off the other two.
+func (p *ShelvesStorage) AddShelfBook(
+	ctx context.Context,
+	userID uuid.UUID,
+	ref shelves.ShelfRef,
+	bookID uuid.UUID,
+) error {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	id, err := p.shelfID(ctx, tx, userID, ref)
+	if err != nil {
+		return err
+	}
+
+	if ref.Builtin() {
+		others := subquery.Select(columnID).
+			From(tableShelves).
+			Where(squirrel.Eq{columnUserID: userID}).
+			Where(squirrel.NotEq{columnKind: []string{string(model.ShelfCustom), string(ref.Kind)}})
+		sql, args, err := p.psql.Delete(tableShelvesBooks).
+			Where(squirrel.Eq{columnBookID: bookID}).
+			Where(squirrel.Expr(columnShelfID+" IN (?)", others)).
+			ToSql()
+		if err != nil {
+			return err
+		}
+		if _, err = tx.Exec(ctx, sql, args...); err != nil {
+			return err
+		}
+	}
+
+	sql, args, err := p.psql.Insert(tableShelvesBooks).
+		Columns(columnShelfID, columnBookID).
+		Values(id, bookID).
+		Suffix("ON CONFLICT DO NOTHING").
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err = tx.Exec(ctx, sql, args...); err != nil {
+		return translateError(err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+func (p *ShelvesStorage) RemoveShelfBook(
+	ctx context.Context,
+	userID uuid.UUID,
+	ref shelves.ShelfRef,
+	bookID uuid.UUID,
+) error {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	id, err := p.shelfID(ctx, tx, userID, ref)
+	if err != nil {
+		return err
+	}
+	sql, args, err := p.psql.Delete(tableShelvesBooks).
+		Where(squirrel.Eq{columnShelfID: id, columnBookID: bookID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrShelfBookNotFound
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (p *ShelvesStorage) ListShelfBooks(ctx context.Context, shelfID uuid.UUID) ([]model.ShelfBook, error) {
+	sql, args, err := p.psql.Select(columnBookID, columnAddedAt).
+		From(tableShelvesBooks).
+		Where(squirrel.Eq{columnShelfID: shelfID}).
+		OrderBy(columnAddedAt+" DESC", columnBookID).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]model.ShelfBook, 0)
+	for rows.Next() {
+		var entry model.ShelfBook
+		if err = rows.Scan(&entry.BookID, &entry.AddedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, entry)
+	}
+	return list, rows.Err()
+}
+
+func (p *ShelvesStorage) ensureBuiltinShelves(ctx context.Context, tx pgx.Tx, userID uuid.UUID) error {
+	insert := p.psql.Insert(tableShelves).Columns(columnUserID, columnKind)
+	for _, kind := range model.BuiltinShelves {
+		insert = insert.Values(userID, string(kind))
+	}
+	sql, args, err := insert.Suffix("ON CONFLICT DO NOTHING").ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, sql, args...)
+	return err
+}
+
+// shelfID resolves the reference to a shelf owned by the user, creating the
+// built-in shelf on first use.
+func (p *ShelvesStorage) shelfID(
+	ctx context.Context,
+	tx pgx.Tx,
+	userID uuid.UUID,
+	ref shelves.ShelfRef,
+) (uuid.UUID, error) {
+	var (
+		q   squirrel.Sqlizer
+		id  uuid.UUID
+		err error
+	)
+	if ref.Builtin() {
+		q = p.psql.Insert(tableShelves).
+			Columns(columnUserID, columnKind).
+			Values(userID, string(ref.Kind)).
+			Suffix(
+				"ON CONFLICT (" + columnUserID + ", " + columnKind + ") WHERE " + columnKind + " <> 'custom' " +
+					"DO UPDATE SET " + columnKind + " = EXCLUDED." + columnKind + " RETURNING " + columnID,
+			)
+	} else {
+		q = p.psql.Select(columnID).
+			From(tableShelves).
+			Where(squirrel.Eq{columnID: ref.ID, columnUserID: userID, columnKind: string(model.ShelfCustom)})
+	}
+	sql, args, err := q.ToSql()
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if err = tx.QueryRow(ctx, sql, args...).Scan(&id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, model.ErrShelfNotFound
+		}
+		return uuid.Nil, err
+	}
+	return id, nil
+}
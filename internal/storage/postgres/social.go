@@ -0,0 +1,302 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/social"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	tableFollows        = "follows"
+	tableActivityEvents = "activity_events"
+
+	columnPublicProfile = "public_profile"
+	columnFollowerID    = "follower_id"
+	columnFolloweeID    = "followee_id"
+	columnAcceptedAt    = "accepted_at"
+	columnRating        = "rating"
+)
+
+type SocialStorage struct {
+	pool *pgxpool.Pool
+	psql squirrel.StatementBuilderType
+}
+
+func NewSocialStorage(pool *pgxpool.Pool) *SocialStorage {
+	return &SocialStorage{
+		pool: pool,
+		psql: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+func (p *SocialStorage) GetProfile(ctx context.Context, userID uuid.UUID) (model.Profile, error) {
+	followers := subquery.Select("count(*)").
+		From(tableFollows).
+		Where(columnFolloweeID + " = " + tableUsers + "." + columnUserID).
+		Where(squirrel.Eq{columnStatus: string(model.FollowAccepted)})
+	following := subquery.Select("count(*)").
+		From(tableFollows).
+		Where(columnFollowerID + " = " + tableUsers + "." + columnUserID).
+		Where(squirrel.Eq{columnStatus: string(model.FollowAccepted)})
+	sql, args, err := p.psql.Select(columnUserID, columnPublicProfile).
+		Column(squirrel.Alias(followers, "followers")).
+		Column(squirrel.Alias(following, "following")).
+		From(tableUsers).
+		Where(squirrel.Eq{columnUserID: userID}).
+		ToSql()
+	if err != nil {
+		return model.Profile{}, err
+	}
+	var profile model.Profile
+	if err = p.pool.QueryRow(ctx, sql, args...).Scan(
+		&profile.UserID, &profile.Public, &profile.Followers, &profile.Following,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Profile{}, model.ErrProfileNotFound
+		}
+		return model.Profile{}, err
+	}
+	return profile, nil
+}
+
+// SetProfilePublic accepts the pending follow requests when the profile goes
+// public, nobody would be asked to approve them any more.
+func (p *SocialStorage) SetProfilePublic(ctx context.Context, userID uuid.UUID, public bool) error {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	sql, args, err := p.psql.Update(tableUsers).
+		Set(columnPublicProfile, public).
+		Where(squirrel.Eq{columnUserID: userID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrProfileNotFound
+	}
+
+	if public {
+		sql, args, err = p.psql.Update(tableFollows).
+			Set(columnStatus, string(model.FollowAccepted)).
+			Set(columnAcceptedAt, squirrel.Expr("CURRENT_TIMESTAMP")).
+			Where(squirrel.Eq{columnFolloweeID: userID, columnStatus: string(model.FollowPending)}).
+			ToSql()
+		if err != nil {
+			return err
+		}
+		if _, err = tx.Exec(ctx, sql, args...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Follow accepts the follow at once when the followee's profile is public
+// and leaves it pending otherwise. Following again keeps the existing follow.
+func (p *SocialStorage) Follow(ctx context.Context, followerID uuid.UUID, followeeID uuid.UUID) (model.Follow, error) {
+	followee := subquery.Select().
+		Column(squirrel.Expr("?::uuid", followerID)).
+		Column(columnUserID).
+		Column(
+			"CASE WHEN "+columnPublicProfile+" THEN ? ELSE ? END",
+			string(model.FollowAccepted), string(model.FollowPending),
+		).
+		Column("CASE WHEN " + columnPublicProfile + " THEN CURRENT_TIMESTAMP END").
+		From(tableUsers).
+		Where(squirrel.Eq{columnUserID: followeeID})
+	sql, args, err := p.psql.Insert(tableFollows).
+		Columns(columnFollowerID, columnFolloweeID, columnStatus, columnAcceptedAt).
+		Select(followee).
+		Suffix(
+			"ON CONFLICT (" + columnFollowerID + ", " + columnFolloweeID + ") DO UPDATE SET " +
+				columnStatus + " = " + tableFollows + "." + columnStatus + " " +
+				"RETURNING " + columnStatus + ", " + columnCreatedAt + ", " + columnAcceptedAt,
+		).
+		ToSql()
+	if err != nil {
+		return model.Follow{}, err
+	}
+	follow := model.Follow{FollowerID: followerID, FolloweeID: followeeID}
+	if err = p.pool.QueryRow(ctx, sql, args...).Scan(&follow.Status, &follow.CreatedAt, &follow.AcceptedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Follow{}, model.ErrProfileNotFound
+		}
+		return model.Follow{}, translateError(err)
+	}
+	return follow, nil
+}
+
+func (p *SocialStorage) RemoveFollow(ctx context.Context, followerID uuid.UUID, followeeID uuid.UUID) error {
+	sql, args, err := p.psql.Delete(tableFollows).
+		Where(squirrel.Eq{columnFollowerID: followerID, columnFolloweeID: followeeID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	tag, err := p.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrFollowNotFound
+	}
+	return nil
+}
+
+func (p *SocialStorage) AcceptFollow(ctx context.Context, followerID uuid.UUID, followeeID uuid.UUID) error {
+	sql, args, err := p.psql.Update(tableFollows).
+		Set(columnStatus, string(model.FollowAccepted)).
+		Set(columnAcceptedAt, squirrel.Expr("COALESCE("+columnAcceptedAt+", CURRENT_TIMESTAMP)")).
+		Where(squirrel.Eq{columnFollowerID: followerID, columnFolloweeID: followeeID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	tag, err := p.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrFollowNotFound
+	}
+	return nil
+}
+
+func (p *SocialStorage) ListFollowers(
+	ctx context.Context,
+	userID uuid.UUID,
+	parameters social.ListFollowsParameters,
+) ([]model.Follow, error) {
+	return p.listFollows(ctx, squirrel.Eq{columnFolloweeID: userID}, parameters)
+}
+
+func (p *SocialStorage) ListFollowing(
+	ctx context.Context,
+	userID uuid.UUID,
+	parameters social.ListFollowsParameters,
+) ([]model.Follow, error) {
+	return p.listFollows(ctx, squirrel.Eq{columnFollowerID: userID}, parameters)
+}
+
+func (p *SocialStorage) listFollows(
+	ctx context.Context,
+	where squirrel.Eq,
+	parameters social.ListFollowsParameters,
+) ([]model.Follow, error) {
+	q := p.psql.Select(columnFollowerID, columnFolloweeID, columnStatus, columnCreatedAt, columnAcceptedAt).
+		From(tableFollows).
+		Where(where).
+		OrderBy(columnCreatedAt+" DESC", columnFollowerID, columnFolloweeID).
+		Limit(parameters.Limit).
+		Offset(parameters.Offset)
+	if parameters.Status != nil {
+		q = q.Where(squirrel.Eq{columnStatus: string(*parameters.Status)})
+	}
+	sql, args, err := q.ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]model.Follow, 0)
+	for rows.Next() {
+		var follow model.Follow
+		if err = rows.Scan(
+			&follow.FollowerID, &follow.FolloweeID, &follow.Status, &follow.CreatedAt, &follow.AcceptedAt,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, follow)
+	}
+	return list, rows.Err()
+}
+
+// Feed lists the events of the users the user follows with an accepted
+// follow, newest first and strictly older than the cursor. Reviews that went
+// back to moderation after an edit stay out until approved again.
+func (p *SocialStorage) Feed(
+	ctx context.Context,
+	userID uuid.UUID,
+	cursor *social.FeedCursor,
+	limit uint64,
+) ([]model.ActivityEvent, error) {
+	q := p.psql.Select(
+		"e."+columnID,
+		"e."+columnUserID,
+		"e."+columnKind,
+		"e."+columnBookID,
+		"e."+columnShelfID,
+		"s."+columnKind,
+		"s."+columnName,
+		"e."+columnRating,
+		"e."+columnReviewID,
+		"e."+columnCreatedAt,
+	).
+		From(tableActivityEvents+" e").
+		Join(
+			tableFollows+" f ON f."+columnFolloweeID+" = e."+columnUserID+
+				" AND f."+columnFollowerID+" = ? AND f."+columnStatus+" = ?",
+			userID, string(model.FollowAccepted),
+		).
+		LeftJoin(tableShelves+" s ON s."+columnID+" = e."+columnShelfID).
+		LeftJoin(tableReviews+" r ON r."+columnID+" = e."+columnReviewID).
+		Where(
+			squirrel.Or{
+				squirrel.Eq{"e." + columnReviewID: nil},
+				squirrel.Eq{"r." + columnStatus: string(model.ReviewApproved)},
+			},
+		).
+		OrderBy("e."+columnCreatedAt+" DESC", "e."+columnID+" DESC").
+		Limit(limit)
+	if cursor != nil {
+		q = q.Where("(e."+columnCreatedAt+", e."+columnID+") < (?, ?)", cursor.CreatedAt, cursor.ID)
+	}
+	sql, args, err := q.ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]model.ActivityEvent, 0)
+	for rows.Next() {
+		var event model.ActivityEvent
+		if err = rows.Scan(
+			&event.ID,
+			&event.UserID,
+			&event.Kind,
+			&event.BookID,
+			&event.ShelfID,
+			&event.ShelfKind,
+			&event.ShelfName,
+			&event.Rating,
+			&event.ReviewID,
+			&event.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, event)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,326 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	tableReadingGoals = "reading_goals"
+	tableFinished     = "finished"
+
+	columnYear       = "year"
+	columnMonth      = "month"
+	columnBooks      = "books"
+	columnFinishedAt = "finished_at"
+
+	finishedYearExpr  = "EXTRACT(YEAR FROM " + columnFinishedAt + ")::integer"
+	finishedMonthExpr = "EXTRACT(MONTH FROM " + columnFinishedAt + ")::integer"
+)
+
+type StatsStorage struct {
+	pool *pgxpool.Pool
+	psql squirrel.StatementBuilderType
+}
+
+func NewStatsStorage(pool *pgxpool.Pool) *StatsStorage {
+	return &StatsStorage{
+		pool: pool,
+		psql: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+// withFinished prefixes the query with the books the user finished: put on
+// the read shelf or read to the end, whichever happened first. The page
+// reached is the length of the books the catalog has no pages for.
+func withFinished(q squirrel.SelectBuilder, userID uuid.UUID, year *int) squirrel.SelectBuilder {
+	shelved := subquery.Select(
+		"sb."+columnBookID,
+		"sb."+columnAddedAt+" AS "+columnFinishedAt,
+		"NULL::integer AS "+columnPage,
+	).
+		From(tableShelvesBooks + " sb").
+		Join(tableShelves + " s ON s." + columnID + " = sb." + columnShelfID).
+		Where(squirrel.Eq{"s." + columnUserID: userID, "s." + columnKind: string(model.ShelfRead)})
+	read := subquery.Select(columnBookID, columnUpdatedAt, columnPage).
+		From(tableReadingProgress).
+		Where(squirrel.Eq{columnUserID: userID}).
+		Where(squirrel.NotEq{columnBookID: nil}).
+		Where(squirrel.GtOrEq{columnFraction: 1})
+
+	finished := subquery.Select(
+		columnBookID,
+		"MIN("+columnFinishedAt+") AS "+columnFinishedAt,
+		"MAX("+columnPage+") AS "+columnPage,
+	).
+		FromSelect(shelved.SuffixExpr(squirrel.Expr("UNION ALL ?", read)), "f").
+		GroupBy(columnBookID)
+	if year != nil {
+		finished = finished.Having("EXTRACT(YEAR FROM MIN("+columnFinishedAt+")) = ?", *year)
+	}
+	return q.PrefixExpr(squirrel.Expr("WITH "+tableFinished+" AS (?)", finished))
+}
+
+// ReadingPeriods sums the finished books per month and per year, the year
+// rows have no month.
+func (p *StatsStorage) ReadingPeriods(
+	ctx context.Context,
+	userID uuid.UUID,
+	year *int,
+) ([]model.ReadingPeriod, error) {
+	finished := subquery.Select(
+		finishedYearExpr+" AS "+columnYear,
+		finishedMonthExpr+" AS "+columnMonth,
+		"COALESCE(b."+columnPages+", f."+columnPage+") AS "+columnPages,
+	).
+		From(tableFinished + " f").
+		Join(tableBooks + " b ON b." + columnID + " = f." + columnBookID)
+	q := p.psql.Select(columnYear, columnMonth, "COUNT(*)", "COALESCE(SUM("+columnPages+"), 0)").
+		FromSelect(finished, "p").
+		GroupBy("GROUPING SETS (("+columnYear+", "+columnMonth+"), ("+columnYear+"))").
+		OrderBy(columnYear, columnMonth+" NULLS FIRST")
+	sql, args, err := withFinished(q, userID, year).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	periods := make([]model.ReadingPeriod, 0)
+	for rows.Next() {
+		var period model.ReadingPeriod
+		if err = rows.Scan(&period.Year, &period.Month, &period.Books, &period.Pages); err != nil {
+			return nil, err
+		}
+		periods = append(periods, period)
+	}
+	return periods, rows.Err()
+}
+
+// FavouriteTags ranks the tags by the finished books, the user's ratings of
+// those books break ties. The same goes for FavouriteAuthors.
+func (p *StatsStorage) FavouriteTags(
+	ctx context.Context,
+	userID uuid.UUID,
+	year *int,
+	limit uint64,
+) ([]model.TagCount, error) {
+	q := p.psql.Select("t."+columnID, "t."+columnName, "COUNT(*)").
+		From(tableFinished + " f").
+		Join(tableBooksTags + " bt ON bt." + columnBookID + " = f." + columnBookID).
+		Join(tableTags + " t ON t." + columnID + " = bt." + columnTagID)
+	q = favourites(q, userID, "t."+columnID, "t."+columnName, limit)
+	sql, args, err := withFinished(q, userID, year).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]model.TagCount, 0)
+	for rows.Next() {
+		var count model.TagCount
+		if err = rows.Scan(&count.Tag.ID, &count.Tag.Name, &count.Books); err != nil {
+			return nil, err
+		}
+		list = append(list, count)
+	}
+	return list, rows.Err()
+}
+
+func (p *StatsStorage) FavouriteAuthors(
+	ctx context.Context,
+	userID uuid.UUID,
+	year *int,
+	limit uint64,
+) ([]model.AuthorCount, error) {
+	q := p.psql.Select(
+		"a."+columnID,
+		"a."+columnFirstName,
+		"a."+columnLastName,
+		"a."+columnMiddleName,
+		"a."+columnPseudonym,
+		"COUNT(*)",
+	).
+		From(tableFinished + " f").
+		Join(tableBooksAuthors + " ba ON ba." + columnBookID + " = f." + columnBookID).
+		Join(tableAuthors + " a ON a." + columnID + " = ba." + columnAuthorID)
+	q = favourites(q, userID, "a."+columnID, "a."+columnLastName, limit)
+	sql, args, err := withFinished(q, userID, year).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]model.AuthorCount, 0)
+	for rows.Next() {
+		var count model.AuthorCount
+		if err = rows.Scan(
+			&count.Author.ID,
+			&count.Author.FirstName,
+			&count.Author.LastName,
+			&count.Author.MiddleName,
+			&count.Author.Pseudonym,
+			&count.Books,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, count)
+	}
+	return list, rows.Err()
+}
+
+func favourites(
+	q squirrel.SelectBuilder,
+	userID uuid.UUID,
+	groupBy string,
+	orderBy string,
+	limit uint64,
+) squirrel.SelectBuilder {
+	return q.LeftJoin(
+		tableBookRatings+" r ON r."+columnBookID+" = f."+columnBookID+" AND r."+columnUserID+" = ?", userID,
+	).
+		GroupBy(groupBy).
+		OrderBy("COUNT(*) DESC", "AVG(r."+columnValue+") DESC NULLS LAST", orderBy, groupBy).
+		Limit(limit)
+}
+
+// RatingsGiven counts the ratings the user gave, in the year they were last
+// changed when the year is set.
+func (p *StatsStorage) RatingsGiven(ctx context.Context, userID uuid.UUID, year *int) (int, *float64, error) {
+	q := p.psql.Select("COUNT(*)", "AVG("+columnValue+")::double precision").
+		From(tableBookRatings).
+		Where(squirrel.Eq{columnUserID: userID})
+	if year != nil {
+		q = q.Where("EXTRACT(YEAR FROM "+columnUpdatedAt+") = ?", *year)
+	}
+	sql, args, err := q.ToSql()
+	if err != nil {
+		return 0, nil, err
+	}
+	var (
+		count   int
+		average *float64
+	)
+	if err = p.pool.QueryRow(ctx, sql, args...).Scan(&count, &average); err != nil {
+		return 0, nil, err
+	}
+	return count, average, nil
+}
+
+func (p *StatsStorage) SetGoal(ctx context.Context, goal model.ReadingGoal) (model.ReadingGoal, error) {
+	sql, args, err := p.psql.Insert(tableReadingGoals).
+		Columns(columnUserID, columnYear, columnBooks, columnPages).
+		Values(goal.UserID, goal.Year, goal.Books, goal.Pages).
+		Suffix(
+			"ON CONFLICT (" + columnUserID + ", " + columnYear + ") DO UPDATE SET " +
+				columnBooks + " = EXCLUDED." + columnBooks + ", " +
+				columnPages + " = EXCLUDED." + columnPages + ", " +
+				columnUpdatedAt + " = CURRENT_TIMESTAMP " +
+				"RETURNING " + columnCreatedAt + ", " + columnUpdatedAt,
+		).
+		ToSql()
+	if err != nil {
+		return model.ReadingGoal{}, err
+	}
+	if err = p.pool.QueryRow(ctx, sql, args...).Scan(&goal.CreatedAt, &goal.UpdatedAt); err != nil {
+		return model.ReadingGoal{}, translateError(err)
+	}
+	return goal, nil
+}
+
+func (p *StatsStorage) GetGoal(ctx context.Context, userID uuid.UUID, year int) (model.ReadingGoal, error) {
+	sql, args, err := p.selectGoals().Where(squirrel.Eq{columnUserID: userID, columnYear: year}).ToSql()
+	if err != nil {
+		return model.ReadingGoal{}, err
+	}
+	goal, err := scanGoal(p.pool.QueryRow(ctx, sql, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.ReadingGoal{}, model.ErrGoalNotFound
+		}
+		return model.ReadingGoal{}, err
+	}
+	return goal, nil
+}
+
+func (p *StatsStorage) ListGoals(ctx context.Context, userID uuid.UUID) ([]model.ReadingGoal, error) {
+	sql, args, err := p.selectGoals().
+		Where(squirrel.Eq{columnUserID: userID}).
+		OrderBy(columnYear + " DESC").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]model.ReadingGoal, 0)
+	for rows.Next() {
+		goal, err := scanGoal(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, goal)
+	}
+	return list, rows.Err()
+}
+
+func (p *StatsStorage) RemoveGoal(ctx context.Context, userID uuid.UUID, year int) error {
+	sql, args, err := p.psql.Delete(tableReadingGoals).
+		Where(squirrel.Eq{columnUserID: userID, columnYear: year}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	tag, err := p.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrGoalNotFound
+	}
+	return nil
+}
+
+func (p *StatsStorage) selectGoals() squirrel.SelectBuilder {
+	return p.psql.Select(
+		columnUserID,
+		columnYear,
+		columnBooks,
+		columnPages,
+		columnCreatedAt,
+		columnUpdatedAt,
+	).From(tableReadingGoals)
+}
+
+func scanGoal(row pgx.Row) (model.ReadingGoal, error) {
+	var goal model.ReadingGoal
+	err := row.Scan(
+		&goal.UserID,
+		&goal.Year,
+		&goal.Books,
+		&goal.Pages,
+		&goal.CreatedAt,
+		&goal.UpdatedAt,
+	)
+	return goal, err
+}
@@ -7,7 +7,6 @@ import (
 	"github.com/google/uuid"
 	"github.com/iamvkosarev/book-shelf/internal/model"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -34,14 +33,7 @@ func (p *TagsStorage) AddTag(ctx context.Context, name string) (uuid.UUID, error
 	}
 	var id uuid.UUID
 	if err = p.pool.QueryRow(ctx, sql, args...).Scan(&id); err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			switch pgErr.Code {
-			case "23505":
-				return uuid.Nil, model.ErrTagAlreadyExists
-			}
-		}
-		return uuid.Nil, err
+		return uuid.Nil, translateError(err)
 	}
 	return id, nil
 }
@@ -75,14 +67,7 @@ func (p *TagsStorage) UpdateTag(ctx context.Context, id uuid.UUID, tag model.Tag
 
 	ct, err := p.pool.Exec(ctx, sql, args...)
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			switch pgErr.Code {
-			case "23505":
-				return model.ErrTagAlreadyExists
-			}
-		}
-		return err
+		return translateError(err)
 	}
 
 	if ct.RowsAffected() == 0 {
@@ -92,19 +77,36 @@ func (p *TagsStorage) UpdateTag(ctx context.Context, id uuid.UUID, tag model.Tag
 	return nil
 }
 
-func (p *TagsStorage) RemoveTag(ctx context.Context, id uuid.UUID) error {
-	sql, args, err := p.psql.Delete(tableTags).Where(squirrel.Eq{columnID: id}).ToSql()
+func (p *TagsStorage) RemoveTag(ctx context.Context, id uuid.UUID, policy model.RemovePolicy) error {
+	tx, err := p.pool.Begin(ctx)
 	if err != nil {
 		return err
 	}
-	if _, err = p.pool.Exec(ctx, sql, args...); err != nil {
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err = removeWithLinkTablePolicy(
+		ctx, tx, tableTags, tableBooksTags, columnTagID, id, policy,
+		model.ErrTagNotFound, model.ErrTagHasBooks,
+	); err != nil {
 		return err
 	}
-	return nil
+
+	return tx.Commit(ctx)
 }
 
 func (p *TagsStorage) ListTags(ctx context.Context) ([]model.Tag, error) {
-	sql, args, err := p.psql.Select(columnID, columnName).From(tableTags).ToSql()
+	return p.queryTags(ctx, p.psql.Select(columnID, columnName).From(tableTags))
+}
+
+func (p *TagsStorage) ListTagsByIDs(ctx context.Context, ids []uuid.UUID) ([]model.Tag, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return p.queryTags(ctx, p.psql.Select(columnID, columnName).From(tableTags).Where(squirrel.Eq{columnID: ids}))
+}
+
+func (p *TagsStorage) queryTags(ctx context.Context, q squirrel.SelectBuilder) ([]model.Tag, error) {
+	sql, args, err := q.ToSql()
 	if err != nil {
 		return nil, err
 	}
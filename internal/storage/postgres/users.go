@@ -9,7 +9,6 @@ import (
 	"github.com/google/uuid"
 	"github.com/iamvkosarev/book-shelf/internal/model"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"strings"
 )
@@ -181,11 +180,7 @@ func (u *UserStorage) CreateUserByEmail(ctx context.Context, email string, hash
 	}
 
 	if _, err = tx.Exec(ctx, q2, a2...); err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
-			return uuid.Nil, model.ErrUserAlreadyExists
-		}
-		return uuid.Nil, fmt.Errorf("exec email_passes insert: %w", err)
+		return uuid.Nil, fmt.Errorf("exec email_passes insert: %w", translateError(err))
 	}
 
 	q3, a3, err := u.psql.
@@ -198,7 +193,7 @@ func (u *UserStorage) CreateUserByEmail(ctx context.Context, email string, hash
 	}
 
 	if _, err = tx.Exec(ctx, q3, a3...); err != nil {
-		return uuid.Nil, fmt.Errorf("exec granted_roles insert: %w", err)
+		return uuid.Nil, fmt.Errorf("exec granted_roles insert: %w", translateError(err))
 	}
 
 	if err = tx.Commit(ctx); err != nil {
@@ -19,14 +19,23 @@ type UpdateAuthorInput struct {
 	LastName   *string
 	MiddleName *string
 	Pseudonym  *string
+	Clear      ClearAuthorFields
+}
+
+type ClearAuthorFields struct {
+	FirstName  bool
+	LastName   bool
+	MiddleName bool
+	Pseudonym  bool
 }
 
 type AuthorsStorage interface {
 	AddAuthor(ctx context.Context, input AddAuthorInput) (uuid.UUID, error)
 	GetAuthor(ctx context.Context, id uuid.UUID) (model.Author, error)
 	UpdateAuthor(ctx context.Context, id uuid.UUID, input UpdateAuthorInput) error
-	RemoveAuthor(ctx context.Context, id uuid.UUID) error
+	RemoveAuthor(ctx context.Context, id uuid.UUID, policy model.RemovePolicy) error
 	ListAuthors(ctx context.Context) ([]model.Author, error)
+	ListAuthorsByIDs(ctx context.Context, ids []uuid.UUID) ([]model.Author, error)
 }
 
 type AuthorsUsecase struct {
@@ -74,6 +83,15 @@ func (p *AuthorsUsecase) UpdateAuthor(ctx context.Context, id uuid.UUID, input U
 	if input.Pseudonym != nil {
 		author.Pseudonym = input.Pseudonym
 	}
+	if input.Clear.FirstName {
+		author.FirstName = nil
+	}
+	if input.Clear.LastName {
+		author.LastName = nil
+	}
+	if input.Clear.Pseudonym {
+		author.Pseudonym = nil
+	}
 
 	if !hasIdentity(author.FirstName, author.LastName, author.Pseudonym) {
 		return model.ErrAuthorInvalidFields
@@ -86,8 +104,11 @@ func (p *AuthorsUsecase) UpdateAuthor(ctx context.Context, id uuid.UUID, input U
 	return nil
 }
 
-func (p *AuthorsUsecase) RemoveAuthor(ctx context.Context, id uuid.UUID) error {
-	err := p.storage.RemoveAuthor(ctx, id)
+func (p *AuthorsUsecase) RemoveAuthor(ctx context.Context, id uuid.UUID, policy model.RemovePolicy) error {
+	if err := validateRemovePolicy(id, policy); err != nil {
+		return err
+	}
+	err := p.storage.RemoveAuthor(ctx, id, policy)
 	if err != nil {
 		return fmt.Errorf("failed to remove author from storage: %w", err)
 	}
@@ -107,3 +128,11 @@ func hasIdentity(first, last, pseudonym *string) bool {
 		(last != nil && *last != "") ||
 		(pseudonym != nil && *pseudonym != "")
 }
+
+func (p *AuthorsUsecase) ListAuthorsByIDs(ctx context.Context, ids []uuid.UUID) ([]model.Author, error) {
+	authors, err := p.storage.ListAuthorsByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list Authors by ids from storage: %w", err)
+	}
+	return authors, nil
+}
@@ -0,0 +1,116 @@
+package backup
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	ArchiveFormat = "book-shelf-backup"
+	// ArchiveVersion 2 leaves out the mark of unrated books, version 1 wrote
+	// the former column default 0 for them. Version 3 adds the ratings of the
+	// users to archives that include users.
+	ArchiveVersion = 3
+
+	fileManifest   = "manifest.json"
+	filePublishers = "publishers.json"
+	fileAuthors    = "authors.json"
+	fileTags       = "tags.json"
+	fileBooks      = "books.json"
+	fileUsers      = "users.json"
+	fileRatings    = "ratings.json"
+)
+
+type Manifest struct {
+	Format        string    `json:"format"`
+	Version       int       `json:"version"`
+	SchemaVersion uint      `json:"schema_version"`
+	CreatedAt     time.Time `json:"created_at"`
+	IncludesUsers bool      `json:"includes_users"`
+	Counts        Counts    `json:"counts"`
+}
+
+// Counts of a restore also report SkippedLinks, the author and tag links of
+// restored books whose target is in neither the archive nor the database.
+type Counts struct {
+	Publishers   int `json:"publishers"`
+	Authors      int `json:"authors"`
+	Tags         int `json:"tags"`
+	Books        int `json:"books"`
+	Users        int `json:"users"`
+	Ratings      int `json:"ratings"`
+	SkippedLinks int `json:"skipped_links,omitempty"`
+}
+
+type Publisher struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+type Author struct {
+	ID         uuid.UUID `json:"id"`
+	FirstName  *string   `json:"first_name,omitempty"`
+	MiddleName *string   `json:"middle_name,omitempty"`
+	LastName   *string   `json:"last_name,omitempty"`
+	Pseudonym  *string   `json:"pseudonym,omitempty"`
+}
+
+type Tag struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+type Book struct {
+	ID          uuid.UUID   `json:"id"`
+	PublisherID *uuid.UUID  `json:"publisher_id,omitempty"`
+	AuthorsIDs  []uuid.UUID `json:"authors_ids"`
+	TagsIDs     []uuid.UUID `json:"tags_ids"`
+	PublishedAt *string     `json:"published_at,omitempty"`
+	Title       string      `json:"title"`
+	Description *string     `json:"description,omitempty"`
+	Price       *float64    `json:"price,omitempty"`
+	Pages       *int32      `json:"pages,omitempty"`
+	Mark        *int16      `json:"mark,omitempty"`
+	ISBN        *string     `json:"isbn,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+type User struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Email     *string   `json:"email,omitempty"`
+	PassHash  []byte    `json:"pass_hash,omitempty"`
+	Roles     []string  `json:"roles"`
+}
+
+// Rating is the rating a user gave a book, the legacy rating without a user
+// is kept in Book.Mark.
+type Rating struct {
+	BookID    uuid.UUID `json:"book_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Value     int16     `json:"value"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Snapshot is the whole catalog as stored in an archive, one slice per file.
+type Snapshot struct {
+	Publishers []Publisher
+	Authors    []Author
+	Tags       []Tag
+	Books      []Book
+	Users      []User
+	Ratings    []Rating
+}
+
+func (s Snapshot) counts() Counts {
+	return Counts{
+		Publishers: len(s.Publishers),
+		Authors:    len(s.Authors),
+		Tags:       len(s.Tags),
+		Books:      len(s.Books),
+		Users:      len(s.Users),
+		Ratings:    len(s.Ratings),
+	}
+}
@@ -0,0 +1,195 @@
+package backup
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"io"
+	"time"
+)
+
+type ConflictStrategy string
+
+const (
+	ConflictFail      ConflictStrategy = "fail"
+	ConflictSkip      ConflictStrategy = "skip"
+	ConflictOverwrite ConflictStrategy = "overwrite"
+)
+
+func ParseConflictStrategy(raw string) (ConflictStrategy, error) {
+	switch strategy := ConflictStrategy(raw); strategy {
+	case "":
+		return ConflictFail, nil
+	case ConflictFail, ConflictSkip, ConflictOverwrite:
+		return strategy, nil
+	default:
+		return "", model.ErrBackupInvalidConflict
+	}
+}
+
+type Storage interface {
+	SchemaVersion(ctx context.Context) (uint, error)
+	Snapshot(ctx context.Context, includeUsers bool) (Snapshot, error)
+	Restore(ctx context.Context, snapshot Snapshot, strategy ConflictStrategy, callerID uuid.UUID) (Counts, error)
+}
+
+type BackupUsecase struct {
+	storage Storage
+}
+
+func NewBackupUsecase(storage Storage) *BackupUsecase {
+	return &BackupUsecase{
+		storage: storage,
+	}
+}
+
+type RestoreReport struct {
+	Manifest Manifest
+	Restored Counts
+}
+
+func (u *BackupUsecase) Backup(ctx context.Context, writer io.Writer, includeUsers bool) (Manifest, error) {
+	version, err := u.storage.SchemaVersion(ctx)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	snapshot, err := u.storage.Snapshot(ctx, includeUsers)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to read catalog snapshot: %w", err)
+	}
+
+	manifest := Manifest{
+		Format:        ArchiveFormat,
+		Version:       ArchiveVersion,
+		SchemaVersion: version,
+		CreatedAt:     time.Now().UTC(),
+		IncludesUsers: includeUsers,
+		Counts:        snapshot.counts(),
+	}
+
+	archive := zip.NewWriter(writer)
+	files := []struct {
+		name     string
+		document any
+	}{
+		{fileManifest, manifest},
+		{filePublishers, snapshot.Publishers},
+		{fileAuthors, snapshot.Authors},
+		{fileTags, snapshot.Tags},
+		{fileBooks, snapshot.Books},
+	}
+	if includeUsers {
+		files = append(files, []struct {
+			name     string
+			document any
+		}{
+			{fileUsers, snapshot.Users},
+			{fileRatings, snapshot.Ratings},
+		}...)
+	}
+	for _, file := range files {
+		entry, err := archive.Create(file.name)
+		if err != nil {
+			return Manifest{}, err
+		}
+		encoder := json.NewEncoder(entry)
+		encoder.SetIndent("", "  ")
+		if err = encoder.Encode(file.document); err != nil {
+			return Manifest{}, fmt.Errorf("failed to write %s: %w", file.name, err)
+		}
+	}
+	if err = archive.Close(); err != nil {
+		return Manifest{}, err
+	}
+	return manifest, nil
+}
+
+// Restore loads an archive into the database. callerID is the user running
+// the restore, uuid.Nil from the command line: their account is kept as it is
+// and does not make the database count as not empty.
+func (u *BackupUsecase) Restore(
+	ctx context.Context,
+	reader io.ReaderAt,
+	size int64,
+	strategy ConflictStrategy,
+	callerID uuid.UUID,
+) (RestoreReport, error) {
+	archive, err := zip.NewReader(reader, size)
+	if err != nil {
+		return RestoreReport{}, model.ErrBackupInvalidArchive
+	}
+
+	var manifest Manifest
+	if err = readDocument(archive, fileManifest, &manifest); err != nil {
+		return RestoreReport{}, err
+	}
+	if manifest.Format != ArchiveFormat {
+		return RestoreReport{}, model.ErrBackupInvalidArchive
+	}
+	if manifest.Version > ArchiveVersion {
+		return RestoreReport{}, model.ErrBackupUnsupportedVersion
+	}
+	version, err := u.storage.SchemaVersion(ctx)
+	if err != nil {
+		return RestoreReport{}, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if manifest.SchemaVersion > version {
+		return RestoreReport{}, model.ErrBackupUnsupportedVersion.WithDetails(
+			fmt.Sprintf("archive schema version %d is newer than database version %d", manifest.SchemaVersion, version),
+		)
+	}
+
+	var snapshot Snapshot
+	documents := []struct {
+		name   string
+		target any
+	}{
+		{filePublishers, &snapshot.Publishers},
+		{fileAuthors, &snapshot.Authors},
+		{fileTags, &snapshot.Tags},
+		{fileBooks, &snapshot.Books},
+	}
+	for _, document := range documents {
+		if err = readDocument(archive, document.name, document.target); err != nil {
+			return RestoreReport{}, err
+		}
+	}
+	if manifest.Version < 2 {
+		for i, book := range snapshot.Books {
+			if book.Mark != nil && *book.Mark == 0 {
+				snapshot.Books[i].Mark = nil
+			}
+		}
+	}
+	if manifest.IncludesUsers {
+		if err = readDocument(archive, fileUsers, &snapshot.Users); err != nil {
+			return RestoreReport{}, err
+		}
+		if manifest.Version >= 3 {
+			if err = readDocument(archive, fileRatings, &snapshot.Ratings); err != nil {
+				return RestoreReport{}, err
+			}
+		}
+	}
+
+	restored, err := u.storage.Restore(ctx, snapshot, strategy, callerID)
+	if err != nil {
+		return RestoreReport{}, fmt.Errorf("failed to restore snapshot: %w", err)
+	}
+	return RestoreReport{Manifest: manifest, Restored: restored}, nil
+}
+
+func readDocument(archive *zip.Reader, name string, target any) error {
+	file, err := archive.Open(name)
+	if err != nil {
+		return model.ErrBackupInvalidArchive.WithDetails(fmt.Sprintf("missing %s", name))
+	}
+	defer file.Close()
+	if err = json.NewDecoder(file).Decode(target); err != nil {
+		return model.ErrBackupInvalidArchive.WithDetails(fmt.Sprintf("%s: %v", name, err))
+	}
+	return nil
+}
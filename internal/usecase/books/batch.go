@@ -0,0 +1,241 @@
+package books
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"strings"
+)
+
+type BatchOperationKind string
+
+const (
+	BatchCreate BatchOperationKind = "create"
+	BatchUpdate BatchOperationKind = "update"
+	BatchDelete BatchOperationKind = "delete"
+)
+
+type BatchOperation struct {
+	Kind   BatchOperationKind
+	ID     uuid.UUID
+	Create CreateBookInput
+	Update UpdateBookPatch
+}
+
+type BatchItemResult struct {
+	ID  uuid.UUID
+	Err error
+}
+
+func (p *BooksUsecase) ApplyBatch(
+	ctx context.Context,
+	operations []BatchOperation,
+	atomic bool,
+) ([]BatchItemResult, bool, error) {
+	results := make([]BatchItemResult, len(operations))
+	for i := range operations {
+		results[i].ID = operations[i].ID
+		results[i].Err = validateBatchOperation(&operations[i])
+	}
+
+	if err := p.validateBatchReferences(ctx, operations, results); err != nil {
+		return nil, false, err
+	}
+	if err := p.checkBatchDuplicates(ctx, operations, results); err != nil {
+		return nil, false, err
+	}
+
+	var (
+		validOperations []BatchOperation
+		validIndexes    []int
+	)
+	for i, operation := range operations {
+		if results[i].Err == nil {
+			validOperations = append(validOperations, operation)
+			validIndexes = append(validIndexes, i)
+		}
+	}
+
+	if atomic && len(validOperations) < len(operations) {
+		for i := range results {
+			if results[i].Err == nil {
+				results[i].Err = model.ErrBatchRolledBack
+			}
+		}
+		return results, false, nil
+	}
+	if len(validOperations) == 0 {
+		return results, false, nil
+	}
+
+	applied, err := p.booksStorage.ApplyBatch(ctx, validOperations, atomic)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to apply batch in storage: %w", err)
+	}
+
+	committed := false
+	for i, result := range applied {
+		results[validIndexes[i]] = result
+		if result.Err == nil {
+			committed = true
+		}
+	}
+	return results, committed, nil
+}
+
+func validateBatchOperation(operation *BatchOperation) error {
+	var err error
+	switch operation.Kind {
+	case BatchCreate:
+		if strings.TrimSpace(operation.Create.Title) == "" {
+			return model.ErrBookTitleRequired.WithFields("title")
+		}
+		operation.Create.ISBN, err = normalizeISBNPtr(operation.Create.ISBN)
+		return err
+	case BatchUpdate:
+		if operation.ID == uuid.Nil {
+			return model.ErrBookIDRequired
+		}
+		operation.Update.ISBN, err = normalizeISBNPtr(operation.Update.ISBN)
+		return err
+	case BatchDelete:
+		if operation.ID == uuid.Nil {
+			return model.ErrBookIDRequired
+		}
+	default:
+		return model.ErrBatchUnknownOperation
+	}
+	return nil
+}
+
+func (p *BooksUsecase) validateBatchReferences(
+	ctx context.Context,
+	operations []BatchOperation,
+	results []BatchItemResult,
+) error {
+	var publishersIDs, authorsIDs, tagsIDs []uuid.UUID
+	for i, operation := range operations {
+		if results[i].Err != nil {
+			continue
+		}
+		publisherID, operationAuthorsIDs, operationTagsIDs := batchReferences(operation)
+		if hasID(publisherID) {
+			publishersIDs = append(publishersIDs, *publisherID)
+		}
+		authorsIDs = append(authorsIDs, operationAuthorsIDs...)
+		tagsIDs = append(tagsIDs, operationTagsIDs...)
+	}
+
+	publishers, err := p.publishersUsecase.ListPublishersByIDs(ctx, uniqueIDs(publishersIDs))
+	if err != nil {
+		return fmt.Errorf("failed to validate publishers: %w", err)
+	}
+	authors, err := p.authorsUsecase.ListAuthorsByIDs(ctx, uniqueIDs(authorsIDs))
+	if err != nil {
+		return fmt.Errorf("failed to validate authors: %w", err)
+	}
+	tags, err := p.tagsUsecase.ListTagsByIDs(ctx, uniqueIDs(tagsIDs))
+	if err != nil {
+		return fmt.Errorf("failed to validate tags: %w", err)
+	}
+
+	existing := make(map[uuid.UUID]struct{}, len(publishers)+len(authors)+len(tags))
+	for _, publisher := range publishers {
+		existing[publisher.ID] = struct{}{}
+	}
+	for _, author := range authors {
+		existing[author.ID] = struct{}{}
+	}
+	for _, tag := range tags {
+		existing[tag.ID] = struct{}{}
+	}
+
+	for i, operation := range operations {
+		if results[i].Err != nil {
+			continue
+		}
+		publisherID, operationAuthorsIDs, operationTagsIDs := batchReferences(operation)
+		if hasID(publisherID) && !containsID(existing, *publisherID) {
+			results[i].Err = model.ErrPublisherNotFound.WithFields("publisher_id")
+			continue
+		}
+		for _, id := range operationAuthorsIDs {
+			if !containsID(existing, id) {
+				results[i].Err = model.ErrAuthorNotFound.WithFields("authors_ids")
+				break
+			}
+		}
+		if results[i].Err != nil {
+			continue
+		}
+		for _, id := range operationTagsIDs {
+			if !containsID(existing, id) {
+				results[i].Err = model.ErrTagNotFound.WithFields("tags_ids")
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// checkBatchDuplicates applies the likely-duplicate check of AddBook to the
+// valid creates of the batch.
+func (p *BooksUsecase) checkBatchDuplicates(
+	ctx context.Context,
+	operations []BatchOperation,
+	results []BatchItemResult,
+) error {
+	var (
+		inputs  []CreateBookInput
+		indexes []int
+	)
+	for i, operation := range operations {
+		if results[i].Err == nil && operation.Kind == BatchCreate {
+			inputs = append(inputs, operation.Create)
+			indexes = append(indexes, i)
+		}
+	}
+	duplicates, err := p.CheckDuplicates(ctx, inputs)
+	if err != nil {
+		return err
+	}
+	for i, candidates := range duplicates {
+		if candidates.Empty() {
+			continue
+		}
+		for j, item := range candidates.CandidatesItems {
+			candidates.CandidatesItems[j] = indexes[item]
+		}
+		results[indexes[i]].Err = model.ErrBookLikelyDuplicate.WithDetails(candidates)
+	}
+	return nil
+}
+
+func batchReferences(operation BatchOperation) (*uuid.UUID, []uuid.UUID, []uuid.UUID) {
+	switch operation.Kind {
+	case BatchCreate:
+		return operation.Create.PublisherID, operation.Create.AuthorsIDs, operation.Create.TagsIDs
+	case BatchUpdate:
+		return operation.Update.PublisherID, operation.Update.AuthorsIDs, operation.Update.TagsIDs
+	}
+	return nil, nil, nil
+}
+
+func uniqueIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]struct{}, len(ids))
+	result := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		result = append(result, id)
+	}
+	return result
+}
+
+func containsID(set map[uuid.UUID]struct{}, id uuid.UUID) bool {
+	_, ok := set[id]
+	return ok
+}
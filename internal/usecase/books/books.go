@@ -16,7 +16,9 @@ type CreateBookInput struct {
 	PublishedAt *time.Time
 	Description *string
 	Price       *float64
-	Mark        *int16
+	Pages       *int32
+	ISBN        *string
+	Force       bool
 }
 
 type UpdateBookPatch struct {
@@ -27,11 +29,45 @@ type UpdateBookPatch struct {
 	Title       *string
 	Description *string
 	Price       *float64
-	Mark        *int16
+	Pages       *int32
+	ISBN        *string
+	Clear       ClearBookFields
 }
 
+type ClearBookFields struct {
+	PublisherID bool
+	PublishedAt bool
+	Description bool
+	Price       bool
+	Pages       bool
+	ISBN        bool
+}
+
+func (c ClearBookFields) Any() bool {
+	return c.PublisherID || c.PublishedAt || c.Description || c.Price || c.Pages || c.ISBN
+}
+
+type BooksOrder string
+
+const (
+	BooksOrderDefault BooksOrder = ""
+	BooksOrderTitle   BooksOrder = "title"
+	BooksOrderNewest  BooksOrder = "newest"
+)
+
 type ListBookParameters struct {
-	AuthorsIDs []uuid.UUID
+	IDs         []uuid.UUID
+	AuthorsIDs  []uuid.UUID
+	TagsIDs     []uuid.UUID
+	PublisherID *uuid.UUID
+	// ISBNs and Titles match any of the listed values, titles are compared
+	// after NormalizeTitle.
+	ISBNs  []string
+	Titles []string
+	Query  string
+	Order  BooksOrder
+	Limit  uint64
+	Offset uint64
 }
 
 type BooksStorage interface {
@@ -40,16 +76,22 @@ type BooksStorage interface {
 	UpdateBook(ctx context.Context, id uuid.UUID, patch UpdateBookPatch) error
 	RemoveBook(ctx context.Context, id uuid.UUID) error
 	ListBooks(ctx context.Context, parameters ListBookParameters) ([]model.Book, error)
+	ListBooksWithRepeatedTitles(ctx context.Context) ([]model.Book, error)
+	FillNormalizedTitles(ctx context.Context) (int, error)
+	ApplyBatch(ctx context.Context, operations []BatchOperation, atomic bool) ([]BatchItemResult, error)
 }
 
 type AuthorsUsecase interface {
 	GetAuthor(ctx context.Context, id uuid.UUID) (model.Author, error)
+	ListAuthorsByIDs(ctx context.Context, ids []uuid.UUID) ([]model.Author, error)
 }
 type PublishersUsecase interface {
 	GetPublisher(ctx context.Context, id uuid.UUID) (model.Publisher, error)
+	ListPublishersByIDs(ctx context.Context, ids []uuid.UUID) ([]model.Publisher, error)
 }
 type TagsUsecase interface {
 	GetTag(ctx context.Context, id uuid.UUID) (model.Tag, error)
+	ListTagsByIDs(ctx context.Context, ids []uuid.UUID) ([]model.Tag, error)
 }
 
 type BooksUsecase struct {
@@ -73,6 +115,12 @@ func NewBooksUsecase(
 	}
 }
 func (p *BooksUsecase) AddBook(ctx context.Context, input CreateBookInput) (uuid.UUID, error) {
+	isbn, err := normalizeISBNPtr(input.ISBN)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	input.ISBN = isbn
+
 	if input.PublisherID != nil && *input.PublisherID != uuid.Nil {
 		if _, err := p.publishersUsecase.GetPublisher(ctx, *input.PublisherID); err != nil {
 			return uuid.Nil, fmt.Errorf("failed to validate publisher: %w", err)
@@ -89,6 +137,16 @@ func (p *BooksUsecase) AddBook(ctx context.Context, input CreateBookInput) (uuid
 		}
 	}
 
+	if !input.Force {
+		candidates, err := p.findDuplicateCandidates(ctx, input)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to check duplicates: %w", err)
+		}
+		if len(candidates) > 0 {
+			return uuid.Nil, model.ErrBookLikelyDuplicate.WithDetails(DuplicateCandidates{CandidatesIDs: candidates})
+		}
+	}
+
 	id, err := p.booksStorage.AddBook(ctx, input)
 	if err != nil {
 		return uuid.Nil, fmt.Errorf("failed to add book to storage: %w", err)
@@ -144,6 +202,12 @@ func (p *BooksUsecase) GetBook(
 }
 
 func (p *BooksUsecase) UpdateBook(ctx context.Context, id uuid.UUID, patch UpdateBookPatch) error {
+	isbn, err := normalizeISBNPtr(patch.ISBN)
+	if err != nil {
+		return err
+	}
+	patch.ISBN = isbn
+
 	if patch.PublisherID != nil && *patch.PublisherID != uuid.Nil {
 		if _, err := p.publishersUsecase.GetPublisher(ctx, *patch.PublisherID); err != nil {
 			return fmt.Errorf("failed to validate publisher: %w", err)
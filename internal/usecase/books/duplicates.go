@@ -0,0 +1,252 @@
+package books
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DuplicateCandidates lists the existing books an input likely duplicates
+// and, in a batch, the indexes of the earlier creates it likely duplicates.
+type DuplicateCandidates struct {
+	CandidatesIDs   []uuid.UUID `json:"candidates_ids,omitempty"`
+	CandidatesItems []int       `json:"candidates_items,omitempty"`
+}
+
+func (c DuplicateCandidates) Empty() bool {
+	return len(c.CandidatesIDs) == 0 && len(c.CandidatesItems) == 0
+}
+
+type duplicateProbe struct {
+	title       string
+	authorsIDs  []uuid.UUID
+	publisherID *uuid.UUID
+	publishedAt *time.Time
+}
+
+// listSameTitleBooks looks up the books sharing a normalised title with any of
+// the inputs in one query and groups them by that title.
+func (p *BooksUsecase) listSameTitleBooks(
+	ctx context.Context,
+	inputs []CreateBookInput,
+) (map[string][]model.Book, error) {
+	var titles []string
+	seen := make(map[string]struct{}, len(inputs))
+	for _, input := range inputs {
+		title := NormalizeTitle(input.Title)
+		if _, ok := seen[title]; ok || title == "" {
+			continue
+		}
+		seen[title] = struct{}{}
+		titles = append(titles, title)
+	}
+	byTitle := make(map[string][]model.Book, len(titles))
+	if len(titles) == 0 {
+		return byTitle, nil
+	}
+
+	sameTitleBooks, err := p.booksStorage.ListBooks(ctx, ListBookParameters{Titles: titles})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list books with the same titles: %w", err)
+	}
+	for _, book := range sameTitleBooks {
+		title := NormalizeTitle(book.Title)
+		byTitle[title] = append(byTitle[title], book)
+	}
+	return byTitle, nil
+}
+
+func duplicateCandidates(probe duplicateProbe, sameTitleBooks []model.Book) []uuid.UUID {
+	var candidates []uuid.UUID
+	for _, book := range sameTitleBooks {
+		if isLikelyDuplicate(probe, bookProbe(book)) {
+			candidates = append(candidates, book.ID)
+		}
+	}
+	return candidates
+}
+
+func (p *BooksUsecase) findDuplicateCandidates(ctx context.Context, input CreateBookInput) ([]uuid.UUID, error) {
+	byTitle, err := p.listSameTitleBooks(ctx, []CreateBookInput{input})
+	if err != nil {
+		return nil, err
+	}
+	probe := inputProbe(input)
+	return duplicateCandidates(probe, byTitle[probe.title]), nil
+}
+
+// CheckDuplicates runs the likely-duplicate check of AddBook on creates that
+// are applied together, with one query for all the titles. An input is also
+// checked against the earlier inputs that pass the check, CandidatesItems
+// holds their indexes in inputs. Forced inputs are not checked.
+func (p *BooksUsecase) CheckDuplicates(ctx context.Context, inputs []CreateBookInput) ([]DuplicateCandidates, error) {
+	byTitle, err := p.listSameTitleBooks(ctx, inputs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check duplicates: %w", err)
+	}
+
+	duplicates := make([]DuplicateCandidates, len(inputs))
+	probes := make([]duplicateProbe, len(inputs))
+	for i, input := range inputs {
+		probes[i] = inputProbe(input)
+		if input.Force {
+			continue
+		}
+		duplicates[i].CandidatesIDs = duplicateCandidates(probes[i], byTitle[probes[i].title])
+		for j := 0; j < i; j++ {
+			if duplicates[j].Empty() && isLikelyDuplicate(probes[i], probes[j]) {
+				duplicates[i].CandidatesItems = append(duplicates[i].CandidatesItems, j)
+			}
+		}
+	}
+	return duplicates, nil
+}
+
+func (p *BooksUsecase) ListDuplicates(ctx context.Context) ([][]model.Book, error) {
+	books, err := p.booksStorage.ListBooksWithRepeatedTitles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list books with repeated titles from storage: %w", err)
+	}
+
+	byTitle := make(map[string][]model.Book)
+	var titles []string
+	for _, book := range books {
+		title := NormalizeTitle(book.Title)
+		if _, ok := byTitle[title]; !ok {
+			titles = append(titles, title)
+		}
+		byTitle[title] = append(byTitle[title], book)
+	}
+
+	var clusters [][]model.Book
+	for _, title := range titles {
+		clusters = append(clusters, clusterDuplicates(byTitle[title])...)
+	}
+	return clusters, nil
+}
+
+// FillNormalizedTitles stores the normalized title of the books written
+// before the storage kept it, the duplicate checks do not see them until then.
+func (p *BooksUsecase) FillNormalizedTitles(ctx context.Context) (int, error) {
+	filled, err := p.booksStorage.FillNormalizedTitles(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fill normalized titles in storage: %w", err)
+	}
+	return filled, nil
+}
+
+func clusterDuplicates(books []model.Book) [][]model.Book {
+	parents := make([]int, len(books))
+	for i := range parents {
+		parents[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parents[i] != i {
+			parents[i] = find(parents[i])
+		}
+		return parents[i]
+	}
+
+	for i := range books {
+		for j := i + 1; j < len(books); j++ {
+			if isLikelyDuplicate(bookProbe(books[i]), bookProbe(books[j])) {
+				parents[find(j)] = find(i)
+			}
+		}
+	}
+
+	groups := make(map[int][]model.Book)
+	var roots []int
+	for i, book := range books {
+		root := find(i)
+		if _, ok := groups[root]; !ok {
+			roots = append(roots, root)
+		}
+		groups[root] = append(groups[root], book)
+	}
+
+	var clusters [][]model.Book
+	for _, root := range roots {
+		if len(groups[root]) > 1 {
+			clusters = append(clusters, groups[root])
+		}
+	}
+	return clusters
+}
+
+func inputProbe(input CreateBookInput) duplicateProbe {
+	return duplicateProbe{
+		title:       NormalizeTitle(input.Title),
+		authorsIDs:  input.AuthorsIDs,
+		publisherID: input.PublisherID,
+		publishedAt: input.PublishedAt,
+	}
+}
+
+func bookProbe(book model.Book) duplicateProbe {
+	return duplicateProbe{
+		title:       NormalizeTitle(book.Title),
+		authorsIDs:  book.AuthorsIDs,
+		publisherID: book.PublisherID,
+		publishedAt: book.PublishedAt,
+	}
+}
+
+// isLikelyDuplicate requires equal normalised titles and treats the other
+// attributes as conflicting only when both sides have them set.
+func isLikelyDuplicate(a, b duplicateProbe) bool {
+	if a.title == "" || a.title != b.title {
+		return false
+	}
+	if len(a.authorsIDs) > 0 && len(b.authorsIDs) > 0 && !sameIDsSet(a.authorsIDs, b.authorsIDs) {
+		return false
+	}
+	if hasID(a.publisherID) && hasID(b.publisherID) && *a.publisherID != *b.publisherID {
+		return false
+	}
+	if a.publishedAt != nil && b.publishedAt != nil && a.publishedAt.Year() != b.publishedAt.Year() {
+		return false
+	}
+	return true
+}
+
+func NormalizeTitle(title string) string {
+	var builder strings.Builder
+	pendingSpace := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if pendingSpace && builder.Len() > 0 {
+				builder.WriteByte(' ')
+			}
+			pendingSpace = false
+			builder.WriteRune(r)
+			continue
+		}
+		pendingSpace = true
+	}
+	return builder.String()
+}
+
+func sameIDsSet(a, b []uuid.UUID) bool {
+	set := make(map[uuid.UUID]struct{}, len(a))
+	for _, id := range a {
+		set[id] = struct{}{}
+	}
+	other := make(map[uuid.UUID]struct{}, len(b))
+	for _, id := range b {
+		if _, ok := set[id]; !ok {
+			return false
+		}
+		other[id] = struct{}{}
+	}
+	return len(set) == len(other)
+}
+
+func hasID(id *uuid.UUID) bool {
+	return id != nil && *id != uuid.Nil
+}
@@ -0,0 +1,88 @@
+package books
+
+import (
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"strings"
+)
+
+func NormalizeISBN(raw string) (string, bool) {
+	var digits strings.Builder
+	for _, r := range raw {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == 'x' || r == 'X':
+			digits.WriteRune('X')
+		case r == '-' || r == ' ' || r == '=' || r == '"':
+		default:
+			return "", false
+		}
+	}
+
+	isbn := digits.String()
+	switch len(isbn) {
+	case 10:
+		if !validISBN10(isbn) {
+			return "", false
+		}
+		return isbn10To13(isbn), true
+	case 13:
+		if !validISBN13(isbn) {
+			return "", false
+		}
+		return isbn, true
+	}
+	return "", false
+}
+
+func normalizeISBNPtr(raw *string) (*string, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	isbn, ok := NormalizeISBN(*raw)
+	if !ok {
+		return nil, model.ErrBookInvalidISBN
+	}
+	return &isbn, nil
+}
+
+func validISBN10(isbn string) bool {
+	sum := 0
+	for i, r := range isbn {
+		var digit int
+		switch {
+		case r == 'X' && i == 9:
+			digit = 10
+		case r >= '0' && r <= '9':
+			digit = int(r - '0')
+		default:
+			return false
+		}
+		sum += digit * (10 - i)
+	}
+	return sum%11 == 0
+}
+
+func validISBN13(isbn string) bool {
+	if strings.ContainsRune(isbn, 'X') {
+		return false
+	}
+	return isbn13CheckDigit(isbn[:12]) == isbn[12]
+}
+
+func isbn10To13(isbn string) string {
+	prefix := "978" + isbn[:9]
+	return prefix + string(isbn13CheckDigit(prefix))
+}
+
+func isbn13CheckDigit(prefix string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		digit := int(prefix[i] - '0')
+		if i%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return byte('0' + (10-sum%10)%10)
+}
@@ -0,0 +1,318 @@
+package exports
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/books"
+	"golang.org/x/text/unicode/norm"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type CitationFormat string
+
+const (
+	CitationBibTeX  CitationFormat = "bibtex"
+	CitationRIS     CitationFormat = "ris"
+	CitationCSLJSON CitationFormat = "csl-json"
+
+	risLineEnd = "\r\n"
+)
+
+var citationStopWords = map[string]struct{}{
+	"a": {}, "an": {}, "the": {}, "on": {}, "of": {}, "in": {}, "and": {}, "to": {}, "for": {},
+}
+
+var bibtexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	"{", `\{`,
+	"}", `\}`,
+	"&", `\&`,
+	"%", `\%`,
+	"$", `\$`,
+	"#", `\#`,
+	"_", `\_`,
+	"~", `\textasciitilde{}`,
+	"^", `\textasciicircum{}`,
+)
+
+type Citation struct {
+	Key  string
+	Book model.Book
+}
+
+func (f CitationFormat) Valid() bool {
+	switch f {
+	case CitationBibTeX, CitationRIS, CitationCSLJSON:
+		return true
+	}
+	return false
+}
+
+func (u *ExportUsecase) CiteBook(ctx context.Context, id uuid.UUID, format CitationFormat) ([]byte, error) {
+	if !format.Valid() {
+		return nil, model.ErrInvalidCitationFormat
+	}
+	book, err := u.getBook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return FormatCitations(Cite([]model.Book{book}), format)
+}
+
+func (u *ExportUsecase) CiteBooks(
+	ctx context.Context,
+	parameters books.ListBookParameters,
+	format CitationFormat,
+) ([]byte, error) {
+	if !format.Valid() {
+		return nil, model.ErrInvalidCitationFormat
+	}
+	list, err := u.booksUsecase.ListBooks(ctx, parameters, true, true, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list books: %w", err)
+	}
+	return FormatCitations(Cite(list), format)
+}
+
+// Cite assigns every book a citation key of the form "herbert1965dune-5c1a",
+// the suffix is a short hash of the book id that tells apart the books with
+// the same author, year and title word. The key depends on the book alone, so
+// it is the same in every list the book is cited from.
+func Cite(list []model.Book) []Citation {
+	citations := make([]Citation, len(list))
+	for i, book := range list {
+		citations[i] = Citation{Key: citationKey(book), Book: book}
+	}
+	return citations
+}
+
+func FormatCitations(citations []Citation, format CitationFormat) ([]byte, error) {
+	switch format {
+	case CitationBibTeX:
+		return []byte(formatBibTeX(citations)), nil
+	case CitationRIS:
+		return []byte(formatRIS(citations)), nil
+	case CitationCSLJSON:
+		return formatCSLJSON(citations)
+	}
+	return nil, model.ErrInvalidCitationFormat
+}
+
+func citationKey(book model.Book) string {
+	var key strings.Builder
+	if len(book.Authors) > 0 {
+		family, _ := authorNameParts(book.Authors[0])
+		if family == "" {
+			family = derefString(book.Authors[0].Pseudonym)
+		}
+		key.WriteString(keyPart(firstWordOf(family)))
+	}
+	if book.PublishedAt != nil {
+		key.WriteString(strconv.Itoa(book.PublishedAt.Year()))
+	}
+	for _, word := range strings.Fields(book.Title) {
+		part := keyPart(word)
+		if _, stop := citationStopWords[part]; part != "" && !stop {
+			key.WriteString(part)
+			break
+		}
+	}
+	if key.Len() == 0 {
+		key.WriteString("book")
+	}
+	hash := fnv.New32a()
+	_, _ = hash.Write(book.ID[:])
+	fmt.Fprintf(&key, "-%04x", hash.Sum32()>>16)
+	return key.String()
+}
+
+func keyPart(s string) string {
+	var builder strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(s)) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
+
+func firstWordOf(s string) string {
+	if fields := strings.Fields(s); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+// authorNameParts splits an author into family and given names. Authors known
+// only by a pseudonym have no parts and are cited by the pseudonym as a whole.
+func authorNameParts(author model.Author) (family, given string) {
+	given = joinNonEmpty(" ", derefString(author.FirstName), derefString(author.MiddleName))
+	family = strings.TrimSpace(derefString(author.LastName))
+	if family == "" && given != "" {
+		return given, ""
+	}
+	return family, given
+}
+
+func formatBibTeX(citations []Citation) string {
+	var builder strings.Builder
+	for i, citation := range citations {
+		if i > 0 {
+			builder.WriteString("\n")
+		}
+		book := citation.Book
+		fmt.Fprintf(&builder, "@book{%s,\n", citation.Key)
+		field := func(name, value string) {
+			if value != "" {
+				fmt.Fprintf(&builder, "  %s = {%s},\n", name, value)
+			}
+		}
+
+		authors := make([]string, 0, len(book.Authors))
+		for _, author := range book.Authors {
+			family, given := authorNameParts(author)
+			switch {
+			case family == "":
+				authors = append(authors, "{"+bibtexEscaper.Replace(derefString(author.Pseudonym))+"}")
+			case given == "":
+				authors = append(authors, "{"+bibtexEscaper.Replace(family)+"}")
+			default:
+				authors = append(authors, bibtexEscaper.Replace(family)+", "+bibtexEscaper.Replace(given))
+			}
+		}
+		field("author", strings.Join(authors, " and "))
+		field("title", "{"+bibtexEscaper.Replace(book.Title)+"}")
+		if book.Publisher != nil {
+			field("publisher", bibtexEscaper.Replace(book.Publisher.Name))
+		}
+		if book.PublishedAt != nil {
+			field("year", strconv.Itoa(book.PublishedAt.Year()))
+			if !isYearOnly(*book.PublishedAt) {
+				field("month", strings.ToLower(book.PublishedAt.Month().String()[:3]))
+			}
+		}
+		field("isbn", derefString(book.ISBN))
+		tags := make([]string, len(book.Tags))
+		for i, tag := range book.Tags {
+			tags[i] = bibtexEscaper.Replace(tag.Name)
+		}
+		field("keywords", strings.Join(tags, ", "))
+		builder.WriteString("}\n")
+	}
+	return builder.String()
+}
+
+func formatRIS(citations []Citation) string {
+	var builder strings.Builder
+	for _, citation := range citations {
+		book := citation.Book
+		field := func(tag, value string) {
+			if value = strings.Join(strings.Fields(value), " "); value != "" {
+				builder.WriteString(tag + "  - " + value + risLineEnd)
+			}
+		}
+
+		field("TY", "BOOK")
+		field("ID", citation.Key)
+		for _, author := range book.Authors {
+			family, given := authorNameParts(author)
+			if family == "" {
+				field("AU", derefString(author.Pseudonym))
+				continue
+			}
+			field("AU", joinNonEmpty(", ", family, given))
+		}
+		field("TI", book.Title)
+		if book.PublishedAt != nil {
+			field("PY", strconv.Itoa(book.PublishedAt.Year()))
+			if !isYearOnly(*book.PublishedAt) {
+				field("DA", book.PublishedAt.Format("2006/01/02"))
+			}
+		}
+		if book.Publisher != nil {
+			field("PB", book.Publisher.Name)
+		}
+		field("SN", derefString(book.ISBN))
+		field("AB", derefString(book.Description))
+		for _, tag := range book.Tags {
+			field("KW", tag.Name)
+		}
+		builder.WriteString("ER  - " + risLineEnd)
+	}
+	return builder.String()
+}
+
+type cslName struct {
+	Family  string `json:"family,omitempty"`
+	Given   string `json:"given,omitempty"`
+	Literal string `json:"literal,omitempty"`
+}
+
+type cslDate struct {
+	DateParts [][]int `json:"date-parts"`
+}
+
+type cslItem struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Title     string    `json:"title"`
+	Author    []cslName `json:"author,omitempty"`
+	Publisher string    `json:"publisher,omitempty"`
+	Issued    *cslDate  `json:"issued,omitempty"`
+	ISBN      string    `json:"ISBN,omitempty"`
+	Abstract  string    `json:"abstract,omitempty"`
+	Keyword   string    `json:"keyword,omitempty"`
+}
+
+func formatCSLJSON(citations []Citation) ([]byte, error) {
+	items := make([]cslItem, len(citations))
+	for i, citation := range citations {
+		book := citation.Book
+		item := cslItem{
+			ID:       citation.Key,
+			Type:     "book",
+			Title:    book.Title,
+			ISBN:     derefString(book.ISBN),
+			Abstract: derefString(book.Description),
+		}
+		for _, author := range book.Authors {
+			family, given := authorNameParts(author)
+			if family == "" {
+				item.Author = append(item.Author, cslName{Literal: derefString(author.Pseudonym)})
+				continue
+			}
+			item.Author = append(item.Author, cslName{Family: family, Given: given})
+		}
+		if book.Publisher != nil {
+			item.Publisher = book.Publisher.Name
+		}
+		if book.PublishedAt != nil {
+			date := *book.PublishedAt
+			item.Issued = &cslDate{DateParts: [][]int{{date.Year(), int(date.Month()), date.Day()}}}
+			if isYearOnly(date) {
+				item.Issued.DateParts = [][]int{{date.Year()}}
+			}
+		}
+		tags := make([]string, len(book.Tags))
+		for i, tag := range book.Tags {
+			tags[i] = tag.Name
+		}
+		item.Keyword = strings.Join(tags, ", ")
+		items[i] = item
+	}
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(items); err != nil {
+		return nil, err
+	}
+	return body.Bytes(), nil
+}
@@ -0,0 +1,64 @@
+package exports
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/books"
+	"strings"
+)
+
+type BooksUsecase interface {
+	GetBook(
+		ctx context.Context,
+		id uuid.UUID,
+		expandAuthors bool,
+		expandTags bool,
+		expandPublisher bool,
+	) (model.Book, error)
+	ListBooks(
+		ctx context.Context,
+		parameters books.ListBookParameters,
+		expandAuthors, expandTags, expandPublisher bool,
+	) ([]model.Book, error)
+}
+
+type ExportUsecase struct {
+	booksUsecase BooksUsecase
+	currencyCode string
+}
+
+// NewExportUsecase takes the ISO 4217 code of the currency the book prices
+// are kept in.
+func NewExportUsecase(booksUsecase BooksUsecase, currencyCode string) *ExportUsecase {
+	return &ExportUsecase{
+		booksUsecase: booksUsecase,
+		currencyCode: currencyCode,
+	}
+}
+
+func (u *ExportUsecase) getBook(ctx context.Context, id uuid.UUID) (model.Book, error) {
+	book, err := u.booksUsecase.GetBook(ctx, id, true, true, true)
+	if err != nil {
+		return model.Book{}, fmt.Errorf("failed to get book: %w", err)
+	}
+	return book, nil
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func joinNonEmpty(separator string, parts ...string) string {
+	var nonEmpty []string
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return strings.Join(nonEmpty, separator)
+}
@@ -0,0 +1,100 @@
+package exports
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/pkg/marc"
+	"time"
+)
+
+const (
+	// 264 second indicator "1" marks a publication statement.
+	marcProductionPublication = '1'
+	// 650 second indicator "4" marks a subject heading without a named source.
+	marcSubjectUnspecified = '4'
+)
+
+func (u *ExportUsecase) BookMARC(ctx context.Context, id uuid.UUID) (marc.Record, error) {
+	book, err := u.getBook(ctx, id)
+	if err != nil {
+		return marc.Record{}, err
+	}
+	return BookToMARC(book), nil
+}
+
+func BookToMARC(book model.Book) marc.Record {
+	record := marc.NewRecord()
+	record.AddControlField(marc.TagControlNumber, book.ID.String())
+
+	if book.ISBN != nil {
+		record.AddDataField(marc.TagISBN, ' ', ' ', marc.Subfield{Code: 'a', Value: *book.ISBN})
+	}
+
+	titleInd1 := byte('0')
+	for i, author := range book.Authors {
+		tag := marc.TagAddedAuthor
+		if i == 0 {
+			tag = marc.TagMainAuthor
+			titleInd1 = '1'
+		}
+		heading, ind1 := MARCAuthorHeading(author)
+		subfields := []marc.Subfield{{Code: 'a', Value: heading}}
+		if isPseudonymHeading(author) {
+			subfields = append(subfields, marc.Subfield{Code: 'c', Value: marc.QualifierPseudonym})
+		}
+		record.AddDataField(tag, ind1, ' ', subfields...)
+	}
+
+	record.AddDataField(marc.TagTitle, titleInd1, '0', marc.Subfield{Code: 'a', Value: book.Title})
+
+	var publication []marc.Subfield
+	if book.Publisher != nil {
+		publication = append(publication, marc.Subfield{Code: 'b', Value: book.Publisher.Name})
+	}
+	if book.PublishedAt != nil {
+		publication = append(publication, marc.Subfield{Code: 'c', Value: MARCDate(*book.PublishedAt)})
+	}
+	if len(publication) > 0 {
+		record.AddDataField(marc.TagProduction, ' ', marcProductionPublication, publication...)
+	}
+
+	if book.Description != nil && *book.Description != "" {
+		record.AddDataField(marc.TagSummary, ' ', ' ', marc.Subfield{Code: 'a', Value: *book.Description})
+	}
+	for _, tag := range book.Tags {
+		record.AddDataField(marc.TagSubject, ' ', marcSubjectUnspecified, marc.Subfield{Code: 'a', Value: tag.Name})
+	}
+	return record
+}
+
+// MARCAuthorHeading returns the inverted "Surname, Forename" heading and the
+// matching first indicator: 1 for a surname entry, 0 for a forename or pseudonym.
+func MARCAuthorHeading(author model.Author) (string, byte) {
+	forenames := joinNonEmpty(" ", derefString(author.FirstName), derefString(author.MiddleName))
+	if lastName := derefString(author.LastName); lastName != "" {
+		return joinNonEmpty(", ", lastName, forenames), '1'
+	}
+	if forenames != "" {
+		return forenames, '0'
+	}
+	return derefString(author.Pseudonym), '0'
+}
+
+func isPseudonymHeading(author model.Author) bool {
+	return derefString(author.LastName) == "" && derefString(author.FirstName) == "" &&
+		derefString(author.MiddleName) == "" && derefString(author.Pseudonym) != ""
+}
+
+func MARCDate(date time.Time) string {
+	if isYearOnly(date) {
+		return date.Format("2006")
+	}
+	return date.Format("2006-01-02")
+}
+
+// isYearOnly reports a date known only by its year, such dates are stored as
+// the first of January.
+func isYearOnly(date time.Time) bool {
+	return date.Month() == time.January && date.Day() == 1
+}
@@ -0,0 +1,151 @@
+package exports
+
+import (
+	"context"
+	"fmt"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/books"
+	"github.com/iamvkosarev/book-shelf/pkg/onix"
+	"strconv"
+	"time"
+)
+
+const (
+	ONIXSenderName     = "Book Shelf"
+	onixRecordPrefix   = "book-shelf:"
+	onixSentTimeLayout = "20060102T1504Z"
+)
+
+func (u *ExportUsecase) ONIXFeed(ctx context.Context, parameters books.ListBookParameters) (onix.Message, error) {
+	list, err := u.booksUsecase.ListBooks(ctx, parameters, true, true, true)
+	if err != nil {
+		return onix.Message{}, fmt.Errorf("failed to list books: %w", err)
+	}
+	return BooksToONIX(list, time.Now(), u.currencyCode), nil
+}
+
+func BooksToONIX(list []model.Book, sentAt time.Time, currencyCode string) onix.Message {
+	message := onix.Message{
+		Header: onix.Header{
+			Sender:       onix.Sender{SenderName: ONIXSenderName},
+			SentDateTime: sentAt.UTC().Format(onixSentTimeLayout),
+		},
+		Products: make([]onix.Product, len(list)),
+	}
+	for i, book := range list {
+		message.Products[i] = bookToONIXProduct(book, currencyCode)
+	}
+	return message
+}
+
+func bookToONIXProduct(book model.Book, currencyCode string) onix.Product {
+	product := onix.Product{
+		RecordReference:  onixRecordPrefix + book.ID.String(),
+		NotificationType: onix.NotificationConfirmed,
+		ProductIdentifiers: []onix.ProductIdentifier{
+			{ProductIDType: onix.ProductIDTypeProprietary, IDValue: book.ID.String()},
+		},
+		DescriptiveDetail: &onix.DescriptiveDetail{
+			ProductComposition: onix.ProductCompositionSingleItem,
+			ProductForm:        onix.ProductFormBook,
+			TitleDetails: []onix.TitleDetail{
+				{
+					TitleType: onix.TitleTypeDistinctive,
+					TitleElements: []onix.TitleElement{
+						{TitleElementLevel: onix.TitleElementProduct, TitleText: book.Title},
+					},
+				},
+			},
+		},
+	}
+	if book.ISBN != nil {
+		product.ProductIdentifiers = append(
+			product.ProductIdentifiers,
+			onix.ProductIdentifier{ProductIDType: onix.ProductIDTypeISBN13, IDValue: *book.ISBN},
+		)
+	}
+
+	for i, author := range book.Authors {
+		contributor := onix.Contributor{
+			SequenceNumber:   i + 1,
+			ContributorRoles: []string{onix.ContributorRoleAuthor},
+		}
+		family, given := authorNameParts(author)
+		switch {
+		case family == "":
+			contributor.PersonName = derefString(author.Pseudonym)
+			contributor.KeyNames = contributor.PersonName
+		case author.LastName == nil || *author.LastName == "":
+			contributor.PersonName = family
+			contributor.KeyNames = family
+		default:
+			contributor.PersonName = joinNonEmpty(" ", given, family)
+			contributor.PersonNameInverted = joinNonEmpty(", ", family, given)
+			contributor.NamesBeforeKey = given
+			contributor.KeyNames = family
+		}
+		product.DescriptiveDetail.Contributors = append(product.DescriptiveDetail.Contributors, contributor)
+	}
+	for _, tag := range book.Tags {
+		product.DescriptiveDetail.Subjects = append(
+			product.DescriptiveDetail.Subjects,
+			onix.Subject{SubjectSchemeIdentifier: onix.SubjectSchemeKeywords, SubjectHeadingText: tag.Name},
+		)
+	}
+
+	if book.Description != nil && *book.Description != "" {
+		product.CollateralDetail = &onix.CollateralDetail{
+			TextContents: []onix.TextContent{
+				{
+					TextType:        onix.TextTypeDescription,
+					ContentAudience: onix.ContentAudienceAny,
+					Text:            onix.Text{Value: *book.Description},
+				},
+			},
+		}
+	}
+
+	if book.Publisher != nil || book.PublishedAt != nil {
+		product.PublishingDetail = &onix.PublishingDetail{}
+		if book.Publisher != nil {
+			product.PublishingDetail.Publishers = []onix.Publisher{
+				{PublishingRole: onix.PublishingRolePublisher, PublisherName: book.Publisher.Name},
+			}
+		}
+		if book.PublishedAt != nil {
+			product.PublishingDetail.PublishingDates = []onix.PublishingDate{
+				{
+					PublishingDateRole: onix.PublishingDateRoleDate,
+					Date: onix.Date{
+						DateFormat: onix.DateFormatYearMonthDay,
+						Value:      book.PublishedAt.Format("20060102"),
+					},
+				},
+			}
+		}
+	}
+
+	if book.Price != nil {
+		product.ProductSupplies = []onix.ProductSupply{
+			{
+				SupplyDetails: []onix.SupplyDetail{
+					{
+						Supplier: onix.Supplier{
+							SupplierRole: onix.SupplierRolePublisher,
+							SupplierName: ONIXSenderName,
+						},
+						ProductAvailability: onix.ProductAvailabilityCheck,
+						Prices: []onix.Price{
+							{
+								PriceType:    onix.PriceTypeRRPIncludingTax,
+								PriceAmount:  strconv.FormatFloat(*book.Price, 'f', 2, 64),
+								CurrencyCode: currencyCode,
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	return product
+}
@@ -0,0 +1,80 @@
+package exports
+
+import (
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/pkg/onix"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const onixSchemaPath = "testdata/onix/ONIX_BookProduct_3.0_reference_subset.xsd"
+
+// TestBooksToONIXValidates checks the exported feed against the schema with
+// xmllint, the standard library has no XSD validator.
+func TestBooksToONIXValidates(t *testing.T) {
+	xmllint, err := exec.LookPath("xmllint")
+	if err != nil {
+		t.Skip("xmllint is not installed")
+	}
+
+	message := BooksToONIX(onixTestBooks(), time.Date(2024, time.March, 5, 14, 30, 0, 0, time.UTC), "EUR")
+	path := filepath.Join(t.TempDir(), "feed.xml")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = onix.Write(file, message); err != nil {
+		t.Fatal(err)
+	}
+	if err = file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := exec.Command(xmllint, "--noout", "--schema", onixSchemaPath, path).CombinedOutput()
+	if err != nil {
+		t.Fatalf("feed does not validate: %v\n%s", err, output)
+	}
+
+	for _, product := range message.Products {
+		for _, supply := range product.ProductSupplies {
+			for _, detail := range supply.SupplyDetails {
+				for _, price := range detail.Prices {
+					if price.CurrencyCode != "EUR" {
+						t.Errorf("%s: price currency = %q, want EUR", product.RecordReference, price.CurrencyCode)
+					}
+				}
+			}
+		}
+	}
+}
+
+func onixTestBooks() []model.Book {
+	text := func(s string) *string { return &s }
+	publishedAt := time.Date(1965, time.August, 1, 0, 0, 0, 0, time.UTC)
+	price := 12.5
+	return []model.Book{
+		{
+			ID:    uuid.MustParse("3f2b8c1d-7a4e-4b6f-9c2d-1e5a7b3c9d40"),
+			Title: "Dune",
+			ISBN:  text("9780441172719"),
+			Authors: []model.Author{
+				{FirstName: text("Frank"), MiddleName: text("Patrick"), LastName: text("Herbert")},
+				{FirstName: text("Augustine")},
+				{Pseudonym: text("Anonymous")},
+			},
+			Tags:        []model.Tag{{Name: "Science fiction"}, {Name: "Desert & ecology"}},
+			Publisher:   &model.Publisher{Name: "Chilton Books"},
+			PublishedAt: &publishedAt,
+			Description: text("A desert planet <and> its spice."),
+			Price:       &price,
+		},
+		{
+			ID:    uuid.MustParse("8a1c5e2f-3b7d-4c9a-a6e0-2f4d8b1c7e53"),
+			Title: "Untitled",
+		},
+	}
+}
@@ -0,0 +1,327 @@
+package harvest
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/books"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	SetTag       = "tag"
+	SetPublisher = "publisher"
+
+	setSeparator = ":"
+	PageSize     = 100
+)
+
+type Header struct {
+	ID        uuid.UUID
+	Datestamp time.Time
+	Deleted   bool
+	Sets      []string
+}
+
+type Record struct {
+	Header
+	Book *model.Book
+}
+
+type Set struct {
+	Spec string
+	Name string
+}
+
+type SetFilter struct {
+	Kind string
+	ID   *uuid.UUID
+}
+
+type HeaderParameters struct {
+	From           *time.Time
+	Until          *time.Time
+	Set            *SetFilter
+	AfterDatestamp *time.Time
+	AfterID        uuid.UUID
+	Limit          uint64
+}
+
+type Storage interface {
+	ListHeaders(ctx context.Context, parameters HeaderParameters) ([]Header, error)
+	GetDeletedBook(ctx context.Context, id uuid.UUID) (Header, error)
+	EarliestDatestamp(ctx context.Context) (time.Time, error)
+}
+
+type BooksUsecase interface {
+	GetBook(
+		ctx context.Context,
+		id uuid.UUID,
+		expandAuthors bool,
+		expandTags bool,
+		expandPublisher bool,
+	) (model.Book, error)
+	ListBooks(
+		ctx context.Context,
+		parameters books.ListBookParameters,
+		expandAuthors, expandTags, expandPublisher bool,
+	) ([]model.Book, error)
+}
+
+type TagsUsecase interface {
+	ListTags(ctx context.Context) ([]model.Tag, error)
+}
+
+type PublishersUsecase interface {
+	ListPublishers(ctx context.Context) ([]model.Publisher, error)
+}
+
+type HarvestUsecaseDeps struct {
+	Storage           Storage
+	BooksUsecase      BooksUsecase
+	TagsUsecase       TagsUsecase
+	PublishersUsecase PublishersUsecase
+}
+
+type HarvestUsecase struct {
+	HarvestUsecaseDeps
+}
+
+func NewHarvestUsecase(deps HarvestUsecaseDeps) *HarvestUsecase {
+	return &HarvestUsecase{
+		HarvestUsecaseDeps: deps,
+	}
+}
+
+// ListParameters bounds a harvest. From is inclusive and Until is exclusive.
+type ListParameters struct {
+	From  *time.Time
+	Until *time.Time
+	Set   string
+}
+
+type Page struct {
+	Records []Record
+	Cursor  int
+	Next    string
+}
+
+type resumptionToken struct {
+	From      *time.Time `json:"f,omitempty"`
+	Until     *time.Time `json:"u,omitempty"`
+	Set       string     `json:"s,omitempty"`
+	Datestamp time.Time  `json:"d"`
+	ID        uuid.UUID  `json:"i"`
+	Cursor    int        `json:"c"`
+}
+
+func (u *HarvestUsecase) EarliestDatestamp(ctx context.Context) (time.Time, error) {
+	earliest, err := u.Storage.EarliestDatestamp(ctx)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get earliest datestamp: %w", err)
+	}
+	return earliest, nil
+}
+
+func (u *HarvestUsecase) ListSets(ctx context.Context) ([]Set, error) {
+	tags, err := u.TagsUsecase.ListTags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	publishers, err := u.PublishersUsecase.ListPublishers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list publishers: %w", err)
+	}
+
+	sets := []Set{{Spec: SetTag, Name: "Tags"}, {Spec: SetPublisher, Name: "Publishers"}}
+	for _, tag := range tags {
+		sets = append(sets, Set{Spec: setSpec(SetTag, tag.ID), Name: tag.Name})
+	}
+	for _, publisher := range publishers {
+		sets = append(sets, Set{Spec: setSpec(SetPublisher, publisher.ID), Name: publisher.Name})
+	}
+	return sets, nil
+}
+
+func (u *HarvestUsecase) GetRecord(ctx context.Context, id uuid.UUID) (Record, error) {
+	book, err := u.BooksUsecase.GetBook(ctx, id, true, true, true)
+	if err == nil {
+		return Record{Header: bookHeader(book, book.UpdatedAt), Book: &book}, nil
+	}
+	if !errors.Is(err, model.ErrBookNotFound) {
+		return Record{}, err
+	}
+
+	header, err := u.Storage.GetDeletedBook(ctx, id)
+	if err != nil {
+		return Record{}, fmt.Errorf("failed to get deleted book: %w", err)
+	}
+	return Record{Header: header}, nil
+}
+
+// ListRecords returns one page of records in datestamp order. When token is
+// set it replaces parameters; expand controls whether book metadata is loaded.
+func (u *HarvestUsecase) ListRecords(
+	ctx context.Context,
+	parameters ListParameters,
+	token string,
+	expand bool,
+) (Page, error) {
+	var state resumptionToken
+	if token != "" {
+		decoded, err := decodeToken(token)
+		if err != nil {
+			return Page{}, err
+		}
+		state = decoded
+		parameters = ListParameters{From: state.From, Until: state.Until, Set: state.Set}
+	}
+
+	set, err := parseSet(parameters.Set)
+	if err != nil {
+		return Page{}, err
+	}
+	headerParameters := HeaderParameters{
+		From:    parameters.From,
+		Until:   parameters.Until,
+		Set:     set,
+		AfterID: state.ID,
+		Limit:   PageSize + 1,
+	}
+	if token != "" {
+		headerParameters.AfterDatestamp = &state.Datestamp
+	}
+
+	headers, err := u.Storage.ListHeaders(ctx, headerParameters)
+	if err != nil {
+		return Page{}, fmt.Errorf("failed to list record headers: %w", err)
+	}
+	if len(headers) == 0 {
+		if token != "" {
+			return Page{Cursor: state.Cursor}, nil
+		}
+		return Page{}, model.ErrHarvestNoRecordsMatch
+	}
+
+	page := Page{Cursor: state.Cursor}
+	if len(headers) > PageSize {
+		headers = headers[:PageSize]
+		last := headers[len(headers)-1]
+		page.Next = encodeToken(
+			resumptionToken{
+				From:      parameters.From,
+				Until:     parameters.Until,
+				Set:       parameters.Set,
+				Datestamp: last.Datestamp,
+				ID:        last.ID,
+				Cursor:    state.Cursor + len(headers),
+			},
+		)
+	}
+
+	page.Records, err = u.loadRecords(ctx, headers, expand)
+	if err != nil {
+		return Page{}, err
+	}
+	return page, nil
+}
+
+func (u *HarvestUsecase) loadRecords(ctx context.Context, headers []Header, expand bool) ([]Record, error) {
+	var ids []uuid.UUID
+	for _, header := range headers {
+		if !header.Deleted {
+			ids = append(ids, header.ID)
+		}
+	}
+
+	booksByID := make(map[uuid.UUID]model.Book, len(ids))
+	if len(ids) > 0 {
+		list, err := u.BooksUsecase.ListBooks(ctx, books.ListBookParameters{IDs: ids}, expand, expand, expand)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load harvested books: %w", err)
+		}
+		for _, book := range list {
+			booksByID[book.ID] = book
+		}
+	}
+
+	records := make([]Record, 0, len(headers))
+	for _, header := range headers {
+		if header.Deleted {
+			records = append(records, Record{Header: header})
+			continue
+		}
+		// A book removed between the two queries shows up as deleted on the
+		// next harvest, so it is simply left out of this page.
+		book, ok := booksByID[header.ID]
+		if !ok {
+			continue
+		}
+		records = append(records, Record{Header: bookHeader(book, header.Datestamp), Book: &book})
+	}
+	return records, nil
+}
+
+func bookHeader(book model.Book, datestamp time.Time) Header {
+	header := Header{ID: book.ID, Datestamp: datestamp}
+	if book.PublisherID != nil {
+		header.Sets = append(header.Sets, SetPublisher, setSpec(SetPublisher, *book.PublisherID))
+	}
+	if len(book.TagsIDs) > 0 {
+		header.Sets = append(header.Sets, SetTag)
+		for _, tagID := range book.TagsIDs {
+			header.Sets = append(header.Sets, setSpec(SetTag, tagID))
+		}
+	}
+	sort.Strings(header.Sets)
+	return header
+}
+
+func setSpec(kind string, id uuid.UUID) string {
+	return kind + setSeparator + id.String()
+}
+
+func parseSet(spec string) (*SetFilter, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	kind, rawID, hasID := strings.Cut(spec, setSeparator)
+	if kind != SetTag && kind != SetPublisher {
+		return nil, model.ErrHarvestUnknownSet
+	}
+	filter := &SetFilter{Kind: kind}
+	if hasID {
+		id, err := uuid.Parse(rawID)
+		if err != nil {
+			return nil, model.ErrHarvestUnknownSet
+		}
+		filter.ID = &id
+	}
+	return filter, nil
+}
+
+func encodeToken(token resumptionToken) string {
+	body, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(body)
+}
+
+func decodeToken(value string) (resumptionToken, error) {
+	body, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return resumptionToken{}, model.ErrHarvestBadResumptionToken
+	}
+	var token resumptionToken
+	if err = json.Unmarshal(body, &token); err != nil || token.ID == uuid.Nil || token.Cursor <= 0 {
+		return resumptionToken{}, model.ErrHarvestBadResumptionToken
+	}
+	if _, err = parseSet(token.Set); err != nil {
+		return resumptionToken{}, model.ErrHarvestBadResumptionToken
+	}
+	return token, nil
+}
@@ -0,0 +1,197 @@
+package harvest
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/books"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+)
+
+// storageStub keeps the headers in the order ListHeaders uses: by datestamp,
+// then by id. Records share datestamps so the id tie-break is exercised.
+type storageStub struct {
+	headers []Header
+	calls   []HeaderParameters
+}
+
+func newStorageStub(count int) *storageStub {
+	start := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+	storage := &storageStub{}
+	for i := 0; i < count; i++ {
+		storage.headers = append(storage.headers, Header{
+			ID:        uuid.New(),
+			Datestamp: start.Add(time.Duration(i/3) * time.Minute),
+			Deleted:   i%7 == 0,
+		})
+	}
+	sort.Slice(storage.headers, func(i, j int) bool {
+		a, b := storage.headers[i], storage.headers[j]
+		if !a.Datestamp.Equal(b.Datestamp) {
+			return a.Datestamp.Before(b.Datestamp)
+		}
+		return a.ID.String() < b.ID.String()
+	})
+	return storage
+}
+
+func (s *storageStub) ListHeaders(_ context.Context, parameters HeaderParameters) ([]Header, error) {
+	s.calls = append(s.calls, parameters)
+	var result []Header
+	for _, header := range s.headers {
+		if parameters.From != nil && header.Datestamp.Before(*parameters.From) {
+			continue
+		}
+		if parameters.Until != nil && !header.Datestamp.Before(*parameters.Until) {
+			continue
+		}
+		if after := parameters.AfterDatestamp; after != nil {
+			if header.Datestamp.Before(*after) ||
+				header.Datestamp.Equal(*after) && header.ID.String() <= parameters.AfterID.String() {
+				continue
+			}
+		}
+		result = append(result, header)
+		if uint64(len(result)) == parameters.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+func (s *storageStub) GetDeletedBook(context.Context, uuid.UUID) (Header, error) {
+	return Header{}, model.ErrBookNotFound
+}
+
+func (s *storageStub) EarliestDatestamp(context.Context) (time.Time, error) {
+	return s.headers[0].Datestamp, nil
+}
+
+type booksStub struct{}
+
+func (booksStub) GetBook(context.Context, uuid.UUID, bool, bool, bool) (model.Book, error) {
+	return model.Book{}, model.ErrBookNotFound
+}
+
+func (booksStub) ListBooks(
+	_ context.Context,
+	parameters books.ListBookParameters,
+	_, _, _ bool,
+) ([]model.Book, error) {
+	list := make([]model.Book, len(parameters.IDs))
+	for i, id := range parameters.IDs {
+		list[i] = model.Book{ID: id, Title: id.String()}
+	}
+	return list, nil
+}
+
+// TestListRecordsResumption harvests the whole list through resumption
+// tokens and checks that every record is returned once, in order, with the
+// cursor counting the records sent before each page.
+func TestListRecordsResumption(t *testing.T) {
+	const total = 2*PageSize + 37
+	storage := newStorageStub(total)
+	usecase := NewHarvestUsecase(HarvestUsecaseDeps{Storage: storage, BooksUsecase: booksStub{}})
+	ctx := context.Background()
+
+	var (
+		harvested []Header
+		cursors   []int
+		token     string
+	)
+	for {
+		page, err := usecase.ListRecords(ctx, ListParameters{}, token, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cursors = append(cursors, page.Cursor)
+		for _, record := range page.Records {
+			if record.Deleted != (record.Book == nil) {
+				t.Fatalf("record %s: deleted = %v, book = %v", record.ID, record.Deleted, record.Book)
+			}
+			harvested = append(harvested, record.Header)
+		}
+		if page.Next == "" {
+			break
+		}
+		token = page.Next
+	}
+
+	if want := []int{0, PageSize, 2 * PageSize}; !slices.Equal(cursors, want) {
+		t.Errorf("cursors = %v, want %v", cursors, want)
+	}
+	if len(harvested) != total {
+		t.Fatalf("harvested %d records, want %d", len(harvested), total)
+	}
+	for i, header := range harvested {
+		if header.ID != storage.headers[i].ID {
+			t.Fatalf("record %d = %s, want %s", i, header.ID, storage.headers[i].ID)
+		}
+	}
+}
+
+// TestListRecordsTokenKeepsRange checks that a resumed request keeps the
+// range and set of the first request, the arguments are not repeated with
+// the token.
+func TestListRecordsTokenKeepsRange(t *testing.T) {
+	storage := newStorageStub(3 * PageSize)
+	usecase := NewHarvestUsecase(HarvestUsecaseDeps{Storage: storage, BooksUsecase: booksStub{}})
+	ctx := context.Background()
+
+	from := storage.headers[10].Datestamp
+	until := storage.headers[len(storage.headers)-10].Datestamp
+	tagID := uuid.New()
+	parameters := ListParameters{From: &from, Until: &until, Set: setSpec(SetTag, tagID)}
+	first, err := usecase.ListRecords(ctx, parameters, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Next == "" {
+		t.Fatal("first page has no resumption token")
+	}
+	if _, err = usecase.ListRecords(ctx, ListParameters{}, first.Next, false); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed := storage.calls[1]
+	if resumed.From == nil || !resumed.From.Equal(from) || resumed.Until == nil || !resumed.Until.Equal(until) {
+		t.Errorf("resumed range = %v..%v, want %v..%v", resumed.From, resumed.Until, from, until)
+	}
+	if resumed.Set == nil || resumed.Set.Kind != SetTag || resumed.Set.ID == nil || *resumed.Set.ID != tagID {
+		t.Errorf("resumed set = %+v, want tag %s", resumed.Set, tagID)
+	}
+	if resumed.AfterDatestamp == nil || resumed.AfterID == uuid.Nil {
+		t.Errorf("resumed request does not continue after the last record: %+v", resumed)
+	}
+}
+
+func TestListRecordsBadResumptionToken(t *testing.T) {
+	usecase := NewHarvestUsecase(HarvestUsecaseDeps{Storage: newStorageStub(1), BooksUsecase: booksStub{}})
+	tokens := []string{
+		"not base64!",
+		encodeToken(resumptionToken{Cursor: 100}),
+		encodeToken(resumptionToken{ID: uuid.New()}),
+		encodeToken(resumptionToken{ID: uuid.New(), Cursor: 100, Set: "shelf"}),
+		"eyJpIjoibm90IGEgdXVpZCJ9",
+	}
+	for _, token := range tokens {
+		_, err := usecase.ListRecords(context.Background(), ListParameters{}, token, false)
+		if !errors.Is(err, model.ErrHarvestBadResumptionToken) {
+			t.Errorf("token %q: error = %v, want %v", token, err, model.ErrHarvestBadResumptionToken)
+		}
+	}
+}
+
+func TestListRecordsNoRecordsMatch(t *testing.T) {
+	storage := newStorageStub(5)
+	usecase := NewHarvestUsecase(HarvestUsecaseDeps{Storage: storage, BooksUsecase: booksStub{}})
+	from := storage.headers[len(storage.headers)-1].Datestamp.Add(time.Second)
+	_, err := usecase.ListRecords(context.Background(), ListParameters{From: &from}, "", false)
+	if !errors.Is(err, model.ErrHarvestNoRecordsMatch) {
+		t.Errorf("error = %v, want %v", err, model.ErrHarvestNoRecordsMatch)
+	}
+}
@@ -0,0 +1,237 @@
+package highlights
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/books"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	TagsMax      = 20
+	TagMaxLength = 50
+)
+
+func ParseHighlightKind(raw string) (model.HighlightKind, error) {
+	switch kind := model.HighlightKind(raw); kind {
+	case model.HighlightKindHighlight, model.HighlightKindQuote, model.HighlightKindNote, model.HighlightKindBookmark:
+		return kind, nil
+	}
+	return "", model.ErrHighlightInvalidKind
+}
+
+func ParseHighlightColour(raw string) (model.HighlightColour, error) {
+	switch colour := model.HighlightColour(raw); colour {
+	case model.HighlightYellow, model.HighlightGreen, model.HighlightBlue, model.HighlightPink,
+		model.HighlightOrange, model.HighlightPurple:
+		return colour, nil
+	}
+	return "", model.ErrHighlightInvalidColour
+}
+
+type HighlightInput struct {
+	Kind          model.HighlightKind
+	Text          string
+	Page          *int32
+	LocationStart *int32
+	LocationEnd   *int32
+	Colour        *model.HighlightColour
+	Tags          []string
+}
+
+// ListParameters filter the highlights of one user, the zero values match
+// everything. Query is a full-text search over the highlight text.
+type ListParameters struct {
+	BookID *uuid.UUID
+	Kind   model.HighlightKind
+	Colour model.HighlightColour
+	Tag    string
+	Query  string
+	Limit  uint64
+	Offset uint64
+}
+
+type ImportedHighlight struct {
+	Highlight model.Highlight
+	// Fingerprint identifies the source entry, so uploading the same file
+	// again does not duplicate highlights.
+	Fingerprint string
+}
+
+type Source struct {
+	Title  string
+	Author string
+}
+
+type UnmatchedSource struct {
+	Source
+	Highlights int
+}
+
+type Storage interface {
+	AddHighlight(ctx context.Context, highlight model.Highlight) (uuid.UUID, error)
+	GetHighlight(ctx context.Context, userID uuid.UUID, id uuid.UUID) (model.Highlight, error)
+	UpdateHighlight(ctx context.Context, highlight model.Highlight) error
+	RemoveHighlight(ctx context.Context, userID uuid.UUID, id uuid.UUID) error
+	ListHighlights(ctx context.Context, userID uuid.UUID, parameters ListParameters) ([]model.Highlight, error)
+
+	AddImportedHighlights(ctx context.Context, highlights []ImportedHighlight) (int, error)
+	ListUnmatchedSources(ctx context.Context, userID uuid.UUID) ([]UnmatchedSource, error)
+	AssignSource(ctx context.Context, userID uuid.UUID, source Source, bookID uuid.UUID) (int, error)
+}
+
+type BooksUsecase interface {
+	ListBooks(
+		ctx context.Context,
+		parameters books.ListBookParameters,
+		expandAuthors, expandTags, expandPublisher bool,
+	) ([]model.Book, error)
+}
+
+type HighlightsUsecase struct {
+	storage      Storage
+	booksUsecase BooksUsecase
+}
+
+func NewHighlightsUsecase(storage Storage, booksUsecase BooksUsecase) *HighlightsUsecase {
+	return &HighlightsUsecase{
+		storage:      storage,
+		booksUsecase: booksUsecase,
+	}
+}
+
+func (u *HighlightsUsecase) ListUnmatchedSources(ctx context.Context, userID uuid.UUID) ([]UnmatchedSource, error) {
+	return u.storage.ListUnmatchedSources(ctx, userID)
+}
+
+// ResolveSource attaches every unmatched highlight of the source to the book.
+func (u *HighlightsUsecase) ResolveSource(
+	ctx context.Context,
+	userID uuid.UUID,
+	source Source,
+	bookID uuid.UUID,
+) (int, error) {
+	return u.storage.AssignSource(ctx, userID, source, bookID)
+}
+
+func (u *HighlightsUsecase) AddHighlight(
+	ctx context.Context,
+	userID uuid.UUID,
+	bookID uuid.UUID,
+	input HighlightInput,
+) (model.Highlight, error) {
+	highlight, err := newHighlight(input)
+	if err != nil {
+		return model.Highlight{}, err
+	}
+	highlight.UserID = userID
+	highlight.BookID = &bookID
+
+	id, err := u.storage.AddHighlight(ctx, highlight)
+	if err != nil {
+		return model.Highlight{}, err
+	}
+	return u.storage.GetHighlight(ctx, userID, id)
+}
+
+func (u *HighlightsUsecase) GetHighlight(ctx context.Context, userID uuid.UUID, id uuid.UUID) (model.Highlight, error) {
+	return u.storage.GetHighlight(ctx, userID, id)
+}
+
+// UpdateHighlight replaces what the user wrote, the book and what a Kindle
+// import recorded about the source stay.
+func (u *HighlightsUsecase) UpdateHighlight(
+	ctx context.Context,
+	userID uuid.UUID,
+	id uuid.UUID,
+	input HighlightInput,
+) (model.Highlight, error) {
+	highlight, err := newHighlight(input)
+	if err != nil {
+		return model.Highlight{}, err
+	}
+	highlight.ID = id
+	highlight.UserID = userID
+
+	if err = u.storage.UpdateHighlight(ctx, highlight); err != nil {
+		return model.Highlight{}, err
+	}
+	return u.storage.GetHighlight(ctx, userID, id)
+}
+
+func (u *HighlightsUsecase) RemoveHighlight(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	return u.storage.RemoveHighlight(ctx, userID, id)
+}
+
+func (u *HighlightsUsecase) ListHighlights(
+	ctx context.Context,
+	userID uuid.UUID,
+	parameters ListParameters,
+) ([]model.Highlight, error) {
+	parameters.Tag = normalizeTag(parameters.Tag)
+	parameters.Query = strings.TrimSpace(parameters.Query)
+	return u.storage.ListHighlights(ctx, userID, parameters)
+}
+
+func newHighlight(input HighlightInput) (model.Highlight, error) {
+	kind, err := ParseHighlightKind(string(input.Kind))
+	if err != nil {
+		return model.Highlight{}, err
+	}
+	if input.Colour != nil {
+		if _, err = ParseHighlightColour(string(*input.Colour)); err != nil {
+			return model.Highlight{}, err
+		}
+	}
+	text := strings.TrimSpace(input.Text)
+	if text == "" && kind != model.HighlightKindBookmark {
+		return model.Highlight{}, model.ErrHighlightTextRequired
+	}
+	if input.LocationEnd != nil && (input.LocationStart == nil || *input.LocationEnd < *input.LocationStart) {
+		return model.Highlight{}, model.ErrHighlightInvalidLocation
+	}
+	tags, err := normalizeTags(input.Tags)
+	if err != nil {
+		return model.Highlight{}, err
+	}
+	return model.Highlight{
+		Kind:          kind,
+		Text:          text,
+		Page:          input.Page,
+		LocationStart: input.LocationStart,
+		LocationEnd:   input.LocationEnd,
+		Colour:        input.Colour,
+		Tags:          tags,
+	}, nil
+}
+
+// normalizeTags lowercases the tags and drops the repeated ones, so
+// filtering by a tag does not depend on how it was typed.
+func normalizeTags(raw []string) ([]string, error) {
+	tags := make([]string, 0, len(raw))
+	seen := make(map[string]struct{}, len(raw))
+	for _, tag := range raw {
+		tag = normalizeTag(tag)
+		if tag == "" {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > TagMaxLength {
+			return nil, model.ErrHighlightInvalidTags
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		tags = append(tags, tag)
+	}
+	if len(tags) > TagsMax {
+		return nil, model.ErrHighlightInvalidTags
+	}
+	return tags, nil
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(tag), "#")))
+}
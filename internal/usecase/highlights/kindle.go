@@ -0,0 +1,122 @@
+package highlights
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/books"
+	"github.com/iamvkosarev/book-shelf/pkg/kindle"
+	"io"
+)
+
+type KindleSource struct {
+	Source
+	Clippings     int
+	BookID        *uuid.UUID
+	CandidatesIDs []uuid.UUID
+}
+
+type KindleReport struct {
+	Clippings  int
+	Imported   int
+	Duplicates int
+	Matched    []KindleSource
+	Unmatched  []KindleSource
+}
+
+// ImportKindleClippings stores every clipping of the file as a highlight of
+// the user. Clippings of titles that match no book are kept without a book so
+// they can be resolved later.
+func (u *HighlightsUsecase) ImportKindleClippings(
+	ctx context.Context,
+	userID uuid.UUID,
+	reader io.Reader,
+) (KindleReport, error) {
+	clippings, err := kindle.Parse(reader)
+	if errors.Is(err, kindle.ErrNoClippings) {
+		return KindleReport{}, model.ErrHighlightsNoClippings
+	}
+	if err != nil {
+		return KindleReport{}, fmt.Errorf("failed to read clippings: %w", err)
+	}
+
+	existing, err := u.booksUsecase.ListBooks(ctx, books.ListBookParameters{}, true, false, false)
+	if err != nil {
+		return KindleReport{}, fmt.Errorf("failed to list books: %w", err)
+	}
+	matcher := newBookMatcher(existing)
+
+	var (
+		order   []Source
+		sources = make(map[Source]*KindleSource)
+		rows    = make([]ImportedHighlight, 0, len(clippings))
+	)
+	for _, clipping := range clippings {
+		key := Source{Title: clipping.Title, Author: clipping.Author}
+		source, ok := sources[key]
+		if !ok {
+			source = &KindleSource{Source: key}
+			source.BookID, source.CandidatesIDs = matcher.match(key.Title, key.Author)
+			sources[key] = source
+			order = append(order, key)
+		}
+		source.Clippings++
+		rows = append(rows, importedHighlight(userID, source, clipping))
+	}
+
+	imported, err := u.storage.AddImportedHighlights(ctx, rows)
+	if err != nil {
+		return KindleReport{}, fmt.Errorf("failed to store highlights: %w", err)
+	}
+
+	report := KindleReport{
+		Clippings:  len(clippings),
+		Imported:   imported,
+		Duplicates: len(clippings) - imported,
+	}
+	for _, key := range order {
+		source := sources[key]
+		if source.BookID == nil {
+			report.Unmatched = append(report.Unmatched, *source)
+			continue
+		}
+		// Earlier uploads may have left the title unmatched before the book
+		// was added to the shelf.
+		if _, err = u.storage.AssignSource(ctx, userID, key, *source.BookID); err != nil {
+			return KindleReport{}, fmt.Errorf("failed to attach earlier highlights: %w", err)
+		}
+		report.Matched = append(report.Matched, *source)
+	}
+	return report, nil
+}
+
+func importedHighlight(userID uuid.UUID, source *KindleSource, clipping kindle.Clipping) ImportedHighlight {
+	highlight := model.Highlight{
+		UserID:        userID,
+		BookID:        source.BookID,
+		Kind:          model.HighlightKind(clipping.Kind),
+		Text:          clipping.Text,
+		Page:          toInt32Ptr(clipping.Page),
+		LocationStart: toInt32Ptr(clipping.LocationStart),
+		LocationEnd:   toInt32Ptr(clipping.LocationEnd),
+		ClippedAt:     clipping.AddedAt,
+		SourceTitle:   &source.Title,
+	}
+	if source.Author != "" {
+		highlight.SourceAuthor = &source.Author
+	}
+	sum := sha256.Sum256([]byte(clipping.Raw + "\n" + clipping.Text))
+	return ImportedHighlight{Highlight: highlight, Fingerprint: hex.EncodeToString(sum[:])}
+}
+
+func toInt32Ptr(value *int) *int32 {
+	if value == nil {
+		return nil
+	}
+	v := int32(*value)
+	return &v
+}
@@ -0,0 +1,101 @@
+package highlights
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/books"
+	"strings"
+)
+
+// ExportMarkdown writes every highlight of the user for the book in reading
+// order: quotes and highlights as block quotes, notes as plain paragraphs.
+func (u *HighlightsUsecase) ExportMarkdown(ctx context.Context, userID uuid.UUID, bookID uuid.UUID) ([]byte, error) {
+	list, err := u.booksUsecase.ListBooks(ctx, books.ListBookParameters{IDs: []uuid.UUID{bookID}}, true, false, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get book: %w", err)
+	}
+	if len(list) == 0 {
+		return nil, model.ErrBookNotFound
+	}
+	highlights, err := u.storage.ListHighlights(ctx, userID, ListParameters{BookID: &bookID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list highlights: %w", err)
+	}
+	return BookToMarkdown(list[0], highlights), nil
+}
+
+func BookToMarkdown(book model.Book, highlights []model.Highlight) []byte {
+	var builder strings.Builder
+	builder.WriteString("# " + escapeMarkdownLine(book.Title) + "\n")
+	if len(book.Authors) > 0 {
+		names := make([]string, 0, len(book.Authors))
+		for _, author := range book.Authors {
+			names = append(names, authorName(author))
+		}
+		builder.WriteString("\n*" + escapeMarkdownLine(strings.Join(names, ", ")) + "*\n")
+	}
+
+	for _, highlight := range highlights {
+		builder.WriteString("\n")
+		switch highlight.Kind {
+		case model.HighlightKindNote:
+			builder.WriteString(highlight.Text + "\n")
+		case model.HighlightKindBookmark:
+			builder.WriteString("**Bookmark**\n")
+			if highlight.Text != "" {
+				builder.WriteString("\n" + highlight.Text + "\n")
+			}
+		default:
+			for _, line := range strings.Split(highlight.Text, "\n") {
+				builder.WriteString(strings.TrimRight("> "+line, " ") + "\n")
+			}
+		}
+		if details := highlightDetails(highlight); details != "" {
+			builder.WriteString("\n" + details + "\n")
+		}
+	}
+	return []byte(builder.String())
+}
+
+func highlightDetails(highlight model.Highlight) string {
+	var details []string
+	if highlight.Page != nil {
+		details = append(details, fmt.Sprintf("Page %d", *highlight.Page))
+	}
+	if highlight.LocationStart != nil {
+		location := fmt.Sprintf("Location %d", *highlight.LocationStart)
+		if highlight.LocationEnd != nil && *highlight.LocationEnd != *highlight.LocationStart {
+			location += fmt.Sprintf("-%d", *highlight.LocationEnd)
+		}
+		details = append(details, location)
+	}
+	if highlight.Colour != nil {
+		details = append(details, string(*highlight.Colour))
+	}
+	for _, tag := range highlight.Tags {
+		details = append(details, "#"+tag)
+	}
+	if len(details) == 0 {
+		return ""
+	}
+	return "*" + escapeMarkdownLine(strings.Join(details, " · ")) + "*"
+}
+
+func authorName(author model.Author) string {
+	var parts []string
+	for _, part := range []*string{author.FirstName, author.MiddleName, author.LastName} {
+		if part != nil && strings.TrimSpace(*part) != "" {
+			parts = append(parts, strings.TrimSpace(*part))
+		}
+	}
+	if len(parts) == 0 && author.Pseudonym != nil {
+		return *author.Pseudonym
+	}
+	return strings.Join(parts, " ")
+}
+
+func escapeMarkdownLine(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "\n", " ").Replace(s)
+}
@@ -0,0 +1,150 @@
+package highlights
+
+import (
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/books"
+	"strings"
+)
+
+const (
+	titleSimilarityThreshold       = 0.85
+	authorTitleSimilarityThreshold = 0.7
+	similarityTieDelta             = 0.01
+)
+
+type matchCandidate struct {
+	id      uuid.UUID
+	titles  []string
+	authors []string
+}
+
+type bookMatcher struct {
+	candidates []matchCandidate
+}
+
+func newBookMatcher(existing []model.Book) *bookMatcher {
+	matcher := &bookMatcher{candidates: make([]matchCandidate, 0, len(existing))}
+	for _, book := range existing {
+		candidate := matchCandidate{id: book.ID, titles: titleVariants(book.Title)}
+		for _, author := range book.Authors {
+			for _, name := range []*string{author.LastName, author.Pseudonym} {
+				if name == nil {
+					continue
+				}
+				if normalized := books.NormalizeTitle(*name); normalized != "" {
+					candidate.authors = append(candidate.authors, normalized)
+				}
+			}
+		}
+		if len(candidate.titles) > 0 {
+			matcher.candidates = append(matcher.candidates, candidate)
+		}
+	}
+	return matcher
+}
+
+// match looks for the book with the most similar title. A matching author
+// surname lowers the title threshold, a conflicting one rules the book out.
+// Equally good candidates are returned instead of a match.
+func (m *bookMatcher) match(title, author string) (*uuid.UUID, []uuid.UUID) {
+	titles := titleVariants(title)
+	if len(titles) == 0 {
+		return nil, nil
+	}
+	authorWords := " " + books.NormalizeTitle(author) + " "
+
+	var (
+		best      float64
+		bestIDs   []uuid.UUID
+		threshold float64
+	)
+	for _, candidate := range m.candidates {
+		threshold = titleSimilarityThreshold
+		if author != "" && len(candidate.authors) > 0 {
+			if !anyAuthorIn(candidate.authors, authorWords) {
+				continue
+			}
+			threshold = authorTitleSimilarityThreshold
+		}
+		score := 0.0
+		for _, a := range titles {
+			for _, b := range candidate.titles {
+				score = max(score, similarity(a, b))
+			}
+		}
+		switch {
+		case score < threshold:
+		case score > best+similarityTieDelta:
+			best, bestIDs = score, []uuid.UUID{candidate.id}
+		case score >= best-similarityTieDelta:
+			bestIDs = append(bestIDs, candidate.id)
+		}
+	}
+	if len(bestIDs) == 1 {
+		return &bestIDs[0], nil
+	}
+	return nil, bestIDs
+}
+
+func anyAuthorIn(authors []string, words string) bool {
+	for _, author := range authors {
+		if strings.Contains(words, " "+author+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// titleVariants adds the title without its subtitle or trailing edition
+// note, which stores and devices add inconsistently.
+func titleVariants(title string) []string {
+	full := books.NormalizeTitle(title)
+	if full == "" {
+		return nil
+	}
+	variants := []string{full}
+	short := title
+	for _, cut := range []string{":", " (", " - ", " — "} {
+		if before, _, ok := strings.Cut(short, cut); ok {
+			short = before
+		}
+	}
+	if normalized := books.NormalizeTitle(short); normalized != "" && normalized != full {
+		variants = append(variants, normalized)
+	}
+	return variants
+}
+
+// similarity is one minus the edit distance relative to the longer title.
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
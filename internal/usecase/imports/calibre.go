@@ -0,0 +1,203 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const calibreIdentifierISBN = "isbn"
+
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+type CalibreBook struct {
+	ID          int64
+	Title       string
+	Authors     []string
+	Tags        []string
+	Series      string
+	Publisher   string
+	PublishedAt *time.Time
+	Comments    string
+	ISBN        string
+	Identifiers map[string]string
+}
+
+type CalibreLibrary interface {
+	LibraryID(ctx context.Context) (string, error)
+	ListBooks(ctx context.Context) ([]CalibreBook, error)
+	Close() error
+}
+
+type CalibreOpener interface {
+	OpenCalibreLibrary(path string) (CalibreLibrary, error)
+}
+
+type CalibreImportsStorage interface {
+	ListImportedCalibreBooks(ctx context.Context, libraryID string) (map[int64]uuid.UUID, error)
+	SaveImportedCalibreBook(
+		ctx context.Context,
+		libraryID string,
+		calibreID int64,
+		bookID uuid.UUID,
+		identifiers map[string]string,
+	) error
+}
+
+func (u *ImportUsecase) ImportCalibre(ctx context.Context, path string, options Options) (Report, error) {
+	library, err := u.CalibreOpener.OpenCalibreLibrary(path)
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to open calibre library: %w", err)
+	}
+	defer library.Close()
+
+	libraryID, err := library.LibraryID(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to read calibre library id: %w", err)
+	}
+	calibreBooks, err := library.ListBooks(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to read calibre books: %w", err)
+	}
+	imported, err := u.CalibreImportsStorage.ListImportedCalibreBooks(ctx, libraryID)
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to list imported calibre books: %w", err)
+	}
+	catalog, err := u.loadCatalog(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+	drafts := make([]BookDraft, len(calibreBooks))
+	for i, book := range calibreBooks {
+		if _, ok := imported[book.ID]; ok {
+			continue
+		}
+		drafts[i] = calibreDraft(book)
+		validateDraft(&drafts[i])
+	}
+	index, err := u.loadBookIndex(ctx, drafts)
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{
+		DryRun: options.DryRun,
+		Rows:   make([]RowReport, len(calibreBooks)),
+	}
+	for i, book := range calibreBooks {
+		report.Rows[i] = RowReport{Row: int(book.ID), Status: RowPlanned}
+		if bookID, ok := imported[book.ID]; ok {
+			report.Rows[i].Status = RowExisting
+			report.Rows[i].BookID = bookID
+			continue
+		}
+
+		if len(drafts[i].Errors) == 0 {
+			if match, ok := index.find(drafts[i].ISBN, catalog.draftTitleKey(drafts[i])); ok {
+				report.Rows[i].Status = RowExisting
+				report.Rows[i].BookID = match.id
+				continue
+			}
+		}
+		catalog.plan(&drafts[i], options.CreateMissing)
+		report.Rows[i].Errors = drafts[i].Errors
+		if len(drafts[i].Errors) > 0 {
+			report.Rows[i].Status = RowFailed
+		}
+	}
+	report.Planned = catalog.planned
+
+	if options.DryRun {
+		if err = u.checkPlannedDuplicates(ctx, catalog, drafts, &report, options.Force); err != nil {
+			return Report{}, err
+		}
+		return report, nil
+	}
+
+	if err = u.createPlanned(ctx, catalog); err != nil {
+		return Report{}, u.discardCreated(ctx, catalog, err)
+	}
+
+	for i, book := range calibreBooks {
+		row := &report.Rows[i]
+		switch row.Status {
+		case RowPlanned:
+			input := catalog.createInput(drafts[i])
+			input.Force = options.Force
+			id, err := u.BooksUsecase.AddBook(ctx, input)
+			if candidates, ok := duplicateCandidatesOf(err); ok {
+				failDuplicate(&report, nil, i, candidates)
+				continue
+			}
+			var internalError *model.InternalError
+			if errors.As(err, &internalError) {
+				row.Status = RowFailed
+				row.Errors = append(row.Errors, err.Error())
+				continue
+			}
+			if err != nil {
+				err = fmt.Errorf("failed to add calibre book %d: %w", book.ID, err)
+				return Report{}, u.discardCreated(ctx, catalog, err)
+			}
+			row.Status = RowCreated
+			row.BookID = id
+		case RowExisting:
+			if _, ok := imported[book.ID]; ok {
+				continue
+			}
+		default:
+			continue
+		}
+		if err = u.CalibreImportsStorage.SaveImportedCalibreBook(
+			ctx, libraryID, book.ID, row.BookID, book.Identifiers,
+		); err != nil {
+			err = fmt.Errorf("failed to remember calibre book %d: %w", book.ID, err)
+			return Report{}, u.discardCreated(ctx, catalog, err)
+		}
+	}
+	report.Orphans = u.removeUnused(ctx, catalog)
+	return report, nil
+}
+
+func calibreDraft(book CalibreBook) BookDraft {
+	draft := BookDraft{
+		Row:         int(book.ID),
+		Title:       book.Title,
+		ISBN:        book.Identifiers[calibreIdentifierISBN],
+		Tags:        book.Tags,
+		Publisher:   book.Publisher,
+		PublishedAt: book.PublishedAt,
+	}
+	if draft.ISBN == "" {
+		draft.ISBN = book.ISBN
+	}
+	if book.Series != "" {
+		draft.Tags = append(draft.Tags, book.Series)
+	}
+	for _, name := range book.Authors {
+		draft.Authors = append(draft.Authors, ParseAuthorName(name))
+	}
+	if description := htmlToText(book.Comments); description != "" {
+		draft.Description = &description
+	}
+	return draft
+}
+
+func htmlToText(source string) string {
+	return plainDescription(html.UnescapeString(htmlTagPattern.ReplaceAllString(source, " ")))
+}
+
+func plainDescription(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= maxDescriptionLength {
+		return text
+	}
+	return string([]rune(text)[:maxDescriptionLength])
+}
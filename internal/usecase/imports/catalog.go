@@ -0,0 +1,266 @@
+package imports
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/books"
+	"strings"
+	"unicode/utf8"
+)
+
+type AuthorName struct {
+	FirstName  string
+	MiddleName string
+	LastName   string
+	Pseudonym  string
+}
+
+func ParseAuthorName(raw string) AuthorName {
+	raw = strings.Join(strings.Fields(raw), " ")
+	if last, rest, ok := strings.Cut(raw, ","); ok {
+		parts := strings.Fields(rest)
+		name := AuthorName{LastName: strings.TrimSpace(last)}
+		if len(parts) > 0 {
+			name.FirstName = parts[0]
+			name.MiddleName = strings.Join(parts[1:], " ")
+		}
+		return name
+	}
+
+	parts := strings.Fields(raw)
+	switch len(parts) {
+	case 0:
+		return AuthorName{}
+	case 1:
+		return AuthorName{LastName: parts[0]}
+	default:
+		return AuthorName{
+			FirstName:  parts[0],
+			MiddleName: strings.Join(parts[1:len(parts)-1], " "),
+			LastName:   parts[len(parts)-1],
+		}
+	}
+}
+
+func AuthorNameOf(author model.Author) AuthorName {
+	return AuthorName{
+		FirstName:  derefString(author.FirstName),
+		MiddleName: derefString(author.MiddleName),
+		LastName:   derefString(author.LastName),
+		Pseudonym:  derefString(author.Pseudonym),
+	}
+}
+
+func (n AuthorName) String() string {
+	full := strings.Join(strings.Fields(n.FirstName+" "+n.MiddleName+" "+n.LastName), " ")
+	if full == "" {
+		return n.Pseudonym
+	}
+	return full
+}
+
+func (n AuthorName) keys() []string {
+	var keys []string
+	if full := normalizeName(n.FirstName + " " + n.MiddleName + " " + n.LastName); full != "" {
+		keys = append(keys, full)
+	}
+	if short := normalizeName(n.FirstName + " " + n.LastName); short != "" && n.MiddleName != "" {
+		keys = append(keys, short)
+	}
+	if pseudonym := normalizeName(n.Pseudonym); pseudonym != "" {
+		keys = append(keys, pseudonym)
+	}
+	return keys
+}
+
+func (n AuthorName) valid() bool {
+	for _, part := range []string{n.FirstName, n.MiddleName, n.LastName, n.Pseudonym} {
+		if utf8.RuneCountInString(part) > maxAuthorNameLength {
+			return false
+		}
+	}
+	return len(n.keys()) > 0
+}
+
+func (n AuthorName) addAuthorInput() usecase.AddAuthorInput {
+	return usecase.AddAuthorInput{
+		FirstName:  nonEmpty(n.FirstName),
+		LastName:   nonEmpty(n.LastName),
+		MiddleName: nonEmpty(n.MiddleName),
+		Pseudonym:  nonEmpty(n.Pseudonym),
+	}
+}
+
+type catalog struct {
+	authors    map[string]uuid.UUID
+	tags       map[string]uuid.UUID
+	publishers map[string]uuid.UUID
+	planned    Creations
+	plannedSet map[string]struct{}
+	created    createdIDs
+}
+
+// createdIDs follows the planned entities that are already created, in the
+// order of Creations.
+type createdIDs struct {
+	authors    []uuid.UUID
+	tags       []uuid.UUID
+	publishers []uuid.UUID
+}
+
+func (u *ImportUsecase) loadCatalog(ctx context.Context) (*catalog, error) {
+	authors, err := u.AuthorsUsecase.ListAuthors(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list authors: %w", err)
+	}
+	tags, err := u.TagsUsecase.ListTags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	publishers, err := u.PublishersUsecase.ListPublishers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list publishers: %w", err)
+	}
+
+	c := &catalog{
+		authors:    make(map[string]uuid.UUID, len(authors)),
+		tags:       make(map[string]uuid.UUID, len(tags)),
+		publishers: make(map[string]uuid.UUID, len(publishers)),
+		plannedSet: make(map[string]struct{}),
+	}
+	for _, author := range authors {
+		c.addAuthor(AuthorNameOf(author), author.ID)
+	}
+	for _, tag := range tags {
+		c.tags[normalizeName(tag.Name)] = tag.ID
+	}
+	for _, publisher := range publishers {
+		c.publishers[normalizeName(publisher.Name)] = publisher.ID
+	}
+	return c, nil
+}
+
+func (c *catalog) addAuthor(name AuthorName, id uuid.UUID) {
+	for _, key := range name.keys() {
+		if _, ok := c.authors[key]; !ok {
+			c.authors[key] = id
+		}
+	}
+}
+
+func (c *catalog) authorID(name AuthorName) (uuid.UUID, bool) {
+	for _, key := range name.keys() {
+		if id, ok := c.authors[key]; ok {
+			return id, true
+		}
+	}
+	return uuid.Nil, false
+}
+
+func (c *catalog) plan(draft *BookDraft, createMissing bool) {
+	if draft.Publisher != "" {
+		if _, ok := c.publishers[normalizeName(draft.Publisher)]; !ok {
+			c.planOrFail(draft, createMissing, "publisher", draft.Publisher, func() {
+				c.planned.Publishers = append(c.planned.Publishers, draft.Publisher)
+			})
+		}
+	}
+	for _, tag := range draft.Tags {
+		if _, ok := c.tags[normalizeName(tag)]; !ok {
+			c.planOrFail(draft, createMissing, "tag", tag, func() {
+				c.planned.Tags = append(c.planned.Tags, tag)
+			})
+		}
+	}
+	for _, author := range draft.Authors {
+		if _, ok := c.authorID(author); !ok && author.valid() {
+			c.planOrFail(draft, createMissing, "author", author.String(), func() {
+				c.planned.Authors = append(c.planned.Authors, author)
+			})
+		}
+	}
+}
+
+func (c *catalog) planOrFail(draft *BookDraft, createMissing bool, kind, name string, add func()) {
+	if !createMissing {
+		draft.Errors = append(draft.Errors, fmt.Sprintf("%s %q not found", kind, name))
+		return
+	}
+	key := kind + ":" + normalizeName(name)
+	if _, ok := c.plannedSet[key]; ok {
+		return
+	}
+	c.plannedSet[key] = struct{}{}
+	add()
+}
+
+// placeholdPlanned gives the planned entities ids that no stored book uses,
+// so that a dry run compares its books with the stored ones as if the
+// entities were created.
+func (c *catalog) placeholdPlanned() {
+	for _, name := range c.planned.Publishers {
+		c.publishers[normalizeName(name)] = uuid.New()
+	}
+	for _, name := range c.planned.Tags {
+		c.tags[normalizeName(name)] = uuid.New()
+	}
+	for _, name := range c.planned.Authors {
+		c.addAuthor(name, uuid.New())
+	}
+}
+
+func (c *catalog) createInput(draft BookDraft) books.CreateBookInput {
+	input := books.CreateBookInput{
+		Title:       draft.Title,
+		ISBN:        nonEmpty(draft.ISBN),
+		PublishedAt: draft.PublishedAt,
+		Description: draft.Description,
+		Price:       draft.Price,
+		Pages:       draft.Pages,
+	}
+	if id, ok := c.publishers[normalizeName(draft.Publisher)]; ok && draft.Publisher != "" {
+		input.PublisherID = &id
+	}
+	for _, author := range draft.Authors {
+		if id, ok := c.authorID(author); ok && !containsUUID(input.AuthorsIDs, id) {
+			input.AuthorsIDs = append(input.AuthorsIDs, id)
+		}
+	}
+	for _, tag := range draft.Tags {
+		if id, ok := c.tags[normalizeName(tag)]; ok && !containsUUID(input.TagsIDs, id) {
+			input.TagsIDs = append(input.TagsIDs, id)
+		}
+	}
+	return input
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func nonEmpty(s string) *string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func containsUUID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, existing := range ids {
+		if existing == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+package imports
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CSVField string
+
+const (
+	CSVFieldTitle       CSVField = "title"
+	CSVFieldISBN        CSVField = "isbn"
+	CSVFieldAuthors     CSVField = "authors"
+	CSVFieldTags        CSVField = "tags"
+	CSVFieldPublisher   CSVField = "publisher"
+	CSVFieldPublishedAt CSVField = "published_at"
+	CSVFieldDescription CSVField = "description"
+	CSVFieldPrice       CSVField = "price"
+	CSVFieldPages       CSVField = "pages"
+)
+
+var CSVFields = []CSVField{
+	CSVFieldTitle,
+	CSVFieldISBN,
+	CSVFieldAuthors,
+	CSVFieldTags,
+	CSVFieldPublisher,
+	CSVFieldPublishedAt,
+	CSVFieldDescription,
+	CSVFieldPrice,
+	CSVFieldPages,
+}
+
+var publishedAtLayouts = []string{"2006-01-02", "2006/01/02", "02.01.2006", "2006-01", "2006"}
+
+type CSVOptions struct {
+	Options
+	Columns       map[CSVField]string
+	Delimiter     rune
+	ListSeparator string
+}
+
+func (u *ImportUsecase) ImportBooksCSV(ctx context.Context, reader io.Reader, options CSVOptions) (Report, error) {
+	drafts, err := parseBooksCSV(reader, options)
+	if err != nil {
+		return Report{}, err
+	}
+	return u.importDrafts(ctx, drafts, options.Options)
+}
+
+func parseBooksCSV(reader io.Reader, options CSVOptions) ([]BookDraft, error) {
+	csvReader := csv.NewReader(reader)
+	if options.Delimiter != 0 {
+		csvReader.Comma = options.Delimiter
+	}
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, model.ErrImportCSVEmpty
+		}
+		return nil, model.NewInternalError(http.StatusBadRequest, fmt.Sprintf("invalid csv: %s", err))
+	}
+
+	positions := make(map[CSVField]int)
+	for _, field := range CSVFields {
+		column := string(field)
+		if mapped, ok := options.Columns[field]; ok {
+			column = mapped
+		}
+		for i, name := range header {
+			if strings.EqualFold(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")), column) {
+				positions[field] = i
+				break
+			}
+		}
+	}
+	if _, ok := positions[CSVFieldTitle]; !ok {
+		return nil, model.ErrImportCSVTitleNotMapped
+	}
+
+	listSeparator := options.ListSeparator
+	if listSeparator == "" {
+		listSeparator = ";"
+	}
+
+	var drafts []BookDraft
+	for {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			drafts = append(drafts, BookDraft{Row: parseErr.StartLine, Errors: []string{parseErr.Err.Error()}})
+			continue
+		}
+		if err != nil {
+			return nil, model.NewInternalError(http.StatusBadRequest, fmt.Sprintf("invalid csv: %s", err))
+		}
+		row, _ := csvReader.FieldPos(0)
+		value := func(field CSVField) string {
+			position, ok := positions[field]
+			if !ok || position >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[position])
+		}
+		if isBlankRecord(record) {
+			continue
+		}
+
+		draft := BookDraft{
+			Row:       row,
+			Title:     value(CSVFieldTitle),
+			ISBN:      value(CSVFieldISBN),
+			Publisher: value(CSVFieldPublisher),
+			Tags:      splitList(value(CSVFieldTags), listSeparator),
+		}
+		for _, name := range splitList(value(CSVFieldAuthors), listSeparator) {
+			draft.Authors = append(draft.Authors, ParseAuthorName(name))
+		}
+		if description := value(CSVFieldDescription); description != "" {
+			draft.Description = &description
+		}
+		if raw := value(CSVFieldPublishedAt); raw != "" {
+			if publishedAt, ok := parsePublishedAt(raw); ok {
+				draft.PublishedAt = &publishedAt
+			} else {
+				draft.Errors = append(draft.Errors, fmt.Sprintf("published_at %q is not a date", raw))
+			}
+		}
+		if raw := value(CSVFieldPrice); raw != "" {
+			if price, err := strconv.ParseFloat(strings.ReplaceAll(raw, ",", "."), 64); err == nil {
+				draft.Price = &price
+			} else {
+				draft.Errors = append(draft.Errors, fmt.Sprintf("price %q is not a number", raw))
+			}
+		}
+		if raw := value(CSVFieldPages); raw != "" {
+			if pages, err := strconv.ParseInt(raw, 10, 32); err == nil {
+				p := int32(pages)
+				draft.Pages = &p
+			} else {
+				draft.Errors = append(draft.Errors, fmt.Sprintf("pages %q is not an integer", raw))
+			}
+		}
+		drafts = append(drafts, draft)
+	}
+	return drafts, nil
+}
+
+func parsePublishedAt(raw string) (time.Time, bool) {
+	for _, layout := range publishedAtLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func splitList(raw, separator string) []string {
+	if raw == "" {
+		return nil
+	}
+	var result []string
+	for _, part := range strings.Split(raw, separator) {
+		if part = strings.TrimSpace(part); part != "" {
+			result = append(result, part)
+		}
+	}
+	return result
+}
+
+func isBlankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
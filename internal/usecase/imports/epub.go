@@ -0,0 +1,132 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/books"
+	"github.com/iamvkosarev/book-shelf/pkg/epub"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const epubURNISBN = "urn:isbn:"
+
+type EPUBOptions struct {
+	Commit bool
+	Force  bool
+}
+
+type EPUBResult struct {
+	Draft    books.CreateBookInput
+	Language string
+	BookID   uuid.UUID
+	Created  Creations
+}
+
+// ImportEPUB reads the OPF metadata of an EPUB, matching authors, tags and the
+// publisher by name and creating the missing ones so the draft is complete.
+func (u *ImportUsecase) ImportEPUB(
+	ctx context.Context,
+	reader io.ReaderAt,
+	size int64,
+	options EPUBOptions,
+) (EPUBResult, error) {
+	pkg, err := epub.ReadPackage(reader, size)
+	if errors.Is(err, epub.ErrInvalidEPUB) {
+		return EPUBResult{}, model.NewInternalError(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		return EPUBResult{}, err
+	}
+
+	draft := epubDraft(pkg)
+	validateDraft(&draft)
+	if len(draft.Errors) > 0 {
+		return EPUBResult{}, model.ErrImportEPUBInvalid.WithDetails(draft.Errors)
+	}
+
+	catalog, err := u.loadCatalog(ctx)
+	if err != nil {
+		return EPUBResult{}, err
+	}
+	catalog.plan(&draft, true)
+	if err = u.createPlanned(ctx, catalog); err != nil {
+		return EPUBResult{}, u.discardCreated(ctx, catalog, err)
+	}
+
+	result := EPUBResult{
+		Draft:   catalog.createInput(draft),
+		Created: catalog.planned,
+	}
+	if len(pkg.Languages) > 0 {
+		result.Language = pkg.Languages[0]
+	}
+	if !options.Commit {
+		return result, nil
+	}
+
+	input := result.Draft
+	input.Force = options.Force
+	if result.BookID, err = u.BooksUsecase.AddBook(ctx, input); err != nil {
+		return EPUBResult{}, u.discardCreated(ctx, catalog, fmt.Errorf("failed to add epub book: %w", err))
+	}
+	return result, nil
+}
+
+func epubDraft(pkg epub.Package) BookDraft {
+	draft := BookDraft{Row: 1, Tags: pkg.Subjects}
+	if len(pkg.Titles) > 0 {
+		draft.Title = pkg.Titles[0]
+	}
+	for _, creator := range pkg.Creators {
+		if !creator.IsAuthor() {
+			continue
+		}
+		name := creator.Name
+		if strings.Contains(creator.FileAs, ",") {
+			name = creator.FileAs
+		}
+		draft.Authors = append(draft.Authors, ParseAuthorName(name))
+	}
+	if len(pkg.Publishers) > 0 {
+		draft.Publisher = pkg.Publishers[0]
+	}
+	for _, raw := range pkg.Dates {
+		if date, ok := parseEPUBDate(raw); ok {
+			draft.PublishedAt = &date
+			break
+		}
+	}
+	draft.ISBN = epubISBN(pkg.Identifiers)
+	if description := htmlToText(pkg.Description); description != "" {
+		draft.Description = &description
+	}
+	return draft
+}
+
+// epubISBN picks the first identifier that is a valid ISBN, whether it is
+// marked by scheme, written as a URN or left as a bare number.
+func epubISBN(identifiers []epub.Identifier) string {
+	for _, identifier := range identifiers {
+		value := identifier.Value
+		if len(value) > len(epubURNISBN) && strings.EqualFold(value[:len(epubURNISBN)], epubURNISBN) {
+			value = value[len(epubURNISBN):]
+		}
+		if isbn, ok := books.NormalizeISBN(value); ok {
+			return isbn
+		}
+	}
+	return ""
+}
+
+// parseEPUBDate keeps only the calendar date of W3CDTF values such as
+// 1965-08-01T00:00:00+02:00, so the offset cannot shift the day.
+func parseEPUBDate(raw string) (time.Time, bool) {
+	date, _, _ := strings.Cut(raw, "T")
+	return parsePublishedAt(date)
+}
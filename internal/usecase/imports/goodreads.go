@@ -0,0 +1,134 @@
+package imports
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	goodreadsTitle             = "Title"
+	goodreadsAuthor            = "Author"
+	goodreadsAdditionalAuthors = "Additional Authors"
+	goodreadsISBN              = "ISBN"
+	goodreadsISBN13            = "ISBN13"
+	goodreadsPublisher         = "Publisher"
+	goodreadsNumberOfPages     = "Number of Pages"
+	goodreadsYearPublished     = "Year Published"
+	goodreadsOriginalYear      = "Original Publication Year"
+	goodreadsBookshelves       = "Bookshelves"
+
+	goodreadsListSeparator = ","
+)
+
+func (u *ImportUsecase) ImportGoodreadsCSV(ctx context.Context, reader io.Reader, options Options) (Report, error) {
+	drafts, err := parseGoodreadsCSV(reader)
+	if err != nil {
+		return Report{}, err
+	}
+	return u.importDrafts(ctx, drafts, options)
+}
+
+func parseGoodreadsCSV(reader io.Reader) ([]BookDraft, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+
+	header, err := csvReader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, model.ErrImportCSVEmpty
+		}
+		return nil, model.NewInternalError(http.StatusBadRequest, fmt.Sprintf("invalid csv: %s", err))
+	}
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		positions[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	if _, ok := positions[goodreadsTitle]; !ok {
+		return nil, model.ErrImportCSVTitleNotMapped
+	}
+
+	var drafts []BookDraft
+	for {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			drafts = append(drafts, BookDraft{Row: parseErr.StartLine, Errors: []string{parseErr.Err.Error()}})
+			continue
+		}
+		if err != nil {
+			return nil, model.NewInternalError(http.StatusBadRequest, fmt.Sprintf("invalid csv: %s", err))
+		}
+		if isBlankRecord(record) {
+			continue
+		}
+		row, _ := csvReader.FieldPos(0)
+		value := func(column string) string {
+			position, ok := positions[column]
+			if !ok || position >= len(record) {
+				return ""
+			}
+			return unquoteGoodreadsValue(record[position])
+		}
+
+		draft := BookDraft{
+			Row:       row,
+			Title:     value(goodreadsTitle),
+			Publisher: value(goodreadsPublisher),
+			Tags:      splitList(value(goodreadsBookshelves), goodreadsListSeparator),
+		}
+		draft.ISBN = value(goodreadsISBN13)
+		if draft.ISBN == "" {
+			draft.ISBN = value(goodreadsISBN)
+		}
+
+		if author := value(goodreadsAuthor); author != "" {
+			draft.Authors = append(draft.Authors, ParseAuthorName(author))
+		}
+		for _, name := range splitList(value(goodreadsAdditionalAuthors), goodreadsListSeparator) {
+			draft.Authors = append(draft.Authors, ParseAuthorName(name))
+		}
+
+		year := value(goodreadsYearPublished)
+		if year == "" {
+			year = value(goodreadsOriginalYear)
+		}
+		if year != "" {
+			if parsed, err := strconv.Atoi(year); err == nil {
+				publishedAt := time.Date(parsed, time.January, 1, 0, 0, 0, 0, time.UTC)
+				draft.PublishedAt = &publishedAt
+			} else {
+				draft.Errors = append(draft.Errors, fmt.Sprintf("year %q is not a number", year))
+			}
+		}
+
+		if raw := value(goodreadsNumberOfPages); raw != "" {
+			if pages, err := strconv.ParseInt(raw, 10, 32); err != nil {
+				draft.Errors = append(draft.Errors, fmt.Sprintf("number of pages %q is not an integer", raw))
+			} else if pages > 0 {
+				p := int32(pages)
+				draft.Pages = &p
+			}
+		}
+		drafts = append(drafts, draft)
+	}
+	return drafts, nil
+}
+
+func unquoteGoodreadsValue(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, `="`) && strings.HasSuffix(raw, `"`) {
+		raw = raw[2 : len(raw)-1]
+	}
+	return strings.TrimSpace(raw)
+}
@@ -0,0 +1,459 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/books"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxTitleLength       = 100
+	maxDescriptionLength = 1000
+	maxNameLength        = 50
+	maxAuthorNameLength  = 100
+)
+
+type BooksUsecase interface {
+	AddBook(ctx context.Context, input books.CreateBookInput) (uuid.UUID, error)
+	ApplyBatch(ctx context.Context, operations []books.BatchOperation, atomic bool) ([]books.BatchItemResult, bool, error)
+	CheckDuplicates(ctx context.Context, inputs []books.CreateBookInput) ([]books.DuplicateCandidates, error)
+	ListBooks(
+		ctx context.Context,
+		parameters books.ListBookParameters,
+		expandAuthors, expandTags, expandPublisher bool,
+	) ([]model.Book, error)
+}
+
+type AuthorsUsecase interface {
+	AddAuthor(ctx context.Context, input usecase.AddAuthorInput) (uuid.UUID, error)
+	RemoveAuthor(ctx context.Context, id uuid.UUID, policy model.RemovePolicy) error
+	ListAuthors(ctx context.Context) ([]model.Author, error)
+}
+
+type TagsUsecase interface {
+	AddTag(ctx context.Context, name string) (uuid.UUID, error)
+	RemoveTag(ctx context.Context, id uuid.UUID, policy model.RemovePolicy) error
+	ListTags(ctx context.Context) ([]model.Tag, error)
+}
+
+type PublishersUsecase interface {
+	AddPublisher(ctx context.Context, name string) (uuid.UUID, error)
+	RemovePublisher(ctx context.Context, id uuid.UUID, policy model.RemovePolicy) error
+	ListPublishers(ctx context.Context) ([]model.Publisher, error)
+}
+
+type ImportUsecaseDeps struct {
+	BooksUsecase          BooksUsecase
+	AuthorsUsecase        AuthorsUsecase
+	TagsUsecase           TagsUsecase
+	PublishersUsecase     PublishersUsecase
+	CalibreOpener         CalibreOpener
+	CalibreImportsStorage CalibreImportsStorage
+	// CurrencyCode is the ISO 4217 code of the book prices, imported prices in
+	// other currencies are skipped.
+	CurrencyCode string
+}
+
+type ImportUsecase struct {
+	ImportUsecaseDeps
+}
+
+func NewImportUsecase(deps ImportUsecaseDeps) *ImportUsecase {
+	return &ImportUsecase{
+		ImportUsecaseDeps: deps,
+	}
+}
+
+// Options.Force creates the new books the likely-duplicate check of the books
+// usecase refuses.
+type Options struct {
+	DryRun        bool
+	CreateMissing bool
+	Upsert        bool
+	Force         bool
+}
+
+type BookDraft struct {
+	Row         int
+	Title       string
+	ISBN        string
+	Authors     []AuthorName
+	Tags        []string
+	Publisher   string
+	PublishedAt *time.Time
+	Description *string
+	Price       *float64
+	Pages       *int32
+	Errors      []string
+}
+
+type RowStatus string
+
+const (
+	RowPlanned  RowStatus = "planned"
+	RowCreated  RowStatus = "created"
+	RowExisting RowStatus = "existing"
+	RowUpdated  RowStatus = "updated"
+	RowFailed   RowStatus = "failed"
+)
+
+// RowReport of a row refused as a likely duplicate names the stored books in
+// Candidates and the earlier rows in CandidateRows that it likely duplicates.
+type RowReport struct {
+	Row           int
+	Status        RowStatus
+	BookID        uuid.UUID
+	Errors        []string
+	Candidates    []uuid.UUID
+	CandidateRows []int
+}
+
+type Creations struct {
+	Authors    []AuthorName
+	Tags       []string
+	Publishers []string
+}
+
+// Report lists in Orphans the created entities that no imported book uses
+// and that could not be removed again.
+type Report struct {
+	DryRun  bool
+	Rows    []RowReport
+	Planned Creations
+	Orphans Creations
+}
+
+func (u *ImportUsecase) importDrafts(ctx context.Context, drafts []BookDraft, options Options) (Report, error) {
+	catalog, err := u.loadCatalog(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+
+	for i := range drafts {
+		validateDraft(&drafts[i])
+	}
+	index, err := u.loadBookIndex(ctx, drafts)
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{
+		DryRun: options.DryRun,
+		Rows:   make([]RowReport, len(drafts)),
+	}
+	sameAs := make([]int, len(drafts))
+	for i := range drafts {
+		sameAs[i] = -1
+		report.Rows[i] = RowReport{Row: drafts[i].Row, Status: RowPlanned}
+		titleKey := catalog.draftTitleKey(drafts[i])
+		if len(drafts[i].Errors) == 0 {
+			if match, ok := index.find(drafts[i].ISBN, titleKey); ok {
+				report.Rows[i].BookID = match.id
+				if !options.Upsert || match.draft >= 0 {
+					report.Rows[i].Status = RowExisting
+					sameAs[i] = match.draft
+					continue
+				}
+			}
+		}
+		catalog.plan(&drafts[i], options.CreateMissing)
+		report.Rows[i].Errors = drafts[i].Errors
+		if len(drafts[i].Errors) > 0 {
+			report.Rows[i].Status = RowFailed
+			continue
+		}
+		if report.Rows[i].BookID == uuid.Nil {
+			index.add(drafts[i].ISBN, titleKey, bookMatch{draft: i})
+		}
+	}
+	report.Planned = catalog.planned
+
+	if options.DryRun {
+		if err = u.checkPlannedDuplicates(ctx, catalog, drafts, &report, options.Force); err != nil {
+			return Report{}, err
+		}
+		followSameBook(&report, sameAs)
+		return report, nil
+	}
+
+	if err = u.createPlanned(ctx, catalog); err != nil {
+		return Report{}, u.discardCreated(ctx, catalog, err)
+	}
+
+	var (
+		operations []books.BatchOperation
+		indexes    []int
+	)
+	for i, draft := range drafts {
+		if report.Rows[i].Status != RowPlanned {
+			continue
+		}
+		if id := report.Rows[i].BookID; id != uuid.Nil {
+			operations = append(operations, books.BatchOperation{
+				Kind:   books.BatchUpdate,
+				ID:     id,
+				Update: updatePatch(catalog.createInput(draft)),
+			})
+		} else {
+			input := catalog.createInput(draft)
+			input.Force = options.Force
+			operations = append(operations, books.BatchOperation{
+				Kind:   books.BatchCreate,
+				Create: input,
+			})
+		}
+		indexes = append(indexes, i)
+	}
+	if len(operations) == 0 {
+		return report, nil
+	}
+
+	results, _, err := u.BooksUsecase.ApplyBatch(ctx, operations, false)
+	if err != nil {
+		return Report{}, u.discardCreated(ctx, catalog, fmt.Errorf("failed to create imported books: %w", err))
+	}
+	for i, result := range results {
+		row := &report.Rows[indexes[i]]
+		if candidates, ok := duplicateCandidatesOf(result.Err); ok {
+			failDuplicate(&report, indexes, indexes[i], candidates)
+			continue
+		}
+		if result.Err != nil {
+			row.Status = RowFailed
+			row.Errors = append(row.Errors, result.Err.Error())
+			continue
+		}
+		row.Status = RowCreated
+		if operations[i].Kind == books.BatchUpdate {
+			row.Status = RowUpdated
+		}
+		row.BookID = result.ID
+	}
+	followSameBook(&report, sameAs)
+	report.Orphans = u.removeUnused(ctx, catalog)
+	return report, nil
+}
+
+// followSameBook gives the rows matched to an earlier row of the same book the
+// outcome of that row.
+func followSameBook(report *Report, sameAs []int) {
+	for i, draft := range sameAs {
+		if draft < 0 {
+			continue
+		}
+		if report.Rows[draft].Status == RowFailed {
+			report.Rows[i].Status = RowFailed
+			report.Rows[i].Errors = []string{fmt.Sprintf("row %d with the same book failed", report.Rows[draft].Row)}
+			continue
+		}
+		report.Rows[i].BookID = report.Rows[draft].BookID
+	}
+}
+
+// checkPlannedDuplicates runs the likely-duplicate check of the books usecase
+// on the books a dry run would create, so that it fails the rows the import
+// fails. Planned entities get placeholder ids for the comparison.
+func (u *ImportUsecase) checkPlannedDuplicates(
+	ctx context.Context,
+	catalog *catalog,
+	drafts []BookDraft,
+	report *Report,
+	force bool,
+) error {
+	catalog.placeholdPlanned()
+	var (
+		inputs  []books.CreateBookInput
+		indexes []int
+	)
+	for i, row := range report.Rows {
+		if row.Status != RowPlanned || row.BookID != uuid.Nil {
+			continue
+		}
+		input := catalog.createInput(drafts[i])
+		input.Force = force
+		inputs = append(inputs, input)
+		indexes = append(indexes, i)
+	}
+	if len(inputs) == 0 {
+		return nil
+	}
+
+	duplicates, err := u.BooksUsecase.CheckDuplicates(ctx, inputs)
+	if err != nil {
+		return err
+	}
+	for i, candidates := range duplicates {
+		if !candidates.Empty() {
+			failDuplicate(report, indexes, indexes[i], candidates)
+		}
+	}
+	return nil
+}
+
+func duplicateCandidatesOf(err error) (books.DuplicateCandidates, bool) {
+	var internalError *model.InternalError
+	if !errors.Is(err, model.ErrBookLikelyDuplicate) || !errors.As(err, &internalError) {
+		return books.DuplicateCandidates{}, false
+	}
+	candidates, ok := internalError.Details().(books.DuplicateCandidates)
+	return candidates, ok
+}
+
+// failDuplicate fails a row refused as a likely duplicate, indexes maps the
+// items of the check to rows.
+func failDuplicate(report *Report, indexes []int, row int, candidates books.DuplicateCandidates) {
+	report.Rows[row].Status = RowFailed
+	report.Rows[row].Errors = append(report.Rows[row].Errors, model.ErrBookLikelyDuplicate.Error())
+	report.Rows[row].Candidates = candidates.CandidatesIDs
+	for _, item := range candidates.CandidatesItems {
+		report.Rows[row].CandidateRows = append(report.Rows[row].CandidateRows, report.Rows[indexes[item]].Row)
+	}
+}
+
+func updatePatch(input books.CreateBookInput) books.UpdateBookPatch {
+	return books.UpdateBookPatch{
+		PublisherID: input.PublisherID,
+		TagsIDs:     input.TagsIDs,
+		AuthorsIDs:  input.AuthorsIDs,
+		PublishedAt: input.PublishedAt,
+		Title:       &input.Title,
+		Description: input.Description,
+		Price:       input.Price,
+		Pages:       input.Pages,
+		ISBN:        input.ISBN,
+	}
+}
+
+func (u *ImportUsecase) createPlanned(ctx context.Context, catalog *catalog) error {
+	for _, name := range catalog.planned.Publishers {
+		id, err := u.PublishersUsecase.AddPublisher(ctx, name)
+		if err != nil {
+			return fmt.Errorf("failed to create publisher %q: %w", name, err)
+		}
+		catalog.publishers[normalizeName(name)] = id
+		catalog.created.publishers = append(catalog.created.publishers, id)
+	}
+	for _, name := range catalog.planned.Tags {
+		id, err := u.TagsUsecase.AddTag(ctx, name)
+		if err != nil {
+			return fmt.Errorf("failed to create tag %q: %w", name, err)
+		}
+		catalog.tags[normalizeName(name)] = id
+		catalog.created.tags = append(catalog.created.tags, id)
+	}
+	for _, name := range catalog.planned.Authors {
+		id, err := u.AuthorsUsecase.AddAuthor(ctx, name.addAuthorInput())
+		if err != nil {
+			return fmt.Errorf("failed to create author %q: %w", name.String(), err)
+		}
+		catalog.addAuthor(name, id)
+		catalog.created.authors = append(catalog.created.authors, id)
+	}
+	return nil
+}
+
+// removeUnused removes the created entities that ended up without books, the
+// ones used by a book are kept by the restrict policy. It returns the entities
+// that could not be removed.
+func (u *ImportUsecase) removeUnused(ctx context.Context, catalog *catalog) Creations {
+	var orphans Creations
+	policy := model.RemovePolicy{OnBooks: model.OnBooksRestrict}
+	for i, id := range catalog.created.publishers {
+		err := u.PublishersUsecase.RemovePublisher(ctx, id, policy)
+		if err != nil && !errors.Is(err, model.ErrPublisherHasBooks) {
+			orphans.Publishers = append(orphans.Publishers, catalog.planned.Publishers[i])
+		}
+	}
+	for i, id := range catalog.created.tags {
+		err := u.TagsUsecase.RemoveTag(ctx, id, policy)
+		if err != nil && !errors.Is(err, model.ErrTagHasBooks) {
+			orphans.Tags = append(orphans.Tags, catalog.planned.Tags[i])
+		}
+	}
+	for i, id := range catalog.created.authors {
+		err := u.AuthorsUsecase.RemoveAuthor(ctx, id, policy)
+		if err != nil && !errors.Is(err, model.ErrAuthorHasBooks) {
+			orphans.Authors = append(orphans.Authors, catalog.planned.Authors[i])
+		}
+	}
+	catalog.created = createdIDs{}
+	return orphans
+}
+
+// discardCreated removes the created entities after a failed import and names
+// the ones left behind in the error.
+func (u *ImportUsecase) discardCreated(ctx context.Context, catalog *catalog, err error) error {
+	orphans := u.removeUnused(ctx, catalog)
+	if orphans.Empty() {
+		return err
+	}
+	return fmt.Errorf("%w (left unused: %s)", err, orphans)
+}
+
+func (c Creations) Empty() bool {
+	return len(c.Authors) == 0 && len(c.Tags) == 0 && len(c.Publishers) == 0
+}
+
+func (c Creations) String() string {
+	names := make([]string, 0, len(c.Authors)+len(c.Tags)+len(c.Publishers))
+	for _, author := range c.Authors {
+		names = append(names, "author "+strconv.Quote(author.String()))
+	}
+	for _, tag := range c.Tags {
+		names = append(names, "tag "+strconv.Quote(tag))
+	}
+	for _, publisher := range c.Publishers {
+		names = append(names, "publisher "+strconv.Quote(publisher))
+	}
+	return strings.Join(names, ", ")
+}
+
+func validateDraft(draft *BookDraft) {
+	title := strings.TrimSpace(draft.Title)
+	switch {
+	case title == "":
+		draft.Errors = append(draft.Errors, "title is required")
+	case utf8.RuneCountInString(title) > maxTitleLength:
+		draft.Errors = append(draft.Errors, fmt.Sprintf("title is longer than %d characters", maxTitleLength))
+	}
+	draft.Title = title
+	if draft.ISBN != "" {
+		if isbn, ok := books.NormalizeISBN(draft.ISBN); ok {
+			draft.ISBN = isbn
+		} else {
+			draft.Errors = append(draft.Errors, fmt.Sprintf("isbn %q is not a valid ISBN", draft.ISBN))
+		}
+	}
+	if draft.Description != nil && utf8.RuneCountInString(*draft.Description) > maxDescriptionLength {
+		draft.Errors = append(
+			draft.Errors, fmt.Sprintf("description is longer than %d characters", maxDescriptionLength),
+		)
+	}
+	if draft.Price != nil && *draft.Price < 0 {
+		draft.Errors = append(draft.Errors, "price must not be negative")
+	}
+	if draft.Pages != nil && *draft.Pages < 1 {
+		draft.Errors = append(draft.Errors, "pages must be positive")
+	}
+	if utf8.RuneCountInString(draft.Publisher) > maxNameLength {
+		draft.Errors = append(draft.Errors, fmt.Sprintf("publisher is longer than %d characters", maxNameLength))
+	}
+	for _, tag := range draft.Tags {
+		if utf8.RuneCountInString(tag) > maxNameLength {
+			draft.Errors = append(draft.Errors, fmt.Sprintf("tag %q is longer than %d characters", tag, maxNameLength))
+		}
+	}
+	for _, author := range draft.Authors {
+		if !author.valid() {
+			draft.Errors = append(draft.Errors, fmt.Sprintf("author %q is not a valid name", author.String()))
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package imports
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/pkg/marc"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const marcTrailingMarks = " /:;,.="
+
+var marcYearPattern = regexp.MustCompile(`\d{4}`)
+
+func (u *ImportUsecase) ImportMARC(ctx context.Context, reader io.Reader, options Options) (Report, error) {
+	records, err := readMARC(reader)
+	if err != nil {
+		return Report{}, err
+	}
+	drafts := make([]BookDraft, len(records))
+	for i, record := range records {
+		drafts[i] = marcDraft(i+1, record)
+	}
+	return u.importDrafts(ctx, drafts, options)
+}
+
+func readMARC(reader io.Reader) ([]marc.Record, error) {
+	buffered := bufio.NewReader(reader)
+	head, _ := buffered.Peek(512)
+	head = bytes.TrimLeft(bytes.TrimPrefix(head, []byte("\ufeff")), " \t\r\n")
+
+	var (
+		records []marc.Record
+		err     error
+	)
+	if bytes.HasPrefix(head, []byte("<")) {
+		records, err = marc.ReadXML(buffered)
+	} else {
+		records, err = marc.ReadBinary(buffered)
+	}
+	if errors.Is(err, marc.ErrInvalidRecord) {
+		return nil, model.NewInternalError(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, model.ErrImportMARCEmpty
+	}
+	return records, nil
+}
+
+func marcDraft(row int, record marc.Record) BookDraft {
+	draft := BookDraft{Row: row}
+	if fields := record.DataFieldsByTag(marc.TagTitle); len(fields) > 0 {
+		draft.Title = joinMARC(" : ", fields[0].Subfield('a'), fields[0].Subfield('b'))
+	}
+	if fields := record.DataFieldsByTag(marc.TagISBN); len(fields) > 0 {
+		draft.ISBN = firstWord(fields[0].Subfield('a'))
+	}
+
+	authorFields := append(record.DataFieldsByTag(marc.TagMainAuthor), record.DataFieldsByTag(marc.TagAddedAuthor)...)
+	for _, field := range authorFields {
+		heading := trimMARC(field.Subfield('a'))
+		if heading == "" {
+			continue
+		}
+		if field.Ind1 == '0' {
+			draft.Authors = append(draft.Authors, marcForenameAuthor(heading, field.Subfield('c')))
+			continue
+		}
+		draft.Authors = append(draft.Authors, ParseAuthorName(heading))
+	}
+
+	publication := record.DataFieldsByTag(marc.TagProduction)
+	if len(publication) == 0 {
+		publication = record.DataFieldsByTag(marc.TagPublication)
+	}
+	if len(publication) > 0 {
+		draft.Publisher = trimMARC(publication[0].Subfield('b'))
+		if raw := trimMARC(publication[0].Subfield('c')); raw != "" {
+			if publishedAt, ok := parseMARCDate(raw); ok {
+				draft.PublishedAt = &publishedAt
+			} else {
+				draft.Errors = append(draft.Errors, fmt.Sprintf("date %q is not a date", raw))
+			}
+		}
+	}
+
+	if fields := record.DataFieldsByTag(marc.TagSummary); len(fields) > 0 {
+		if description := strings.TrimSpace(fields[0].Subfield('a')); description != "" {
+			draft.Description = &description
+		}
+	}
+	for _, field := range record.DataFieldsByTag(marc.TagSubject) {
+		if tag := trimMARC(field.Subfield('a')); tag != "" {
+			draft.Tags = append(draft.Tags, tag)
+		}
+	}
+	return draft
+}
+
+// marcForenameAuthor reads a forename heading, the one qualified as a
+// pseudonym in $c is kept whole as the pseudonym.
+func marcForenameAuthor(heading, qualifier string) AuthorName {
+	if strings.Contains(strings.ToLower(qualifier), "pseud") {
+		return AuthorName{Pseudonym: heading}
+	}
+	parts := strings.Fields(heading)
+	return AuthorName{FirstName: parts[0], MiddleName: strings.Join(parts[1:], " ")}
+}
+
+func parseMARCDate(raw string) (time.Time, bool) {
+	if publishedAt, ok := parsePublishedAt(raw); ok {
+		return publishedAt, true
+	}
+	year := marcYearPattern.FindString(raw)
+	if year == "" {
+		return time.Time{}, false
+	}
+	return parsePublishedAt(year)
+}
+
+func trimMARC(value string) string {
+	return strings.TrimRight(strings.TrimSpace(value), marcTrailingMarks)
+}
+
+func joinMARC(separator string, values ...string) string {
+	var parts []string
+	for _, value := range values {
+		if value = trimMARC(value); value != "" {
+			parts = append(parts, value)
+		}
+	}
+	return strings.Join(parts, separator)
+}
+
+func firstWord(value string) string {
+	if fields := strings.Fields(value); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
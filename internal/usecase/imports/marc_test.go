@@ -0,0 +1,212 @@
+package imports
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/exports"
+	"github.com/iamvkosarev/book-shelf/pkg/marc"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the expected MARCXML files in testdata")
+
+type marcFixture struct {
+	ID          uuid.UUID           `json:"id"`
+	Title       string              `json:"title"`
+	ISBN        string              `json:"isbn"`
+	Authors     []marcFixtureAuthor `json:"authors"`
+	Publisher   string              `json:"publisher"`
+	PublishedAt string              `json:"published_at"`
+	Description string              `json:"description"`
+	Tags        []string            `json:"tags"`
+}
+
+type marcFixtureAuthor struct {
+	FirstName  string `json:"first_name"`
+	MiddleName string `json:"middle_name"`
+	LastName   string `json:"last_name"`
+	Pseudonym  string `json:"pseudonym"`
+}
+
+// TestMARCRoundTrip exports every fixture book, imports the record back and
+// exports the imported book again: both exports and the imported fields must
+// match the fixture.
+func TestMARCRoundTrip(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "marc", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no MARC fixtures")
+	}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		t.Run(name, func(t *testing.T) {
+			fixture := readMARCFixture(t, path)
+			exported := exports.BookToMARC(fixture.book(t))
+			exportedXML := marcXML(t, exported)
+
+			goldenPath := strings.TrimSuffix(path, ".json") + ".xml"
+			if *update {
+				if err := os.WriteFile(goldenPath, exportedXML, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			golden, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(exportedXML, golden) {
+				t.Fatalf("export differs from %s:\n%s", goldenPath, exportedXML)
+			}
+
+			records, err := marc.ReadXML(bytes.NewReader(exportedXML))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != 1 {
+				t.Fatalf("read %d records, want 1", len(records))
+			}
+			draft := marcDraft(1, records[0])
+			validateDraft(&draft)
+			if len(draft.Errors) > 0 {
+				t.Fatalf("imported draft has errors: %v", draft.Errors)
+			}
+			fixture.compare(t, draft)
+
+			var binary bytes.Buffer
+			if err = marc.WriteBinary(&binary, exported); err != nil {
+				t.Fatal(err)
+			}
+			binaryRecords, err := marc.ReadBinary(&binary)
+			if err != nil {
+				t.Fatal(err)
+			}
+			binaryDraft := marcDraft(1, binaryRecords[0])
+			validateDraft(&binaryDraft)
+			if !reflect.DeepEqual(binaryDraft, draft) {
+				t.Errorf("binary import %+v differs from MARCXML import %+v", binaryDraft, draft)
+			}
+
+			reexported := marcXML(t, exports.BookToMARC(draftBook(fixture.ID, draft)))
+			if !bytes.Equal(reexported, exportedXML) {
+				t.Errorf("second export differs from the first:\n%s", reexported)
+			}
+		})
+	}
+}
+
+func readMARCFixture(t *testing.T, path string) marcFixture {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fixture marcFixture
+	if err = json.Unmarshal(data, &fixture); err != nil {
+		t.Fatalf("failed to decode %s: %v", path, err)
+	}
+	return fixture
+}
+
+func (f marcFixture) publishedAt(t *testing.T) *time.Time {
+	t.Helper()
+	if f.PublishedAt == "" {
+		return nil
+	}
+	publishedAt, err := time.Parse(time.DateOnly, f.PublishedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &publishedAt
+}
+
+func (f marcFixture) authors() []AuthorName {
+	names := make([]AuthorName, len(f.Authors))
+	for i, author := range f.Authors {
+		names[i] = AuthorName(author)
+	}
+	return names
+}
+
+func (f marcFixture) book(t *testing.T) model.Book {
+	t.Helper()
+	draft := BookDraft{
+		Title:       f.Title,
+		ISBN:        f.ISBN,
+		Authors:     f.authors(),
+		Tags:        f.Tags,
+		Publisher:   f.Publisher,
+		PublishedAt: f.publishedAt(t),
+		Description: nonEmpty(f.Description),
+	}
+	return draftBook(f.ID, draft)
+}
+
+func (f marcFixture) compare(t *testing.T, draft BookDraft) {
+	t.Helper()
+	if draft.Title != f.Title {
+		t.Errorf("title = %q, want %q", draft.Title, f.Title)
+	}
+	if draft.ISBN != f.ISBN {
+		t.Errorf("isbn = %q, want %q", draft.ISBN, f.ISBN)
+	}
+	if !reflect.DeepEqual(draft.Authors, f.authors()) {
+		t.Errorf("authors = %+v, want %+v", draft.Authors, f.authors())
+	}
+	if !reflect.DeepEqual(draft.Tags, f.Tags) {
+		t.Errorf("tags = %q, want %q", draft.Tags, f.Tags)
+	}
+	if draft.Publisher != f.Publisher {
+		t.Errorf("publisher = %q, want %q", draft.Publisher, f.Publisher)
+	}
+	if got, want := derefString(draft.Description), f.Description; got != want {
+		t.Errorf("description = %q, want %q", got, want)
+	}
+	if got, want := draft.PublishedAt, f.publishedAt(t); !reflect.DeepEqual(got, want) {
+		t.Errorf("published at = %v, want %v", got, want)
+	}
+}
+
+func draftBook(id uuid.UUID, draft BookDraft) model.Book {
+	book := model.Book{
+		ID:          id,
+		Title:       draft.Title,
+		ISBN:        nonEmpty(draft.ISBN),
+		PublishedAt: draft.PublishedAt,
+		Description: draft.Description,
+	}
+	for _, name := range draft.Authors {
+		input := name.addAuthorInput()
+		book.Authors = append(book.Authors, model.Author{
+			FirstName:  input.FirstName,
+			MiddleName: input.MiddleName,
+			LastName:   input.LastName,
+			Pseudonym:  input.Pseudonym,
+		})
+	}
+	if draft.Publisher != "" {
+		book.Publisher = &model.Publisher{Name: draft.Publisher}
+	}
+	for _, tag := range draft.Tags {
+		book.Tags = append(book.Tags, model.Tag{Name: tag})
+	}
+	return book
+}
+
+func marcXML(t *testing.T, record marc.Record) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	if err := marc.WriteXML(&buffer, record); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
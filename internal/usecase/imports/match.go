@@ -0,0 +1,119 @@
+package imports
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/books"
+	"sort"
+	"strings"
+)
+
+type bookMatch struct {
+	id    uuid.UUID
+	draft int
+}
+
+type bookIndex struct {
+	byISBN  map[string]bookMatch
+	byTitle map[string]bookMatch
+}
+
+// indexChunkSize keeps the candidate queries well below the Postgres limit on
+// bind parameters.
+const indexChunkSize = 1000
+
+// loadBookIndex indexes the books sharing an ISBN or a normalized title with
+// one of the valid drafts, the rest of the catalog can not match them.
+func (u *ImportUsecase) loadBookIndex(ctx context.Context, drafts []BookDraft) (*bookIndex, error) {
+	var isbns, titles []string
+	seen := make(map[string]bool)
+	for _, draft := range drafts {
+		if len(draft.Errors) > 0 {
+			continue
+		}
+		if draft.ISBN != "" && !seen["isbn:"+draft.ISBN] {
+			seen["isbn:"+draft.ISBN] = true
+			isbns = append(isbns, draft.ISBN)
+		}
+		if title := books.NormalizeTitle(draft.Title); title != "" && !seen["title:"+title] {
+			seen["title:"+title] = true
+			titles = append(titles, title)
+		}
+	}
+
+	index := &bookIndex{
+		byISBN:  make(map[string]bookMatch),
+		byTitle: make(map[string]bookMatch),
+	}
+	for start := 0; start < len(isbns); start += indexChunkSize {
+		parameters := books.ListBookParameters{ISBNs: isbns[start:min(start+indexChunkSize, len(isbns))]}
+		if err := u.indexBooks(ctx, index, parameters); err != nil {
+			return nil, err
+		}
+	}
+	for start := 0; start < len(titles); start += indexChunkSize {
+		parameters := books.ListBookParameters{Titles: titles[start:min(start+indexChunkSize, len(titles))]}
+		if err := u.indexBooks(ctx, index, parameters); err != nil {
+			return nil, err
+		}
+	}
+	return index, nil
+}
+
+func (u *ImportUsecase) indexBooks(ctx context.Context, index *bookIndex, parameters books.ListBookParameters) error {
+	existing, err := u.BooksUsecase.ListBooks(ctx, parameters, false, false, false)
+	if err != nil {
+		return fmt.Errorf("failed to list books: %w", err)
+	}
+	for _, book := range existing {
+		index.add(derefString(book.ISBN), titleKey(book.Title, book.AuthorsIDs), bookMatch{id: book.ID, draft: -1})
+	}
+	return nil
+}
+
+func (i *bookIndex) add(isbn, title string, match bookMatch) {
+	if _, ok := i.byISBN[isbn]; isbn != "" && !ok {
+		i.byISBN[isbn] = match
+	}
+	if _, ok := i.byTitle[title]; title != "" && !ok {
+		i.byTitle[title] = match
+	}
+}
+
+func (i *bookIndex) find(isbn, title string) (bookMatch, bool) {
+	if isbn != "" {
+		if match, ok := i.byISBN[isbn]; ok {
+			return match, true
+		}
+	}
+	match, ok := i.byTitle[title]
+	return match, ok && title != ""
+}
+
+func (c *catalog) draftTitleKey(draft BookDraft) string {
+	authorsIDs := make([]uuid.UUID, 0, len(draft.Authors))
+	for _, author := range draft.Authors {
+		id, ok := c.authorID(author)
+		if !ok {
+			return ""
+		}
+		if !containsUUID(authorsIDs, id) {
+			authorsIDs = append(authorsIDs, id)
+		}
+	}
+	return titleKey(draft.Title, authorsIDs)
+}
+
+func titleKey(title string, authorsIDs []uuid.UUID) string {
+	normalized := books.NormalizeTitle(title)
+	if normalized == "" {
+		return ""
+	}
+	ids := make([]string, len(authorsIDs))
+	for i, id := range authorsIDs {
+		ids[i] = id.String()
+	}
+	sort.Strings(ids)
+	return normalized + "|" + strings.Join(ids, ",")
+}
@@ -0,0 +1,211 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/pkg/onix"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const onixKeywordsSeparator = ";"
+
+var onixDateLayouts = map[int]string{8: "20060102", 6: "200601", 4: "2006"}
+
+func (u *ImportUsecase) ImportONIX(ctx context.Context, reader io.Reader, options Options) (Report, error) {
+	message, err := onix.Read(reader)
+	if errors.Is(err, onix.ErrInvalidMessage) {
+		return Report{}, model.NewInternalError(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		return Report{}, err
+	}
+	if len(message.Products) == 0 {
+		return Report{}, model.ErrImportONIXEmpty
+	}
+
+	drafts := make([]BookDraft, len(message.Products))
+	for i, product := range message.Products {
+		drafts[i] = onixDraft(i+1, product, u.CurrencyCode, message.Header.DefaultCurrencyCode)
+	}
+	options.Upsert = true
+	return u.importDrafts(ctx, drafts, options)
+}
+
+func onixDraft(row int, product onix.Product, currencyCode, defaultCurrencyCode string) BookDraft {
+	draft := BookDraft{Row: row}
+	for _, identifier := range product.ProductIdentifiers {
+		if identifier.ProductIDType == onix.ProductIDTypeISBN13 ||
+			identifier.ProductIDType == onix.ProductIDTypeGTIN13 && draft.ISBN == "" {
+			draft.ISBN = strings.TrimSpace(identifier.IDValue)
+		}
+	}
+
+	if detail := product.DescriptiveDetail; detail != nil {
+		draft.Title = onixTitle(detail.TitleDetails)
+		for _, contributor := range detail.Contributors {
+			if name, ok := onixAuthor(contributor); ok {
+				draft.Authors = append(draft.Authors, name)
+			}
+		}
+		for _, subject := range detail.Subjects {
+			heading := strings.TrimSpace(subject.SubjectHeadingText)
+			if subject.SubjectSchemeIdentifier == onix.SubjectSchemeKeywords {
+				draft.Tags = append(draft.Tags, splitList(heading, onixKeywordsSeparator)...)
+			} else if heading != "" {
+				draft.Tags = append(draft.Tags, heading)
+			}
+		}
+	}
+
+	if detail := product.CollateralDetail; detail != nil {
+		draft.Description = onixDescription(detail.TextContents)
+	}
+
+	if detail := product.PublishingDetail; detail != nil {
+		for _, publisher := range detail.Publishers {
+			if publisher.PublishingRole == onix.PublishingRolePublisher || draft.Publisher == "" {
+				draft.Publisher = strings.TrimSpace(publisher.PublisherName)
+			}
+		}
+		if draft.Publisher == "" && len(detail.Imprints) > 0 {
+			draft.Publisher = strings.TrimSpace(detail.Imprints[0].ImprintName)
+		}
+		for _, date := range detail.PublishingDates {
+			if date.PublishingDateRole != onix.PublishingDateRoleDate {
+				continue
+			}
+			raw := strings.TrimSpace(date.Date.Value)
+			if publishedAt, ok := parseONIXDate(raw); ok {
+				draft.PublishedAt = &publishedAt
+			} else {
+				draft.Errors = append(draft.Errors, fmt.Sprintf("publishing date %q is not a date", raw))
+			}
+		}
+	}
+
+	if price := onixPrice(product.ProductSupplies, currencyCode, defaultCurrencyCode); price != nil {
+		raw := strings.TrimSpace(price.PriceAmount)
+		if amount, err := strconv.ParseFloat(raw, 64); err == nil {
+			draft.Price = &amount
+		} else {
+			draft.Errors = append(draft.Errors, fmt.Sprintf("price %q is not a number", raw))
+		}
+	}
+	return draft
+}
+
+// onixPrice takes the price in the configured currency, a price without
+// CurrencyCode is in the default currency of the message header. Among several
+// prices the recommended retail price including tax wins, as that is what the
+// export writes.
+func onixPrice(supplies []onix.ProductSupply, currencyCode, defaultCurrencyCode string) *onix.Price {
+	var chosen *onix.Price
+	for _, supply := range supplies {
+		for _, detail := range supply.SupplyDetails {
+			for i, price := range detail.Prices {
+				priceCurrencyCode := strings.TrimSpace(price.CurrencyCode)
+				if priceCurrencyCode == "" {
+					priceCurrencyCode = strings.TrimSpace(defaultCurrencyCode)
+				}
+				if !strings.EqualFold(priceCurrencyCode, currencyCode) {
+					continue
+				}
+				if chosen == nil || chosen.PriceType != onix.PriceTypeRRPIncludingTax &&
+					price.PriceType == onix.PriceTypeRRPIncludingTax {
+					chosen = &detail.Prices[i]
+				}
+			}
+		}
+	}
+	return chosen
+}
+
+func onixTitle(details []onix.TitleDetail) string {
+	for _, detail := range details {
+		if detail.TitleType != onix.TitleTypeDistinctive {
+			continue
+		}
+		for _, element := range detail.TitleElements {
+			if element.TitleElementLevel != onix.TitleElementProduct {
+				continue
+			}
+			title := element.TitleText
+			if title == "" {
+				title = strings.TrimSpace(element.TitlePrefix + " " + element.TitleWithoutPrefix)
+			}
+			if element.Subtitle != "" {
+				title += ": " + element.Subtitle
+			}
+			return strings.TrimSpace(title)
+		}
+	}
+	return ""
+}
+
+// onixAuthor keeps only authorship roles (A01-A99); editors, translators and
+// other contributors have no counterpart in the schema.
+func onixAuthor(contributor onix.Contributor) (AuthorName, bool) {
+	isAuthor := false
+	for _, role := range contributor.ContributorRoles {
+		if strings.HasPrefix(role, "A") {
+			isAuthor = true
+		}
+	}
+	if !isAuthor {
+		return AuthorName{}, false
+	}
+
+	switch {
+	case contributor.KeyNames != "":
+		name := AuthorName{LastName: strings.TrimSpace(contributor.KeyNames)}
+		if forenames := strings.Fields(contributor.NamesBeforeKey); len(forenames) > 0 {
+			name.FirstName = forenames[0]
+			name.MiddleName = strings.Join(forenames[1:], " ")
+		}
+		return name, true
+	case contributor.PersonNameInverted != "":
+		return ParseAuthorName(contributor.PersonNameInverted), true
+	case contributor.PersonName != "":
+		return ParseAuthorName(contributor.PersonName), true
+	case contributor.CorporateName != "":
+		return AuthorName{Pseudonym: strings.TrimSpace(contributor.CorporateName)}, true
+	}
+	return AuthorName{}, false
+}
+
+func onixDescription(contents []onix.TextContent) *string {
+	var short *string
+	for _, content := range contents {
+		text := plainDescription(content.Text.Value)
+		if content.Text.TextFormat == onix.TextFormatHTML {
+			text = htmlToText(content.Text.Value)
+		}
+		if text == "" {
+			continue
+		}
+		switch content.TextType {
+		case onix.TextTypeDescription:
+			return &text
+		case onix.TextTypeShortDescription:
+			if short == nil {
+				short = &text
+			}
+		}
+	}
+	return short
+}
+
+func parseONIXDate(raw string) (time.Time, bool) {
+	if layout, ok := onixDateLayouts[len(raw)]; ok {
+		if date, err := time.Parse(layout, raw); err == nil {
+			return date, true
+		}
+	}
+	return parsePublishedAt(raw)
+}
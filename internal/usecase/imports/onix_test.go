@@ -0,0 +1,56 @@
+package imports
+
+import (
+	"github.com/iamvkosarev/book-shelf/pkg/onix"
+	"os"
+	"testing"
+)
+
+// TestONIXPrice reads a fixture whose products carry prices in several
+// currencies and price types, only the configured currency may be imported.
+func TestONIXPrice(t *testing.T) {
+	file, err := os.Open("testdata/onix/prices.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	message, err := onix.Read(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		currencyCode string
+		want         []*float64
+	}{
+		{currencyCode: "EUR", want: []*float64{pricePtr(11.40), pricePtr(7.25), nil}},
+		{currencyCode: "GBP", want: []*float64{pricePtr(8.99), nil, nil}},
+		{currencyCode: "USD", want: []*float64{nil, nil, pricePtr(15)}},
+		{currencyCode: "JPY", want: []*float64{nil, nil, nil}},
+	}
+	for _, test := range tests {
+		t.Run(test.currencyCode, func(t *testing.T) {
+			for i, product := range message.Products {
+				draft := onixDraft(i+1, product, test.currencyCode, message.Header.DefaultCurrencyCode)
+				if len(draft.Errors) > 0 {
+					t.Fatalf("%s: draft has errors: %v", product.RecordReference, draft.Errors)
+				}
+				got, want := draft.Price, test.want[i]
+				if (got == nil) != (want == nil) || got != nil && *got != *want {
+					t.Errorf("%s: price = %v, want %v", product.RecordReference, priceValue(got), priceValue(want))
+				}
+			}
+		})
+	}
+}
+
+func pricePtr(value float64) *float64 {
+	return &value
+}
+
+func priceValue(value *float64) any {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
@@ -0,0 +1,226 @@
+package progress
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"golang.org/x/crypto/bcrypt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ProgressInput struct {
+	// Percentage is the read part of the book, from 0 to 100.
+	Percentage float64
+	Page       *int32
+	Position   *string
+	Device     *string
+	DeviceID   *string
+}
+
+type Storage interface {
+	SetBookProgress(
+		ctx context.Context,
+		userID uuid.UUID,
+		bookID uuid.UUID,
+		document *string,
+		progress model.ReadingProgress,
+	) error
+	GetBookProgress(ctx context.Context, userID uuid.UUID, bookID uuid.UUID) (model.ReadingProgress, error)
+	RemoveBookProgress(ctx context.Context, userID uuid.UUID, bookID uuid.UUID) error
+	ListProgress(ctx context.Context, userID uuid.UUID) ([]model.ReadingProgress, error)
+	SetDocumentProgress(
+		ctx context.Context,
+		userID uuid.UUID,
+		document string,
+		progress model.ReadingProgress,
+	) (time.Time, error)
+	GetDocumentProgress(ctx context.Context, userID uuid.UUID, document string) (model.ReadingProgress, error)
+
+	SetKOReaderAccount(ctx context.Context, userID uuid.UUID, username string, keyHash []byte) error
+	GetKOReaderAccount(ctx context.Context, userID uuid.UUID) (model.KOReaderAccount, error)
+	RemoveKOReaderAccount(ctx context.Context, userID uuid.UUID) error
+	GetKOReaderKeyHash(ctx context.Context, username string) (uuid.UUID, []byte, error)
+}
+
+type ProgressUsecase struct {
+	storage Storage
+}
+
+func NewProgressUsecase(storage Storage) *ProgressUsecase {
+	return &ProgressUsecase{
+		storage: storage,
+	}
+}
+
+// SetBookProgress records the progress through our own API. A KOReader
+// document hash given with it links that document to the book, so later
+// syncs from the device update the same progress.
+func (u *ProgressUsecase) SetBookProgress(
+	ctx context.Context,
+	userID uuid.UUID,
+	bookID uuid.UUID,
+	document *string,
+	input ProgressInput,
+) (model.ReadingProgress, error) {
+	progress, err := newProgress(input)
+	if err != nil {
+		return model.ReadingProgress{}, err
+	}
+	if document != nil {
+		trimmed := strings.TrimSpace(*document)
+		if trimmed == "" {
+			return model.ReadingProgress{}, model.ErrProgressDocumentRequired
+		}
+		document = &trimmed
+	}
+	if err = u.storage.SetBookProgress(ctx, userID, bookID, document, progress); err != nil {
+		return model.ReadingProgress{}, err
+	}
+	return u.storage.GetBookProgress(ctx, userID, bookID)
+}
+
+func (u *ProgressUsecase) GetBookProgress(
+	ctx context.Context,
+	userID uuid.UUID,
+	bookID uuid.UUID,
+) (model.ReadingProgress, error) {
+	return u.storage.GetBookProgress(ctx, userID, bookID)
+}
+
+func (u *ProgressUsecase) RemoveBookProgress(ctx context.Context, userID uuid.UUID, bookID uuid.UUID) error {
+	return u.storage.RemoveBookProgress(ctx, userID, bookID)
+}
+
+// ListProgress returns every progress of the user, most recently read first.
+// KOReader documents that are not linked to a book have no book id.
+func (u *ProgressUsecase) ListProgress(ctx context.Context, userID uuid.UUID) ([]model.ReadingProgress, error) {
+	return u.storage.ListProgress(ctx, userID)
+}
+
+// SyncDocumentProgress stores the progress a KOReader device pushed. The
+// device sends its position as text, a page number for paged documents.
+func (u *ProgressUsecase) SyncDocumentProgress(
+	ctx context.Context,
+	userID uuid.UUID,
+	document string,
+	fraction float64,
+	position string,
+	device string,
+	deviceID string,
+) (time.Time, error) {
+	if strings.TrimSpace(document) == "" {
+		return time.Time{}, model.ErrProgressDocumentRequired
+	}
+	if math.IsNaN(fraction) || fraction < 0 || fraction > 1 {
+		return time.Time{}, model.ErrProgressInvalidPercentage
+	}
+	progress := model.ReadingProgress{
+		Fraction: fraction,
+		Position: optionalText(position),
+		Device:   optionalText(device),
+		DeviceID: optionalText(deviceID),
+	}
+	if page, err := strconv.ParseInt(position, 10, 32); err == nil && page > 0 {
+		value := int32(page)
+		progress.Page = &value
+	}
+	return u.storage.SetDocumentProgress(ctx, userID, document, progress)
+}
+
+func (u *ProgressUsecase) GetDocumentProgress(
+	ctx context.Context,
+	userID uuid.UUID,
+	document string,
+) (model.ReadingProgress, error) {
+	return u.storage.GetDocumentProgress(ctx, userID, document)
+}
+
+// LinkKOReader lets the user sign in to the sync server from KOReader with
+// the username and password given here. KOReader never sends the password
+// itself, only its MD5 hex digest, so that digest is what gets hashed.
+func (u *ProgressUsecase) LinkKOReader(ctx context.Context, userID uuid.UUID, username, password string) error {
+	keyHash, err := bcrypt.GenerateFromPassword([]byte(koreaderKey(password)), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash koreader key: %w", err)
+	}
+	return u.storage.SetKOReaderAccount(ctx, userID, strings.TrimSpace(username), keyHash)
+}
+
+func (u *ProgressUsecase) GetKOReaderAccount(ctx context.Context, userID uuid.UUID) (model.KOReaderAccount, error) {
+	return u.storage.GetKOReaderAccount(ctx, userID)
+}
+
+func (u *ProgressUsecase) UnlinkKOReader(ctx context.Context, userID uuid.UUID) error {
+	return u.storage.RemoveKOReaderAccount(ctx, userID)
+}
+
+// AuthenticateKOReader maps the sync protocol credentials onto the user that
+// linked them.
+func (u *ProgressUsecase) AuthenticateKOReader(ctx context.Context, username, key string) (uuid.UUID, error) {
+	if username == "" || key == "" {
+		return uuid.Nil, model.ErrKOReaderUnauthorized
+	}
+	userID, keyHash, err := u.storage.GetKOReaderKeyHash(ctx, username)
+	if err != nil {
+		if errors.Is(err, model.ErrKOReaderNotLinked) {
+			return uuid.Nil, model.ErrKOReaderUnauthorized
+		}
+		return uuid.Nil, err
+	}
+	if err = bcrypt.CompareHashAndPassword(keyHash, []byte(strings.ToLower(key))); err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return uuid.Nil, model.ErrKOReaderUnauthorized
+		}
+		return uuid.Nil, err
+	}
+	return userID, nil
+}
+
+// RegisterKOReader answers the registration call of the sync protocol. Users
+// are only created through our own API, so registering succeeds for an
+// account that is already linked with the same credentials.
+func (u *ProgressUsecase) RegisterKOReader(ctx context.Context, username, key string) error {
+	_, err := u.AuthenticateKOReader(ctx, username, key)
+	if !errors.Is(err, model.ErrKOReaderUnauthorized) {
+		return err
+	}
+	if _, _, err = u.storage.GetKOReaderKeyHash(ctx, username); err == nil {
+		return model.ErrKOReaderUsernameTaken
+	}
+	return err
+}
+
+func newProgress(input ProgressInput) (model.ReadingProgress, error) {
+	if math.IsNaN(input.Percentage) || input.Percentage < 0 || input.Percentage > 100 {
+		return model.ReadingProgress{}, model.ErrProgressInvalidPercentage
+	}
+	if input.Page != nil && *input.Page < 1 {
+		return model.ReadingProgress{}, model.ErrProgressInvalidPage
+	}
+	return model.ReadingProgress{
+		Fraction: input.Percentage / 100,
+		Page:     input.Page,
+		Position: input.Position,
+		Device:   input.Device,
+		DeviceID: input.DeviceID,
+	}, nil
+}
+
+func koreaderKey(password string) string {
+	sum := md5.Sum([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+func optionalText(value string) *string {
+	if value = strings.TrimSpace(value); value == "" {
+		return nil
+	}
+	return &value
+}
@@ -11,8 +11,9 @@ type PublishersStorage interface {
 	AddPublisher(ctx context.Context, name string) (uuid.UUID, error)
 	GetPublisher(ctx context.Context, id uuid.UUID) (model.Publisher, error)
 	UpdatePublisher(ctx context.Context, id uuid.UUID, publisher model.Publisher) error
-	RemovePublisher(ctx context.Context, id uuid.UUID) error
+	RemovePublisher(ctx context.Context, id uuid.UUID, policy model.RemovePolicy) error
 	ListPublishers(ctx context.Context) ([]model.Publisher, error)
+	ListPublishersByIDs(ctx context.Context, ids []uuid.UUID) ([]model.Publisher, error)
 }
 
 type PublishersUsecase struct {
@@ -54,8 +55,11 @@ func (p *PublishersUsecase) UpdatePublisher(ctx context.Context, id uuid.UUID, n
 	return nil
 }
 
-func (p *PublishersUsecase) RemovePublisher(ctx context.Context, id uuid.UUID) error {
-	err := p.storage.RemovePublisher(ctx, id)
+func (p *PublishersUsecase) RemovePublisher(ctx context.Context, id uuid.UUID, policy model.RemovePolicy) error {
+	if err := validateRemovePolicy(id, policy); err != nil {
+		return err
+	}
+	err := p.storage.RemovePublisher(ctx, id, policy)
 	if err != nil {
 		return fmt.Errorf("failed to remove publisher from storage: %w", err)
 	}
@@ -69,3 +73,11 @@ func (p *PublishersUsecase) ListPublishers(ctx context.Context) ([]model.Publish
 	}
 	return publishers, nil
 }
+
+func (p *PublishersUsecase) ListPublishersByIDs(ctx context.Context, ids []uuid.UUID) ([]model.Publisher, error) {
+	publishers, err := p.storage.ListPublishersByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list publishers by ids from storage: %w", err)
+	}
+	return publishers, nil
+}
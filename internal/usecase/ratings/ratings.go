@@ -0,0 +1,62 @@
+package ratings
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+)
+
+type Storage interface {
+	SetRating(ctx context.Context, userID uuid.UUID, bookID uuid.UUID, value int16) error
+	RemoveRating(ctx context.Context, userID uuid.UUID, bookID uuid.UUID) error
+	GetRatingSummary(ctx context.Context, bookID uuid.UUID) (model.RatingSummary, error)
+	UserRatings(ctx context.Context, userID uuid.UUID, bookIDs []uuid.UUID) (map[uuid.UUID]int16, error)
+}
+
+type RatingsUsecase struct {
+	storage Storage
+}
+
+func NewRatingsUsecase(storage Storage) *RatingsUsecase {
+	return &RatingsUsecase{
+		storage: storage,
+	}
+}
+
+// SetRating creates or replaces the rating the user gave the book and returns
+// the summary that already counts it.
+func (u *RatingsUsecase) SetRating(
+	ctx context.Context,
+	userID uuid.UUID,
+	bookID uuid.UUID,
+	value int16,
+) (model.RatingSummary, error) {
+	if value < model.RatingMin || value > model.RatingMax {
+		return model.RatingSummary{}, model.ErrRatingInvalidValue
+	}
+	if err := u.storage.SetRating(ctx, userID, bookID, value); err != nil {
+		return model.RatingSummary{}, err
+	}
+	return u.storage.GetRatingSummary(ctx, bookID)
+}
+
+func (u *RatingsUsecase) RemoveRating(
+	ctx context.Context,
+	userID uuid.UUID,
+	bookID uuid.UUID,
+) (model.RatingSummary, error) {
+	if err := u.storage.RemoveRating(ctx, userID, bookID); err != nil {
+		return model.RatingSummary{}, err
+	}
+	return u.storage.GetRatingSummary(ctx, bookID)
+}
+
+// UserRatings returns the ratings the user gave to any of the books, unrated
+// books are missing from the map.
+func (u *RatingsUsecase) UserRatings(
+	ctx context.Context,
+	userID uuid.UUID,
+	bookIDs []uuid.UUID,
+) (map[uuid.UUID]int16, error) {
+	return u.storage.UserRatings(ctx, userID, bookIDs)
+}
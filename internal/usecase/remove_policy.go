@@ -0,0 +1,20 @@
+package usecase
+
+import (
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+)
+
+func validateRemovePolicy(id uuid.UUID, policy model.RemovePolicy) error {
+	switch policy.OnBooks {
+	case model.OnBooksRestrict, model.OnBooksDetach:
+		return nil
+	case model.OnBooksReassign:
+		if policy.ReassignTo == uuid.Nil || policy.ReassignTo == id {
+			return model.ErrInvalidReassignTarget
+		}
+		return nil
+	default:
+		return model.ErrInvalidOnBooksMode
+	}
+}
@@ -0,0 +1,190 @@
+package reviews
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"strings"
+)
+
+type ReviewsOrder string
+
+const (
+	ReviewsOrderNewest  ReviewsOrder = "newest"
+	ReviewsOrderHelpful ReviewsOrder = "helpful"
+)
+
+func ParseReviewsOrder(raw string) (ReviewsOrder, error) {
+	switch order := ReviewsOrder(raw); order {
+	case "":
+		return ReviewsOrderNewest, nil
+	case ReviewsOrderNewest, ReviewsOrderHelpful:
+		return order, nil
+	}
+	return "", model.ErrReviewInvalidOrder
+}
+
+func ParseReviewStatus(raw string) (model.ReviewStatus, error) {
+	switch status := model.ReviewStatus(raw); status {
+	case "":
+		return model.ReviewPending, nil
+	case model.ReviewPending, model.ReviewApproved, model.ReviewRejected:
+		return status, nil
+	}
+	return "", model.ErrReviewInvalidStatus
+}
+
+type ReviewInput struct {
+	Body    string
+	Spoiler bool
+}
+
+type ListParameters struct {
+	Order  ReviewsOrder
+	Limit  uint64
+	Offset uint64
+}
+
+type Storage interface {
+	AddReview(ctx context.Context, userID uuid.UUID, bookID uuid.UUID, input ReviewInput) (uuid.UUID, error)
+	GetReview(ctx context.Context, viewerID uuid.UUID, id uuid.UUID) (model.Review, error)
+	UpdateReview(ctx context.Context, userID uuid.UUID, id uuid.UUID, input ReviewInput) error
+	RemoveReview(ctx context.Context, userID uuid.UUID, id uuid.UUID) error
+	ListBookReviews(
+		ctx context.Context,
+		viewerID uuid.UUID,
+		bookID uuid.UUID,
+		parameters ListParameters,
+	) ([]model.Review, error)
+	ListUserReviews(ctx context.Context, userID uuid.UUID) ([]model.Review, error)
+	ListReviewsByStatus(ctx context.Context, status model.ReviewStatus, limit, offset uint64) ([]model.Review, error)
+	ModerateReview(
+		ctx context.Context,
+		moderatorID uuid.UUID,
+		id uuid.UUID,
+		status model.ReviewStatus,
+		reason *string,
+	) error
+	AddVote(ctx context.Context, userID uuid.UUID, id uuid.UUID) error
+	RemoveVote(ctx context.Context, userID uuid.UUID, id uuid.UUID) error
+}
+
+type ReviewsUsecase struct {
+	storage Storage
+}
+
+func NewReviewsUsecase(storage Storage) *ReviewsUsecase {
+	return &ReviewsUsecase{
+		storage: storage,
+	}
+}
+
+// AddReview stores the review as pending, nobody but its author sees it until
+// an admin approves it.
+func (u *ReviewsUsecase) AddReview(
+	ctx context.Context,
+	userID uuid.UUID,
+	bookID uuid.UUID,
+	input ReviewInput,
+) (uuid.UUID, error) {
+	input, err := normalizeInput(input)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return u.storage.AddReview(ctx, userID, bookID, input)
+}
+
+// GetReview hides reviews that are not approved from everyone except their
+// author.
+func (u *ReviewsUsecase) GetReview(ctx context.Context, viewerID uuid.UUID, id uuid.UUID) (model.Review, error) {
+	review, err := u.storage.GetReview(ctx, viewerID, id)
+	if err != nil {
+		return model.Review{}, err
+	}
+	if review.Status != model.ReviewApproved && review.UserID != viewerID {
+		return model.Review{}, model.ErrReviewNotFound
+	}
+	return review, nil
+}
+
+// UpdateReview sends the edited review back to the moderation queue.
+func (u *ReviewsUsecase) UpdateReview(
+	ctx context.Context,
+	userID uuid.UUID,
+	id uuid.UUID,
+	input ReviewInput,
+) error {
+	input, err := normalizeInput(input)
+	if err != nil {
+		return err
+	}
+	return u.storage.UpdateReview(ctx, userID, id, input)
+}
+
+func (u *ReviewsUsecase) RemoveReview(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	return u.storage.RemoveReview(ctx, userID, id)
+}
+
+// ListBookReviews returns the approved reviews of the book.
+func (u *ReviewsUsecase) ListBookReviews(
+	ctx context.Context,
+	viewerID uuid.UUID,
+	bookID uuid.UUID,
+	parameters ListParameters,
+) ([]model.Review, error) {
+	return u.storage.ListBookReviews(ctx, viewerID, bookID, parameters)
+}
+
+// ListUserReviews returns every review of the user whatever its status.
+func (u *ReviewsUsecase) ListUserReviews(ctx context.Context, userID uuid.UUID) ([]model.Review, error) {
+	return u.storage.ListUserReviews(ctx, userID)
+}
+
+// ListModerationQueue returns the reviews in the status, oldest first.
+func (u *ReviewsUsecase) ListModerationQueue(
+	ctx context.Context,
+	status model.ReviewStatus,
+	limit, offset uint64,
+) ([]model.Review, error) {
+	return u.storage.ListReviewsByStatus(ctx, status, limit, offset)
+}
+
+func (u *ReviewsUsecase) ApproveReview(ctx context.Context, moderatorID uuid.UUID, id uuid.UUID) error {
+	return u.storage.ModerateReview(ctx, moderatorID, id, model.ReviewApproved, nil)
+}
+
+func (u *ReviewsUsecase) RejectReview(ctx context.Context, moderatorID uuid.UUID, id uuid.UUID, reason *string) error {
+	if reason != nil {
+		if trimmed := strings.TrimSpace(*reason); trimmed != "" {
+			reason = &trimmed
+		} else {
+			reason = nil
+		}
+	}
+	return u.storage.ModerateReview(ctx, moderatorID, id, model.ReviewRejected, reason)
+}
+
+// VoteHelpful counts the review as helpful for the user, voting twice has no
+// further effect.
+func (u *ReviewsUsecase) VoteHelpful(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	review, err := u.GetReview(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if review.UserID == userID {
+		return model.ErrReviewOwnVote
+	}
+	return u.storage.AddVote(ctx, userID, id)
+}
+
+func (u *ReviewsUsecase) RemoveHelpfulVote(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	return u.storage.RemoveVote(ctx, userID, id)
+}
+
+func normalizeInput(input ReviewInput) (ReviewInput, error) {
+	input.Body = strings.TrimSpace(input.Body)
+	if input.Body == "" {
+		return ReviewInput{}, model.ErrReviewInvalidBody
+	}
+	return input, nil
+}
@@ -0,0 +1,83 @@
+package shelves
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"time"
+)
+
+const shareTokenBytes = 24
+
+// CreateShare makes a new link to the shelf; the link never expires when
+// expiresAt is nil.
+func (u *ShelvesUsecase) CreateShare(
+	ctx context.Context,
+	userID uuid.UUID,
+	ref ShelfRef,
+	expiresAt *time.Time,
+) (model.ShelfShare, error) {
+	shelf, err := u.storage.GetShelf(ctx, userID, ref)
+	if err != nil {
+		return model.ShelfShare{}, err
+	}
+	if expiresAt != nil {
+		if !expiresAt.After(time.Now()) {
+			return model.ShelfShare{}, model.ErrShareInvalidExpiry
+		}
+		utc := expiresAt.UTC()
+		expiresAt = &utc
+	}
+	token, err := newShareToken()
+	if err != nil {
+		return model.ShelfShare{}, fmt.Errorf("failed to generate share token: %w", err)
+	}
+	return u.storage.AddShare(ctx, model.ShelfShare{ShelfID: shelf.ID, Token: token, ExpiresAt: expiresAt})
+}
+
+func (u *ShelvesUsecase) ListShares(ctx context.Context, userID uuid.UUID, ref ShelfRef) ([]model.ShelfShare, error) {
+	shelf, err := u.storage.GetShelf(ctx, userID, ref)
+	if err != nil {
+		return nil, err
+	}
+	return u.storage.ListShares(ctx, shelf.ID)
+}
+
+func (u *ShelvesUsecase) RevokeShare(ctx context.Context, userID uuid.UUID, ref ShelfRef, id uuid.UUID) error {
+	shelf, err := u.storage.GetShelf(ctx, userID, ref)
+	if err != nil {
+		return err
+	}
+	return u.storage.RevokeShare(ctx, shelf.ID, id, time.Now().UTC())
+}
+
+// ViewSharedShelf counts a view of the share and returns the shelf with its
+// books, the same way the owner sees them.
+func (u *ShelvesUsecase) ViewSharedShelf(
+	ctx context.Context,
+	token string,
+	expandAuthors, expandTags, expandPublisher bool,
+) (model.Shelf, []ShelfEntry, error) {
+	shelf, err := u.storage.ViewShare(ctx, token, time.Now().UTC())
+	if err != nil {
+		return model.Shelf{}, nil, err
+	}
+	nameBuiltin(&shelf)
+
+	entries, err := u.shelfEntries(ctx, shelf.ID, expandAuthors, expandTags, expandPublisher)
+	if err != nil {
+		return model.Shelf{}, nil, err
+	}
+	return shelf, entries, nil
+}
+
+func newShareToken() (string, error) {
+	raw := make([]byte, shareTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
@@ -0,0 +1,187 @@
+package shelves
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/books"
+	"strings"
+	"time"
+)
+
+var builtinNames = map[model.ShelfKind]string{
+	model.ShelfWantToRead: "Want to read",
+	model.ShelfReading:    "Reading",
+	model.ShelfRead:       "Read",
+}
+
+// ShelfRef addresses a shelf of the current user either by its built-in kind
+// or by the id of a custom shelf.
+type ShelfRef struct {
+	Kind model.ShelfKind
+	ID   uuid.UUID
+}
+
+func ParseShelfRef(raw string) (ShelfRef, error) {
+	for _, kind := range model.BuiltinShelves {
+		if raw == string(kind) {
+			return ShelfRef{Kind: kind}, nil
+		}
+	}
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return ShelfRef{}, model.ErrShelfNotFound
+	}
+	return ShelfRef{Kind: model.ShelfCustom, ID: id}, nil
+}
+
+func (r ShelfRef) Builtin() bool {
+	return r.Kind != model.ShelfCustom
+}
+
+type ShelfEntry struct {
+	model.ShelfBook
+	Book model.Book
+}
+
+type Storage interface {
+	ListShelves(ctx context.Context, userID uuid.UUID) ([]model.Shelf, error)
+	GetShelf(ctx context.Context, userID uuid.UUID, ref ShelfRef) (model.Shelf, error)
+	AddShelf(ctx context.Context, userID uuid.UUID, name string) (uuid.UUID, error)
+	RenameShelf(ctx context.Context, userID uuid.UUID, id uuid.UUID, name string) error
+	RemoveShelf(ctx context.Context, userID uuid.UUID, id uuid.UUID) error
+	AddShelfBook(ctx context.Context, userID uuid.UUID, ref ShelfRef, bookID uuid.UUID) error
+	RemoveShelfBook(ctx context.Context, userID uuid.UUID, ref ShelfRef, bookID uuid.UUID) error
+	ListShelfBooks(ctx context.Context, shelfID uuid.UUID) ([]model.ShelfBook, error)
+
+	AddShare(ctx context.Context, share model.ShelfShare) (model.ShelfShare, error)
+	ListShares(ctx context.Context, shelfID uuid.UUID) ([]model.ShelfShare, error)
+	RevokeShare(ctx context.Context, shelfID uuid.UUID, id uuid.UUID, now time.Time) error
+	ViewShare(ctx context.Context, token string, now time.Time) (model.Shelf, error)
+}
+
+type BooksUsecase interface {
+	ListBooks(
+		ctx context.Context,
+		parameters books.ListBookParameters,
+		expandAuthors, expandTags, expandPublisher bool,
+	) ([]model.Book, error)
+}
+
+type ShelvesUsecase struct {
+	storage      Storage
+	booksUsecase BooksUsecase
+}
+
+func NewShelvesUsecase(storage Storage, booksUsecase BooksUsecase) *ShelvesUsecase {
+	return &ShelvesUsecase{
+		storage:      storage,
+		booksUsecase: booksUsecase,
+	}
+}
+
+func (u *ShelvesUsecase) ListShelves(ctx context.Context, userID uuid.UUID) ([]model.Shelf, error) {
+	shelves, err := u.storage.ListShelves(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shelves from storage: %w", err)
+	}
+	for i := range shelves {
+		nameBuiltin(&shelves[i])
+	}
+	return shelves, nil
+}
+
+func (u *ShelvesUsecase) AddShelf(ctx context.Context, userID uuid.UUID, name string) (uuid.UUID, error) {
+	return u.storage.AddShelf(ctx, userID, strings.TrimSpace(name))
+}
+
+func (u *ShelvesUsecase) RenameShelf(ctx context.Context, userID uuid.UUID, ref ShelfRef, name string) error {
+	if ref.Builtin() {
+		return model.ErrShelfBuiltin
+	}
+	return u.storage.RenameShelf(ctx, userID, ref.ID, strings.TrimSpace(name))
+}
+
+func (u *ShelvesUsecase) RemoveShelf(ctx context.Context, userID uuid.UUID, ref ShelfRef) error {
+	if ref.Builtin() {
+		return model.ErrShelfBuiltin
+	}
+	return u.storage.RemoveShelf(ctx, userID, ref.ID)
+}
+
+func (u *ShelvesUsecase) AddShelfBook(ctx context.Context, userID uuid.UUID, ref ShelfRef, bookID uuid.UUID) error {
+	return u.storage.AddShelfBook(ctx, userID, ref, bookID)
+}
+
+func (u *ShelvesUsecase) RemoveShelfBook(
+	ctx context.Context,
+	userID uuid.UUID,
+	ref ShelfRef,
+	bookID uuid.UUID,
+) error {
+	return u.storage.RemoveShelfBook(ctx, userID, ref, bookID)
+}
+
+// ListShelfBooks returns the shelf with its books, most recently added first.
+func (u *ShelvesUsecase) ListShelfBooks(
+	ctx context.Context,
+	userID uuid.UUID,
+	ref ShelfRef,
+	expandAuthors, expandTags, expandPublisher bool,
+) (model.Shelf, []ShelfEntry, error) {
+	shelf, err := u.storage.GetShelf(ctx, userID, ref)
+	if err != nil {
+		return model.Shelf{}, nil, err
+	}
+	nameBuiltin(&shelf)
+
+	entries, err := u.shelfEntries(ctx, shelf.ID, expandAuthors, expandTags, expandPublisher)
+	if err != nil {
+		return model.Shelf{}, nil, err
+	}
+	return shelf, entries, nil
+}
+
+func (u *ShelvesUsecase) shelfEntries(
+	ctx context.Context,
+	shelfID uuid.UUID,
+	expandAuthors, expandTags, expandPublisher bool,
+) ([]ShelfEntry, error) {
+	shelved, err := u.storage.ListShelfBooks(ctx, shelfID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shelf books from storage: %w", err)
+	}
+	if len(shelved) == 0 {
+		return []ShelfEntry{}, nil
+	}
+
+	ids := make([]uuid.UUID, len(shelved))
+	for i, entry := range shelved {
+		ids[i] = entry.BookID
+	}
+	found, err := u.booksUsecase.ListBooks(
+		ctx, books.ListBookParameters{IDs: ids}, expandAuthors, expandTags, expandPublisher,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shelf books: %w", err)
+	}
+	byID := make(map[uuid.UUID]model.Book, len(found))
+	for _, book := range found {
+		byID[book.ID] = book
+	}
+
+	entries := make([]ShelfEntry, 0, len(shelved))
+	for _, entry := range shelved {
+		if book, ok := byID[entry.BookID]; ok {
+			entries = append(entries, ShelfEntry{ShelfBook: entry, Book: book})
+		}
+	}
+	return entries, nil
+}
+
+func nameBuiltin(shelf *model.Shelf) {
+	if name, ok := builtinNames[shelf.Kind]; ok {
+		shelf.Name = name
+	}
+}
@@ -0,0 +1,195 @@
+package social
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"github.com/iamvkosarev/book-shelf/internal/usecase/books"
+	"time"
+)
+
+func ParseFollowStatus(raw string) (*model.FollowStatus, error) {
+	switch status := model.FollowStatus(raw); status {
+	case "":
+		return nil, nil
+	case model.FollowPending, model.FollowAccepted:
+		return &status, nil
+	}
+	return nil, model.ErrFollowInvalidStatus
+}
+
+type ListFollowsParameters struct {
+	Status *model.FollowStatus
+	Limit  uint64
+	Offset uint64
+}
+
+// FeedCursor points at the last event of a feed page, the next page starts
+// right after it.
+type FeedCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"i"`
+}
+
+func ParseFeedCursor(raw string) (*FeedCursor, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	body, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, model.ErrFeedInvalidCursor
+	}
+	var cursor FeedCursor
+	if err = json.Unmarshal(body, &cursor); err != nil || cursor.ID == uuid.Nil || cursor.CreatedAt.IsZero() {
+		return nil, model.ErrFeedInvalidCursor
+	}
+	return &cursor, nil
+}
+
+func (c FeedCursor) String() string {
+	body, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(body)
+}
+
+type FeedEntry struct {
+	model.ActivityEvent
+	Book model.Book
+}
+
+type FeedPage struct {
+	Entries []FeedEntry
+	// Next is empty on the last page.
+	Next string
+}
+
+type Storage interface {
+	GetProfile(ctx context.Context, userID uuid.UUID) (model.Profile, error)
+	SetProfilePublic(ctx context.Context, userID uuid.UUID, public bool) error
+	Follow(ctx context.Context, followerID uuid.UUID, followeeID uuid.UUID) (model.Follow, error)
+	RemoveFollow(ctx context.Context, followerID uuid.UUID, followeeID uuid.UUID) error
+	AcceptFollow(ctx context.Context, followerID uuid.UUID, followeeID uuid.UUID) error
+	ListFollowers(ctx context.Context, userID uuid.UUID, parameters ListFollowsParameters) ([]model.Follow, error)
+	ListFollowing(ctx context.Context, userID uuid.UUID, parameters ListFollowsParameters) ([]model.Follow, error)
+	Feed(ctx context.Context, userID uuid.UUID, cursor *FeedCursor, limit uint64) ([]model.ActivityEvent, error)
+}
+
+type BooksUsecase interface {
+	ListBooks(
+		ctx context.Context,
+		parameters books.ListBookParameters,
+		expandAuthors, expandTags, expandPublisher bool,
+	) ([]model.Book, error)
+}
+
+type SocialUsecase struct {
+	storage      Storage
+	booksUsecase BooksUsecase
+}
+
+func NewSocialUsecase(storage Storage, booksUsecase BooksUsecase) *SocialUsecase {
+	return &SocialUsecase{
+		storage:      storage,
+		booksUsecase: booksUsecase,
+	}
+}
+
+func (u *SocialUsecase) GetProfile(ctx context.Context, userID uuid.UUID) (model.Profile, error) {
+	return u.storage.GetProfile(ctx, userID)
+}
+
+func (u *SocialUsecase) SetProfilePublic(ctx context.Context, userID uuid.UUID, public bool) (model.Profile, error) {
+	if err := u.storage.SetProfilePublic(ctx, userID, public); err != nil {
+		return model.Profile{}, err
+	}
+	return u.storage.GetProfile(ctx, userID)
+}
+
+func (u *SocialUsecase) Follow(ctx context.Context, followerID uuid.UUID, followeeID uuid.UUID) (model.Follow, error) {
+	if followerID == followeeID {
+		return model.Follow{}, model.ErrFollowSelf
+	}
+	return u.storage.Follow(ctx, followerID, followeeID)
+}
+
+// Unfollow also withdraws a follow request that is still pending.
+func (u *SocialUsecase) Unfollow(ctx context.Context, followerID uuid.UUID, followeeID uuid.UUID) error {
+	return u.storage.RemoveFollow(ctx, followerID, followeeID)
+}
+
+func (u *SocialUsecase) AcceptFollower(ctx context.Context, userID uuid.UUID, followerID uuid.UUID) error {
+	return u.storage.AcceptFollow(ctx, followerID, userID)
+}
+
+// RemoveFollower rejects a pending request or drops an accepted follower.
+func (u *SocialUsecase) RemoveFollower(ctx context.Context, userID uuid.UUID, followerID uuid.UUID) error {
+	return u.storage.RemoveFollow(ctx, followerID, userID)
+}
+
+func (u *SocialUsecase) ListFollowers(
+	ctx context.Context,
+	userID uuid.UUID,
+	parameters ListFollowsParameters,
+) ([]model.Follow, error) {
+	return u.storage.ListFollowers(ctx, userID, parameters)
+}
+
+func (u *SocialUsecase) ListFollowing(
+	ctx context.Context,
+	userID uuid.UUID,
+	parameters ListFollowsParameters,
+) ([]model.Follow, error) {
+	return u.storage.ListFollowing(ctx, userID, parameters)
+}
+
+// Feed returns up to limit events of the followed users with their books.
+// Events of books removed from the catalog since are skipped.
+func (u *SocialUsecase) Feed(
+	ctx context.Context,
+	userID uuid.UUID,
+	cursor *FeedCursor,
+	limit uint64,
+	expandAuthors, expandTags, expandPublisher bool,
+) (FeedPage, error) {
+	events, err := u.storage.Feed(ctx, userID, cursor, limit+1)
+	if err != nil {
+		return FeedPage{}, fmt.Errorf("failed to list feed events from storage: %w", err)
+	}
+	page := FeedPage{Entries: make([]FeedEntry, 0, min(uint64(len(events)), limit))}
+	if uint64(len(events)) > limit {
+		events = events[:limit]
+		last := events[len(events)-1]
+		page.Next = FeedCursor{CreatedAt: last.CreatedAt, ID: last.ID}.String()
+	}
+	if len(events) == 0 {
+		return page, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(events))
+	seen := make(map[uuid.UUID]struct{}, len(events))
+	for _, event := range events {
+		if _, ok := seen[event.BookID]; !ok {
+			seen[event.BookID] = struct{}{}
+			ids = append(ids, event.BookID)
+		}
+	}
+	found, err := u.booksUsecase.ListBooks(
+		ctx, books.ListBookParameters{IDs: ids}, expandAuthors, expandTags, expandPublisher,
+	)
+	if err != nil {
+		return FeedPage{}, fmt.Errorf("failed to list feed books: %w", err)
+	}
+	byID := make(map[uuid.UUID]model.Book, len(found))
+	for _, book := range found {
+		byID[book.ID] = book
+	}
+
+	for _, event := range events {
+		if book, ok := byID[event.BookID]; ok {
+			page.Entries = append(page.Entries, FeedEntry{ActivityEvent: event, Book: book})
+		}
+	}
+	return page, nil
+}
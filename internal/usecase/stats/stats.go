@@ -0,0 +1,179 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/iamvkosarev/book-shelf/internal/model"
+	"time"
+)
+
+const FavouritesLimit = 5
+
+type GoalInput struct {
+	Books int32
+	Pages *int32
+}
+
+type Storage interface {
+	ReadingPeriods(ctx context.Context, userID uuid.UUID, year *int) ([]model.ReadingPeriod, error)
+	FavouriteTags(ctx context.Context, userID uuid.UUID, year *int, limit uint64) ([]model.TagCount, error)
+	FavouriteAuthors(ctx context.Context, userID uuid.UUID, year *int, limit uint64) ([]model.AuthorCount, error)
+	RatingsGiven(ctx context.Context, userID uuid.UUID, year *int) (int, *float64, error)
+
+	SetGoal(ctx context.Context, goal model.ReadingGoal) (model.ReadingGoal, error)
+	GetGoal(ctx context.Context, userID uuid.UUID, year int) (model.ReadingGoal, error)
+	ListGoals(ctx context.Context, userID uuid.UUID) ([]model.ReadingGoal, error)
+	RemoveGoal(ctx context.Context, userID uuid.UUID, year int) error
+}
+
+type StatsUsecase struct {
+	storage Storage
+}
+
+func NewStatsUsecase(storage Storage) *StatsUsecase {
+	return &StatsUsecase{
+		storage: storage,
+	}
+}
+
+// GetStats covers the whole reading history, or only the given year. The
+// goal of that year, or of the current one, comes along when it is set.
+func (u *StatsUsecase) GetStats(ctx context.Context, userID uuid.UUID, year *int) (model.ReadingStats, error) {
+	if year != nil {
+		if err := validateYear(*year); err != nil {
+			return model.ReadingStats{}, err
+		}
+	}
+
+	periods, err := u.storage.ReadingPeriods(ctx, userID, year)
+	if err != nil {
+		return model.ReadingStats{}, fmt.Errorf("failed to sum reading periods: %w", err)
+	}
+	stats := model.ReadingStats{
+		Months: make([]model.ReadingPeriod, 0),
+		Years:  make([]model.ReadingPeriod, 0),
+	}
+	for _, period := range periods {
+		if period.Month == nil {
+			stats.Years = append(stats.Years, period)
+		} else {
+			stats.Months = append(stats.Months, period)
+		}
+	}
+
+	if stats.FavouriteTags, err = u.storage.FavouriteTags(ctx, userID, year, FavouritesLimit); err != nil {
+		return model.ReadingStats{}, fmt.Errorf("failed to rank tags: %w", err)
+	}
+	if stats.FavouriteAuthors, err = u.storage.FavouriteAuthors(ctx, userID, year, FavouritesLimit); err != nil {
+		return model.ReadingStats{}, fmt.Errorf("failed to rank authors: %w", err)
+	}
+	if stats.RatingsCount, stats.AverageRating, err = u.storage.RatingsGiven(ctx, userID, year); err != nil {
+		return model.ReadingStats{}, fmt.Errorf("failed to average ratings: %w", err)
+	}
+
+	goalYear := time.Now().Year()
+	if year != nil {
+		goalYear = *year
+	}
+	goal, err := u.storage.GetGoal(ctx, userID, goalYear)
+	if errors.Is(err, model.ErrGoalNotFound) {
+		return stats, nil
+	}
+	if err != nil {
+		return model.ReadingStats{}, err
+	}
+	progress := goalProgress(goal, stats.Years)
+	stats.Goal = &progress
+	return stats, nil
+}
+
+func (u *StatsUsecase) SetGoal(
+	ctx context.Context,
+	userID uuid.UUID,
+	year int,
+	input GoalInput,
+) (model.ReadingGoalProgress, error) {
+	if err := validateYear(year); err != nil {
+		return model.ReadingGoalProgress{}, err
+	}
+	if input.Books < 1 {
+		return model.ReadingGoalProgress{}, model.ErrGoalInvalidBooks
+	}
+	if input.Pages != nil && *input.Pages < 1 {
+		return model.ReadingGoalProgress{}, model.ErrGoalInvalidPages
+	}
+
+	goal, err := u.storage.SetGoal(
+		ctx, model.ReadingGoal{UserID: userID, Year: year, Books: input.Books, Pages: input.Pages},
+	)
+	if err != nil {
+		return model.ReadingGoalProgress{}, err
+	}
+	return u.progress(ctx, goal)
+}
+
+func (u *StatsUsecase) GetGoal(ctx context.Context, userID uuid.UUID, year int) (model.ReadingGoalProgress, error) {
+	if err := validateYear(year); err != nil {
+		return model.ReadingGoalProgress{}, err
+	}
+	goal, err := u.storage.GetGoal(ctx, userID, year)
+	if err != nil {
+		return model.ReadingGoalProgress{}, err
+	}
+	return u.progress(ctx, goal)
+}
+
+func (u *StatsUsecase) ListGoals(ctx context.Context, userID uuid.UUID) ([]model.ReadingGoalProgress, error) {
+	goals, err := u.storage.ListGoals(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]model.ReadingGoalProgress, len(goals))
+	if len(goals) == 0 {
+		return list, nil
+	}
+	periods, err := u.storage.ReadingPeriods(ctx, userID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum reading periods: %w", err)
+	}
+	for i, goal := range goals {
+		list[i] = goalProgress(goal, periods)
+	}
+	return list, nil
+}
+
+func (u *StatsUsecase) RemoveGoal(ctx context.Context, userID uuid.UUID, year int) error {
+	if err := validateYear(year); err != nil {
+		return err
+	}
+	return u.storage.RemoveGoal(ctx, userID, year)
+}
+
+func (u *StatsUsecase) progress(ctx context.Context, goal model.ReadingGoal) (model.ReadingGoalProgress, error) {
+	periods, err := u.storage.ReadingPeriods(ctx, goal.UserID, &goal.Year)
+	if err != nil {
+		return model.ReadingGoalProgress{}, fmt.Errorf("failed to sum reading periods: %w", err)
+	}
+	return goalProgress(goal, periods), nil
+}
+
+func goalProgress(goal model.ReadingGoal, periods []model.ReadingPeriod) model.ReadingGoalProgress {
+	progress := model.ReadingGoalProgress{Goal: goal}
+	for _, period := range periods {
+		if period.Month == nil && period.Year == goal.Year {
+			progress.BooksFinished = period.Books
+			progress.PagesFinished = period.Pages
+			break
+		}
+	}
+	return progress
+}
+
+func validateYear(year int) error {
+	if year < model.ReadingGoalYearMin || year > model.ReadingGoalYearMax {
+		return model.ErrGoalInvalidYear
+	}
+	return nil
+}
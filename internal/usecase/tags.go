@@ -11,8 +11,9 @@ type TagsStorage interface {
 	AddTag(ctx context.Context, name string) (uuid.UUID, error)
 	GetTag(ctx context.Context, id uuid.UUID) (model.Tag, error)
 	UpdateTag(ctx context.Context, id uuid.UUID, tag model.Tag) error
-	RemoveTag(ctx context.Context, id uuid.UUID) error
+	RemoveTag(ctx context.Context, id uuid.UUID, policy model.RemovePolicy) error
 	ListTags(ctx context.Context) ([]model.Tag, error)
+	ListTagsByIDs(ctx context.Context, ids []uuid.UUID) ([]model.Tag, error)
 }
 
 type TagsUsecase struct {
@@ -54,8 +55,11 @@ func (p *TagsUsecase) UpdateTag(ctx context.Context, id uuid.UUID, name string)
 	return nil
 }
 
-func (p *TagsUsecase) RemoveTag(ctx context.Context, id uuid.UUID) error {
-	err := p.storage.RemoveTag(ctx, id)
+func (p *TagsUsecase) RemoveTag(ctx context.Context, id uuid.UUID, policy model.RemovePolicy) error {
+	if err := validateRemovePolicy(id, policy); err != nil {
+		return err
+	}
+	err := p.storage.RemoveTag(ctx, id, policy)
 	if err != nil {
 		return fmt.Errorf("failed to remove tag from storage: %w", err)
 	}
@@ -69,3 +73,11 @@ func (p *TagsUsecase) ListTags(ctx context.Context) ([]model.Tag, error) {
 	}
 	return tags, nil
 }
+
+func (p *TagsUsecase) ListTagsByIDs(ctx context.Context, ids []uuid.UUID) ([]model.Tag, error) {
+	tags, err := p.storage.ListTagsByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list tags by ids from storage: %w", err)
+	}
+	return tags, nil
+}
@@ -107,26 +107,34 @@ func (u *UserUsecase) RegisterByEmail(ctx context.Context, email, password strin
 }
 
 func (u *UserUsecase) AuthenticateByEmail(ctx context.Context, email, password string) (string, error) {
+	userID, err := u.VerifyEmailPassword(ctx, email, password)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := u.TokenProvider.GenerateUserToken(userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate user token: %w", err)
+	}
+	return token, nil
+}
+
+func (u *UserUsecase) VerifyEmailPassword(ctx context.Context, email, password string) (uuid.UUID, error) {
 	userID, passHash, err := u.UserStorage.GetIDAndPassHash(ctx, email)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return "", model.ErrUserNotExists
+			return uuid.Nil, model.ErrUserNotExists
 		}
-		return "", fmt.Errorf("failed to get user by email: %w", err)
+		return uuid.Nil, fmt.Errorf("failed to get user by email: %w", err)
 	}
 
 	if err = bcrypt.CompareHashAndPassword(passHash, []byte(password)); err != nil {
 		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-			return "", model.ErrPasswordNotCorrect
+			return uuid.Nil, model.ErrPasswordNotCorrect
 		}
-		return "", err
-	}
-
-	token, err := u.TokenProvider.GenerateUserToken(userID)
-	if err != nil {
-		return "", fmt.Errorf("failed to generate user token: %w", err)
+		return uuid.Nil, err
 	}
-	return token, nil
+	return userID, nil
 }
 
 func (u *UserUsecase) CheckUserAnyRole(ctx context.Context, userID uuid.UUID, needRoleList []model.Role) error {
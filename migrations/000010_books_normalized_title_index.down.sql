@@ -0,0 +1 @@
+DROP INDEX IF EXISTS ix_books_normalized_title;
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS ix_books_normalized_title
+	ON books ((btrim(regexp_replace(lower(title), '[^[:alnum:]]+', ' ', 'g'))));
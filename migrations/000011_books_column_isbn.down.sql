@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS uq_books_isbn;
+
+ALTER TABLE books
+	DROP COLUMN IF EXISTS isbn;
@@ -0,0 +1,4 @@
+ALTER TABLE books
+	ADD COLUMN IF NOT EXISTS isbn VARCHAR(13);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_books_isbn ON books(isbn) WHERE isbn IS NOT NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_calibre_imported_books_book_id;
+
+DROP TABLE IF EXISTS calibre_imported_books;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS calibre_imported_books (
+	library_id VARCHAR(64) NOT NULL,
+	calibre_id BIGINT NOT NULL,
+	book_id UUID REFERENCES books(id) ON DELETE SET NULL,
+	identifiers JSONB NOT NULL DEFAULT '{}',
+	imported_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (library_id, calibre_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_calibre_imported_books_book_id ON calibre_imported_books(book_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_books_created_at;
+
+ALTER TABLE books
+	DROP COLUMN IF EXISTS created_at;
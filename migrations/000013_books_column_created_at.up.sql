@@ -0,0 +1,4 @@
+ALTER TABLE books
+	ADD COLUMN IF NOT EXISTS created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_books_created_at ON books(created_at);
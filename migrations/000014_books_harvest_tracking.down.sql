@@ -0,0 +1,21 @@
+DROP TRIGGER IF EXISTS trg_books_track_deleted ON books;
+DROP TRIGGER IF EXISTS trg_publishers_touch_books ON publishers;
+DROP TRIGGER IF EXISTS trg_tags_touch_books ON tags;
+DROP TRIGGER IF EXISTS trg_authors_touch_books ON authors;
+DROP TRIGGER IF EXISTS trg_books_tags_touch ON books_tags;
+DROP TRIGGER IF EXISTS trg_books_authors_touch ON books_authors;
+DROP TRIGGER IF EXISTS trg_books_touch ON books;
+
+DROP FUNCTION IF EXISTS track_deleted_book();
+DROP FUNCTION IF EXISTS touch_books_of_publisher();
+DROP FUNCTION IF EXISTS touch_books_of_tag();
+DROP FUNCTION IF EXISTS touch_books_of_author();
+DROP FUNCTION IF EXISTS touch_linked_book();
+DROP FUNCTION IF EXISTS touch_book_row();
+
+DROP TABLE IF EXISTS deleted_books;
+
+DROP INDEX IF EXISTS idx_books_updated_at;
+
+ALTER TABLE books
+	DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,101 @@
+ALTER TABLE books
+	ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+UPDATE books SET updated_at = created_at;
+
+CREATE INDEX IF NOT EXISTS idx_books_updated_at ON books(updated_at, id);
+
+CREATE TABLE IF NOT EXISTS deleted_books (
+	id UUID PRIMARY KEY,
+	deleted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_deleted_books_deleted_at ON deleted_books(deleted_at, id);
+
+CREATE OR REPLACE FUNCTION touch_book_row() RETURNS TRIGGER AS $$
+BEGIN
+	NEW.updated_at = CURRENT_TIMESTAMP;
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION touch_linked_book() RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		UPDATE books SET updated_at = CURRENT_TIMESTAMP WHERE id = OLD.book_id;
+	ELSE
+		UPDATE books SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.book_id;
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION touch_books_of_author() RETURNS TRIGGER AS $$
+BEGIN
+	UPDATE books SET updated_at = CURRENT_TIMESTAMP
+	WHERE id IN (SELECT book_id FROM books_authors WHERE author_id = NEW.id);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION touch_books_of_tag() RETURNS TRIGGER AS $$
+BEGIN
+	UPDATE books SET updated_at = CURRENT_TIMESTAMP
+	WHERE id IN (SELECT book_id FROM books_tags WHERE tag_id = NEW.id);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION touch_books_of_publisher() RETURNS TRIGGER AS $$
+BEGIN
+	UPDATE books SET updated_at = CURRENT_TIMESTAMP WHERE publisher_id = NEW.id;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION track_deleted_book() RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		INSERT INTO deleted_books(id) VALUES (OLD.id)
+		ON CONFLICT (id) DO UPDATE SET deleted_at = EXCLUDED.deleted_at;
+	ELSE
+		DELETE FROM deleted_books WHERE id = NEW.id;
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_books_touch ON books;
+CREATE TRIGGER trg_books_touch
+	BEFORE UPDATE ON books
+	FOR EACH ROW EXECUTE FUNCTION touch_book_row();
+
+DROP TRIGGER IF EXISTS trg_books_authors_touch ON books_authors;
+CREATE TRIGGER trg_books_authors_touch
+	AFTER INSERT OR DELETE ON books_authors
+	FOR EACH ROW EXECUTE FUNCTION touch_linked_book();
+
+DROP TRIGGER IF EXISTS trg_books_tags_touch ON books_tags;
+CREATE TRIGGER trg_books_tags_touch
+	AFTER INSERT OR DELETE ON books_tags
+	FOR EACH ROW EXECUTE FUNCTION touch_linked_book();
+
+DROP TRIGGER IF EXISTS trg_authors_touch_books ON authors;
+CREATE TRIGGER trg_authors_touch_books
+	AFTER UPDATE ON authors
+	FOR EACH ROW EXECUTE FUNCTION touch_books_of_author();
+
+DROP TRIGGER IF EXISTS trg_tags_touch_books ON tags;
+CREATE TRIGGER trg_tags_touch_books
+	AFTER UPDATE ON tags
+	FOR EACH ROW EXECUTE FUNCTION touch_books_of_tag();
+
+DROP TRIGGER IF EXISTS trg_publishers_touch_books ON publishers;
+CREATE TRIGGER trg_publishers_touch_books
+	AFTER UPDATE ON publishers
+	FOR EACH ROW EXECUTE FUNCTION touch_books_of_publisher();
+
+DROP TRIGGER IF EXISTS trg_books_track_deleted ON books;
+CREATE TRIGGER trg_books_track_deleted
+	AFTER INSERT OR DELETE ON books
+	FOR EACH ROW EXECUTE FUNCTION track_deleted_book();
@@ -0,0 +1,6 @@
+CREATE OR REPLACE FUNCTION touch_book_row() RETURNS TRIGGER AS $$
+BEGIN
+	NEW.updated_at = CURRENT_TIMESTAMP;
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
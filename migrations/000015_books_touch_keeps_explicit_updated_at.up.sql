@@ -0,0 +1,8 @@
+CREATE OR REPLACE FUNCTION touch_book_row() RETURNS TRIGGER AS $$
+BEGIN
+	IF NEW.updated_at IS NOT DISTINCT FROM OLD.updated_at THEN
+		NEW.updated_at = CURRENT_TIMESTAMP;
+	END IF;
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
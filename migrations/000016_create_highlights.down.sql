@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS uq_highlights_user_fingerprint;
+DROP INDEX IF EXISTS idx_highlights_user_book;
+
+DROP TABLE IF EXISTS highlights;
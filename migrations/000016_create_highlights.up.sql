@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS highlights (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	book_id UUID REFERENCES books(id) ON DELETE SET NULL,
+	kind VARCHAR(16) NOT NULL,
+	text TEXT NOT NULL DEFAULT '',
+	page INTEGER,
+	location_start INTEGER,
+	location_end INTEGER,
+	clipped_at TIMESTAMP,
+	source_title TEXT,
+	source_author TEXT,
+	fingerprint VARCHAR(64),
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT highlights_kind_chk CHECK (kind IN ('highlight', 'note', 'bookmark'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_highlights_user_book ON highlights(user_id, book_id);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_highlights_user_fingerprint
+	ON highlights(user_id, fingerprint) WHERE fingerprint IS NOT NULL;
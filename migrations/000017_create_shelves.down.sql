@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_shelves_books_book_id;
+
+DROP TABLE IF EXISTS shelves_books;
+
+DROP INDEX IF EXISTS uq_shelves_user_builtin;
+DROP INDEX IF EXISTS uq_shelves_user_name;
+
+DROP TABLE IF EXISTS shelves;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS shelves (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	kind VARCHAR(16) NOT NULL DEFAULT 'custom',
+	name VARCHAR(50),
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT shelves_kind_chk CHECK (kind IN ('want-to-read', 'reading', 'read', 'custom')),
+	CONSTRAINT shelves_name_chk CHECK ((kind = 'custom') = (name IS NOT NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_shelves_user_name ON shelves(user_id, lower(name)) WHERE name IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS uq_shelves_user_builtin ON shelves(user_id, kind) WHERE kind <> 'custom';
+
+CREATE TABLE IF NOT EXISTS shelves_books (
+	shelf_id UUID NOT NULL REFERENCES shelves(id) ON DELETE CASCADE,
+	book_id UUID NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+	added_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (shelf_id, book_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_shelves_books_book_id ON shelves_books(book_id);
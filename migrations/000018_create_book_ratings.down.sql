@@ -0,0 +1,18 @@
+ALTER TABLE books
+	ADD COLUMN IF NOT EXISTS mark SMALLINT NOT NULL DEFAULT 0,
+	ADD CONSTRAINT books_mark_chk CHECK (mark BETWEEN 0 AND 10);
+
+UPDATE books SET mark = r.value
+FROM book_ratings r
+WHERE r.book_id = books.id AND r.user_id IS NULL;
+
+DROP TRIGGER IF EXISTS trg_book_ratings_stats ON book_ratings;
+DROP FUNCTION IF EXISTS apply_book_rating();
+
+DROP TABLE IF EXISTS book_rating_stats;
+
+DROP INDEX IF EXISTS idx_book_ratings_user_id;
+DROP INDEX IF EXISTS uq_book_ratings_legacy;
+DROP INDEX IF EXISTS uq_book_ratings_user;
+
+DROP TABLE IF EXISTS book_ratings;
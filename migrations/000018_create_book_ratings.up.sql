@@ -0,0 +1,55 @@
+CREATE TABLE IF NOT EXISTS book_ratings (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	book_id UUID NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+	user_id UUID REFERENCES users(user_id) ON DELETE CASCADE,
+	value SMALLINT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT book_ratings_value_chk CHECK (value BETWEEN 0 AND 10)
+);
+
+-- A rating without a user is the legacy mark the book had before ratings
+-- became personal.
+CREATE UNIQUE INDEX IF NOT EXISTS uq_book_ratings_user ON book_ratings(book_id, user_id) WHERE user_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS uq_book_ratings_legacy ON book_ratings(book_id) WHERE user_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_book_ratings_user_id ON book_ratings(user_id);
+
+CREATE TABLE IF NOT EXISTS book_rating_stats (
+	book_id UUID PRIMARY KEY REFERENCES books(id) ON DELETE CASCADE,
+	ratings_count INTEGER NOT NULL DEFAULT 0,
+	ratings_sum INTEGER NOT NULL DEFAULT 0,
+	histogram INTEGER[] NOT NULL DEFAULT array_fill(0, ARRAY[11])
+);
+
+CREATE OR REPLACE FUNCTION apply_book_rating() RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP IN ('UPDATE', 'DELETE') THEN
+		UPDATE book_rating_stats SET
+			ratings_count = ratings_count - 1,
+			ratings_sum = ratings_sum - OLD.value,
+			histogram[OLD.value + 1] = histogram[OLD.value + 1] - 1
+		WHERE book_id = OLD.book_id;
+	END IF;
+	IF TG_OP IN ('INSERT', 'UPDATE') THEN
+		INSERT INTO book_rating_stats(book_id) VALUES (NEW.book_id) ON CONFLICT (book_id) DO NOTHING;
+		UPDATE book_rating_stats SET
+			ratings_count = ratings_count + 1,
+			ratings_sum = ratings_sum + NEW.value,
+			histogram[NEW.value + 1] = histogram[NEW.value + 1] + 1
+		WHERE book_id = NEW.book_id;
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_book_ratings_stats ON book_ratings;
+CREATE TRIGGER trg_book_ratings_stats
+	AFTER INSERT OR UPDATE OF value OR DELETE ON book_ratings
+	FOR EACH ROW EXECUTE FUNCTION apply_book_rating();
+
+-- Zero was the column default, so it cannot be told apart from "not rated".
+INSERT INTO book_ratings(book_id, value, created_at, updated_at)
+SELECT id, mark, created_at, updated_at FROM books WHERE mark > 0
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE books DROP COLUMN IF EXISTS mark;
@@ -0,0 +1,13 @@
+DROP TRIGGER IF EXISTS trg_review_votes_count ON review_votes;
+DROP FUNCTION IF EXISTS count_review_vote();
+
+DROP INDEX IF EXISTS idx_review_votes_user_id;
+
+DROP TABLE IF EXISTS review_votes;
+
+DROP INDEX IF EXISTS idx_reviews_status_created_at;
+DROP INDEX IF EXISTS idx_reviews_book_status;
+DROP INDEX IF EXISTS idx_reviews_user_id;
+DROP INDEX IF EXISTS uq_reviews_book_user;
+
+DROP TABLE IF EXISTS reviews;
@@ -0,0 +1,46 @@
+CREATE TABLE IF NOT EXISTS reviews (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	book_id UUID NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+	user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	body TEXT NOT NULL,
+	spoiler BOOLEAN NOT NULL DEFAULT FALSE,
+	status VARCHAR(16) NOT NULL DEFAULT 'pending',
+	helpful_count INTEGER NOT NULL DEFAULT 0,
+	moderated_by UUID REFERENCES users(user_id) ON DELETE SET NULL,
+	moderated_at TIMESTAMP,
+	rejection_reason TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT reviews_status_chk CHECK (status IN ('pending', 'approved', 'rejected')),
+	CONSTRAINT reviews_body_chk CHECK (length(btrim(body)) > 0)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_reviews_book_user ON reviews(book_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_reviews_user_id ON reviews(user_id);
+CREATE INDEX IF NOT EXISTS idx_reviews_book_status ON reviews(book_id, status);
+CREATE INDEX IF NOT EXISTS idx_reviews_status_created_at ON reviews(status, created_at);
+
+CREATE TABLE IF NOT EXISTS review_votes (
+	review_id UUID NOT NULL REFERENCES reviews(id) ON DELETE CASCADE,
+	user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (review_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_review_votes_user_id ON review_votes(user_id);
+
+CREATE OR REPLACE FUNCTION count_review_vote() RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP = 'INSERT' THEN
+		UPDATE reviews SET helpful_count = helpful_count + 1 WHERE id = NEW.review_id;
+	ELSE
+		UPDATE reviews SET helpful_count = helpful_count - 1 WHERE id = OLD.review_id;
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_review_votes_count ON review_votes;
+CREATE TRIGGER trg_review_votes_count
+	AFTER INSERT OR DELETE ON review_votes
+	FOR EACH ROW EXECUTE FUNCTION count_review_vote();
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_reading_progress_book_id;
+DROP INDEX IF EXISTS uq_reading_progress_user_document;
+DROP INDEX IF EXISTS uq_reading_progress_user_book;
+
+DROP TABLE IF EXISTS reading_progress;
+
+DROP TABLE IF EXISTS koreader_accounts;
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS koreader_accounts (
+	user_id UUID PRIMARY KEY REFERENCES users(user_id) ON DELETE CASCADE,
+	username VARCHAR(100) NOT NULL UNIQUE,
+	key_hash BYTEA NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Progress is kept per book for our own API and per document hash for
+-- KOReader, a row carries both once the document is linked to a book.
+CREATE TABLE IF NOT EXISTS reading_progress (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	book_id UUID REFERENCES books(id) ON DELETE CASCADE,
+	document VARCHAR(64),
+	fraction DOUBLE PRECISION NOT NULL,
+	page INTEGER,
+	position TEXT,
+	device VARCHAR(100),
+	device_id VARCHAR(100),
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT reading_progress_fraction_chk CHECK (fraction BETWEEN 0 AND 1),
+	CONSTRAINT reading_progress_page_chk CHECK (page > 0),
+	CONSTRAINT reading_progress_target_chk CHECK (book_id IS NOT NULL OR document IS NOT NULL)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_reading_progress_user_book
+	ON reading_progress(user_id, book_id) WHERE book_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS uq_reading_progress_user_document
+	ON reading_progress(user_id, document) WHERE document IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_reading_progress_book_id ON reading_progress(book_id);
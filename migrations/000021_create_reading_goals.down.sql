@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS reading_goals;
+
+ALTER TABLE books
+	DROP CONSTRAINT IF EXISTS books_pages_chk,
+	DROP COLUMN IF EXISTS pages;
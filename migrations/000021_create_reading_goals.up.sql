@@ -0,0 +1,15 @@
+ALTER TABLE books
+	ADD COLUMN IF NOT EXISTS pages INTEGER,
+	ADD CONSTRAINT books_pages_chk CHECK (pages > 0);
+
+CREATE TABLE IF NOT EXISTS reading_goals (
+	user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	year SMALLINT NOT NULL,
+	books INTEGER NOT NULL,
+	pages INTEGER,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, year),
+	CONSTRAINT reading_goals_books_chk CHECK (books > 0),
+	CONSTRAINT reading_goals_pages_chk CHECK (pages > 0)
+);
@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_highlights_tags;
+DROP INDEX IF EXISTS idx_highlights_search;
+
+UPDATE highlights SET kind = 'highlight' WHERE kind = 'quote';
+
+ALTER TABLE highlights DROP CONSTRAINT IF EXISTS highlights_kind_chk;
+ALTER TABLE highlights
+	DROP CONSTRAINT IF EXISTS highlights_colour_chk,
+	DROP COLUMN IF EXISTS search,
+	DROP COLUMN IF EXISTS updated_at,
+	DROP COLUMN IF EXISTS tags,
+	DROP COLUMN IF EXISTS colour,
+	ADD CONSTRAINT highlights_kind_chk CHECK (kind IN ('highlight', 'note', 'bookmark'));
@@ -0,0 +1,13 @@
+ALTER TABLE highlights DROP CONSTRAINT IF EXISTS highlights_kind_chk;
+ALTER TABLE highlights
+	ADD CONSTRAINT highlights_kind_chk CHECK (kind IN ('highlight', 'quote', 'note', 'bookmark')),
+	ADD COLUMN IF NOT EXISTS colour VARCHAR(16),
+	ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}',
+	ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	ADD COLUMN IF NOT EXISTS search TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', text)) STORED,
+	ADD CONSTRAINT highlights_colour_chk CHECK (colour IN ('yellow', 'green', 'blue', 'pink', 'orange', 'purple'));
+
+UPDATE highlights SET updated_at = created_at;
+
+CREATE INDEX IF NOT EXISTS idx_highlights_search ON highlights USING GIN (search);
+CREATE INDEX IF NOT EXISTS idx_highlights_tags ON highlights USING GIN (tags);
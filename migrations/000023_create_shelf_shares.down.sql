@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_shelf_shares_shelf_id;
+
+DROP TABLE IF EXISTS shelf_shares;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS shelf_shares (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	shelf_id UUID NOT NULL REFERENCES shelves(id) ON DELETE CASCADE,
+	token VARCHAR(64) NOT NULL UNIQUE,
+	expires_at TIMESTAMP,
+	revoked_at TIMESTAMP,
+	views INTEGER NOT NULL DEFAULT 0,
+	last_viewed_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_shelf_shares_shelf_id ON shelf_shares(shelf_id);
@@ -0,0 +1,19 @@
+DROP TRIGGER IF EXISTS trg_reviews_activity ON reviews;
+DROP TRIGGER IF EXISTS trg_book_ratings_activity ON book_ratings;
+DROP TRIGGER IF EXISTS trg_reading_progress_activity ON reading_progress;
+DROP TRIGGER IF EXISTS trg_shelves_books_activity ON shelves_books;
+
+DROP FUNCTION IF EXISTS record_reviewed_activity();
+DROP FUNCTION IF EXISTS record_rated_activity();
+DROP FUNCTION IF EXISTS record_finished_activity();
+DROP FUNCTION IF EXISTS record_shelved_activity();
+DROP FUNCTION IF EXISTS add_activity_event(UUID, VARCHAR, UUID, UUID, SMALLINT, UUID, TIMESTAMP);
+
+DROP INDEX IF EXISTS idx_activity_events_book_id;
+DROP INDEX IF EXISTS idx_activity_events_user_created_at;
+DROP TABLE IF EXISTS activity_events;
+
+DROP INDEX IF EXISTS idx_follows_followee_id;
+DROP TABLE IF EXISTS follows;
+
+ALTER TABLE users DROP COLUMN IF EXISTS public_profile;
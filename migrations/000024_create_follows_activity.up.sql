@@ -0,0 +1,133 @@
+-- Profiles start private, so turning the feature on exposes nobody's
+-- activity without their consent.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS public_profile BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS follows (
+	follower_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	followee_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	status VARCHAR(16) NOT NULL DEFAULT 'pending',
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	accepted_at TIMESTAMP,
+	PRIMARY KEY (follower_id, followee_id),
+	CONSTRAINT follows_self_chk CHECK (follower_id <> followee_id),
+	CONSTRAINT follows_status_chk CHECK (status IN ('pending', 'accepted'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_follows_followee_id ON follows(followee_id, status);
+
+CREATE TABLE IF NOT EXISTS activity_events (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	kind VARCHAR(16) NOT NULL,
+	book_id UUID NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+	shelf_id UUID REFERENCES shelves(id) ON DELETE CASCADE,
+	rating SMALLINT,
+	review_id UUID REFERENCES reviews(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT activity_events_kind_chk CHECK (kind IN ('shelved', 'finished', 'rated', 'reviewed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_activity_events_user_created_at ON activity_events(user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_activity_events_book_id ON activity_events(book_id);
+
+-- A new event replaces the earlier one of the same kind for the same book and
+-- shelf, so re-rating or re-shelving a book moves it up the feed instead of
+-- repeating it.
+CREATE OR REPLACE FUNCTION add_activity_event(
+	p_user_id UUID,
+	p_kind VARCHAR,
+	p_book_id UUID,
+	p_shelf_id UUID,
+	p_rating SMALLINT,
+	p_review_id UUID,
+	p_created_at TIMESTAMP
+) RETURNS VOID AS $$
+BEGIN
+	DELETE FROM activity_events
+	WHERE user_id = p_user_id
+		AND kind = p_kind
+		AND book_id = p_book_id
+		AND shelf_id IS NOT DISTINCT FROM p_shelf_id;
+	INSERT INTO activity_events(user_id, kind, book_id, shelf_id, rating, review_id, created_at)
+	VALUES (p_user_id, p_kind, p_book_id, p_shelf_id, p_rating, p_review_id, p_created_at);
+END;
+$$ LANGUAGE plpgsql;
+
+-- Putting a book on the read shelf finishes it, any other shelf only shelves it.
+CREATE OR REPLACE FUNCTION record_shelved_activity() RETURNS TRIGGER AS $$
+DECLARE
+	shelf shelves%ROWTYPE;
+BEGIN
+	SELECT * INTO shelf FROM shelves WHERE id = NEW.shelf_id;
+	PERFORM add_activity_event(
+		shelf.user_id,
+		CASE WHEN shelf.kind = 'read' THEN 'finished' ELSE 'shelved' END,
+		NEW.book_id,
+		NEW.shelf_id,
+		NULL,
+		NULL,
+		NEW.added_at
+	);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_shelves_books_activity ON shelves_books;
+CREATE TRIGGER trg_shelves_books_activity
+	AFTER INSERT ON shelves_books
+	FOR EACH ROW EXECUTE FUNCTION record_shelved_activity();
+
+CREATE OR REPLACE FUNCTION record_finished_activity() RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP = 'UPDATE' AND OLD.fraction >= 1 AND OLD.book_id IS NOT DISTINCT FROM NEW.book_id THEN
+		RETURN NULL;
+	END IF;
+	PERFORM add_activity_event(NEW.user_id, 'finished', NEW.book_id, NULL, NULL, NULL, NEW.updated_at);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_reading_progress_activity ON reading_progress;
+CREATE TRIGGER trg_reading_progress_activity
+	AFTER INSERT OR UPDATE OF fraction, book_id ON reading_progress
+	FOR EACH ROW WHEN (NEW.book_id IS NOT NULL AND NEW.fraction >= 1)
+	EXECUTE FUNCTION record_finished_activity();
+
+-- Removing a rating takes its event out of the feed as well.
+CREATE OR REPLACE FUNCTION record_rated_activity() RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		DELETE FROM activity_events
+		WHERE user_id = OLD.user_id AND kind = 'rated' AND book_id = OLD.book_id;
+		RETURN NULL;
+	END IF;
+	-- Legacy ratings belong to nobody.
+	IF NEW.user_id IS NULL THEN
+		RETURN NULL;
+	END IF;
+	PERFORM add_activity_event(NEW.user_id, 'rated', NEW.book_id, NULL, NEW.value, NULL, NEW.updated_at);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_book_ratings_activity ON book_ratings;
+CREATE TRIGGER trg_book_ratings_activity
+	AFTER INSERT OR UPDATE OF value OR DELETE ON book_ratings
+	FOR EACH ROW EXECUTE FUNCTION record_rated_activity();
+
+-- A review only becomes activity once it is approved, pending reviews are
+-- visible to nobody but their author.
+CREATE OR REPLACE FUNCTION record_reviewed_activity() RETURNS TRIGGER AS $$
+BEGIN
+	PERFORM add_activity_event(
+		NEW.user_id, 'reviewed', NEW.book_id, NULL, NULL, NEW.id, COALESCE(NEW.moderated_at, NEW.updated_at)
+	);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_reviews_activity ON reviews;
+CREATE TRIGGER trg_reviews_activity
+	AFTER UPDATE OF status ON reviews
+	FOR EACH ROW WHEN (NEW.status = 'approved' AND OLD.status <> 'approved')
+	EXECUTE FUNCTION record_reviewed_activity();
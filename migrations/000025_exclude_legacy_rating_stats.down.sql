@@ -0,0 +1,31 @@
+CREATE OR REPLACE FUNCTION apply_book_rating() RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP IN ('UPDATE', 'DELETE') THEN
+		UPDATE book_rating_stats SET
+			ratings_count = ratings_count - 1,
+			ratings_sum = ratings_sum - OLD.value,
+			histogram[OLD.value + 1] = histogram[OLD.value + 1] - 1
+		WHERE book_id = OLD.book_id;
+	END IF;
+	IF TG_OP IN ('INSERT', 'UPDATE') THEN
+		INSERT INTO book_rating_stats(book_id) VALUES (NEW.book_id) ON CONFLICT (book_id) DO NOTHING;
+		UPDATE book_rating_stats SET
+			ratings_count = ratings_count + 1,
+			ratings_sum = ratings_sum + NEW.value,
+			histogram[NEW.value + 1] = histogram[NEW.value + 1] + 1
+		WHERE book_id = NEW.book_id;
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+INSERT INTO book_rating_stats(book_id)
+SELECT book_id FROM book_ratings WHERE user_id IS NULL
+ON CONFLICT (book_id) DO NOTHING;
+
+UPDATE book_rating_stats s SET
+	ratings_count = s.ratings_count + 1,
+	ratings_sum = s.ratings_sum + r.value,
+	histogram[r.value + 1] = s.histogram[r.value + 1] + 1
+FROM book_ratings r
+WHERE r.book_id = s.book_id AND r.user_id IS NULL;
@@ -0,0 +1,29 @@
+-- The legacy rating is the mark an admin set, not a reader's rating, so it no
+-- longer counts in the public stats.
+CREATE OR REPLACE FUNCTION apply_book_rating() RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP IN ('UPDATE', 'DELETE') AND OLD.user_id IS NOT NULL THEN
+		UPDATE book_rating_stats SET
+			ratings_count = ratings_count - 1,
+			ratings_sum = ratings_sum - OLD.value,
+			histogram[OLD.value + 1] = histogram[OLD.value + 1] - 1
+		WHERE book_id = OLD.book_id;
+	END IF;
+	IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.user_id IS NOT NULL THEN
+		INSERT INTO book_rating_stats(book_id) VALUES (NEW.book_id) ON CONFLICT (book_id) DO NOTHING;
+		UPDATE book_rating_stats SET
+			ratings_count = ratings_count + 1,
+			ratings_sum = ratings_sum + NEW.value,
+			histogram[NEW.value + 1] = histogram[NEW.value + 1] + 1
+		WHERE book_id = NEW.book_id;
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+UPDATE book_rating_stats s SET
+	ratings_count = s.ratings_count - 1,
+	ratings_sum = s.ratings_sum - r.value,
+	histogram[r.value + 1] = s.histogram[r.value + 1] - 1
+FROM book_ratings r
+WHERE r.book_id = s.book_id AND r.user_id IS NULL;
@@ -0,0 +1,16 @@
+CREATE OR REPLACE FUNCTION touch_book_row() RETURNS TRIGGER AS $$
+BEGIN
+	IF NEW.updated_at IS NOT DISTINCT FROM OLD.updated_at THEN
+		NEW.updated_at = CURRENT_TIMESTAMP;
+	END IF;
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP INDEX IF EXISTS ix_books_normalized_title;
+
+ALTER TABLE books
+	DROP COLUMN IF EXISTS normalized_title;
+
+CREATE INDEX IF NOT EXISTS ix_books_normalized_title
+	ON books ((btrim(regexp_replace(lower(title), '[^[:alnum:]]+', ' ', 'g'))));
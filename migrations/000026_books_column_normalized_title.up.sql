@@ -0,0 +1,23 @@
+-- The normalized title is written by the application, the SQL lower() and
+-- [:alnum:] depend on the database locale and disagree with it on non-ASCII
+-- titles. Existing books are filled in by the application on start.
+ALTER TABLE books
+	ADD COLUMN IF NOT EXISTS normalized_title TEXT;
+
+DROP INDEX IF EXISTS ix_books_normalized_title;
+CREATE INDEX IF NOT EXISTS ix_books_normalized_title ON books(normalized_title);
+
+-- Filling in the normalized title of an existing book is not a change
+-- harvesters need to see.
+CREATE OR REPLACE FUNCTION touch_book_row() RETURNS TRIGGER AS $$
+BEGIN
+	IF NEW.updated_at IS NOT DISTINCT FROM OLD.updated_at
+		AND NOT (
+			OLD.normalized_title IS NULL
+			AND to_jsonb(NEW) - 'normalized_title' = to_jsonb(OLD) - 'normalized_title'
+		) THEN
+		NEW.updated_at = CURRENT_TIMESTAMP;
+	END IF;
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,195 @@
+package epub
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+const (
+	containerPath = "META-INF/container.xml"
+	mimetypePath  = "mimetype"
+	MediaType     = "application/epub+zip"
+
+	RoleAuthor = "aut"
+
+	maxPackageSize = 4 << 20
+)
+
+var ErrInvalidEPUB = errors.New("invalid epub")
+
+type Creator struct {
+	Name   string
+	FileAs string
+	Role   string
+}
+
+type Identifier struct {
+	ID     string
+	Scheme string
+	Value  string
+}
+
+// Package is the Dublin Core metadata of an OPF package document, with
+// EPUB 3 refinements already folded into the creators.
+type Package struct {
+	Version     string
+	Titles      []string
+	Creators    []Creator
+	Publishers  []string
+	Dates       []string
+	Languages   []string
+	Identifiers []Identifier
+	Subjects    []string
+	Description string
+	UniqueID    string
+}
+
+type container struct {
+	Rootfiles []struct {
+		FullPath  string `xml:"full-path,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+type opfElement struct {
+	ID     string `xml:"id,attr"`
+	Role   string `xml:"role,attr"`
+	FileAs string `xml:"file-as,attr"`
+	Scheme string `xml:"scheme,attr"`
+	Value  string `xml:",chardata"`
+}
+
+type opfMeta struct {
+	Refines  string `xml:"refines,attr"`
+	Property string `xml:"property,attr"`
+	Scheme   string `xml:"scheme,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type opfPackage struct {
+	Version          string `xml:"version,attr"`
+	UniqueIdentifier string `xml:"unique-identifier,attr"`
+	Metadata         struct {
+		Titles      []opfElement `xml:"http://purl.org/dc/elements/1.1/ title"`
+		Creators    []opfElement `xml:"http://purl.org/dc/elements/1.1/ creator"`
+		Publishers  []opfElement `xml:"http://purl.org/dc/elements/1.1/ publisher"`
+		Dates       []opfElement `xml:"http://purl.org/dc/elements/1.1/ date"`
+		Languages   []opfElement `xml:"http://purl.org/dc/elements/1.1/ language"`
+		Identifiers []opfElement `xml:"http://purl.org/dc/elements/1.1/ identifier"`
+		Subjects    []opfElement `xml:"http://purl.org/dc/elements/1.1/ subject"`
+		Description []opfElement `xml:"http://purl.org/dc/elements/1.1/ description"`
+		Metas       []opfMeta    `xml:"meta"`
+	} `xml:"metadata"`
+}
+
+func ReadPackage(reader io.ReaderAt, size int64) (Package, error) {
+	archive, err := zip.NewReader(reader, size)
+	if err != nil {
+		return Package{}, fmt.Errorf("%w: %v", ErrInvalidEPUB, err)
+	}
+
+	var root container
+	if err = decodeFile(archive, containerPath, &root); err != nil {
+		return Package{}, err
+	}
+	if len(root.Rootfiles) == 0 || root.Rootfiles[0].FullPath == "" {
+		return Package{}, fmt.Errorf("%w: container has no rootfile", ErrInvalidEPUB)
+	}
+
+	var document opfPackage
+	if err = decodeFile(archive, path.Clean(root.Rootfiles[0].FullPath), &document); err != nil {
+		return Package{}, err
+	}
+	return document.toPackage(), nil
+}
+
+func decodeFile(archive *zip.Reader, name string, target any) error {
+	file, err := archive.Open(name)
+	if err != nil {
+		return fmt.Errorf("%w: missing %s", ErrInvalidEPUB, name)
+	}
+	defer file.Close()
+
+	decoder := xml.NewDecoder(io.LimitReader(file, maxPackageSize))
+	decoder.Strict = false
+	if err = decoder.Decode(target); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidEPUB, name, err)
+	}
+	return nil
+}
+
+func (d opfPackage) toPackage() Package {
+	refinements := make(map[string]map[string]string)
+	for _, meta := range d.Metadata.Metas {
+		id := strings.TrimPrefix(meta.Refines, "#")
+		if id == "" || meta.Property == "" {
+			continue
+		}
+		if refinements[id] == nil {
+			refinements[id] = make(map[string]string)
+		}
+		refinements[id][meta.Property] = strings.TrimSpace(meta.Value)
+	}
+
+	result := Package{
+		Version:     d.Version,
+		Titles:      values(d.Metadata.Titles),
+		Publishers:  values(d.Metadata.Publishers),
+		Dates:       values(d.Metadata.Dates),
+		Languages:   values(d.Metadata.Languages),
+		Subjects:    values(d.Metadata.Subjects),
+		Description: strings.TrimSpace(strings.Join(values(d.Metadata.Description), "\n")),
+	}
+	for _, element := range d.Metadata.Creators {
+		name := strings.TrimSpace(element.Value)
+		if name == "" {
+			continue
+		}
+		creator := Creator{Name: name, FileAs: element.FileAs, Role: element.Role}
+		if refined, ok := refinements[element.ID]; ok {
+			if role := refined["role"]; role != "" {
+				creator.Role = role
+			}
+			if fileAs := refined["file-as"]; fileAs != "" {
+				creator.FileAs = fileAs
+			}
+		}
+		result.Creators = append(result.Creators, creator)
+	}
+	for _, element := range d.Metadata.Identifiers {
+		value := strings.TrimSpace(element.Value)
+		if value == "" {
+			continue
+		}
+		identifier := Identifier{ID: element.ID, Scheme: element.Scheme, Value: value}
+		if refined, ok := refinements[element.ID]; ok && identifier.Scheme == "" {
+			identifier.Scheme = refined["identifier-type"]
+		}
+		result.Identifiers = append(result.Identifiers, identifier)
+		if element.ID != "" && element.ID == d.UniqueIdentifier {
+			result.UniqueID = value
+		}
+	}
+	return result
+}
+
+// IsAuthor reports whether the creator wrote the book; creators without a
+// role are treated as authors, as the OPF specification suggests.
+func (c Creator) IsAuthor() bool {
+	return c.Role == "" || strings.EqualFold(c.Role, RoleAuthor)
+}
+
+func values(elements []opfElement) []string {
+	var result []string
+	for _, element := range elements {
+		if value := strings.Join(strings.Fields(element.Value), " "); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,140 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the expected packages in testdata")
+
+// TestReadPackage zips every fixture directory into an EPUB and compares the
+// package read from it with the JSON file next to the directory.
+func TestReadPackage(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "packages", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dirs []string
+	for _, path := range paths {
+		if filepath.Ext(path) == "" {
+			dirs = append(dirs, path)
+		}
+	}
+	if len(dirs) == 0 {
+		t.Fatal("no EPUB fixtures")
+	}
+	for _, dir := range dirs {
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			archive := zipFixture(t, dir)
+			document, err := ReadPackage(bytes.NewReader(archive), int64(len(archive)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := json.MarshalIndent(document, "", "\t")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			goldenPath := dir + ".json"
+			if *update {
+				if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			golden, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, golden) {
+				t.Fatalf("package differs from %s:\n%s", goldenPath, got)
+			}
+		})
+	}
+}
+
+func TestReadPackageInvalid(t *testing.T) {
+	dirs, err := filepath.Glob(filepath.Join("testdata", "invalid", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	archives := map[string][]byte{"not-a-zip": []byte(MediaType)}
+	for _, dir := range dirs {
+		archives[filepath.Base(dir)] = zipFixture(t, dir)
+	}
+	for name, archive := range archives {
+		t.Run(name, func(t *testing.T) {
+			_, err := ReadPackage(bytes.NewReader(archive), int64(len(archive)))
+			if !errors.Is(err, ErrInvalidEPUB) {
+				t.Fatalf("error = %v, want %v", err, ErrInvalidEPUB)
+			}
+		})
+	}
+}
+
+func TestCreatorIsAuthor(t *testing.T) {
+	tests := []struct {
+		role string
+		want bool
+	}{
+		{role: "", want: true},
+		{role: RoleAuthor, want: true},
+		{role: "AUT", want: true},
+		{role: "edt", want: false},
+		{role: "ill", want: false},
+	}
+	for _, test := range tests {
+		if got := (Creator{Name: "Someone", Role: test.role}).IsAuthor(); got != test.want {
+			t.Errorf("role %q: IsAuthor() = %v, want %v", test.role, got, test.want)
+		}
+	}
+}
+
+// zipFixture stores the mimetype first and uncompressed, like an EPUB
+// writer does, and every other file of the directory after it.
+func zipFixture(t *testing.T, dir string) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	add := func(name string, method uint16) {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		file, err := writer.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = file.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add(mimetypePath, zip.Store)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if name = filepath.ToSlash(name); name != mimetypePath {
+			add(name, zip.Deflate)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
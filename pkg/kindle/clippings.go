@@ -0,0 +1,313 @@
+package kindle
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	separator     = "=========="
+	byteOrderMark = "\ufeff"
+)
+
+type Kind string
+
+const (
+	KindHighlight Kind = "highlight"
+	KindNote      Kind = "note"
+	KindBookmark  Kind = "bookmark"
+)
+
+type Clipping struct {
+	Title         string
+	Author        string
+	Kind          Kind
+	Page          *int
+	LocationStart *int
+	LocationEnd   *int
+	AddedAt       *time.Time
+	Text          string
+	// Raw holds the title and metadata lines exactly as written by the device,
+	// it identifies the clipping across repeated uploads of the same file.
+	Raw string
+}
+
+var ErrNoClippings = errors.New("file contains no kindle clippings")
+
+// Keywords are matched against the lower-cased metadata line. Locales differ
+// only in wording, the layout "- <kind> <position> | <position> | <added>" is
+// the same on every device language.
+var (
+	kindKeywords = []struct {
+		kind     Kind
+		keywords []string
+	}{
+		{KindBookmark, []string{"bookmark", "lesezeichen", "signet", "marcador", "segnalibro", "закладка", "bladwijzer"}},
+		{KindNote, []string{"note", "notiz", "nota", "заметка", "notitie"}},
+		{
+			KindHighlight,
+			[]string{
+				"highlight", "markierung", "surlignement", "subrayado", "evidenziazione", "destaque",
+				"выделенный", "выделение", "markering",
+			},
+		},
+	}
+	pageKeywords = []string{
+		"page", "seite", "página", "pagina", "странице", "стр.",
+	}
+	locationKeywords = []string{
+		"location", "loc.", "position", "emplacement", "posición", "posizione", "posição", "место", "locatie",
+	}
+	addedKeywords = []string{
+		"added on", "hinzugefügt am", "ajouté le", "añadido el", "aggiunto il", "aggiunto in data",
+		"agregado el", "adicionado:", "adicionado em", "добавлено:", "toegevoegd op",
+	}
+	months = map[string]time.Month{}
+
+	numberPattern = regexp.MustCompile(`\d+(?:\s*-\s*\d+)?`)
+	timePattern   = regexp.MustCompile(`(\d{1,2}):(\d{2})(?::(\d{2}))?\s*(am|pm|a\.\s?m\.|p\.\s?m\.)?`)
+	yearPattern   = regexp.MustCompile(`\b(\d{4})\b`)
+	dayPattern    = regexp.MustCompile(`\b(\d{1,2})\b`)
+)
+
+func init() {
+	names := [][]string{
+		{"january", "february", "march", "april", "may", "june", "july", "august", "september", "october", "november", "december"},
+		{"januar", "februar", "märz", "april", "mai", "juni", "juli", "august", "september", "oktober", "november", "dezember"},
+		{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+		{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+		{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"},
+		{"января", "февраля", "марта", "апреля", "мая", "июня", "июля", "августа", "сентября", "октября", "ноября", "декабря"},
+	}
+	for _, locale := range names {
+		for i, name := range locale {
+			months[name] = time.Month(i + 1)
+		}
+	}
+	months["setiembre"] = time.September
+}
+
+// Parse reads a "My Clippings.txt" file. Entries that do not have a title and
+// a recognisable metadata line are skipped.
+func Parse(reader io.Reader) ([]Clipping, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+
+	var (
+		clippings []Clipping
+		lines     []string
+	)
+	flush := func() {
+		if clipping, ok := parseEntry(lines); ok {
+			clippings = append(clippings, clipping)
+		}
+		lines = lines[:0]
+	}
+	for scanner.Scan() {
+		line := strings.TrimRight(strings.TrimPrefix(scanner.Text(), byteOrderMark), "\r")
+		if strings.TrimSpace(line) == separator {
+			flush()
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	if len(clippings) == 0 {
+		return nil, ErrNoClippings
+	}
+	return clippings, nil
+}
+
+func parseEntry(lines []string) (Clipping, bool) {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	if len(lines) < 2 {
+		return Clipping{}, false
+	}
+	heading := strings.TrimSpace(lines[0])
+	meta := strings.TrimSpace(lines[1])
+	if heading == "" || !strings.HasPrefix(meta, "-") {
+		return Clipping{}, false
+	}
+
+	clipping := Clipping{Raw: heading + "\n" + meta}
+	clipping.Title, clipping.Author = splitHeading(heading)
+	if !parseMeta(&clipping, strings.TrimSpace(strings.TrimPrefix(meta, "-"))) {
+		return Clipping{}, false
+	}
+	clipping.Text = strings.TrimSpace(strings.Join(lines[2:], "\n"))
+	return clipping, true
+}
+
+// splitHeading takes the author from the last parenthesised group, titles may
+// contain their own parentheses, for example a series name.
+func splitHeading(heading string) (string, string) {
+	if !strings.HasSuffix(heading, ")") {
+		return heading, ""
+	}
+	depth := 0
+	for i := len(heading) - 1; i >= 0; i-- {
+		switch heading[i] {
+		case ')':
+			depth++
+		case '(':
+			depth--
+			if depth == 0 {
+				title := strings.TrimSpace(heading[:i])
+				if title == "" {
+					return heading, ""
+				}
+				return title, strings.TrimSpace(heading[i+1 : len(heading)-1])
+			}
+		}
+	}
+	return heading, ""
+}
+
+func parseMeta(clipping *Clipping, meta string) bool {
+	segments := strings.Split(meta, "|")
+	lower := strings.ToLower(segments[0])
+	for _, candidate := range kindKeywords {
+		if containsAny(lower, candidate.keywords) {
+			clipping.Kind = candidate.kind
+			break
+		}
+	}
+	if clipping.Kind == "" {
+		return false
+	}
+
+	for _, segment := range segments {
+		lower := strings.ToLower(strings.TrimSpace(segment))
+		if prefix, ok := addedPrefix(lower); ok {
+			clipping.AddedAt = parseDate(strings.TrimSpace(lower[len(prefix):]))
+			continue
+		}
+		switch {
+		case containsAny(lower, locationKeywords):
+			start, end, ok := parseRange(afterAny(lower, locationKeywords))
+			if ok {
+				clipping.LocationStart, clipping.LocationEnd = &start, &end
+			}
+		case containsAny(lower, pageKeywords):
+			if page, _, ok := parseRange(afterAny(lower, pageKeywords)); ok {
+				clipping.Page = &page
+			}
+		}
+	}
+	return true
+}
+
+func addedPrefix(segment string) (string, bool) {
+	for _, keyword := range addedKeywords {
+		if strings.HasPrefix(segment, keyword) {
+			return keyword, true
+		}
+	}
+	return "", false
+}
+
+// parseRange reads "180", "180-182" and the old abbreviated "180-82" form.
+func parseRange(text string) (int, int, bool) {
+	match := numberPattern.FindString(text)
+	if match == "" {
+		return 0, 0, false
+	}
+	first, last, isRange := strings.Cut(match, "-")
+	first, last = strings.TrimSpace(first), strings.TrimSpace(last)
+	start, err := strconv.Atoi(first)
+	if err != nil {
+		return 0, 0, false
+	}
+	if !isRange {
+		return start, start, true
+	}
+	if len(last) < len(first) {
+		last = first[:len(first)-len(last)] + last
+	}
+	end, err := strconv.Atoi(last)
+	if err != nil || end < start {
+		return start, start, true
+	}
+	return start, end, true
+}
+
+// parseDate accepts both "Sunday, October 8, 2017 3:49:24 PM" and
+// "dimanche 8 octobre 2017 15:49:24" orders by looking for the parts rather
+// than following one layout per locale.
+func parseDate(text string) *time.Time {
+	hour, minute, second := 0, 0, 0
+	if match := timePattern.FindStringSubmatch(text); match != nil {
+		hour, _ = strconv.Atoi(match[1])
+		minute, _ = strconv.Atoi(match[2])
+		if match[3] != "" {
+			second, _ = strconv.Atoi(match[3])
+		}
+		switch strings.ReplaceAll(strings.ReplaceAll(match[4], ".", ""), " ", "") {
+		case "pm":
+			if hour < 12 {
+				hour += 12
+			}
+		case "am":
+			if hour == 12 {
+				hour = 0
+			}
+		}
+		text = strings.Replace(text, match[0], " ", 1)
+	}
+
+	yearMatch := yearPattern.FindStringSubmatch(text)
+	if yearMatch == nil {
+		return nil
+	}
+	year, _ := strconv.Atoi(yearMatch[1])
+	text = strings.Replace(text, yearMatch[0], " ", 1)
+
+	var month time.Month
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) }) {
+		if found, ok := months[word]; ok {
+			month = found
+			break
+		}
+	}
+	dayMatch := dayPattern.FindStringSubmatch(text)
+	if month == 0 || dayMatch == nil {
+		return nil
+	}
+	day, _ := strconv.Atoi(dayMatch[1])
+	if day < 1 || day > 31 || hour > 23 || minute > 59 || second > 59 {
+		return nil
+	}
+	date := time.Date(year, month, day, hour, minute, second, 0, time.UTC)
+	return &date
+}
+
+func containsAny(text string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(text, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func afterAny(text string, keywords []string) string {
+	for _, keyword := range keywords {
+		if _, after, ok := strings.Cut(text, keyword); ok {
+			return after
+		}
+	}
+	return text
+}
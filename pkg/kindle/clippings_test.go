@@ -0,0 +1,67 @@
+package kindle
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the expected clippings in testdata")
+
+// TestParse reads every "My Clippings.txt" fixture, one per device language,
+// and compares the clippings with the JSON file next to it.
+func TestParse(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no clippings fixtures")
+	}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".txt")
+		t.Run(name, func(t *testing.T) {
+			file, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			clippings, err := Parse(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := json.MarshalIndent(clippings, "", "\t")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			goldenPath := strings.TrimSuffix(path, ".txt") + ".json"
+			if *update {
+				if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			golden, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, golden) {
+				t.Fatalf("clippings differ from %s:\n%s", goldenPath, got)
+			}
+		})
+	}
+}
+
+func TestParseWithoutClippings(t *testing.T) {
+	for _, input := range []string{"", "==========\n", "Title only\n==========\n"} {
+		if _, err := Parse(strings.NewReader(input)); !errors.Is(err, ErrNoClippings) {
+			t.Errorf("Parse(%q) error = %v, want %v", input, err, ErrNoClippings)
+		}
+	}
+}
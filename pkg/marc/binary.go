@@ -0,0 +1,145 @@
+package marc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	subfieldDelimiter = 0x1F
+	fieldTerminator   = 0x1E
+	recordTerminator  = 0x1D
+
+	directoryEntryLength = 12
+	maxRecordLength      = 99999
+)
+
+var ErrInvalidRecord = errors.New("invalid marc record")
+
+func WriteBinary(writer io.Writer, records ...Record) error {
+	for _, record := range records {
+		data, err := record.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if _, err = writer.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r Record) MarshalBinary() ([]byte, error) {
+	var (
+		directory strings.Builder
+		data      strings.Builder
+	)
+	addField := func(tag, value string) {
+		fmt.Fprintf(&directory, "%3s%04d%05d", tag, len(value)+1, data.Len())
+		data.WriteString(value)
+		data.WriteByte(fieldTerminator)
+	}
+	for _, field := range r.ControlFields {
+		addField(field.Tag, field.Value)
+	}
+	for _, field := range r.DataFields {
+		var value strings.Builder
+		value.WriteByte(indicator(field.Ind1))
+		value.WriteByte(indicator(field.Ind2))
+		for _, subfield := range field.Subfields {
+			value.WriteByte(subfieldDelimiter)
+			value.WriteByte(subfield.Code)
+			value.WriteString(subfield.Value)
+		}
+		addField(field.Tag, value.String())
+	}
+	directory.WriteByte(fieldTerminator)
+	data.WriteByte(recordTerminator)
+
+	baseAddress := leaderLength + directory.Len()
+	recordLength := baseAddress + data.Len()
+	if recordLength > maxRecordLength {
+		return nil, fmt.Errorf("%w: record length %d exceeds %d", ErrInvalidRecord, recordLength, maxRecordLength)
+	}
+
+	leader := r.Leader
+	if len(leader) != leaderLength {
+		leader = DefaultLeader
+	}
+	leader = fmt.Sprintf("%05d%s%05d%s", recordLength, leader[5:12], baseAddress, leader[17:])
+	return []byte(leader + directory.String() + data.String()), nil
+}
+
+func ReadBinary(reader io.Reader) ([]Record, error) {
+	var records []Record
+	buffered := bufio.NewReader(reader)
+	for {
+		data, err := buffered.ReadBytes(recordTerminator)
+		if errors.Is(err, io.EOF) {
+			if strings.TrimSpace(string(data)) != "" {
+				return nil, fmt.Errorf("%w: missing record terminator", ErrInvalidRecord)
+			}
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		record, err := UnmarshalBinary(data)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+}
+
+func UnmarshalBinary(data []byte) (Record, error) {
+	if len(data) < leaderLength+1 {
+		return Record{}, fmt.Errorf("%w: record is shorter than leader", ErrInvalidRecord)
+	}
+	leader := string(data[:leaderLength])
+	baseAddress, err := strconv.Atoi(leader[12:17])
+	if err != nil || baseAddress <= leaderLength || baseAddress > len(data) {
+		return Record{}, fmt.Errorf("%w: bad base address %q", ErrInvalidRecord, leader[12:17])
+	}
+
+	record := Record{Leader: leader}
+	directory := data[leaderLength : baseAddress-1]
+	if len(directory)%directoryEntryLength != 0 {
+		return Record{}, fmt.Errorf("%w: bad directory length %d", ErrInvalidRecord, len(directory))
+	}
+	for i := 0; i < len(directory); i += directoryEntryLength {
+		entry := string(directory[i : i+directoryEntryLength])
+		length, lengthErr := strconv.Atoi(entry[3:7])
+		start, startErr := strconv.Atoi(entry[7:12])
+		if lengthErr != nil || startErr != nil || length < 1 || baseAddress+start+length > len(data) {
+			return Record{}, fmt.Errorf("%w: bad directory entry %q", ErrInvalidRecord, entry)
+		}
+		tag := entry[:3]
+		value := string(data[baseAddress+start : baseAddress+start+length-1])
+		if isControlTag(tag) {
+			record.AddControlField(tag, value)
+			continue
+		}
+		record.DataFields = append(record.DataFields, parseDataField(tag, value))
+	}
+	return record, nil
+}
+
+func parseDataField(tag, value string) DataField {
+	field := DataField{Tag: tag, Ind1: ' ', Ind2: ' '}
+	if len(value) >= 2 {
+		field.Ind1, field.Ind2 = value[0], value[1]
+		value = value[2:]
+	}
+	for _, part := range strings.Split(value, string(rune(subfieldDelimiter))) {
+		if part == "" {
+			continue
+		}
+		field.Subfields = append(field.Subfields, Subfield{Code: part[0], Value: part[1:]})
+	}
+	return field
+}
@@ -0,0 +1,83 @@
+package marc
+
+import "strings"
+
+const (
+	DefaultLeader = "00000nam a2200000 i 4500"
+
+	leaderLength = 24
+)
+
+type Subfield struct {
+	Code  byte
+	Value string
+}
+
+type ControlField struct {
+	Tag   string
+	Value string
+}
+
+type DataField struct {
+	Tag       string
+	Ind1      byte
+	Ind2      byte
+	Subfields []Subfield
+}
+
+type Record struct {
+	Leader        string
+	ControlFields []ControlField
+	DataFields    []DataField
+}
+
+func NewRecord() Record {
+	return Record{Leader: DefaultLeader}
+}
+
+func (r *Record) AddControlField(tag, value string) {
+	r.ControlFields = append(r.ControlFields, ControlField{Tag: tag, Value: value})
+}
+
+func (r *Record) AddDataField(tag string, ind1, ind2 byte, subfields ...Subfield) {
+	r.DataFields = append(r.DataFields, DataField{Tag: tag, Ind1: ind1, Ind2: ind2, Subfields: subfields})
+}
+
+func (r Record) ControlField(tag string) string {
+	for _, field := range r.ControlFields {
+		if field.Tag == tag {
+			return field.Value
+		}
+	}
+	return ""
+}
+
+func (r Record) DataFieldsByTag(tag string) []DataField {
+	var fields []DataField
+	for _, field := range r.DataFields {
+		if field.Tag == tag {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+func (f DataField) Subfield(code byte) string {
+	for _, subfield := range f.Subfields {
+		if subfield.Code == code {
+			return subfield.Value
+		}
+	}
+	return ""
+}
+
+func isControlTag(tag string) bool {
+	return strings.HasPrefix(tag, "00")
+}
+
+func indicator(b byte) byte {
+	if b == 0 {
+		return ' '
+	}
+	return b
+}
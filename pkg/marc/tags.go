@@ -0,0 +1,17 @@
+package marc
+
+const (
+	TagControlNumber = "001"
+	TagISBN          = "020"
+	TagMainAuthor    = "100"
+	TagTitle         = "245"
+	TagPublication   = "260"
+	TagProduction    = "264"
+	TagSummary       = "520"
+	TagSubject       = "650"
+	TagAddedAuthor   = "700"
+)
+
+// QualifierPseudonym goes to $c of a personal name heading that is a
+// pseudonym, ind1 "0" alone does not tell it from a forename.
+const QualifierPseudonym = "(pseudonym)"
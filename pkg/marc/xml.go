@@ -0,0 +1,126 @@
+package marc
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+const Namespace = "http://www.loc.gov/MARC21/slim"
+
+type xmlCollection struct {
+	XMLName xml.Name    `xml:"http://www.loc.gov/MARC21/slim collection"`
+	Records []xmlRecord `xml:"record"`
+}
+
+type xmlRecord struct {
+	XMLName       xml.Name          `xml:"http://www.loc.gov/MARC21/slim record"`
+	Leader        string            `xml:"leader"`
+	ControlFields []xmlControlField `xml:"controlfield"`
+	DataFields    []xmlDataField    `xml:"datafield"`
+}
+
+type xmlControlField struct {
+	Tag   string `xml:"tag,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xmlDataField struct {
+	Tag       string        `xml:"tag,attr"`
+	Ind1      string        `xml:"ind1,attr"`
+	Ind2      string        `xml:"ind2,attr"`
+	Subfields []xmlSubfield `xml:"subfield"`
+}
+
+type xmlSubfield struct {
+	Code  string `xml:"code,attr"`
+	Value string `xml:",chardata"`
+}
+
+func WriteXML(writer io.Writer, records ...Record) error {
+	collection := xmlCollection{Records: make([]xmlRecord, len(records))}
+	for i, record := range records {
+		collection.Records[i] = toXMLRecord(record)
+	}
+	if _, err := io.WriteString(writer, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(collection); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+func ReadXML(reader io.Reader) ([]Record, error) {
+	decoder := xml.NewDecoder(reader)
+	var records []Record
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidRecord, err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "record" {
+			continue
+		}
+		var record xmlRecord
+		if err = decoder.DecodeElement(&record, &start); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidRecord, err)
+		}
+		records = append(records, fromXMLRecord(record))
+	}
+}
+
+func toXMLRecord(record Record) xmlRecord {
+	result := xmlRecord{Leader: record.Leader}
+	if len(result.Leader) != leaderLength {
+		result.Leader = DefaultLeader
+	}
+	for _, field := range record.ControlFields {
+		result.ControlFields = append(result.ControlFields, xmlControlField{Tag: field.Tag, Value: field.Value})
+	}
+	for _, field := range record.DataFields {
+		dataField := xmlDataField{
+			Tag:  field.Tag,
+			Ind1: string(indicator(field.Ind1)),
+			Ind2: string(indicator(field.Ind2)),
+		}
+		for _, subfield := range field.Subfields {
+			dataField.Subfields = append(
+				dataField.Subfields, xmlSubfield{Code: string(subfield.Code), Value: subfield.Value},
+			)
+		}
+		result.DataFields = append(result.DataFields, dataField)
+	}
+	return result
+}
+
+func fromXMLRecord(record xmlRecord) Record {
+	result := Record{Leader: record.Leader}
+	for _, field := range record.ControlFields {
+		result.AddControlField(field.Tag, field.Value)
+	}
+	for _, field := range record.DataFields {
+		dataField := DataField{Tag: field.Tag, Ind1: firstByte(field.Ind1), Ind2: firstByte(field.Ind2)}
+		for _, subfield := range field.Subfields {
+			if subfield.Code == "" {
+				continue
+			}
+			dataField.Subfields = append(dataField.Subfields, Subfield{Code: subfield.Code[0], Value: subfield.Value})
+		}
+		result.DataFields = append(result.DataFields, dataField)
+	}
+	return result
+}
+
+func firstByte(s string) byte {
+	if s == "" {
+		return ' '
+	}
+	return s[0]
+}
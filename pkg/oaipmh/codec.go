@@ -0,0 +1,18 @@
+package oaipmh
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+func Write(writer io.Writer, response Response) error {
+	if _, err := io.WriteString(writer, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(response); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
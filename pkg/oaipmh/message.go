@@ -0,0 +1,203 @@
+package oaipmh
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+const (
+	Namespace        = "http://www.openarchives.org/OAI/2.0/"
+	SchemaLocation   = Namespace + " http://www.openarchives.org/OAI/2.0/OAI-PMH.xsd"
+	ProtocolVersion  = "2.0"
+	NamespaceXSI     = "http://www.w3.org/2001/XMLSchema-instance"
+	NamespaceOAIDC   = "http://www.openarchives.org/OAI/2.0/oai_dc/"
+	SchemaOAIDC      = "http://www.openarchives.org/OAI/2.0/oai_dc.xsd"
+	NamespaceDC      = "http://purl.org/dc/elements/1.1/"
+	MetadataPrefixDC = "oai_dc"
+
+	GranularitySeconds = "YYYY-MM-DDThh:mm:ssZ"
+	DeletedPersistent  = "persistent"
+	StatusDeleted      = "deleted"
+
+	datestampLayout = "2006-01-02T15:04:05Z"
+	dayLayout       = "2006-01-02"
+)
+
+const (
+	VerbIdentify            = "Identify"
+	VerbListMetadataFormats = "ListMetadataFormats"
+	VerbListSets            = "ListSets"
+	VerbListIdentifiers     = "ListIdentifiers"
+	VerbListRecords         = "ListRecords"
+	VerbGetRecord           = "GetRecord"
+)
+
+const (
+	ErrorBadArgument             = "badArgument"
+	ErrorBadResumptionToken      = "badResumptionToken"
+	ErrorBadVerb                 = "badVerb"
+	ErrorCannotDisseminateFormat = "cannotDisseminateFormat"
+	ErrorIDDoesNotExist          = "idDoesNotExist"
+	ErrorNoRecordsMatch          = "noRecordsMatch"
+	ErrorNoMetadataFormats       = "noMetadataFormats"
+	ErrorNoSetHierarchy          = "noSetHierarchy"
+)
+
+type Response struct {
+	XMLName             xml.Name             `xml:"OAI-PMH"`
+	Xmlns               string               `xml:"xmlns,attr"`
+	XSI                 string               `xml:"xmlns:xsi,attr"`
+	SchemaLocation      string               `xml:"xsi:schemaLocation,attr"`
+	ResponseDate        string               `xml:"responseDate"`
+	Request             Request              `xml:"request"`
+	Errors              []Error              `xml:"error"`
+	Identify            *Identify            `xml:"Identify"`
+	ListMetadataFormats *ListMetadataFormats `xml:"ListMetadataFormats"`
+	ListSets            *ListSets            `xml:"ListSets"`
+	GetRecord           *GetRecord           `xml:"GetRecord"`
+	ListIdentifiers     *ListIdentifiers     `xml:"ListIdentifiers"`
+	ListRecords         *ListRecords         `xml:"ListRecords"`
+}
+
+type Request struct {
+	URL             string `xml:",chardata"`
+	Verb            string `xml:"verb,attr,omitempty"`
+	Identifier      string `xml:"identifier,attr,omitempty"`
+	MetadataPrefix  string `xml:"metadataPrefix,attr,omitempty"`
+	From            string `xml:"from,attr,omitempty"`
+	Until           string `xml:"until,attr,omitempty"`
+	Set             string `xml:"set,attr,omitempty"`
+	ResumptionToken string `xml:"resumptionToken,attr,omitempty"`
+}
+
+type Error struct {
+	Code    string `xml:"code,attr"`
+	Message string `xml:",chardata"`
+}
+
+func (e Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+type Identify struct {
+	RepositoryName    string   `xml:"repositoryName"`
+	BaseURL           string   `xml:"baseURL"`
+	ProtocolVersion   string   `xml:"protocolVersion"`
+	AdminEmails       []string `xml:"adminEmail"`
+	EarliestDatestamp string   `xml:"earliestDatestamp"`
+	DeletedRecord     string   `xml:"deletedRecord"`
+	Granularity       string   `xml:"granularity"`
+}
+
+type MetadataFormat struct {
+	MetadataPrefix    string `xml:"metadataPrefix"`
+	Schema            string `xml:"schema"`
+	MetadataNamespace string `xml:"metadataNamespace"`
+}
+
+type ListMetadataFormats struct {
+	Formats []MetadataFormat `xml:"metadataFormat"`
+}
+
+type Set struct {
+	Spec string `xml:"setSpec"`
+	Name string `xml:"setName"`
+}
+
+type ListSets struct {
+	Sets []Set `xml:"set"`
+}
+
+type Header struct {
+	Status     string   `xml:"status,attr,omitempty"`
+	Identifier string   `xml:"identifier"`
+	Datestamp  string   `xml:"datestamp"`
+	SetSpecs   []string `xml:"setSpec"`
+}
+
+type Record struct {
+	Header   Header    `xml:"header"`
+	Metadata *Metadata `xml:"metadata"`
+}
+
+type Metadata struct {
+	DC DublinCore `xml:"oai_dc:dc"`
+}
+
+type DublinCore struct {
+	XmlnsOAIDC     string   `xml:"xmlns:oai_dc,attr"`
+	XmlnsDC        string   `xml:"xmlns:dc,attr"`
+	XSI            string   `xml:"xmlns:xsi,attr"`
+	SchemaLocation string   `xml:"xsi:schemaLocation,attr"`
+	Titles         []string `xml:"dc:title"`
+	Creators       []string `xml:"dc:creator"`
+	Subjects       []string `xml:"dc:subject"`
+	Descriptions   []string `xml:"dc:description"`
+	Publishers     []string `xml:"dc:publisher"`
+	Dates          []string `xml:"dc:date"`
+	Types          []string `xml:"dc:type"`
+	Identifiers    []string `xml:"dc:identifier"`
+	Languages      []string `xml:"dc:language"`
+}
+
+func NewDublinCore() DublinCore {
+	return DublinCore{
+		XmlnsOAIDC:     NamespaceOAIDC,
+		XmlnsDC:        NamespaceDC,
+		XSI:            NamespaceXSI,
+		SchemaLocation: NamespaceOAIDC + " " + SchemaOAIDC,
+	}
+}
+
+type ResumptionToken struct {
+	Value            string `xml:",chardata"`
+	Cursor           int    `xml:"cursor,attr"`
+	CompleteListSize int    `xml:"completeListSize,attr,omitempty"`
+}
+
+type GetRecord struct {
+	Record Record `xml:"record"`
+}
+
+type ListIdentifiers struct {
+	Headers         []Header         `xml:"header"`
+	ResumptionToken *ResumptionToken `xml:"resumptionToken"`
+}
+
+type ListRecords struct {
+	Records         []Record         `xml:"record"`
+	ResumptionToken *ResumptionToken `xml:"resumptionToken"`
+}
+
+func NewResponse(request Request, now time.Time) Response {
+	return Response{
+		Xmlns:          Namespace,
+		XSI:            NamespaceXSI,
+		SchemaLocation: SchemaLocation,
+		ResponseDate:   FormatDatestamp(now),
+		Request:        request,
+	}
+}
+
+func FormatDatestamp(t time.Time) string {
+	return t.UTC().Format(datestampLayout)
+}
+
+// ParseDatestamp accepts both day and seconds granularity and reports which
+// one was used, so callers can widen an inclusive until bound accordingly.
+// time.Parse would also take fractional seconds, the protocol does not.
+func ParseDatestamp(value string) (time.Time, bool, error) {
+	if len(value) > len(dayLayout) {
+		if len(value) != len(datestampLayout) {
+			return time.Time{}, false, fmt.Errorf("datestamp %q has an illegal granularity", value)
+		}
+		t, err := time.Parse(datestampLayout, value)
+		return t, false, err
+	}
+	t, err := time.Parse(dayLayout, value)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return t, true, nil
+}
@@ -0,0 +1,133 @@
+package oaipmh
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the expected responses in testdata")
+
+func TestParseDatestamp(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+		day   bool
+		fails bool
+	}{
+		{value: "2024-03-05T10:11:12Z", want: time.Date(2024, 3, 5, 10, 11, 12, 0, time.UTC)},
+		{value: "2024-03-05", want: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), day: true},
+		{value: "2024-02-29", want: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), day: true},
+		{value: "2024-03-05T10:11Z", fails: true},
+		{value: "2024-03-05T10:11:12+01:00", fails: true},
+		{value: "2024-03-05T10:11:12.5Z", fails: true},
+		{value: "2023-02-29", fails: true},
+		{value: "2024-03", fails: true},
+		{value: "", fails: true},
+	}
+	for _, test := range tests {
+		got, day, err := ParseDatestamp(test.value)
+		if test.fails {
+			if err == nil {
+				t.Errorf("ParseDatestamp(%q) = %v, want an error", test.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseDatestamp(%q) error = %v", test.value, err)
+			continue
+		}
+		if !got.Equal(test.want) || day != test.day {
+			t.Errorf("ParseDatestamp(%q) = %v, %v, want %v, %v", test.value, got, day, test.want, test.day)
+		}
+	}
+}
+
+func TestFormatDatestamp(t *testing.T) {
+	moment := time.Date(2024, 3, 5, 12, 11, 12, 999, time.FixedZone("CET", 2*60*60))
+	if got, want := FormatDatestamp(moment), "2024-03-05T10:11:12Z"; got != want {
+		t.Errorf("FormatDatestamp() = %q, want %q", got, want)
+	}
+}
+
+// TestWrite encodes responses of every shape the provider sends and compares
+// them with the XML files in testdata.
+func TestWrite(t *testing.T) {
+	responseDate := time.Date(2024, 3, 5, 10, 11, 12, 0, time.UTC)
+	listRecords := NewResponse(
+		Request{URL: "https://books.example.org/oai", Verb: VerbListRecords, MetadataPrefix: MetadataPrefixDC},
+		responseDate,
+	)
+	dc := NewDublinCore()
+	dc.Titles = []string{"Der Prozess"}
+	dc.Creators = []string{"Franz Kafka"}
+	dc.Subjects = []string{"Roman"}
+	dc.Publishers = []string{"Fischer & Söhne"}
+	dc.Dates = []string{"1925-04-26"}
+	dc.Types = []string{"Text"}
+	dc.Identifiers = []string{"urn:uuid:3f1b0c8e-7a51-4c43-9a8e-2b0f6d1c9e01", "urn:isbn:9783596294312"}
+	listRecords.ListRecords = &ListRecords{
+		Records: []Record{
+			{
+				Header: Header{
+					Identifier: "oai:books.example.org:3f1b0c8e-7a51-4c43-9a8e-2b0f6d1c9e01",
+					Datestamp:  "2024-03-01T08:00:00Z",
+					SetSpecs:   []string{"tag", "tag:8c2d5e1f-0b6a-4e3c-9d7f-1a2b3c4d5e6f"},
+				},
+				Metadata: &Metadata{DC: dc},
+			},
+			{
+				Header: Header{
+					Status:     StatusDeleted,
+					Identifier: "oai:books.example.org:9d4e2f10-1c3b-4a5d-8e6f-7a8b9c0d1e2f",
+					Datestamp:  "2024-03-02T09:30:00Z",
+				},
+			},
+		},
+		ResumptionToken: &ResumptionToken{Value: "eyJkIjoiMjAyNC0wMy0wMlQwOTozMDowMFoifQ", Cursor: 100},
+	}
+
+	lastPage := NewResponse(Request{URL: "https://books.example.org/oai", Verb: VerbListIdentifiers}, responseDate)
+	lastPage.ListIdentifiers = &ListIdentifiers{
+		Headers: []Header{
+			{
+				Identifier: "oai:books.example.org:3f1b0c8e-7a51-4c43-9a8e-2b0f6d1c9e01",
+				Datestamp:  "2024-03-01T08:00:00Z",
+			},
+		},
+		ResumptionToken: &ResumptionToken{Cursor: 200},
+	}
+
+	badArgument := NewResponse(Request{URL: "https://books.example.org/oai"}, responseDate)
+	badArgument.Errors = []Error{{Code: ErrorBadArgument, Message: "from must not be after until"}}
+
+	responses := map[string]Response{
+		"list_records":     listRecords,
+		"list_identifiers": lastPage,
+		"bad_argument":     badArgument,
+	}
+	for name, response := range responses {
+		t.Run(name, func(t *testing.T) {
+			var got bytes.Buffer
+			if err := Write(&got, response); err != nil {
+				t.Fatal(err)
+			}
+			goldenPath := filepath.Join("testdata", name+".xml")
+			if *update {
+				if err := os.WriteFile(goldenPath, got.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			golden, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Bytes(), golden) {
+				t.Fatalf("response differs from %s:\n%s", goldenPath, got.Bytes())
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package onix
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrInvalidMessage = errors.New("invalid onix message")
+
+var shortTags = map[string]string{
+	"ONIXmessage":       "ONIXMessage",
+	"header":            "Header",
+	"sender":            "Sender",
+	"x298":              "SenderName",
+	"x307":              "SentDateTime",
+	"m186":              "DefaultCurrencyCode",
+	"product":           "Product",
+	"a001":              "RecordReference",
+	"a002":              "NotificationType",
+	"productidentifier": "ProductIdentifier",
+	"b221":              "ProductIDType",
+	"b244":              "IDValue",
+	"descriptivedetail": "DescriptiveDetail",
+	"x314":              "ProductComposition",
+	"b012":              "ProductForm",
+	"titledetail":       "TitleDetail",
+	"b202":              "TitleType",
+	"titleelement":      "TitleElement",
+	"x409":              "TitleElementLevel",
+	"b203":              "TitleText",
+	"b030":              "TitlePrefix",
+	"b031":              "TitleWithoutPrefix",
+	"b029":              "Subtitle",
+	"contributor":       "Contributor",
+	"b034":              "SequenceNumber",
+	"b035":              "ContributorRole",
+	"b036":              "PersonName",
+	"b037":              "PersonNameInverted",
+	"b039":              "NamesBeforeKey",
+	"b040":              "KeyNames",
+	"b047":              "CorporateName",
+	"subject":           "Subject",
+	"b067":              "SubjectSchemeIdentifier",
+	"b069":              "SubjectCode",
+	"b070":              "SubjectHeadingText",
+	"collateraldetail":  "CollateralDetail",
+	"textcontent":       "TextContent",
+	"x426":              "TextType",
+	"x427":              "ContentAudience",
+	"d104":              "Text",
+	"publishingdetail":  "PublishingDetail",
+	"imprint":           "Imprint",
+	"b079":              "ImprintName",
+	"publisher":         "Publisher",
+	"b291":              "PublishingRole",
+	"b081":              "PublisherName",
+	"publishingdate":    "PublishingDate",
+	"x448":              "PublishingDateRole",
+	"b306":              "Date",
+	"productsupply":     "ProductSupply",
+	"supplydetail":      "SupplyDetail",
+	"supplier":          "Supplier",
+	"j292":              "SupplierRole",
+	"j137":              "SupplierName",
+	"j396":              "ProductAvailability",
+	"price":             "Price",
+	"x462":              "PriceType",
+	"j151":              "PriceAmount",
+	"j152":              "CurrencyCode",
+}
+
+// referenceTags renames ONIX short tags to their reference names and drops
+// namespaces, so both flavours decode into the same structs.
+type referenceTags struct {
+	decoder *xml.Decoder
+}
+
+func (r referenceTags) Token() (xml.Token, error) {
+	token, err := r.decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := token.(type) {
+	case xml.StartElement:
+		t.Name = referenceName(t.Name)
+		attrs := t.Attr[:0]
+		for _, attr := range t.Attr {
+			if attr.Name.Space == "" && attr.Name.Local != "xmlns" {
+				attrs = append(attrs, attr)
+			}
+		}
+		t.Attr = attrs
+		return t, nil
+	case xml.EndElement:
+		t.Name = referenceName(t.Name)
+		return t, nil
+	}
+	return token, nil
+}
+
+func referenceName(name xml.Name) xml.Name {
+	if reference, ok := shortTags[name.Local]; ok {
+		return xml.Name{Local: reference}
+	}
+	return xml.Name{Local: name.Local}
+}
+
+func Read(reader io.Reader) (Message, error) {
+	decoder := xml.NewTokenDecoder(referenceTags{decoder: xml.NewDecoder(reader)})
+	var message Message
+	if err := decoder.Decode(&message); err != nil {
+		return Message{}, fmt.Errorf("%w: %s", ErrInvalidMessage, err)
+	}
+	return message, nil
+}
+
+func Write(writer io.Writer, message Message) error {
+	message.Xmlns = Namespace
+	message.Release = Release
+	if _, err := io.WriteString(writer, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(message); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
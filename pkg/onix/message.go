@@ -0,0 +1,198 @@
+package onix
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+const (
+	Namespace = "http://ns.editeur.org/onix/3.0/reference"
+	Release   = "3.0"
+
+	NotificationConfirmed = "03"
+
+	ProductIDTypeProprietary = "01"
+	ProductIDTypeGTIN13      = "03"
+	ProductIDTypeISBN13      = "15"
+
+	ProductCompositionSingleItem = "00"
+	ProductFormBook              = "BA"
+
+	TitleTypeDistinctive = "01"
+	TitleElementProduct  = "01"
+
+	ContributorRoleAuthor = "A01"
+
+	SubjectSchemeKeywords = "20"
+
+	TextTypeShortDescription = "02"
+	TextTypeDescription      = "03"
+	ContentAudienceAny       = "00"
+	TextFormatHTML           = "02"
+
+	PublishingRolePublisher  = "01"
+	PublishingDateRoleDate   = "01"
+	DateFormatYearMonthDay   = "00"
+	SupplierRolePublisher    = "01"
+	ProductAvailabilityCheck = "99"
+	PriceTypeRRPIncludingTax = "02"
+)
+
+type Message struct {
+	XMLName  xml.Name  `xml:"ONIXMessage"`
+	Xmlns    string    `xml:"xmlns,attr,omitempty"`
+	Release  string    `xml:"release,attr"`
+	Header   Header    `xml:"Header"`
+	Products []Product `xml:"Product"`
+}
+
+type Header struct {
+	Sender              Sender `xml:"Sender"`
+	SentDateTime        string `xml:"SentDateTime"`
+	DefaultCurrencyCode string `xml:"DefaultCurrencyCode,omitempty"`
+}
+
+type Sender struct {
+	SenderName string `xml:"SenderName"`
+}
+
+type Product struct {
+	RecordReference    string              `xml:"RecordReference"`
+	NotificationType   string              `xml:"NotificationType"`
+	ProductIdentifiers []ProductIdentifier `xml:"ProductIdentifier"`
+	DescriptiveDetail  *DescriptiveDetail  `xml:"DescriptiveDetail"`
+	CollateralDetail   *CollateralDetail   `xml:"CollateralDetail"`
+	PublishingDetail   *PublishingDetail   `xml:"PublishingDetail"`
+	ProductSupplies    []ProductSupply     `xml:"ProductSupply"`
+}
+
+type ProductIdentifier struct {
+	ProductIDType string `xml:"ProductIDType"`
+	IDValue       string `xml:"IDValue"`
+}
+
+type DescriptiveDetail struct {
+	ProductComposition string        `xml:"ProductComposition"`
+	ProductForm        string        `xml:"ProductForm"`
+	TitleDetails       []TitleDetail `xml:"TitleDetail"`
+	Contributors       []Contributor `xml:"Contributor"`
+	Subjects           []Subject     `xml:"Subject"`
+}
+
+type TitleDetail struct {
+	TitleType     string         `xml:"TitleType"`
+	TitleElements []TitleElement `xml:"TitleElement"`
+}
+
+type TitleElement struct {
+	TitleElementLevel  string `xml:"TitleElementLevel"`
+	TitleText          string `xml:"TitleText,omitempty"`
+	TitlePrefix        string `xml:"TitlePrefix,omitempty"`
+	TitleWithoutPrefix string `xml:"TitleWithoutPrefix,omitempty"`
+	Subtitle           string `xml:"Subtitle,omitempty"`
+}
+
+type Contributor struct {
+	SequenceNumber     int      `xml:"SequenceNumber,omitempty"`
+	ContributorRoles   []string `xml:"ContributorRole"`
+	PersonName         string   `xml:"PersonName,omitempty"`
+	PersonNameInverted string   `xml:"PersonNameInverted,omitempty"`
+	NamesBeforeKey     string   `xml:"NamesBeforeKey,omitempty"`
+	KeyNames           string   `xml:"KeyNames,omitempty"`
+	CorporateName      string   `xml:"CorporateName,omitempty"`
+}
+
+type Subject struct {
+	SubjectSchemeIdentifier string `xml:"SubjectSchemeIdentifier"`
+	SubjectCode             string `xml:"SubjectCode,omitempty"`
+	SubjectHeadingText      string `xml:"SubjectHeadingText,omitempty"`
+}
+
+type CollateralDetail struct {
+	TextContents []TextContent `xml:"TextContent"`
+}
+
+type TextContent struct {
+	TextType        string `xml:"TextType"`
+	ContentAudience string `xml:"ContentAudience"`
+	Text            Text   `xml:"Text"`
+}
+
+type Text struct {
+	TextFormat string `xml:"textformat,attr,omitempty"`
+	Value      string `xml:",chardata"`
+}
+
+// UnmarshalXML flattens XHTML text content into its character data.
+func (t *Text) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "textformat" {
+			t.TextFormat = attr.Value
+		}
+	}
+	var value strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token := token.(type) {
+		case xml.CharData:
+			value.Write(token)
+		case xml.StartElement:
+			value.WriteByte(' ')
+		case xml.EndElement:
+			if token.Name == start.Name {
+				t.Value = strings.Join(strings.Fields(value.String()), " ")
+				return nil
+			}
+			value.WriteByte(' ')
+		}
+	}
+}
+
+type PublishingDetail struct {
+	Imprints        []Imprint        `xml:"Imprint"`
+	Publishers      []Publisher      `xml:"Publisher"`
+	PublishingDates []PublishingDate `xml:"PublishingDate"`
+}
+
+type Imprint struct {
+	ImprintName string `xml:"ImprintName"`
+}
+
+type Publisher struct {
+	PublishingRole string `xml:"PublishingRole"`
+	PublisherName  string `xml:"PublisherName"`
+}
+
+type PublishingDate struct {
+	PublishingDateRole string `xml:"PublishingDateRole"`
+	Date               Date   `xml:"Date"`
+}
+
+type Date struct {
+	DateFormat string `xml:"dateformat,attr,omitempty"`
+	Value      string `xml:",chardata"`
+}
+
+type ProductSupply struct {
+	SupplyDetails []SupplyDetail `xml:"SupplyDetail"`
+}
+
+type SupplyDetail struct {
+	Supplier            Supplier `xml:"Supplier"`
+	ProductAvailability string   `xml:"ProductAvailability"`
+	Prices              []Price  `xml:"Price"`
+}
+
+type Supplier struct {
+	SupplierRole string `xml:"SupplierRole"`
+	SupplierName string `xml:"SupplierName"`
+}
+
+type Price struct {
+	PriceType    string `xml:"PriceType,omitempty"`
+	PriceAmount  string `xml:"PriceAmount"`
+	CurrencyCode string `xml:"CurrencyCode,omitempty"`
+}